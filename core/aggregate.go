@@ -0,0 +1,592 @@
+package core
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"ne_database/core/base"
+	"ne_database/core/tableschema"
+	"ne_database/utils"
+	"ne_database/utils/set"
+)
+
+// aggregatePartitionLevels 哈希聚合最多分区的层数，分区之后仍然超过内存限制的分区，到达这个层数时改为排序聚合
+const aggregatePartitionLevels = 4
+
+// aggregateState 一个分组中一个聚合函数的中间结果，Null 不参与计算
+type aggregateState struct {
+	count int64
+	sum   int64
+	value []byte // min / max 当前的值
+}
+
+// aggregateGroup 一个分组，values 为分组列的值（取分组中第一行的值）
+type aggregateGroup struct {
+	key    []byte
+	values [][]byte
+	states []*aggregateState
+}
+
+// aggregator 聚合查询的执行计划
+// 分组按照分组列的 IndexKey 比较，所以带排序规则的字符列按照排序规则分组；结果按照分组的 key 排序
+type aggregator struct {
+	tableInfo   *tableschema.TableMetaInfo
+	query       *base.AggregateQuery
+	groupFields []*tableschema.FieldInfo
+	aggFields   []*tableschema.FieldInfo // 聚合的列，count(*) 为 nil
+	resultInfo  *tableschema.TableMetaInfo
+	resultNames []string // 聚合结果的列名
+	// distinct count_distinct 的值不在分组中去重，而是写入外部排序: 分组的 key、聚合函数的下标、值的 IndexKey，
+	// 最后按照分组的顺序计算去重后的数量（见 distinctCounter），没有 count_distinct 时为 nil
+	distinct *externalSorter
+}
+
+// recordScanner 依次把聚合的每一行（见 aggregator.record）交给 fn，fn 返回错误时停止
+type recordScanner func(fn func(record spillRecord) base.StandardError) base.StandardError
+
+func aggregateError(errMsg string) base.StandardError {
+	utils.LogError("[aggregator] " + errMsg)
+	return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+}
+
+// aggregateResultName 聚合结果的列名
+func aggregateResultName(item *base.AggregateItem) string {
+	if item.Alias != "" {
+		return item.Alias
+	}
+	column := item.Column
+	if column == "" {
+		column = "*"
+	}
+	return fmt.Sprintf("%s(%s)", item.Function, column)
+}
+
+// newAggregator 校验聚合查询，生成结果的表结构（用于 Having）
+// count / count_distinct / sum / avg 的结果为 bigint，avg 为截断后的整数；min / max 的结果和列的类型相同
+func newAggregator(tableInfo *tableschema.TableMetaInfo, query *base.AggregateQuery) (*aggregator, base.StandardError) {
+	if query == nil || len(query.GroupBy)+len(query.Aggregates) == 0 {
+		return nil, aggregateError("聚合查询的分组和聚合函数都为空")
+	}
+	a := &aggregator{tableInfo: tableInfo, query: query}
+	resultFields := make([]*tableschema.FieldInfo, 0, len(query.GroupBy)+len(query.Aggregates))
+	existName := set.NewStringsSet()
+	for _, column := range query.GroupBy {
+		field, ok := tableInfo.FieldInfoByName(column)
+		if !ok {
+			return nil, aggregateError(fmt.Sprintf("分组的列<%s>不存在", column))
+		}
+		if existName.Contain(column) {
+			return nil, aggregateError(fmt.Sprintf("分组的列<%s>重复", column))
+		}
+		existName.Add(column)
+		a.groupFields = append(a.groupFields, field)
+		resultFields = append(resultFields, field)
+	}
+	for _, item := range query.Aggregates {
+		if item == nil {
+			return nil, aggregateError("聚合函数为空")
+		}
+		name := aggregateResultName(item)
+		if existName.Contain(name) {
+			return nil, aggregateError(fmt.Sprintf("聚合结果的列名<%s>重复", name))
+		}
+		existName.Add(name)
+		a.resultNames = append(a.resultNames, name)
+
+		var field *tableschema.FieldInfo
+		if item.Column != "" {
+			f, ok := tableInfo.FieldInfoByName(item.Column)
+			if !ok {
+				return nil, aggregateError(fmt.Sprintf("聚合函数<%s>的列<%s>不存在", name, item.Column))
+			}
+			field = f
+		}
+		a.aggFields = append(a.aggFields, field)
+		resultField := &tableschema.FieldInfo{Name: name, Length: base.DataByteLengthInt64, FieldType: tableschema.BigIntType}
+		switch item.Function {
+		case base.AggregateFunctionCount:
+		case base.AggregateFunctionCountDistinct, base.AggregateFunctionMin, base.AggregateFunctionMax:
+			if field == nil {
+				return nil, aggregateError(fmt.Sprintf("聚合函数<%s>需要指定列", name))
+			}
+			if item.Function != base.AggregateFunctionCountDistinct {
+				f := *field
+				f.Name = name
+				resultField = &f
+			}
+		case base.AggregateFunctionSum, base.AggregateFunctionAvg:
+			if field == nil || field.FieldType.GetType() != base.DBDataTypeBigInt {
+				return nil, aggregateError(fmt.Sprintf("聚合函数<%s>只能用于bigint类型的列", name))
+			}
+		default:
+			return nil, aggregateError(fmt.Sprintf("不支持的聚合函数: %s", item.Function))
+		}
+		resultFields = append(resultFields, resultField)
+	}
+	a.resultInfo = &tableschema.TableMetaInfo{
+		Name:                tableInfo.Name,
+		PrimaryKeyFieldInfo: resultFields[0],
+		ValueFieldInfo:      resultFields[1:],
+	}
+	return a, nil
+}
+
+// compositeIndexKey 多个列的值组合成的 key，每列为 4字节的长度 + IndexKey，相等的值得到的 key 相同
+func compositeIndexKey(fields []*tableschema.FieldInfo, values [][]byte) []byte {
+	r := make([]byte, 0)
+	for i, field := range fields {
+		indexKey := tableschema.IndexKey(field.FieldType, values[i])
+		r = binary.BigEndian.AppendUint32(r, uint32(len(indexKey)))
+		r = append(r, indexKey...)
+	}
+	return r
+}
+
+// record 取出一行中需要的列: 分组的 key、分组列的值、各个聚合函数的列的值
+func (a *aggregator) record(key []byte, values map[string][]byte) spillRecord {
+	row := func(name string) []byte {
+		if name == a.tableInfo.PrimaryKeyFieldInfo.Name {
+			return key
+		}
+		return values[name]
+	}
+	r := make(spillRecord, 0, 1+len(a.groupFields)+len(a.aggFields))
+	groupValues := make([][]byte, 0, len(a.groupFields))
+	for _, field := range a.groupFields {
+		groupValues = append(groupValues, row(field.Name))
+	}
+	r = append(r, compositeIndexKey(a.groupFields, groupValues))
+	r = append(r, groupValues...)
+	for _, field := range a.aggFields {
+		if field == nil {
+			r = append(r, nil)
+		} else {
+			r = append(r, row(field.Name))
+		}
+	}
+	return r
+}
+
+func (a *aggregator) newGroup(record spillRecord) *aggregateGroup {
+	g := &aggregateGroup{
+		key:    record[0],
+		values: record[1 : 1+len(a.groupFields)],
+		states: make([]*aggregateState, len(a.aggFields)),
+	}
+	for i := range g.states {
+		g.states[i] = &aggregateState{}
+	}
+	return g
+}
+
+// accumulate 把一行加入分组
+func (a *aggregator) accumulate(g *aggregateGroup, record spillRecord) base.StandardError {
+	for i, field := range a.aggFields {
+		state := g.states[i]
+		if field == nil {
+			state.count++
+			continue
+		}
+		value := record[1+len(a.groupFields)+i]
+		isNull, err := field.FieldType.IsNull(value)
+		if err != nil {
+			return err
+		}
+		if isNull {
+			continue
+		}
+		switch a.query.Aggregates[i].Function {
+		case base.AggregateFunctionCount:
+			state.count++
+		case base.AggregateFunctionCountDistinct:
+			indexKey := tableschema.IndexKey(field.FieldType, value)
+			err = a.distinct.Add(spillRecord{g.key, binary.BigEndian.AppendUint32(nil, uint32(i)), indexKey})
+			if err != nil {
+				return err
+			}
+		case base.AggregateFunctionSum, base.AggregateFunctionAvg:
+			n, err := base.ByteListToInt64(value)
+			if err != nil {
+				return err
+			}
+			if (n > 0 && state.sum > math.MaxInt64-n) || (n < 0 && state.sum < math.MinInt64-n) {
+				return aggregateError(fmt.Sprintf("聚合函数<%s>的结果超出bigint的范围", a.resultNames[i]))
+			}
+			state.sum += n
+			state.count++
+		case base.AggregateFunctionMin, base.AggregateFunctionMax:
+			replace := state.count == 0
+			if !replace {
+				if a.query.Aggregates[i].Function == base.AggregateFunctionMin {
+					replace, err = field.FieldType.Less(value, state.value)
+				} else {
+					replace, err = field.FieldType.Greater(value, state.value)
+				}
+				if err != nil {
+					return err
+				}
+			}
+			if replace {
+				state.value = value
+			}
+			state.count++
+		}
+	}
+	return nil
+}
+
+// result 分组的结果，第二个返回值为是否满足 Having
+func (a *aggregator) result(g *aggregateGroup) (map[string][]byte, bool, base.StandardError) {
+	r := make(map[string][]byte, len(a.groupFields)+len(a.aggFields))
+	for i, field := range a.groupFields {
+		r[field.Name] = g.values[i]
+	}
+	for i, item := range a.query.Aggregates {
+		state := g.states[i]
+		name := a.resultNames[i]
+		resultField, _ := a.resultInfo.FieldInfoByName(name)
+		value := resultField.FieldType.TrimRaw(resultField.NullValue())
+		switch item.Function {
+		case base.AggregateFunctionCount, base.AggregateFunctionCountDistinct:
+			value = tableschema.BigIntValue(state.count)
+		case base.AggregateFunctionSum:
+			if state.count > 0 {
+				value = tableschema.BigIntValue(state.sum)
+			}
+		case base.AggregateFunctionAvg:
+			if state.count > 0 {
+				value = tableschema.BigIntValue(state.sum / state.count)
+			}
+		case base.AggregateFunctionMin, base.AggregateFunctionMax:
+			if state.count > 0 {
+				value = state.value
+			}
+		}
+		r[name] = value
+	}
+	match, err := a.resultInfo.MatchWhereParts(a.query.Having, r, tableschema.NewRegexpCache())
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[aggregator.result] MatchWhereParts错误, %s", err.Error()))
+		return nil, false, err
+	}
+	return r, match, nil
+}
+
+// hashAggregate 哈希聚合，scan 给出的行在内存中按照分组的 key 聚合，内存中最多保存 limit 个分组，计算完的分组交给 fn（没有顺序）
+// 分组达到 limit 之后，内存中已有的分组继续计算，新的分组的行按照 key 的哈希值分区写入临时文件（和哈希连接一样），
+// 内存中的分组计算完之后再逐个分区聚合；分区仍然超过 limit 时继续分区，level 为分区的层数，超过 aggregatePartitionLevels 时改为排序聚合
+func (a *aggregator) hashAggregate(scan recordScanner, limit int, level int, fn func(g *aggregateGroup) base.StandardError) base.StandardError {
+	groups := make(map[string]*aggregateGroup)
+	var partitions *hashPartitions
+	defer func() {
+		if partitions != nil {
+			partitions.Close()
+		}
+	}()
+	err := scan(func(record spillRecord) base.StandardError {
+		g, ok := groups[string(record[0])]
+		if ok {
+			return a.accumulate(g, record)
+		}
+		if len(groups) < limit {
+			g = a.newGroup(record)
+			groups[string(g.key)] = g
+			return a.accumulate(g, record)
+		}
+		if partitions == nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[hashAggregate] 第%d层的分组超过内存限制<%d>，新的分组分区写入临时文件", level, limit))
+			partitions = newHashPartitions(1, level)
+		}
+		return partitions.add(0, record)
+	})
+	if err != nil {
+		return err
+	}
+	for _, g := range groups {
+		err = fn(g)
+		if err != nil {
+			return err
+		}
+	}
+	if partitions == nil {
+		return nil
+	}
+	groups = nil
+	err = partitions.finish()
+	if err != nil {
+		return err
+	}
+	for partition := 0; partition < hashPartitionCount; partition++ {
+		scanPartition := func(fn func(record spillRecord) base.StandardError) base.StandardError {
+			return partitions.each(0, partition, fn)
+		}
+		if level+1 < aggregatePartitionLevels {
+			err = a.hashAggregate(scanPartition, limit, level+1, fn)
+		} else {
+			err = a.sortAggregate(scanPartition, limit, fn)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sortAggregate 排序聚合，先按照分组的 key 外部排序（超过 limit 行时写入临时文件），再依次计算相邻的同一分组
+// 内存中只保存当前分组的中间结果，用于哈希聚合分区多次之后仍然超过内存限制的分区
+func (a *aggregator) sortAggregate(scan recordScanner, limit int, fn func(g *aggregateGroup) base.StandardError) base.StandardError {
+	sorter := newExternalSorter(func(x spillRecord, y spillRecord) int {
+		return bytes.Compare(x[0], y[0])
+	}, limit)
+	defer sorter.Close()
+	err := scan(sorter.Add)
+	if err != nil {
+		return err
+	}
+	var g *aggregateGroup
+	err = sorter.Each(func(record spillRecord) (bool, base.StandardError) {
+		if g != nil && !bytes.Equal(g.key, record[0]) {
+			err := fn(g)
+			if err != nil {
+				return false, err
+			}
+			g = nil
+		}
+		if g == nil {
+			g = a.newGroup(record)
+		}
+		err := a.accumulate(g, record)
+		return err == nil, err
+	})
+	if err != nil || g == nil {
+		return err
+	}
+	return fn(g)
+}
+
+// groupRecord 分组的中间结果: 分组的 key、分组列的值、每个聚合函数的 count、sum、min / max 的值，用于按照分组排序
+func (a *aggregator) groupRecord(g *aggregateGroup) spillRecord {
+	r := make(spillRecord, 0, 1+len(g.values)+3*len(g.states))
+	r = append(r, g.key)
+	r = append(r, g.values...)
+	for _, state := range g.states {
+		r = append(r, binary.BigEndian.AppendUint64(nil, uint64(state.count)), binary.BigEndian.AppendUint64(nil, uint64(state.sum)), state.value)
+	}
+	return r
+}
+
+// restoreGroup 从 groupRecord 恢复分组
+func (a *aggregator) restoreGroup(record spillRecord) *aggregateGroup {
+	g := &aggregateGroup{
+		key:    record[0],
+		values: record[1 : 1+len(a.groupFields)],
+		states: make([]*aggregateState, len(a.aggFields)),
+	}
+	for i := range g.states {
+		columns := record[1+len(a.groupFields)+3*i:]
+		g.states[i] = &aggregateState{
+			count: int64(binary.BigEndian.Uint64(columns[0])),
+			sum:   int64(binary.BigEndian.Uint64(columns[1])),
+			value: columns[2],
+		}
+	}
+	return g
+}
+
+// compareSpillRecords 逐列按照字节比较
+func compareSpillRecords(x spillRecord, y spillRecord) int {
+	for i := 0; i < len(x) && i < len(y); i++ {
+		c := bytes.Compare(x[i], y[i])
+		if c != 0 {
+			return c
+		}
+	}
+	return len(x) - len(y)
+}
+
+// distinctCounter 按照分组的顺序读取排序后的 count_distinct 的值（见 aggregator.distinct），相邻的相同的值只计算一次
+type distinctCounter struct {
+	it     *sortedIterator
+	record spillRecord
+	ok     bool
+}
+
+// newDistinctCounter 没有使用外部排序去重时返回 nil
+func (a *aggregator) newDistinctCounter() (*distinctCounter, base.StandardError) {
+	if a.distinct == nil {
+		return nil, nil
+	}
+	it, err := a.distinct.Iterator()
+	if err != nil {
+		return nil, err
+	}
+	c := &distinctCounter{it: it}
+	err = c.next()
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *distinctCounter) next() base.StandardError {
+	var err base.StandardError
+	c.record, c.ok, err = c.it.Next()
+	return err
+}
+
+// fill 设置分组的 count_distinct 的结果，需要按照分组的 key 的顺序调用
+func (c *distinctCounter) fill(g *aggregateGroup) base.StandardError {
+	if c == nil {
+		return nil
+	}
+	var previous spillRecord
+	for c.ok && bytes.Compare(c.record[0], g.key) <= 0 {
+		if bytes.Equal(c.record[0], g.key) && (previous == nil || compareSpillRecords(previous, c.record) != 0) {
+			g.states[binary.BigEndian.Uint32(c.record[1])].count++
+		}
+		previous = c.record
+		err := c.next()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *distinctCounter) Close() {
+	if c != nil {
+		c.it.Close()
+	}
+}
+
+// hasCountDistinct 是否有 count_distinct
+func (a *aggregator) hasCountDistinct() bool {
+	for _, item := range a.query.Aggregates {
+		if item.Function == base.AggregateFunctionCountDistinct {
+			return true
+		}
+	}
+	return false
+}
+
+// Aggregate 聚合查询，返回满足 Having 的分组数量和每个分组的结果: 分组列名、聚合结果的列名 -> 值，按照分组排序
+// 使用哈希聚合，分组太多时（见 config.OperatorMemoryRows）新的分组分区写入临时文件；count_distinct 的值使用外部排序去重，
+// 计算完的分组也使用外部排序按照分组排序，内存中保存的分组和值的数量都有上限；
+// 没有 GroupBy 时即使没有数据也返回一行；没有条件和分组、并且只有主键的 min / max 时直接读取B+树最左（右）边的叶子结点
+func (e *Engine) Aggregate(tableName string, query *base.AggregateQuery) (int64, []map[string][]byte, base.StandardError) {
+	tableName = e.qualifiedName(tableName)
+	tree, err := e.openTable(tableName)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Aggregate] openTable错误, %s", err.Error()))
+		return 0, nil, err
+	}
+	defer tree.DataManager.Close()
+	a, err := newAggregator(tree.TableInfo, query)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Aggregate] newAggregator错误, %s", err.Error()))
+		return 0, nil, err
+	}
+
+	limit := operatorMemoryRows()
+	if a.hasCountDistinct() {
+		a.distinct = newExternalSorter(compareSpillRecords, limit)
+		defer a.distinct.Close()
+	}
+	sorted := newExternalSorter(func(x spillRecord, y spillRecord) int {
+		return bytes.Compare(x[0], y[0])
+	}, limit)
+	defer sorted.Close()
+	addGroup := func(g *aggregateGroup) base.StandardError {
+		return sorted.Add(a.groupRecord(g))
+	}
+	if len(query.GroupBy) == 0 {
+		g, err := a.aggregateAll(tree)
+		if err == nil {
+			err = addGroup(g)
+		}
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Aggregate] aggregateAll错误, %s", err.Error()))
+			return 0, nil, err
+		}
+	} else {
+		err = a.hashAggregate(func(fn func(record spillRecord) base.StandardError) base.StandardError {
+			return tree.Scan(a.query.Where, func(key []byte, values map[string][]byte) (bool, base.StandardError) {
+				err := fn(a.record(key, values))
+				return err == nil, err
+			})
+		}, limit, 0, addGroup)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Aggregate] hashAggregate错误, %s", err.Error()))
+			return 0, nil, err
+		}
+	}
+
+	counter, err := a.newDistinctCounter()
+	if err != nil {
+		return 0, nil, err
+	}
+	defer counter.Close()
+	r := make([]map[string][]byte, 0)
+	err = sorted.Each(func(record spillRecord) (bool, base.StandardError) {
+		g := a.restoreGroup(record)
+		err := counter.fill(g)
+		if err != nil {
+			return false, err
+		}
+		row, match, err := a.result(g)
+		if err != nil {
+			return false, err
+		}
+		if match {
+			r = append(r, row)
+		}
+		return true, nil
+	})
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Aggregate] 计算分组结果错误, %s", err.Error()))
+		return 0, nil, err
+	}
+	return int64(len(r)), r, nil
+}
+
+// aggregateAll 没有分组时的聚合，全部数据为一个分组
+func (a *aggregator) aggregateAll(tree *BPlusTree) (*aggregateGroup, base.StandardError) {
+	g := a.newGroup(spillRecord{nil})
+	if len(a.query.Where) == 0 && a.onlyPrimaryKeyMinMax() {
+		for i, item := range a.query.Aggregates {
+			key, ok, err := tree.BoundaryKey(item.Function == base.AggregateFunctionMax)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				g.states[i].value = key
+				g.states[i].count = 1
+			}
+		}
+		return g, nil
+	}
+	err := tree.Scan(a.query.Where, func(key []byte, values map[string][]byte) (bool, base.StandardError) {
+		err := a.accumulate(g, a.record(key, values))
+		return err == nil, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// onlyPrimaryKeyMinMax 聚合函数是否都是主键的 min / max
+func (a *aggregator) onlyPrimaryKeyMinMax() bool {
+	for i, item := range a.query.Aggregates {
+		if item.Function != base.AggregateFunctionMin && item.Function != base.AggregateFunctionMax {
+			return false
+		}
+		if a.aggFields[i] != a.tableInfo.PrimaryKeyFieldInfo {
+			return false
+		}
+	}
+	return len(a.query.Aggregates) > 0
+}
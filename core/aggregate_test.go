@@ -0,0 +1,142 @@
+package core
+
+import (
+	"testing"
+
+	"ne_database/core/base"
+	"ne_database/core/tableschema"
+)
+
+// newTestAggregateEngine orders 表，dept 为分组列，amount 为 0 时是 Null
+func newTestAggregateEngine(t *testing.T) (*Engine, *tableschema.TableMetaInfo) {
+	t.Helper()
+	tableInfo := newTestTableInfo("engine_aggregate_orders", testCharField("dept", 10), testBigIntField("amount"))
+	e := newTestEngine(t, tableInfo)
+	data := []struct {
+		id     int64
+		dept   string
+		amount int64
+	}{{1, "a", 10}, {2, "a", 20}, {3, "b", 5}, {4, "b", 0}, {5, "c", 7}, {6, "a", 20}}
+	for _, d := range data {
+		insertTestRows(t, e, tableInfo.Name, map[string][]byte{"id": testInt64(d.id), "dept": []byte(d.dept), "amount": testInt64(d.amount)})
+	}
+	return e, tableInfo
+}
+
+func TestEngine_Aggregate_EmptyTable(t *testing.T) {
+	tableInfo := newTestTableInfo("engine_aggregate_empty", testBigIntField("amount"))
+	e := newTestEngine(t, tableInfo)
+	// 没有分组时返回一行，主键的 min / max 为 Null
+	count, rows, err := e.Aggregate(tableInfo.Name, &base.AggregateQuery{
+		Aggregates: []*base.AggregateItem{
+			{Function: base.AggregateFunctionCount},
+			{Function: base.AggregateFunctionMax, Column: "id"},
+		},
+	})
+	if err != nil || count != 1 || testInt64Values(rows, "count(*)") != "0" || testInt64Values(rows, "max(id)") != "0" {
+		t.Errorf("Aggregate empty table failed, got %d rows %#v, %v", count, rows, err)
+	}
+}
+
+func TestEngine_Aggregate(t *testing.T) {
+	e, tableInfo := newTestAggregateEngine(t)
+	groupQuery := &base.AggregateQuery{
+		GroupBy: []string{"dept"},
+		Aggregates: []*base.AggregateItem{
+			{Function: base.AggregateFunctionCount},
+			{Function: base.AggregateFunctionCount, Column: "amount"},
+			{Function: base.AggregateFunctionCountDistinct, Column: "amount"},
+			{Function: base.AggregateFunctionSum, Column: "amount", Alias: "total"},
+			{Function: base.AggregateFunctionAvg, Column: "amount"},
+			{Function: base.AggregateFunctionMin, Column: "amount"},
+			{Function: base.AggregateFunctionMax, Column: "amount"},
+		},
+		Having: []*base.WherePartItem{{TargetColumn: "count(*)", Operate: base.DataComparatorGreaterAndEqual, Args: [][]byte{testInt64(2)}}},
+	}
+	groupExpect := map[string]string{
+		"dept":     "a,b",
+		"count(*)": "3,2", "count(amount)": "3,1", "count_distinct(amount)": "2,1",
+		"total": "50,5", "avg(amount)": "16,5", "min(amount)": "10,5", "max(amount)": "20,5",
+	}
+
+	testCases := []struct {
+		name string
+		// memoryRows 算子在内存中保存的行数，为 0 时使用默认配置
+		memoryRows int
+		query      *base.AggregateQuery
+		// expect 列名 -> 按结果的顺序以逗号连接的值
+		expect map[string]string
+	}{
+		{"hash aggregate", 0, groupQuery, groupExpect},
+		// 分组超过内存限制时新的分组分区写入临时文件，count_distinct 使用外部排序去重，结果相同
+		{"spilled aggregate", 2, groupQuery, groupExpect},
+		// 内存中只保存一个分组时，其余的分组都写入分区
+		{"spilled aggregate with one group in memory", 1, groupQuery, groupExpect},
+		{
+			"spilled count_distinct", 1,
+			&base.AggregateQuery{Aggregates: []*base.AggregateItem{{Function: base.AggregateFunctionCountDistinct, Column: "amount"}}},
+			map[string]string{"count_distinct(amount)": "4"},
+		},
+		// 主键的 min / max 直接读取B+树两端的叶子结点
+		{
+			"primary key min max", 0,
+			&base.AggregateQuery{Aggregates: []*base.AggregateItem{
+				{Function: base.AggregateFunctionMin, Column: "id"},
+				{Function: base.AggregateFunctionMax, Column: "id"},
+			}},
+			map[string]string{"min(id)": "1", "max(id)": "6"},
+		},
+		// 带条件时扫描
+		{
+			"primary key max with where", 0,
+			&base.AggregateQuery{
+				Where:      testWhereEqual("dept", []byte("b")),
+				Aggregates: []*base.AggregateItem{{Function: base.AggregateFunctionMax, Column: "id"}},
+			},
+			map[string]string{"max(id)": "4"},
+		},
+	}
+
+	for i, c := range testCases {
+		restore := func() {}
+		if c.memoryRows > 0 {
+			// 归并每次只读取两个有序段
+			restore = setTestOperatorMemory(c.memoryRows, 2)
+		}
+		_, rows, err := e.Aggregate(tableInfo.Name, c.query)
+		restore()
+		if err != nil {
+			t.Errorf("case %d %s: unexpected error: %v", i, c.name, err)
+			continue
+		}
+		for column, expect := range c.expect {
+			r := testInt64Values(rows, column)
+			if column == "dept" {
+				r = testColumnValues(rows, column)
+			}
+			if r != expect {
+				t.Errorf("case %d %s: %s expected %s, but got %s", i, c.name, column, expect, r)
+			}
+		}
+		// 临时文件在查询结束后删除
+		checkNoSpillFiles(t)
+	}
+}
+
+func TestEngine_Aggregate_Error(t *testing.T) {
+	e, tableInfo := newTestAggregateEngine(t)
+	testCases := []*base.AggregateItem{
+		// sum 只能用于 bigint
+		{Function: base.AggregateFunctionSum, Column: "dept"},
+		// min 需要指定列
+		{Function: base.AggregateFunctionMin},
+		{Function: base.AggregateFunctionCount, Column: "unknown"},
+		{Function: "median", Column: "amount"},
+	}
+	for i, item := range testCases {
+		_, _, err := e.Aggregate(tableInfo.Name, &base.AggregateQuery{Aggregates: []*base.AggregateItem{item}})
+		if err == nil {
+			t.Errorf("case %d: expected error, but got nil", i)
+		}
+	}
+}
@@ -0,0 +1,368 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"ne_database/core/base"
+	"ne_database/core/dataio"
+	"ne_database/core/tableschema"
+	"ne_database/utils"
+)
+
+// AlterTable 修改表结构
+// 只修改表名、列名或约束时不处理数据，版本不变，表名修改通过 moveTableFiles 保证崩溃时的一致；其余修改表结构的版本加一:
+// 已有的数据能在读取时转换为新版本时（见 canAlterLazily），只写入新的表结构，旧版本的数据由 UpgradeTableRows 重写；
+// 否则按照新的表结构重写表数据，按主键顺序逐行读取原来的数据写入临时文件中新的B+树，再通过替换记录替换数据文件并写入新的表结构，
+// 同时修改表名时一并删除原来的表，中途崩溃时由 Init 继续完成，见 swapTableData
+// 修改表名或主键列名时，同时修改自增主键的序列名和其他表中引用该表的外键
+func (e *Engine) AlterTable(tableName string, items []*tableschema.AlterTableItem) base.StandardError {
+	tableName = e.qualifiedName(tableName)
+	err := e.checkNotView(tableName)
+	if err != nil {
+		return err
+	}
+	exist, err := e.CheckTableExist(tableName)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[AlterTable] CheckTableExist错误, %s", err.Error()))
+		return err
+	}
+	if !exist {
+		errMsg := fmt.Sprintf("表: %s 不存在", tableName)
+		utils.LogError("[Engine AlterTable] " + errMsg)
+		return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+	}
+	oldInfo, err := e.LoadTableSchemaInfo(tableName)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[AlterTable] LoadTableSchemaInfo错误, %s", err.Error()))
+		return err
+	}
+	newInfo, source, err := oldInfo.Alter(items)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[AlterTable] Alter错误, %s", err.Error()))
+		return err
+	}
+	renamed := newInfo.Name != tableName
+	if renamed {
+		exist, err = e.CheckTableExist(newInfo.Name)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[AlterTable] CheckTableExist错误, %s", err.Error()))
+			return err
+		}
+		if exist {
+			errMsg := fmt.Sprintf("表: %s 已存在", newInfo.Name)
+			utils.LogError("[Engine AlterTable] " + errMsg)
+			return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+		}
+		// 和建表一样，表名不能和视图重名
+		exist, err = e.viewExist(newInfo.Name)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[AlterTable] viewExist错误, %s", err.Error()))
+			return err
+		}
+		if exist {
+			errMsg := fmt.Sprintf("视图: %s 已存在", newInfo.Name)
+			utils.LogError("[Engine AlterTable] " + errMsg)
+			return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+		}
+	}
+	err = e.foreignKeyVerification(newInfo)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[AlterTable] foreignKeyVerification错误, %s", err.Error()))
+		return err
+	}
+	refs, err := e.referencingForeignKeys(tableName)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[AlterTable] referencingForeignKeys错误, %s", err.Error()))
+		return err
+	}
+
+	// 行数据的储存方式不变时不需要处理数据，否则尽量在读取时转换，都不行时重写数据
+	rewrite := false
+	if oldInfo.SameRowLayout(newInfo, source) {
+		oldInfo.KeepVersion(newInfo, source)
+	} else {
+		lazy, err := canAlterLazily(oldInfo, newInfo, source)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[AlterTable] canAlterLazily错误, %s", err.Error()))
+			return err
+		}
+		oldInfo.NextVersion(newInfo, source, lazy)
+		rewrite = !lazy
+	}
+
+	if !rewrite {
+		if renamed {
+			err = e.moveTableFiles(oldInfo, newInfo)
+		} else {
+			err = e.saveTableSchemaInfo(newInfo)
+		}
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[AlterTable] 写入新的表结构错误, %s", err.Error()))
+			return err
+		}
+	} else {
+		oldTree, err := e.openTable(tableName)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[AlterTable] openTable错误, %s", err.Error()))
+			return err
+		}
+		tempPath, err := e.writeTableDataFile(newInfo, source, func(fn func(key []byte, values map[string][]byte) (bool, base.StandardError)) base.StandardError {
+			return oldTree.Scan(nil, fn)
+		})
+		_ = oldTree.DataManager.Close()
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[AlterTable] writeTableDataFile错误, %s", err.Error()))
+			return err
+		}
+		from := ""
+		if renamed {
+			from = tableName
+		}
+		err = e.swapTableData(newInfo, tempPath, from)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[AlterTable] swapTableData错误, %s", err.Error()))
+			return err
+		}
+	}
+
+	if oldInfo.PrimaryKeyFieldInfo.AutoIncrement {
+		sequenceName := tableschema.AutoIncrementSequenceName(tableName, oldInfo.PrimaryKeyFieldInfo.Name)
+		newSequenceName := tableschema.AutoIncrementSequenceName(newInfo.Name, newInfo.PrimaryKeyFieldInfo.Name)
+		// 只修改表名时序列已经由 moveTableFiles 修改
+		exist, err = e.sequenceExist(sequenceName)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[AlterTable] CheckSequenceExist错误, %s", err.Error()))
+			return err
+		}
+		if sequenceName != newSequenceName && exist {
+			err = e.renameSequence(sequenceName, newSequenceName)
+			if err != nil {
+				utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[AlterTable] renameSequence错误, %s", err.Error()))
+				return err
+			}
+		}
+	}
+
+	for _, ref := range refs {
+		if ref.TableInfo.Name == tableName {
+			continue
+		}
+		ref.ForeignKey.RefTable = newInfo.Name
+		ref.ForeignKey.RefColumn = newInfo.PrimaryKeyFieldInfo.Name
+		err = e.saveTableSchemaInfo(ref.TableInfo)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[AlterTable] 修改表<%s>的外键错误, %s", ref.TableInfo.Name, err.Error()))
+			return err
+		}
+	}
+	return nil
+}
+
+// tableRowScanner 逐行提供表数据，fn 返回 false 时停止
+type tableRowScanner func(fn func(key []byte, values map[string][]byte) (bool, base.StandardError)) base.StandardError
+
+// getTableTempDataFilePath 重建表数据时的临时数据文件
+func getTableTempDataFilePath(tableName string) string {
+	database, n := tableschema.SplitTableName(tableName)
+	return fmt.Sprintf("%s%s.%s.%s", getDatabaseDirPath(database), n, base.DataIOFileTempSuffix, base.DataIOFileTableDataSuffix)
+}
+
+// rebuildTableData 按照新的表结构重建表数据，见 writeTableDataFile；写入之后把临时文件重命名为表的数据文件，出错时原来的数据文件不变
+func (e *Engine) rebuildTableData(tableInfo *tableschema.TableMetaInfo, source map[string]string, scan tableRowScanner) base.StandardError {
+	tempPath, err := e.writeTableDataFile(tableInfo, source, scan)
+	if err != nil {
+		return err
+	}
+	er := os.Rename(tempPath, getTableDataFilePath(tableInfo.Name))
+	if er != nil {
+		_ = os.Remove(tempPath)
+		errMsg := fmt.Sprintf("写入表<%s>的TableData发生错误: %s", tableInfo.Name, er.Error())
+		utils.LogError("[Engine rebuildTableData] " + errMsg)
+		return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeIO, base.ErrorBaseCodeIOError, fmt.Errorf(errMsg))
+	}
+	return nil
+}
+
+// refreshJournal 重写表数据时替换数据和表结构的记录，用于修改表结构和重建物化视图，新的数据写入临时数据文件之后写入，记录写入之后替换才生效
+// 记录写入之前崩溃时原来的数据和表结构都不变；之后崩溃时由 Init 继续完成替换，见 finishRefresh
+type refreshJournal struct {
+	Name      string `json:"name"`
+	TableInfo string `json:"table_info"`     // 新的表结构 json
+	TempPath  string `json:"temp_path"`      // 新的数据所在的临时数据文件
+	From      string `json:"from,omitempty"` // 同时修改表名时原来的表名
+}
+
+func getRefreshJournalFilePath(tableName string) string {
+	return getTableFilePath(tableName, base.DataIOFileRefreshJournalSuffix)
+}
+
+// swapTableData 写入替换记录，再用临时数据文件 tempPath 替换表 tableInfo.Name 的数据文件并写入新的表结构，见 finishRefresh
+// from 不为空时表名同时由 from 修改为 tableInfo.Name，替换之后删除原来的表；出错时删除临时数据文件，原来的数据和表结构不变
+func (e *Engine) swapTableData(tableInfo *tableschema.TableMetaInfo, tempPath string, from string) base.StandardError {
+	data, err := tableInfo.TableMetaInfoToJsonByte()
+	if err != nil {
+		_ = os.Remove(tempPath)
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[swapTableData] TableMetaInfoToJsonByte错误, %s", err.Error()))
+		return err
+	}
+	journal := &refreshJournal{Name: tableInfo.Name, TableInfo: string(data), TempPath: tempPath, From: from}
+	er := utils.WriteFileAtomic(getRefreshJournalFilePath(tableInfo.Name), []byte(utils.ToJSON(journal)), base.DataIOFileTempSuffix)
+	if er != nil {
+		_ = os.Remove(tempPath)
+		errMsg := fmt.Sprintf("写入表<%s>的替换记录发生错误: %s", tableInfo.Name, er.Error())
+		utils.LogError("[Engine swapTableData] " + errMsg)
+		return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeIO, base.ErrorBaseCodeIOError, fmt.Errorf(errMsg))
+	}
+	return e.finishRefresh(journal)
+}
+
+// finishRefresh 替换记录写入之后，用临时数据文件替换表的数据文件，写入新的表结构，修改了表名时删除原来的表，最后删除记录，重复执行不影响结果
+func (e *Engine) finishRefresh(journal *refreshJournal) base.StandardError {
+	refreshError := func(errMsg string) base.StandardError {
+		utils.LogError("[Engine finishRefresh] " + errMsg)
+		return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeIO, base.ErrorBaseCodeIOError, fmt.Errorf(errMsg))
+	}
+	exist, er := utils.FileExist(journal.TempPath)
+	if er != nil {
+		return refreshError(fmt.Sprintf("检查表<%s>的临时数据文件发生错误: %s", journal.Name, er.Error()))
+	}
+	if exist {
+		er = os.Rename(journal.TempPath, getTableDataFilePath(journal.Name))
+		if er != nil {
+			return refreshError(fmt.Sprintf("替换表<%s>的TableData发生错误: %s", journal.Name, er.Error()))
+		}
+	}
+	tableInfo, err := tableschema.InitTableMetaInfoByJson(journal.TableInfo)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[finishRefresh] InitTableMetaInfoByJson错误, %s", err.Error()))
+		return err
+	}
+	err = e.saveTableSchemaInfo(tableInfo)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[finishRefresh] saveTableSchemaInfo错误, %s", err.Error()))
+		return err
+	}
+	if journal.From != "" && journal.From != journal.Name {
+		for _, path := range []string{getTableDataFilePath(journal.From), getTableSchemaFilePath(journal.From)} {
+			er = os.Remove(path)
+			if er != nil && !os.IsNotExist(er) {
+				return refreshError(fmt.Sprintf("删除 %s 发生错误: %s", path, er.Error()))
+			}
+		}
+		err = e.catalogRemoveTable(journal.From)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[finishRefresh] catalogRemoveTable错误, %s", err.Error()))
+			return err
+		}
+	}
+	er = os.Remove(getRefreshJournalFilePath(journal.Name))
+	if er != nil && !os.IsNotExist(er) {
+		return refreshError(fmt.Sprintf("删除表<%s>的替换记录发生错误: %s", journal.Name, er.Error()))
+	}
+	return nil
+}
+
+// recoverRefreshJournal 继续完成一个已经写入替换记录的表数据的替换
+func (e *Engine) recoverRefreshJournal(journalPath string) base.StandardError {
+	data, er := os.ReadFile(journalPath)
+	if er != nil {
+		errMsg := fmt.Sprintf("读取 %s 发生错误: %s", journalPath, er.Error())
+		utils.LogError("[Engine recoverRefreshJournal] " + errMsg)
+		return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeIO, base.ErrorBaseCodeIOError, fmt.Errorf(errMsg))
+	}
+	journal := &refreshJournal{}
+	er = json.Unmarshal(data, journal)
+	if er != nil {
+		errMsg := fmt.Sprintf("解析 %s 发生错误: %s", journalPath, er.Error())
+		utils.LogError("[Engine recoverRefreshJournal] " + errMsg)
+		return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeIO, base.ErrorBaseCodeInnerDataError, fmt.Errorf(errMsg))
+	}
+	utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[recoverRefreshJournal] 继续完成表<%s>的替换", journal.Name))
+	return e.finishRefresh(journal)
+}
+
+// writeTableDataFile 按照新的表结构把数据写入临时数据文件，返回临时文件的路径
+// scan 逐行提供原来的数据，每行写入临时数据文件中新的B+树，不会把整个表读入内存；全部写入之后落盘，出错时删除临时文件
+// source 为 新列名 -> 原来的列名，新增的列使用默认值（没有默认值时为 Null），修改后的数据需要满足 CHECK 约束
+func (e *Engine) writeTableDataFile(tableInfo *tableschema.TableMetaInfo, source map[string]string, scan tableRowScanner) (string, base.StandardError) {
+	database, n := tableschema.SplitTableName(tableInfo.Name)
+	tempName := n + "." + base.DataIOFileTempSuffix
+	tempPath := getTableTempDataFilePath(tableInfo.Name)
+	dataManager, err := dataio.CreateFileManager(getDatabaseDirPath(database), tempName, tableInfo.PageSize)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[writeTableDataFile] CreateFileManager错误, %s", err.Error()))
+		return "", err
+	}
+	done := false
+	defer func() {
+		if !done {
+			_ = dataManager.Close()
+			_ = os.Remove(tempPath)
+		}
+	}()
+	tree, err := LoadBPlusTree(tableInfo, dataManager, true)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[writeTableDataFile] LoadBPlusTree错误, %s", err.Error()))
+		return "", err
+	}
+
+	err = scan(func(key []byte, oldValues map[string][]byte) (bool, base.StandardError) {
+		row := make(map[string][]byte, len(tableInfo.ValueFieldInfo)+1)
+		values := make([][]byte, 0, len(tableInfo.ValueFieldInfo))
+		for _, valueInfo := range tableInfo.ValueFieldInfo {
+			var (
+				value []byte
+				err   base.StandardError
+			)
+			if oldName, ok := source[valueInfo.Name]; ok {
+				value = valueInfo.FieldType.TrimRaw(oldValues[oldName])
+			} else {
+				value, ok, err = valueInfo.DefaultValueByte(e.NextSequenceValue)
+				if err != nil {
+					utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[writeTableDataFile] 列<%s>计算默认值错误, %s", valueInfo.Name, err.Error()))
+					return false, err
+				}
+				if !ok {
+					value = valueInfo.NullValue()
+				}
+			}
+			value, err = valueInfo.FieldType.LengthPadding(value, valueInfo.Length)
+			if err != nil {
+				utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[writeTableDataFile] 已有数据超过列<%s>的长度, %s", valueInfo.Name, err.Error()))
+				return false, err
+			}
+			row[valueInfo.Name] = value
+			values = append(values, value)
+		}
+		row[tableInfo.PrimaryKeyFieldInfo.Name] = key
+		err := tableInfo.CheckRow(row)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[writeTableDataFile] CheckRow错误, %s", err.Error()))
+			return false, err
+		}
+		err = tree.Insert(key, values)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[writeTableDataFile] tree.Insert错误, %s", err.Error()))
+			return false, err
+		}
+		return true, nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	err = dataManager.(*dataio.FileManager).Sync()
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[writeTableDataFile] Sync错误, %s", err.Error()))
+		return "", err
+	}
+	done = true
+	err = dataManager.Close()
+	if err != nil {
+		_ = os.Remove(tempPath)
+		return "", err
+	}
+	return tempPath, nil
+}
@@ -0,0 +1,195 @@
+package core
+
+import (
+	"testing"
+
+	"ne_database/core/base"
+	"ne_database/core/tableschema"
+	"ne_database/utils"
+)
+
+// newTestAlterEngine users 的主键自增，有 Alice、Bob、Catherine 三行；orders 引用 users
+func newTestAlterEngine(t *testing.T) (e *Engine, users, orders *tableschema.TableMetaInfo) {
+	t.Helper()
+	users = newTestTableInfo("engine_alter_users", testCharField("name", 10), testCharField("remark", 20))
+	users.PrimaryKeyFieldInfo.AutoIncrement = true
+	orders = newTestTableInfo("engine_alter_orders", testBigIntField("user_id"))
+	orders.ForeignKeys = []*tableschema.ForeignKey{{Column: "user_id", RefTable: users.Name, RefColumn: "id"}}
+	e = newTestEngine(t, users, orders)
+	for _, name := range []string{"Alice", "Bob", "Catherine"} {
+		insertTestRows(t, e, users.Name, map[string][]byte{"name": []byte(name), "remark": []byte("r-" + name)})
+	}
+	insertTestRows(t, e, orders.Name, map[string][]byte{"id": testInt64(1), "user_id": testInt64(1)})
+	return e, users, orders
+}
+
+func TestEngine_AlterTable_Error(t *testing.T) {
+	e, users, orders := newTestAlterEngine(t)
+	testCases := []struct {
+		tableName string
+		items     []*tableschema.AlterTableItem
+	}{
+		// 表不存在
+		{"engine_alter_not_exist", []*tableschema.AlterTableItem{{Action: base.AlterTableActionDropColumn, Column: "remark"}}},
+		// 已有数据超过新的长度
+		{users.Name, []*tableschema.AlterTableItem{{Action: base.AlterTableActionModifyLength, Column: "name", Length: 5}}},
+		// 新的表名已存在
+		{users.Name, []*tableschema.AlterTableItem{{Action: base.AlterTableActionRenameTable, NewName: orders.Name}}},
+		// 列不存在
+		{users.Name, []*tableschema.AlterTableItem{{Action: base.AlterTableActionDropColumn, Column: "age"}}},
+	}
+	for i, c := range testCases {
+		if err := e.AlterTable(c.tableName, c.items); err == nil {
+			t.Errorf("case %d: expected error, but got nil", i)
+		}
+	}
+
+	// 失败的修改不影响原来的表
+	tableInfo, err := e.LoadTableSchemaInfo(users.Name)
+	if err != nil || tableInfo.Version != users.Version || len(tableInfo.ValueFieldInfo) != 2 {
+		t.Errorf("unexpected table schema: %s, %v", utils.ToJSON(tableInfo), err)
+	}
+}
+
+func TestEngine_AlterTable(t *testing.T) {
+	e, users, _ := newTestAlterEngine(t)
+	err := e.AlterTable(users.Name, []*tableschema.AlterTableItem{
+		{Action: base.AlterTableActionAddColumn, Field: &tableschema.FieldInfo{Name: "level", Length: 8, FieldType: tableschema.BigIntType, DefaultValue: "3"}},
+		{Action: base.AlterTableActionDropColumn, Column: "remark"},
+		{Action: base.AlterTableActionRenameColumn, Column: "name", NewName: "nickname"},
+		{Action: base.AlterTableActionModifyLength, Column: "nickname", Length: 30},
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	tableInfo, err := e.LoadTableSchemaInfo(users.Name)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if len(tableInfo.ValueFieldInfo) != 2 || tableInfo.ValueFieldInfo[0].Name != "nickname" || tableInfo.ValueFieldInfo[0].Length != 30 || tableInfo.ValueFieldInfo[1].Name != "level" {
+		t.Errorf("AlterTable failed, got %s", utils.ToJSON(tableInfo))
+		return
+	}
+	// 行变长，数据已经重写，不保留旧版本
+	if tableInfo.Version != 2 || len(tableInfo.History) != 0 {
+		t.Errorf("AlterTable failed, got version %d, %d history", tableInfo.Version, len(tableInfo.History))
+		return
+	}
+
+	_, rows, err := e.Select(users.Name, nil)
+	if err != nil || len(rows) != 3 {
+		t.Errorf("AlterTable failed, got %d rows, %v", len(rows), err)
+		return
+	}
+	for i, expect := range []string{"Alice", "Bob", "Catherine"} {
+		r := tableschema.CharType.StringValue(rows[i]["nickname"]) + "," + tableschema.BigIntType.StringValue(rows[i]["level"])
+		if r != expect+",3" {
+			t.Errorf("row %d: expected %s,3, but got %s", i, expect, r)
+		}
+		if _, ok := rows[i]["remark"]; ok {
+			t.Errorf("row %d: remark not dropped", i)
+		}
+	}
+}
+
+func TestEngine_AlterTable_RenameTable(t *testing.T) {
+	e, users, orders := newTestAlterEngine(t)
+	newName := "engine_alter_members"
+	err := e.AlterTable(users.Name, []*tableschema.AlterTableItem{
+		{Action: base.AlterTableActionAddColumn, Field: &tableschema.FieldInfo{Name: "level", Length: 8, FieldType: tableschema.BigIntType}},
+		{Action: base.AlterTableActionRenameTable, NewName: newName},
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	defer e.DropTableCascade(newName)
+
+	exist, _ := e.CheckTableExist(users.Name)
+	if exist {
+		t.Error("AlterTable failed, old table still exists")
+		return
+	}
+	// 自增序列跟随新表名
+	_, key, err := e.Insert(newName, map[string][]byte{"name": []byte("Dan")})
+	if err != nil || tableschema.BigIntType.StringValue(key) != "4" {
+		t.Errorf("unexpected auto increment id: %s, %v", tableschema.BigIntType.StringValue(key), err)
+		return
+	}
+	// 引用的外键跟随新表名
+	ordersInfo, err := e.LoadTableSchemaInfo(orders.Name)
+	if err != nil || ordersInfo.ForeignKeys[0].RefTable != newName {
+		t.Errorf("AlterTable failed, foreign key not updated: %v", err)
+		return
+	}
+	if err = e.DeleteTable(newName); err == nil {
+		t.Error("expected error, but got nil")
+	}
+}
+
+func TestEngine_AlterTable_Recover(t *testing.T) {
+	tableInfo := newTestTableInfo("engine_alter_recover_users", testCharField("name", 10))
+	e := newTestEngine(t, tableInfo)
+	insertTestRows(t, e, tableInfo.Name, map[string][]byte{"id": testInt64(1), "name": []byte("Alice")})
+
+	// 修改表名并重写数据时写入替换记录之后崩溃，Init 继续完成替换并删除原来的表
+	newInfo, source, err := tableInfo.Alter([]*tableschema.AlterTableItem{
+		{Action: base.AlterTableActionAddColumn, Field: &tableschema.FieldInfo{Name: "level", Length: 8, FieldType: tableschema.BigIntType, DefaultValue: "3"}},
+		{Action: base.AlterTableActionRenameTable, NewName: "engine_alter_recover_members"},
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	tableInfo.NextVersion(newInfo, source, false)
+	defer e.DeleteTable(newInfo.Name)
+	oldTree, err := e.openTable(tableInfo.Name)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	tempPath, err := e.writeTableDataFile(newInfo, source, func(fn func(key []byte, values map[string][]byte) (bool, base.StandardError)) base.StandardError {
+		return oldTree.Scan(nil, fn)
+	})
+	_ = oldTree.DataManager.Close()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	newInfoJson, err := newInfo.TableMetaInfoToJsonByte()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	journal := &refreshJournal{Name: newInfo.Name, TableInfo: string(newInfoJson), TempPath: tempPath, From: tableInfo.Name}
+	er := utils.WriteFileAtomic(getRefreshJournalFilePath(newInfo.Name), []byte(utils.ToJSON(journal)), base.DataIOFileTempSuffix)
+	if er != nil {
+		t.Errorf("unexpected error: %v", er)
+		return
+	}
+	if err = e.Init(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	allTable, err := e.AllTable()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if _, ok := allTable[tableInfo.Name]; ok || len(allTable) != 1 {
+		t.Errorf("expected only %s, but got %d tables", newInfo.Name, len(allTable))
+		return
+	}
+	_, rows, err := e.Select(newInfo.Name, nil)
+	if err != nil || len(rows) != 1 || tableschema.BigIntType.StringValue(rows[0]["level"]) != "3" {
+		t.Errorf("expected recovered rows, but got %#v, %v", rows, err)
+		return
+	}
+	if exist, _ := utils.FileExist(getRefreshJournalFilePath(newInfo.Name)); exist {
+		t.Error("expected refresh journal to be removed")
+	}
+}
@@ -33,6 +33,7 @@ type BPlusTreeNode struct {
 	Offset           int64                   `json:"offset"`             // 该节点在硬盘文件中的偏移量，也是该节点的id
 	BeforeNodeOffset int64                   `json:"before_node_offset"` // 该节点相连的前一个结点的偏移量
 	AfterNodeOffset  int64                   `json:"after_node_offset"`  // 该节点相连的后一个结点的偏移量
+	DataVersions     []int                   `json:"-"`                  // 叶子结点各行数据写入时的表结构版本，只在从[]byte数据加载时设置
 }
 
 type noLeafNodeByteDataReadLoopData struct {
@@ -47,6 +48,7 @@ type leafNodeByteDataReadLoopData struct {
 	Value             map[string]*ValueInfo // 具体值信息
 	PrimaryKeySuccess bool                  // 主键信息获取是否成功
 	ValueSuccess      bool                  // 具体值信息获取是否成功
+	Version           int                   // 这行数据写入时的表结构版本
 }
 
 type BPlusTreeNodeJSON struct {
@@ -261,6 +263,53 @@ func getLeafNodeByteDataReadLoopData(data []byte, loopTime int, primaryKeyInfo *
 	return &r, nil
 }
 
+// getVersionedLeafNodeByteDataReadLoopData 解析记录了表结构版本的一行数据，旧版本的数据转换为当前版本
+// 每行数据为: 版本、主键、按照该版本顺序的各个值，不同版本的行长度可能不同，所以从 startIndex 开始解析，同时返回下一行的开始位置
+func getVersionedLeafNodeByteDataReadLoopData(data []byte, startIndex int, tableInfo *tableschema.TableMetaInfo) (*leafNodeByteDataReadLoopData, int, base.StandardError) {
+	r := leafNodeByteDataReadLoopData{}
+	if len(data) < startIndex+base.DataByteLengthRowVersion {
+		utils.LogDev(string(base.FunctionModelCoreBPlusTree))("[getVersionedLeafNodeByteDataReadLoopData] 长度不够完成这轮解析，返回空")
+		return &r, startIndex, nil
+	}
+	version, err := base.ByteListToUint32(data[startIndex : startIndex+base.DataByteLengthRowVersion])
+	if err != nil {
+		return nil, startIndex, err
+	}
+	r.Version = int(version)
+	valueInfo, err := tableInfo.VersionValueFieldInfo(r.Version)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreBPlusTree))(fmt.Sprintf("[getVersionedLeafNodeByteDataReadLoopData] VersionValueFieldInfo 出错, %s", err.Error()))
+		return nil, startIndex, err
+	}
+	startIndex += base.DataByteLengthRowVersion
+	loopData, err := getLeafNodeByteDataReadLoopData(data[startIndex:], 0, tableInfo.PrimaryKeyFieldInfo, valueInfo)
+	if err != nil {
+		return nil, startIndex, err
+	}
+	r.PrimaryKey = loopData.PrimaryKey
+	r.PrimaryKeySuccess = loopData.PrimaryKeySuccess
+	r.ValueSuccess = loopData.ValueSuccess
+	if !loopData.ValueSuccess {
+		return &r, startIndex, nil
+	}
+	startIndex += tableInfo.PrimaryKeyFieldInfo.Length
+	values := make(map[string][]byte, len(valueInfo))
+	for _, v := range valueInfo {
+		values[v.Name] = loopData.Value[v.Name].Value
+		startIndex += v.Length
+	}
+	values, err = tableInfo.UpgradeRowValues(r.Version, values)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreBPlusTree))(fmt.Sprintf("[getVersionedLeafNodeByteDataReadLoopData] UpgradeRowValues 出错, %s", err.Error()))
+		return nil, startIndex, err
+	}
+	r.Value = make(map[string]*ValueInfo, len(values))
+	for name, value := range values {
+		r.Value[name] = &ValueInfo{Value: value}
+	}
+	return &r, startIndex, nil
+}
+
 func (tree *BPlusTree) OffsetLoadNode(offset int64) (*BPlusTreeNode, base.StandardError) {
 	rm := tree.DataManager
 	nodeData, er := rm.Reader(offset)
@@ -357,9 +406,18 @@ func (node *BPlusTreeNode) LoadByteData(offset int64, tableInfo *tableschema.Tab
 	} else {
 		node.KeysValueList = make([]*ValueInfo, 0)
 		node.DataValues = make([]map[string]*ValueInfo, 0)
+		if tableInfo.RowVersioned() {
+			node.DataVersions = make([]int, 0)
+		}
+		startIndex := 0
 		for i := 0; i < nodeValueLengthInt; i++ {
 			// 运行数据
-			loopData, err := getLeafNodeByteDataReadLoopData(data, i, tableInfo.PrimaryKeyFieldInfo, tableInfo.ValueFieldInfo)
+			var loopData *leafNodeByteDataReadLoopData
+			if tableInfo.RowVersioned() {
+				loopData, startIndex, err = getVersionedLeafNodeByteDataReadLoopData(data, startIndex, tableInfo)
+			} else {
+				loopData, err = getLeafNodeByteDataReadLoopData(data, i, tableInfo.PrimaryKeyFieldInfo, tableInfo.ValueFieldInfo)
+			}
 			if err != nil {
 				utils.LogDev(string(base.FunctionModelCoreBPlusTree))(fmt.Sprintf("[BPlusTreeNode.LoadByteData] getLeafNodeByteDataReadLoopData 出错, loopTime: <%d>", i))
 				return err
@@ -371,6 +429,9 @@ func (node *BPlusTreeNode) LoadByteData(offset int64, tableInfo *tableschema.Tab
 			}
 			node.KeysValueList = append(node.KeysValueList, loopData.PrimaryKey)
 			node.DataValues = append(node.DataValues, loopData.Value)
+			if tableInfo.RowVersioned() {
+				node.DataVersions = append(node.DataVersions, loopData.Version)
+			}
 		}
 	}
 	return nil
@@ -470,12 +531,20 @@ func (node *BPlusTreeNode) NodeToByteData(tableInfo *tableschema.TableMetaInfo)
 			utils.LogDev(string(base.FunctionModelCoreBPlusTree))(fmt.Sprintf("[NodeToByteData] tableInfo.ValueFieldInfoMap 出错, %s", err.Error()))
 			return nil, err
 		}
+		// 记录版本的表，每行数据都按照当前版本写入
+		versionByte, err := base.Uint32ToByteList(uint32(tableInfo.Version))
+		if err != nil {
+			return nil, err
+		}
 		for i := 0; i < len(node.KeysValueList); i++ {
 			if node.DataValues[i] != nil && len(node.DataValues[i]) != len(tableInfo.ValueFieldInfo) {
 				errMsg := "非法叶子结点，值为空或值内容不足"
 				utils.LogError("[NodeToByteData] " + errMsg)
 				return nil, base.NewDBError(base.FunctionModelCoreBPlusTree, base.ErrorTypeSystem, base.ErrorBaseCodeInnerDataError, fmt.Errorf(errMsg))
 			}
+			if tableInfo.RowVersioned() {
+				d = append(d, versionByte...)
+			}
 			keyValueByte, err := lengthPaddingFunc(node.KeysValueList[i].Value, keyValueLength)
 			if err != nil {
 				utils.LogDev(string(base.FunctionModelCoreBPlusTree))(fmt.Sprintf("[NodeToByteData] keyValueByte.lengthPaddingFunc 出错, %s", err.Error()))
@@ -1508,9 +1577,6 @@ func (tree *BPlusTree) SearchKey(keyWhereArgs []*base.WherePartItem) ([][]byte,
 		return tree.SearchEqualKey(keyWhereArgs[0].Args[0])
 	}
 
-	maxKey := make([]byte, 0)
-	minKey := make([]byte, 0)
-
 	for _, item := range keyWhereArgs {
 		if item == nil {
 			continue
@@ -1523,13 +1589,418 @@ func (tree *BPlusTree) SearchKey(keyWhereArgs []*base.WherePartItem) ([][]byte,
 		}
 	}
 
-	// TODO
+	return tree.Search(keyWhereArgs)
+}
+
+// Search 支持复杂条件的搜索，返回满足条件的key和对应的值
+// 主键上的条件（包括前缀形式的 like）用于确定扫描的范围，其余条件在扫描时逐行过滤，各个条件之间是 and 的关系
+func (tree *BPlusTree) Search(whereArgs []*base.WherePartItem) ([][]byte, []map[string][]byte, base.StandardError) {
+	retKeyList := make([][]byte, 0)
+	retValueList := make([]map[string][]byte, 0)
+	err := tree.Scan(whereArgs, func(key []byte, values map[string][]byte) (bool, base.StandardError) {
+		retKeyList = append(retKeyList, key)
+		retValueList = append(retValueList, values)
+		return true, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return retKeyList, retValueList, nil
+}
+
+// Scan 和 Search 的条件相同，按照主键的顺序把满足条件的每一行交给 fn（values 不包括主键），不保存结果
+// fn 返回 false 时停止扫描
+func (tree *BPlusTree) Scan(whereArgs []*base.WherePartItem, fn func(key []byte, values map[string][]byte) (bool, base.StandardError)) base.StandardError {
+	keyRange, err := tree.TableInfo.PrimaryKeyRange(whereArgs)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreBPlusTree))(fmt.Sprintf("[BPlusTree.Scan] PrimaryKeyRange 错误: %s", err.Error()))
+		return err
+	}
+	regexpCache := tableschema.NewRegexpCache()
+	_, err = tree.scanRange(keyRange, func(row map[string][]byte) (bool, base.StandardError) {
+		return tree.TableInfo.MatchWhereParts(whereArgs, row, regexpCache)
+	}, fn)
+	return err
+}
+
+// SearchNode 使用条件树搜索，返回满足条件的key和对应的值
+func (tree *BPlusTree) SearchNode(node *base.WhereNode) ([][]byte, []map[string][]byte, base.StandardError) {
+	retKeyList := make([][]byte, 0)
+	retValueList := make([]map[string][]byte, 0)
+	err := tree.ScanNode(node, func(key []byte, values map[string][]byte) (bool, base.StandardError) {
+		retKeyList = append(retKeyList, key)
+		retValueList = append(retValueList, values)
+		return true, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return retKeyList, retValueList, nil
+}
+
+// ScanNode 和 Scan 相同，条件为条件树，node 为 nil 时扫描全部的数据
+// 主键上的 or 条件会合并为多个互不相交的范围依次扫描，无法转化为范围时扫描全部的数据并逐行过滤
+func (tree *BPlusTree) ScanNode(node *base.WhereNode, fn func(key []byte, values map[string][]byte) (bool, base.StandardError)) base.StandardError {
+	keyRanges, err := tree.TableInfo.PrimaryKeyRanges(node)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreBPlusTree))(fmt.Sprintf("[BPlusTree.ScanNode] PrimaryKeyRanges 错误: %s", err.Error()))
+		return err
+	}
+	regexpCache := tableschema.NewRegexpCache()
+	match := func(row map[string][]byte) (bool, base.StandardError) {
+		if node == nil {
+			return true, nil
+		}
+		return tree.TableInfo.MatchWhereNode(node, row, regexpCache)
+	}
+	for _, keyRange := range keyRanges {
+		next, err := tree.scanRange(keyRange, match, fn)
+		if err != nil || !next {
+			return err
+		}
+	}
+	return nil
+}
+
+// ScanNodeReverse 和 ScanNode 相同，按照主键的倒序扫描，从上限所在的叶子结点开始沿着链表向前读取，用于按照主键倒序排序的前 N 行
+func (tree *BPlusTree) ScanNodeReverse(node *base.WhereNode, fn func(key []byte, values map[string][]byte) (bool, base.StandardError)) base.StandardError {
+	keyRanges, err := tree.TableInfo.PrimaryKeyRanges(node)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreBPlusTree))(fmt.Sprintf("[BPlusTree.ScanNodeReverse] PrimaryKeyRanges 错误: %s", err.Error()))
+		return err
+	}
+	regexpCache := tableschema.NewRegexpCache()
+	match := func(row map[string][]byte) (bool, base.StandardError) {
+		if node == nil {
+			return true, nil
+		}
+		return tree.TableInfo.MatchWhereNode(node, row, regexpCache)
+	}
+	for i := len(keyRanges) - 1; i >= 0; i-- {
+		next, err := tree.scanRangeReverse(keyRanges[i], match, fn)
+		if err != nil || !next {
+			return err
+		}
+	}
+	return nil
+}
+
+// ScanAll 和 ScanNode 相同，不使用主键的范围，从最左边的叶子结点开始读取全部的数据并逐行过滤，用于执行计划选择了全表扫描时
+func (tree *BPlusTree) ScanAll(node *base.WhereNode, fn func(key []byte, values map[string][]byte) (bool, base.StandardError)) base.StandardError {
+	regexpCache := tableschema.NewRegexpCache()
+	_, err := tree.scanRange(&tableschema.KeyRange{}, func(row map[string][]byte) (bool, base.StandardError) {
+		if node == nil {
+			return true, nil
+		}
+		return tree.TableInfo.MatchWhereNode(node, row, regexpCache)
+	}, fn)
+	return err
+}
+
+// scanRange 按照主键的顺序扫描范围内满足 matchRow 的每一行，fn 返回 false 时停止扫描，此时第一个返回值为 false
+func (tree *BPlusTree) scanRange(keyRange *tableschema.KeyRange, matchRow func(row map[string][]byte) (bool, base.StandardError),
+	fn func(key []byte, values map[string][]byte) (bool, base.StandardError)) (bool, base.StandardError) {
+	var (
+		fieldType = tree.TableInfo.PrimaryKeyFieldInfo.FieldType
+		curNode   = tree.Root
+		err       base.StandardError
+	)
+	if keyRange.Empty {
+		return true, nil
+	}
+	// 1. 查找下限所在的叶子节点，没有下限时查找最左边的叶子节点
+	for !curNode.IsLeaf {
+		index := 0
+		if keyRange.Min != nil {
+			for ; index < len(curNode.KeysValueList); index++ {
+				greater, err := fieldType.Greater(curNode.KeysValueList[index].Value, keyRange.Min)
+				if err != nil {
+					utils.LogDev(string(base.FunctionModelCoreBPlusTree))(fmt.Sprintf("[BPlusTree.scanRange] Greater 错误: %s", err.Error()))
+					return false, err
+				}
+				if greater {
+					break
+				}
+				equal, err := fieldType.Equal(curNode.KeysValueList[index].Value, keyRange.Min)
+				if err != nil {
+					utils.LogDev(string(base.FunctionModelCoreBPlusTree))(fmt.Sprintf("[BPlusTree.scanRange] Equal 错误: %s", err.Error()))
+					return false, err
+				}
+				if equal {
+					break
+				}
+			}
+		}
+		curNode, err = tree.OffsetLoadNode(curNode.KeysOffsetList[index])
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreBPlusTree))(fmt.Sprintf("[BPlusTree.scanRange] tree.OffsetLoadNode 错误: %s", err.Error()))
+			return false, err
+		}
+	}
+
+	// 2. 存在重复的 key 时，前面的叶子节点中也可能有满足条件的数据
+	for curNode.BeforeNodeOffset != base.OffsetNull && keyRange.Min != nil {
+		beforeNode, err := tree.OffsetLoadNode(curNode.BeforeNodeOffset)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreBPlusTree))(fmt.Sprintf("[BPlusTree.scanRange] tree.OffsetLoadNode 错误: %s", err.Error()))
+			return false, err
+		}
+		if len(beforeNode.KeysValueList) > 0 {
+			before, err := keyRange.BeforeMin(fieldType, beforeNode.KeysValueList[len(beforeNode.KeysValueList)-1].Value)
+			if err != nil {
+				utils.LogDev(string(base.FunctionModelCoreBPlusTree))(fmt.Sprintf("[BPlusTree.scanRange] BeforeMin 错误: %s", err.Error()))
+				return false, err
+			}
+			if before {
+				break
+			}
+		}
+		curNode = beforeNode
+	}
+
+	// 3. 按顺序扫描叶子节点，直到超过上限
+	for {
+		for index := 0; index < len(curNode.KeysValueList); index++ {
+			key := curNode.KeysValueList[index].Value
+			after, err := keyRange.AfterMax(fieldType, key)
+			if err != nil {
+				utils.LogDev(string(base.FunctionModelCoreBPlusTree))(fmt.Sprintf("[BPlusTree.scanRange] AfterMax 错误: %s", err.Error()))
+				return false, err
+			}
+			if after {
+				return true, nil
+			}
+			contains, err := keyRange.Contains(fieldType, key)
+			if err != nil {
+				utils.LogDev(string(base.FunctionModelCoreBPlusTree))(fmt.Sprintf("[BPlusTree.scanRange] Contains 错误: %s", err.Error()))
+				return false, err
+			}
+			if !contains {
+				continue
+			}
+
+			values := make(map[string][]byte)
+			for k, v := range curNode.DataValues[index] {
+				values[k] = v.Value
+			}
+			row := make(map[string][]byte, len(values)+1)
+			for k, v := range values {
+				row[k] = v
+			}
+			row[tree.TableInfo.PrimaryKeyFieldInfo.Name] = key
+			match, err := matchRow(row)
+			if err != nil {
+				utils.LogDev(string(base.FunctionModelCoreBPlusTree))(fmt.Sprintf("[BPlusTree.scanRange] matchRow 错误: %s", err.Error()))
+				return false, err
+			}
+			if !match {
+				continue
+			}
+			next, err := fn(key, values)
+			if err != nil {
+				utils.LogDev(string(base.FunctionModelCoreBPlusTree))(fmt.Sprintf("[BPlusTree.scanRange] fn 错误: %s", err.Error()))
+				return false, err
+			}
+			if !next {
+				return false, nil
+			}
+		}
+		if curNode.AfterNodeOffset == base.OffsetNull {
+			break
+		}
+		curNode, err = tree.OffsetLoadNode(curNode.AfterNodeOffset)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreBPlusTree))(fmt.Sprintf("[BPlusTree.scanRange] tree.OffsetLoadNode 错误: %s", err.Error()))
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// scanRangeReverse 和 scanRange 相同，按照主键的倒序扫描
+func (tree *BPlusTree) scanRangeReverse(keyRange *tableschema.KeyRange, matchRow func(row map[string][]byte) (bool, base.StandardError),
+	fn func(key []byte, values map[string][]byte) (bool, base.StandardError)) (bool, base.StandardError) {
+	var (
+		fieldType = tree.TableInfo.PrimaryKeyFieldInfo.FieldType
+		curNode   = tree.Root
+		err       base.StandardError
+	)
+	if keyRange.Empty {
+		return true, nil
+	}
+	// 1. 查找上限所在的叶子节点，没有上限时查找最右边的叶子节点
+	for !curNode.IsLeaf {
+		index := len(curNode.KeysOffsetList) - 1
+		if keyRange.Max != nil {
+			for i := 0; i < len(curNode.KeysValueList); i++ {
+				greater, err := fieldType.Greater(curNode.KeysValueList[i].Value, keyRange.Max)
+				if err != nil {
+					utils.LogDev(string(base.FunctionModelCoreBPlusTree))(fmt.Sprintf("[BPlusTree.scanRangeReverse] Greater 错误: %s", err.Error()))
+					return false, err
+				}
+				if greater {
+					index = i
+					break
+				}
+			}
+		}
+		curNode, err = tree.OffsetLoadNode(curNode.KeysOffsetList[index])
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreBPlusTree))(fmt.Sprintf("[BPlusTree.scanRangeReverse] tree.OffsetLoadNode 错误: %s", err.Error()))
+			return false, err
+		}
+	}
+
+	// 2. 存在重复的 key 时，后面的叶子节点中也可能有满足条件的数据
+	for curNode.AfterNodeOffset != base.OffsetNull && keyRange.Max != nil {
+		afterNode, err := tree.OffsetLoadNode(curNode.AfterNodeOffset)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreBPlusTree))(fmt.Sprintf("[BPlusTree.scanRangeReverse] tree.OffsetLoadNode 错误: %s", err.Error()))
+			return false, err
+		}
+		if len(afterNode.KeysValueList) > 0 {
+			after, err := keyRange.AfterMax(fieldType, afterNode.KeysValueList[0].Value)
+			if err != nil {
+				utils.LogDev(string(base.FunctionModelCoreBPlusTree))(fmt.Sprintf("[BPlusTree.scanRangeReverse] AfterMax 错误: %s", err.Error()))
+				return false, err
+			}
+			if after {
+				break
+			}
+		}
+		curNode = afterNode
+	}
+
+	// 3. 倒序扫描叶子节点，直到低于下限
+	for {
+		for index := len(curNode.KeysValueList) - 1; index >= 0; index-- {
+			key := curNode.KeysValueList[index].Value
+			before, err := keyRange.BeforeMin(fieldType, key)
+			if err != nil {
+				utils.LogDev(string(base.FunctionModelCoreBPlusTree))(fmt.Sprintf("[BPlusTree.scanRangeReverse] BeforeMin 错误: %s", err.Error()))
+				return false, err
+			}
+			if before {
+				return true, nil
+			}
+			contains, err := keyRange.Contains(fieldType, key)
+			if err != nil {
+				utils.LogDev(string(base.FunctionModelCoreBPlusTree))(fmt.Sprintf("[BPlusTree.scanRangeReverse] Contains 错误: %s", err.Error()))
+				return false, err
+			}
+			if !contains {
+				continue
+			}
+
+			values := make(map[string][]byte, len(curNode.DataValues[index]))
+			row := make(map[string][]byte, len(curNode.DataValues[index])+1)
+			for k, v := range curNode.DataValues[index] {
+				values[k] = v.Value
+				row[k] = v.Value
+			}
+			row[tree.TableInfo.PrimaryKeyFieldInfo.Name] = key
+			match, err := matchRow(row)
+			if err != nil {
+				utils.LogDev(string(base.FunctionModelCoreBPlusTree))(fmt.Sprintf("[BPlusTree.scanRangeReverse] matchRow 错误: %s", err.Error()))
+				return false, err
+			}
+			if !match {
+				continue
+			}
+			next, err := fn(key, values)
+			if err != nil {
+				utils.LogDev(string(base.FunctionModelCoreBPlusTree))(fmt.Sprintf("[BPlusTree.scanRangeReverse] fn 错误: %s", err.Error()))
+				return false, err
+			}
+			if !next {
+				return false, nil
+			}
+		}
+		if curNode.BeforeNodeOffset == base.OffsetNull {
+			break
+		}
+		curNode, err = tree.OffsetLoadNode(curNode.BeforeNodeOffset)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreBPlusTree))(fmt.Sprintf("[BPlusTree.scanRangeReverse] tree.OffsetLoadNode 错误: %s", err.Error()))
+			return false, err
+		}
+	}
+
+	return true, nil
+}
 
+// BoundaryKey 最小的主键，max 为 true 时为最大的主键；表为空时第二个返回值为 false
+// 只读取一条从根结点到最左（右）边叶子结点的路径，叶子结点为空时沿着链表继续查找
+func (tree *BPlusTree) BoundaryKey(max bool) ([]byte, bool, base.StandardError) {
+	var (
+		curNode = tree.Root
+		err     base.StandardError
+	)
+	for !curNode.IsLeaf {
+		index := 0
+		if max {
+			index = len(curNode.KeysOffsetList) - 1
+		}
+		curNode, err = tree.OffsetLoadNode(curNode.KeysOffsetList[index])
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreBPlusTree))(fmt.Sprintf("[BPlusTree.BoundaryKey] tree.OffsetLoadNode 错误: %s", err.Error()))
+			return nil, false, err
+		}
+	}
+	for len(curNode.KeysValueList) == 0 {
+		nextOffset := curNode.AfterNodeOffset
+		if max {
+			nextOffset = curNode.BeforeNodeOffset
+		}
+		if nextOffset == base.OffsetNull {
+			return nil, false, nil
+		}
+		curNode, err = tree.OffsetLoadNode(nextOffset)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreBPlusTree))(fmt.Sprintf("[BPlusTree.BoundaryKey] tree.OffsetLoadNode 错误: %s", err.Error()))
+			return nil, false, err
+		}
+	}
+	if max {
+		return curNode.KeysValueList[len(curNode.KeysValueList)-1].Value, true, nil
+	}
+	return curNode.KeysValueList[0].Value, true, nil
 }
 
-// Search 支持复杂条件的搜索，返回key的值
-func (tree *BPlusTree) Search(whereArgs []*base.WherePartItem) ([]byte, base.StandardError) {
-	// TODO
+// LeafOffsets 按照从左到右的顺序返回全部叶子结点的 offset 和树的高度（只有根结点时为1）
+// 只读取非叶子结点，用于统计信息的采样
+func (tree *BPlusTree) LeafOffsets() ([]int64, int, base.StandardError) {
+	if tree.Root.IsLeaf {
+		return []int64{tree.Root.Offset}, 1, nil
+	}
+	height := 1
+	level := []*BPlusTreeNode{tree.Root}
+	for {
+		height++
+		offsets := make([]int64, 0)
+		for _, node := range level {
+			offsets = append(offsets, node.KeysOffsetList...)
+		}
+		child, err := tree.OffsetLoadNode(offsets[0])
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreBPlusTree))(fmt.Sprintf("[BPlusTree.LeafOffsets] tree.OffsetLoadNode 错误: %s", err.Error()))
+			return nil, 0, err
+		}
+		if child.IsLeaf {
+			return offsets, height, nil
+		}
+		level = make([]*BPlusTreeNode, 0, len(offsets))
+		level = append(level, child)
+		for _, offset := range offsets[1:] {
+			node, err := tree.OffsetLoadNode(offset)
+			if err != nil {
+				utils.LogDev(string(base.FunctionModelCoreBPlusTree))(fmt.Sprintf("[BPlusTree.LeafOffsets] tree.OffsetLoadNode 错误: %s", err.Error()))
+				return nil, 0, err
+			}
+			level = append(level, node)
+		}
+	}
 }
 
 func (tree *BPlusTree) ChangeRoot(newRootOffset int64) base.StandardError {
@@ -1848,6 +2319,77 @@ func (tree *BPlusTree) PrintBPlusTree() base.StandardError {
 	return nil
 }
 
+// TreeOrder 根据页大小计算B+树的阶数，保证分裂前的结点都能放入一页
+func TreeOrder(tableInfo *tableschema.TableMetaInfo) (int, int, base.StandardError) {
+	// 结点头部: 前后相连偏移位、是否为leaf结点的位、结点长度
+	headerLength := base.DataByteLengthOffset + 1 + base.DataByteLengthInt64 + base.DataByteLengthOffset
+	keyLength := tableInfo.PrimaryKeyFieldInfo.Length
+	valueLength := 0
+	for _, valueInfo := range tableInfo.ValueFieldInfo {
+		valueLength += valueInfo.Length
+	}
+	if tableInfo.RowVersioned() {
+		valueLength += base.DataByteLengthRowVersion
+	}
+	leafOrder := (tableInfo.PageSize - headerLength) / (keyLength + valueLength)
+	indexOrder := (tableInfo.PageSize - headerLength + keyLength) / (keyLength + base.DataByteLengthOffset)
+	if leafOrder < 3 || indexOrder < 3 {
+		errMsg := fmt.Sprintf("页大小<%d>过小，B+树阶数不足: leafOrder: %d, indexOrder: %d", tableInfo.PageSize, leafOrder, indexOrder)
+		utils.LogError("[TreeOrder] " + errMsg)
+		return 0, 0, base.NewDBError(base.FunctionModelCoreBPlusTree, base.ErrorTypeConfig, base.ErrorBaseCodeTableSchemaError, fmt.Errorf(errMsg))
+	}
+	return leafOrder, indexOrder, nil
+}
+
+// NewEmptyRootNode 空表的根结点
+func NewEmptyRootNode() *BPlusTreeNode {
+	return &BPlusTreeNode{
+		IsLeaf:           true,
+		KeysValueList:    []*ValueInfo{},
+		KeysOffsetList:   nil,
+		DataValues:       []map[string]*ValueInfo{},
+		Offset:           base.RootOffsetValue,
+		BeforeNodeOffset: base.OffsetNull,
+		AfterNodeOffset:  base.OffsetNull,
+	}
+}
+
+// LoadBPlusTree 从数据管理器中加载表的B+树，isEmpty 为 true 时写入空的根结点
+func LoadBPlusTree(tableInfo *tableschema.TableMetaInfo, dataManager dataio.IOManager, isEmpty bool) (*BPlusTree, base.StandardError) {
+	leafOrder, indexOrder, err := TreeOrder(tableInfo)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreBPlusTree))(fmt.Sprintf("[LoadBPlusTree] TreeOrder 错误: %s", err.Error()))
+		return nil, err
+	}
+	tree := &BPlusTree{
+		TableInfo:   tableInfo,
+		LeafOrder:   leafOrder,
+		IndexOrder:  indexOrder,
+		DataManager: dataManager,
+	}
+	if isEmpty {
+		root := NewEmptyRootNode()
+		rootByte, err := root.NodeToByteData(tableInfo)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreBPlusTree))(fmt.Sprintf("[LoadBPlusTree] NodeToByteData 错误: %s", err.Error()))
+			return nil, err
+		}
+		_, err = dataManager.Writer(base.RootOffsetValue, rootByte)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreBPlusTree))(fmt.Sprintf("[LoadBPlusTree] 写入根结点错误: %s", err.Error()))
+			return nil, err
+		}
+		tree.Root = root
+		return tree, nil
+	}
+	tree.Root, err = tree.OffsetLoadNode(base.RootOffsetValue)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreBPlusTree))(fmt.Sprintf("[LoadBPlusTree] 加载根结点错误: %s", err.Error()))
+		return nil, err
+	}
+	return tree, nil
+}
+
 // LoadBPlusTreeFromJson 用于加载整个B+树
 func LoadBPlusTreeFromJson(jsonData []byte) (*BPlusTree, base.StandardError) {
 	var (
@@ -3509,3 +3509,66 @@ func TestBPlusTree_SearchEqualKey_2(t *testing.T) {
 		}
 	}
 }
+
+func TestBPlusTree_Search(t *testing.T) {
+	_ = os.Setenv("LOG_DEV", "1")
+	_ = os.Setenv("LOG_DEV_MODULES", "All")
+	pageSize := 1000
+	_ = config.CoreConfig.InitByJSON(fmt.Sprintf("{\"Dev\":true,\"PageSize\":%d}", pageSize))
+
+	rawJsonString := fmt.Sprintf("{\"root_node\":{\"is_leaf\":false,\"keys_offset_list\":[6000,5000],\"offset\":0,\"before_node_offset\":-1,\"after_node_offset\":-1,\"keys_value\":[\"4\"],\"data_values\":[]},\"value_node\":[{\"is_leaf\":true,\"keys_offset_list\":null,\"offset\":2000,\"before_node_offset\":-1,\"after_node_offset\":1000,\"keys_value\":[\"1\",\"2\"],\"data_values\":[{\"age\":\"20\",\"name\":\"Alice\"},{\"age\":\"22\",\"name\":\"aa\"}]},{\"is_leaf\":true,\"keys_offset_list\":null,\"offset\":1000,\"before_node_offset\":2000,\"after_node_offset\":3000,\"keys_value\":[\"3\",\"4\"],\"data_values\":[{\"age\":\"23\",\"name\":\"ab\"},{\"age\":\"24\",\"name\":\"bb\"}]},{\"is_leaf\":true,\"keys_offset_list\":null,\"offset\":3000,\"before_node_offset\":1000,\"after_node_offset\":4000,\"keys_value\":[\"5\",\"6\"],\"data_values\":[{\"age\":\"25\",\"name\":\"ac\"},{\"age\":\"26\",\"name\":\"cc\"}]},{\"is_leaf\":true,\"keys_offset_list\":null,\"offset\":4000,\"before_node_offset\":3000,\"after_node_offset\":7000,\"keys_value\":[\"7\",\"8\"],\"data_values\":[{\"age\":\"27\",\"name\":\"bc\"},{\"age\":\"28\",\"name\":\"ca\"}]},{\"is_leaf\":true,\"keys_offset_list\":null,\"offset\":7000,\"before_node_offset\":4000,\"after_node_offset\":-1,\"keys_value\":[\"9\",\"10\"],\"data_values\":[{\"age\":\"29\",\"name\":\"cb\"},{\"age\":\"30\",\"name\":\"ba\"}]},{\"is_leaf\":false,\"keys_offset_list\":[2000,1000,3000],\"offset\":6000,\"before_node_offset\":-1,\"after_node_offset\":5000,\"keys_value\":[\"2\",\"4\"],\"data_values\":[]},{\"is_leaf\":false,\"keys_offset_list\":[3000,4000,7000],\"offset\":5000,\"before_node_offset\":6000,\"after_node_offset\":-1,\"keys_value\":[\"6\",\"8\"],\"data_values\":[]}],\"table_info\":{\"name\":\"users\",\"primary_key\":{\"name\":\"id\",\"length\":8,\"default\":\"\",\"type\":\"bigint\"},\"value\":[{\"name\":\"name\",\"length\":20,\"default\":\"\",\"type\":\"char\"},{\"name\":\"age\",\"length\":8,\"default\":\"\",\"type\":\"char\"}],\"page_size\":1000,\"storage_type\":\"%s\"},\"leaf_order\":4,\"index_order\":4}", testStorageType)
+	tree, err := LoadBPlusTreeFromJson([]byte(rawJsonString))
+	if err != nil {
+		t.Error("Expected nil error, but got error")
+		return
+	}
+	minKey, _ := base.Int64ToByteList(3)
+	maxKey, _ := base.Int64ToByteList(6)
+
+	// 主键范围 + 非主键的 like 条件
+	keyList, valueList, err := tree.Search([]*base.WherePartItem{
+		{TargetColumn: "id", Operate: base.DataComparatorBetween, Args: [][]byte{minKey, maxKey}},
+		{TargetColumn: "name", Operate: base.DataComparatorLike, Args: [][]byte{[]byte("a_")}},
+	})
+	if err != nil {
+		t.Error("Expected nil error, but got error")
+		return
+	}
+	if len(keyList) != 2 || len(valueList) != 2 {
+		t.Errorf("Expected 2 result, but got %d", len(keyList))
+		return
+	}
+	expectKey3, _ := base.Int64ToByteList(3)
+	expectKey5, _ := base.Int64ToByteList(5)
+	if !list.ByteListEqual(keyList[0], expectKey3) || !list.ByteListEqual(keyList[1], expectKey5) {
+		t.Error("key error")
+		return
+	}
+	nameByte, _ := base.StringToByteList("ac")
+	if !list.ByteListEqual(valueList[1]["name"], nameByte) {
+		t.Error("value error")
+		return
+	}
+
+	// 只有主键条件
+	keyList, _, err = tree.SearchKey([]*base.WherePartItem{
+		{TargetColumn: "id", Operate: base.DataComparatorGreater, Args: [][]byte{expectKey5}},
+		{TargetColumn: "id", Operate: base.DataComparatorNotEqual, Args: [][]byte{maxKey}},
+	})
+	if err != nil {
+		t.Error("Expected nil error, but got error")
+		return
+	}
+	if len(keyList) != 4 {
+		t.Errorf("Expected 4 result, but got %d", len(keyList))
+		return
+	}
+
+	_, _, err = tree.SearchKey([]*base.WherePartItem{
+		{TargetColumn: "name", Operate: base.DataComparatorEqual, Args: [][]byte{nameByte}},
+	})
+	if err == nil {
+		t.Error("Expected error, but got nil")
+		return
+	}
+}
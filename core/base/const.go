@@ -4,9 +4,22 @@ const (
 	// 不同类型的字节长度
 	DataByteLengthInt64  = 8
 	DataByteLengthUint64 = 8
+	DataByteLengthUint32 = 4
 	DataByteLengthString = 4
+	// DataByteLengthBlobHeader blob 类型储存时，记录实际数据长度的头部字节长度
+	DataByteLengthBlobHeader = DataByteLengthUint32
+	DataByteLengthUUID       = 16
+	// DataByteLengthEnum enum 类型储存的是可选值的序号
+	DataByteLengthEnum = 2
 	// DataByteLengthOffset offset的字节长度，对应的是int64的字节长度
 	DataByteLengthOffset = DataByteLengthInt64
+	// DataByteLengthRowVersion 叶子结点中每行数据记录的表结构版本的字节长度
+	DataByteLengthRowVersion = DataByteLengthUint32
+
+	// TableSchemaVersionLegacy 没有版本的旧表，行数据中不记录版本
+	TableSchemaVersionLegacy = 0
+	// TableSchemaVersionInitial 新建的表的版本
+	TableSchemaVersionInitial = 1
 
 	// OffsetNull 空offset对应的值
 	OffsetNull = int64(-1)
@@ -22,6 +35,27 @@ const (
 	// 字段类型
 	DBDataTypeBigInt DBDataTypeEnumeration = "bigint"
 	DBDataTypeChar   DBDataTypeEnumeration = "char"
+	DBDataTypeBlob   DBDataTypeEnumeration = "blob"
+	DBDataTypeJSON   DBDataTypeEnumeration = "json"
+	DBDataTypeUUID   DBDataTypeEnumeration = "uuid"
+	DBDataTypeEnum   DBDataTypeEnumeration = "enum"
+
+	// 字符排序规则
+	// CollationBinary 按字节比较（默认）
+	CollationBinary Collation = "binary"
+	// CollationCaseInsensitive 忽略大小写（Unicode case folding 之后按字节比较）
+	CollationCaseInsensitive Collation = "case_insensitive"
+	// CollationUnicode Unicode 根排序规则（UCA / CLDR root）
+	CollationUnicode Collation = "unicode"
+
+	// 默认值（不区分大小写），函数类型的默认值在插入时才计算具体值
+	DefaultValueNull                     = "NULL"
+	DefaultValueFunctionCurrentTimestamp = "CURRENT_TIMESTAMP"
+	DefaultValueFunctionUUID             = "UUID()"
+	// DefaultValueFunctionNextValue 序列的下一个值，如: NEXTVAL(order_seq)
+	DefaultValueFunctionNextValue = "NEXTVAL"
+	// DefaultValueCurrentTimestampFormat char 类型使用 CURRENT_TIMESTAMP 时的格式
+	DefaultValueCurrentTimestampFormat = "2006-01-02 15:04:05"
 
 	// 模块
 	FunctionModelCoreConfig         FunctionModel = "core.config"
@@ -49,10 +83,194 @@ const (
 	ErrorBaseCodeCoreLogicError      = "code_logic_error"
 	ErrorBaseCodeConfigError         = "config"
 	ErrorBaseCodeTableSchemaError    = "table_schema"
+	ErrorBaseCodeConstraintError     = "constraint"
 
 	// 文件后缀
 	DataIOFileTableDataSuffix   = "nedb"
 	DataIOFileTableSchemaSuffix = "neds"
+	DataIOFileSequenceSuffix    = "neseq"
+	DataIOFileViewSuffix        = "neview"
+	// DataIOFileTempSuffix 写入临时文件之后再重命名，保证文件内容不会写一半
+	DataIOFileTempSuffix = "tmp"
+	// DataIOFileRenameJournalSuffix 重命名表时记录进度的文件，文件名为原表名
+	DataIOFileRenameJournalSuffix = "nerj"
+	// DataIOFileRefreshJournalSuffix 重写表数据（修改表结构、重建物化视图）时替换数据和表结构的记录，文件名为新的表名
+	DataIOFileRefreshJournalSuffix = "nefj"
+	// DataIOFileCatalogName 系统目录的文件名
+	DataIOFileCatalogName   = "catalog"
+	DataIOFileCatalogSuffix = "necat"
+	// DataIOFileDatabaseName 数据库目录中保存数据库配置的文件名
+	DataIOFileDatabaseName   = "database"
+	DataIOFileDatabaseSuffix = "nedbc"
+	// DataIOFileSpillSuffix 聚合、排序超过内存限制时写入的临时文件，使用完之后删除
+	DataIOFileSpillSuffix = "nespill"
+
+	// DatabaseNameSeparator 数据库名和表名之间的分隔符，完整的表名为: 数据库名.表名，没有数据库名时为默认数据库
+	// 和 SymbolJSONPathSeparator 相同，按照名称出现的位置区分: 表名、视图名、序列名中的分隔符为数据库的分隔符，
+	// 列名（TargetColumn 等）中不会出现数据库名和表名，其中的分隔符为 json 路径的分隔符；因此数据库名、表名和列名都不能包含分隔符
+	DatabaseNameSeparator = "."
+
+	// CatalogSchemaName 系统目录虚拟表所在的数据库，用户不能使用
+	CatalogSchemaName       = "information_schema"
+	CatalogTableDatabases   = CatalogSchemaName + ".databases"
+	CatalogTableTables      = CatalogSchemaName + ".tables"
+	CatalogTableColumns     = CatalogSchemaName + ".columns"
+	CatalogTableConstraints = CatalogSchemaName + ".constraints"
+	CatalogTableIndexes     = CatalogSchemaName + ".indexes"
+	CatalogTableSequences   = CatalogSchemaName + ".sequences"
+	CatalogTableStatistics  = CatalogSchemaName + ".statistics"
+	CatalogTableViews       = CatalogSchemaName + ".views"
+
+	// 系统目录中约束的种类
+	ConstraintTypePrimaryKey = "PRIMARY KEY"
+	ConstraintTypeCheck      = "CHECK"
+	ConstraintTypeForeignKey = "FOREIGN KEY"
+	// PrimaryKeySuffix 主键约束和索引的名称后缀，名称为: 表名_pkey
+	PrimaryKeySuffix = "pkey"
+
+	// AutoIncrementSequenceSuffix 自增主键对应的序列名后缀，序列名为: 表名$列名$列名长度$seq
+	// 带上列名长度，表名或者列名中有 $ 时也可以唯一确定表名和列名；用户创建的序列名不能以 $seq 结尾，不会和自增主键的序列重名
+	AutoIncrementSequenceSuffix    = "seq"
+	AutoIncrementSequenceSeparator = "$"
+
+	// CheckConstraintSuffix 列级 CHECK 约束的名称后缀，约束名为: 表名_列名_check
+	CheckConstraintSuffix = "check"
+	// ForeignKeySuffix 没有指定名称的外键的名称后缀，外键名为: 表名_列名_fkey
+	ForeignKeySuffix = "fkey"
+
+	// 外键在父表数据删除、更新时的动作
+	ForeignKeyActionRestrict = "restrict"
+	ForeignKeyActionCascade  = "cascade"
+	ForeignKeyActionSetNull  = "set_null"
+
+	// ALTER TABLE 的操作
+	AlterTableActionAddColumn    = "add_column"
+	AlterTableActionDropColumn   = "drop_column"
+	AlterTableActionRenameColumn = "rename_column"
+	AlterTableActionModifyLength = "modify_length"
+	AlterTableActionRenameTable  = "rename_table"
+
+	// 聚合函数
+	AggregateFunctionCount         AggregateFunction = "count"
+	AggregateFunctionCountDistinct AggregateFunction = "count_distinct"
+	AggregateFunctionSum           AggregateFunction = "sum"
+	AggregateFunctionAvg           AggregateFunction = "avg"
+	AggregateFunctionMin           AggregateFunction = "min"
+	AggregateFunctionMax           AggregateFunction = "max"
+
+	// 连接类型
+	JoinTypeInner JoinType = "inner"
+	JoinTypeLeft  JoinType = "left"
+	JoinTypeRight JoinType = "right"
+	JoinTypeCross JoinType = "cross"
+
+	// 连接算法，为空时由执行计划选择
+	JoinAlgorithmNestedLoop      JoinAlgorithm = "nested_loop"
+	JoinAlgorithmIndexNestedLoop JoinAlgorithm = "index_nested_loop"
+	JoinAlgorithmHash            JoinAlgorithm = "hash"
+	JoinAlgorithmMerge           JoinAlgorithm = "merge"
+
+	// 集合运算，union / intersect / except 的结果去掉重复的行
+	SetOperatorUnion     SetOperator = "union"
+	SetOperatorUnionAll  SetOperator = "union_all"
+	SetOperatorIntersect SetOperator = "intersect"
+	SetOperatorExcept    SetOperator = "except"
+
+	// 子查询的类型
+	SubQueryTypeScalar    SubQueryType = "scalar"
+	SubQueryTypeIn        SubQueryType = "in"
+	SubQueryTypeNotIn     SubQueryType = "not_in"
+	SubQueryTypeExists    SubQueryType = "exists"
+	SubQueryTypeNotExists SubQueryType = "not_exists"
+
+	// 窗口函数
+	WindowFunctionRowNumber  WindowFunction = "row_number"
+	WindowFunctionRank       WindowFunction = "rank"
+	WindowFunctionDenseRank  WindowFunction = "dense_rank"
+	WindowFunctionLag        WindowFunction = "lag"
+	WindowFunctionLead       WindowFunction = "lead"
+	WindowFunctionCount      WindowFunction = "count"
+	WindowFunctionSum        WindowFunction = "sum"
+	WindowFunctionAvg        WindowFunction = "avg"
+	WindowFunctionFirstValue WindowFunction = "first_value"
+	WindowFunctionLastValue  WindowFunction = "last_value"
+
+	// 窗口的范围按照行数（rows）或者排序的值（range）计算
+	WindowFrameUnitRows  WindowFrameUnit = "rows"
+	WindowFrameUnitRange WindowFrameUnit = "range"
+
+	// 表达式的种类
+	ExpressionKindColumn    ExpressionKind = "column"
+	ExpressionKindLiteral   ExpressionKind = "literal"
+	ExpressionKindOperator  ExpressionKind = "operator"
+	ExpressionKindFunction  ExpressionKind = "function"
+	ExpressionKindCase      ExpressionKind = "case"
+	ExpressionKindParameter ExpressionKind = "parameter"
+
+	// 表达式的值的类型，列的值中 bigint 为 bigint，其他类型都作为 char（可读值）
+	ExpressionTypeBigInt ExpressionType = "bigint"
+	ExpressionTypeChar   ExpressionType = "char"
+	ExpressionTypeBool   ExpressionType = "bool"
+	ExpressionTypeNull   ExpressionType = "null"
+
+	// 表达式的运算符，- 只有一个参数时为取负
+	ExpressionOperatorAdd             ExpressionOperator = "+"
+	ExpressionOperatorSubtract        ExpressionOperator = "-"
+	ExpressionOperatorMultiply        ExpressionOperator = "*"
+	ExpressionOperatorDivide          ExpressionOperator = "/"
+	ExpressionOperatorModulo          ExpressionOperator = "%"
+	ExpressionOperatorEqual           ExpressionOperator = "="
+	ExpressionOperatorNotEqual        ExpressionOperator = "!="
+	ExpressionOperatorGreater         ExpressionOperator = ">"
+	ExpressionOperatorGreaterAndEqual ExpressionOperator = ">="
+	ExpressionOperatorLess            ExpressionOperator = "<"
+	ExpressionOperatorLessAndEqual    ExpressionOperator = "<="
+	ExpressionOperatorAnd             ExpressionOperator = "and"
+	ExpressionOperatorOr              ExpressionOperator = "or"
+	ExpressionOperatorNot             ExpressionOperator = "not"
+	ExpressionOperatorIsNull          ExpressionOperator = "is_null"
+	ExpressionOperatorIsNotNull       ExpressionOperator = "is_not_null"
+	ExpressionOperatorLike            ExpressionOperator = "like"
+	ExpressionOperatorIn              ExpressionOperator = "in"
+
+	// 表达式的函数，时间为 DefaultValueCurrentTimestampFormat 格式的 char（本地时区）
+	ExpressionFunctionLower         ExpressionFunction = "lower"
+	ExpressionFunctionUpper         ExpressionFunction = "upper"
+	ExpressionFunctionLength        ExpressionFunction = "length"
+	ExpressionFunctionTrim          ExpressionFunction = "trim"
+	ExpressionFunctionSubstr        ExpressionFunction = "substr"
+	ExpressionFunctionConcat        ExpressionFunction = "concat"
+	ExpressionFunctionReplace       ExpressionFunction = "replace"
+	ExpressionFunctionStrpos        ExpressionFunction = "strpos"
+	ExpressionFunctionAbs           ExpressionFunction = "abs"
+	ExpressionFunctionMod           ExpressionFunction = "mod"
+	ExpressionFunctionGreatest      ExpressionFunction = "greatest"
+	ExpressionFunctionLeast         ExpressionFunction = "least"
+	ExpressionFunctionCoalesce      ExpressionFunction = "coalesce"
+	ExpressionFunctionNullIf        ExpressionFunction = "nullif"
+	ExpressionFunctionCast          ExpressionFunction = "cast"
+	ExpressionFunctionNow           ExpressionFunction = "now"
+	ExpressionFunctionDate          ExpressionFunction = "date"
+	ExpressionFunctionYear          ExpressionFunction = "year"
+	ExpressionFunctionMonth         ExpressionFunction = "month"
+	ExpressionFunctionDay           ExpressionFunction = "day"
+	ExpressionFunctionHour          ExpressionFunction = "hour"
+	ExpressionFunctionMinute        ExpressionFunction = "minute"
+	ExpressionFunctionSecond        ExpressionFunction = "second"
+	ExpressionFunctionUnixTimestamp ExpressionFunction = "unix_timestamp"
+	ExpressionFunctionFromUnixTime  ExpressionFunction = "from_unixtime"
+	ExpressionFunctionDateAdd       ExpressionFunction = "date_add"  // date_add(时间, 秒数)
+	ExpressionFunctionDateDiff      ExpressionFunction = "date_diff" // date_diff(结束时间, 开始时间)，单位为秒
+
+	// ExpressionDateFormat date 函数的结果格式
+	ExpressionDateFormat = "2006-01-02"
+
+	// 单表的访问路径
+	AccessPathFullScan        = "full_scan"
+	AccessPathPrimaryKeyRange = "primary_key_range"
+
+	// StatisticsHistogramBucketCount 统计信息中等深直方图的桶数
+	StatisticsHistogramBucketCount = 32
 
 	// 数据储存类型
 	StorageTypeFile   = "file"
@@ -72,6 +290,14 @@ const (
 	DataComparatorILike           DataComparator = "ilike"
 	DataComparatorIsNull          DataComparator = "is_null"
 	DataComparatorIsNotNull       DataComparator = "is_not_null"
+	DataComparatorContains        DataComparator = "contains"
+	DataComparatorRegexp          DataComparator = "regexp"
+	DataComparatorNotRegexp       DataComparator = "not_regexp"
+
+	// 条件树的逻辑关系
+	WhereLogicAnd WhereLogic = "and"
+	WhereLogicOr  WhereLogic = "or"
+	WhereLogicNot WhereLogic = "not"
 
 	// 比较符支持的参数数量
 	DataComparatorArgsCountGreater         = 1
@@ -87,6 +313,20 @@ const (
 	DataComparatorArgsCountILike           = 1
 	DataComparatorArgsCountIsNull          = 0
 	DataComparatorArgsCountIsNotNull       = 0
+	DataComparatorArgsCountContains        = 1
+	DataComparatorArgsCountRegexp          = 1
+	DataComparatorArgsCountNotRegexp       = 1
+	// DataComparatorArgsCountLikeWithEscape like / ilike 第二个参数为 ESCAPE 字符
+	DataComparatorArgsCountLikeWithEscape = 2
 
-	SymbolDataComparatorLikePlaceholder = 0x37 // %
+	SymbolDataComparatorLikePlaceholder = 0x25 // %
+	SymbolDataComparatorLikeSingle      = 0x5F // _
+	// SymbolDataComparatorLikeEscape like 默认的转义字符，可以通过 like 的第二个参数指定其他转义字符
+	SymbolDataComparatorLikeEscape = 0x5C // \
+	// SymbolJSONPathSeparator TargetColumn 中列名和 json 路径、json 路径各层之间的分隔符，如: attrs.color，和数据库名的区分见 DatabaseNameSeparator
+	SymbolJSONPathSeparator = "."
+	// SymbolParameterPositional 预处理语句中按出现顺序编号的参数占位符
+	SymbolParameterPositional = "?"
+	// SymbolParameterNumberedPrefix 预处理语句中指定序号的参数占位符的前缀，如: $1（从 1 开始）
+	SymbolParameterNumberedPrefix = "$"
 )
@@ -67,6 +67,20 @@ func Uint64ToByteList(data uint64) ([]byte, StandardError) {
 	return b, nil
 }
 
+func ByteListToUint32(data []byte) (uint32, StandardError) {
+	if len(data) != DataByteLengthUint32 {
+		return 0, NewDBError(FunctionModelCoreDataConversion, ErrorTypeSystem, ErrorBaseCodeInnerParameterError, fmt.Errorf("[ByteListToUint32], len(data) != %d, %#v", DataByteLengthUint32, data))
+	}
+	return binary.BigEndian.Uint32(data), nil
+}
+
+// Uint32ToByteList 大端字节序，将uint32的数据转为[]byte的数据
+func Uint32ToByteList(data uint32) ([]byte, StandardError) {
+	b := make([]byte, DataByteLengthUint32)
+	binary.BigEndian.PutUint32(b, data)
+	return b, nil
+}
+
 func ByteListToString(data []byte) (string, StandardError) {
 	str := string(data)
 	return str, nil
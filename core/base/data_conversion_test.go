@@ -240,6 +240,74 @@ func TestUint64ToByteList(t *testing.T) {
 	}
 }
 
+func TestByteListToUint32(t *testing.T) {
+	// 正整数测试用例
+	data := []byte{0x00, 0x01, 0xE2, 0x40}
+	expectedVal := uint32(123456)
+	val, err := ByteListToUint32(data)
+	if err != nil {
+		t.Errorf("ByteListToUint32 failed: %v", err)
+		return
+	}
+	if val != expectedVal {
+		t.Errorf("ByteListToUint32 failed, expected %d but got %d", expectedVal, val)
+		return
+	}
+
+	// 长度不足测试用例
+	_, err = ByteListToUint32([]byte{0x00, 0x01, 0xE2})
+	if err == nil {
+		t.Error("ByteListToUint32 failed, expected an error when len(data) < 4")
+		return
+	}
+
+	// 长度超出测试用例
+	_, err = ByteListToUint32([]byte{0x00, 0x01, 0xE2, 0x40, 0x00})
+	if err == nil {
+		t.Error("ByteListToUint32 failed, expected an error when len(data) > 4")
+		return
+	}
+
+	// 边界值测试用例
+	data = []byte{0xFF, 0xFF, 0xFF, 0xFF}
+	expectedVal = uint32(4294967295)
+	val, err = ByteListToUint32(data)
+	if err != nil {
+		t.Errorf("ByteListToUint32 failed: %v", err)
+		return
+	}
+	if val != expectedVal {
+		t.Errorf("ByteListToUint32 failed, expected %d but got %d", expectedVal, val)
+		return
+	}
+}
+
+func TestUint32ToByteList(t *testing.T) {
+	// 正常值测试用例
+	expectedData := []byte{0x00, 0x01, 0xE2, 0x40}
+	data, err := Uint32ToByteList(uint32(123456))
+	if err != nil {
+		t.Errorf("Uint32ToByteList failed: %v", err)
+		return
+	}
+	if !reflect.DeepEqual(data, expectedData) {
+		t.Errorf("Uint32ToByteList failed, expected %#v but got %#v", expectedData, data)
+		return
+	}
+
+	// 边界值测试用例
+	expectedData = []byte{0x00, 0x00, 0x00, 0x00}
+	data, err = Uint32ToByteList(uint32(0))
+	if err != nil {
+		t.Errorf("Uint32ToByteList failed: %v", err)
+		return
+	}
+	if !reflect.DeepEqual(data, expectedData) {
+		t.Errorf("Uint32ToByteList failed, expected %#v but got %#v", expectedData, data)
+		return
+	}
+}
+
 func TestByteListToString(t *testing.T) {
 	data := []byte{
 		0x48, 0x65, 0x6c, 0x6c, 0x6f, 0x2c, 0x20, 0xe4, 0xb8, 0x96, 0xe7, 0x95, 0x8c, 0x21, 0xf0, 0x9f, 0x91, 0x8b,
@@ -5,3 +5,16 @@ type ErrorType string
 type ErrorBaseCode string
 type DBDataTypeEnumeration string
 type DataComparator string
+type Collation string
+type WhereLogic string
+type AggregateFunction string
+type JoinType string
+type JoinAlgorithm string
+type SetOperator string
+type SubQueryType string
+type WindowFunction string
+type WindowFrameUnit string
+type ExpressionKind string
+type ExpressionType string
+type ExpressionOperator string
+type ExpressionFunction string
@@ -4,4 +4,184 @@ type WherePartItem struct {
 	TargetColumn string         `json:"target_column"`
 	Operate      DataComparator `json:"operate"`
 	Args         [][]byte       `json:"args"`
+	// Placeholders 预处理语句的参数占位符，和 Args 一一对应: 为空时使用 Args 中的值，否则为 ? 或者 $n，执行时绑定参数
+	// 没有绑定参数的条件不合法
+	Placeholders []string `json:"placeholders,omitempty"`
+}
+
+// Expression 表达式树，Kind 为:
+// column: 列 Column 的值；literal: 字面值，Value 为可读值（如: 12、abc、true），类型为 Type，Type 为 null 时是 Null；
+// operator: 运算符 Operator 作用于 Args；function: 函数 Function 作用于 Args，cast 的目标类型为 Type；
+// case: Args 为 条件1、结果1、条件2、结果2 ...，个数为奇数时最后一个为 ELSE 的结果
+// parameter: 预处理语句的参数，Value 为 ? 或者 $n，Type 为参数的类型，执行时绑定为字面值
+// 和 SQL 一样使用三值逻辑: 参数有 Null 时结果一般为 Null，条件的结果为 Null 时不满足
+type Expression struct {
+	Kind     ExpressionKind     `json:"kind"`
+	Column   string             `json:"column,omitempty"`
+	Value    string             `json:"value,omitempty"`
+	Type     ExpressionType     `json:"type,omitempty"`
+	Operator ExpressionOperator `json:"operator,omitempty"`
+	Function ExpressionFunction `json:"function,omitempty"`
+	Args     []*Expression      `json:"args,omitempty"`
+}
+
+// SelectExpression 查询结果中由表达式计算的列，bool 的结果为 char 类型的 true / false
+type SelectExpression struct {
+	Alias      string      `json:"alias"`
+	Expression *Expression `json:"expression"`
+}
+
+// WhereNode 条件树，Logic 为空时是单个条件 Item，否则按 Logic 组合 Children
+// not 只能有一个子节点
+type WhereNode struct {
+	Logic    WhereLogic     `json:"logic,omitempty"`
+	Item     *WherePartItem `json:"item,omitempty"`
+	Children []*WhereNode   `json:"children,omitempty"`
+}
+
+// AggregateItem 聚合函数，Column 为空时只能是 count，统计行数
+// Alias 为结果中的列名，为空时为 函数名(列名)，如: sum(price)、count(*)
+type AggregateItem struct {
+	Function AggregateFunction `json:"function"`
+	Column   string            `json:"column,omitempty"`
+	Alias    string            `json:"alias,omitempty"`
+}
+
+// AggregateQuery 聚合查询: 先按 Where 过滤，再按 GroupBy 分组计算 Aggregates，最后按 Having 过滤分组
+// Having 中的列为分组的列或者聚合结果的列名
+type AggregateQuery struct {
+	Where      []*WherePartItem `json:"where,omitempty"`
+	GroupBy    []string         `json:"group_by,omitempty"`
+	Aggregates []*AggregateItem `json:"aggregates,omitempty"`
+	Having     []*WherePartItem `json:"having,omitempty"`
+}
+
+// OrderByItem 排序的列，Desc 为 true 时降序
+type OrderByItem struct {
+	Column string `json:"column"`
+	Desc   bool   `json:"desc,omitempty"`
+}
+
+// SelectQuery 查询: 先按 Where 和 Predicate 过滤（可以使用主键的范围），再按 Filter 过滤，之后按 OrderBy 排序（为空时按照主键的顺序），最后跳过 Offset 行、最多返回 Limit 行
+// Where 和 Predicate 之间是 and 的关系；Limit 为 0 时不限制返回的行数
+type SelectQuery struct {
+	Where     []*WherePartItem `json:"where,omitempty"`
+	Predicate *WhereNode       `json:"predicate,omitempty"`
+	Filter    *Expression      `json:"filter,omitempty"`
+	OrderBy   []*OrderByItem   `json:"order_by,omitempty"`
+	Limit     int64            `json:"limit,omitempty"`
+	Offset    int64            `json:"offset,omitempty"`
+}
+
+// JoinOn 连接条件: 左表的列等于右表的列，Null 和任何值都不相等
+type JoinOn struct {
+	LeftColumn  string `json:"left_column"`
+	RightColumn string `json:"right_column"`
+}
+
+// JoinTable 参与连接的表，Where 在连接之前过滤该表；Alias 为结果中列名的前缀，为空时为表名（不包括数据库名）
+type JoinTable struct {
+	Name  string           `json:"name"`
+	Alias string           `json:"alias,omitempty"`
+	Where []*WherePartItem `json:"where,omitempty"`
+}
+
+// JoinQuery 两个表的连接，结果的列名为 别名.列名，外连接中没有匹配的一边为 Null
+// 多个 On 之间是 and 的关系，cross 连接没有 On；Algorithm 为空时由执行计划选择
+type JoinQuery struct {
+	Left      *JoinTable    `json:"left"`
+	Right     *JoinTable    `json:"right"`
+	Type      JoinType      `json:"type"`
+	On        []*JoinOn     `json:"on,omitempty"`
+	Algorithm JoinAlgorithm `json:"algorithm,omitempty"`
+}
+
+// QueryPlan 执行计划的说明，Cost 为估计的代价（单位为读取一页），Rows 为估计的结果行数
+// 单表查询只有 AccessPath；连接的 Outer / Inner 为外表（驱动表）和内表的别名
+type QueryPlan struct {
+	AccessPath string        `json:"access_path,omitempty"`
+	Algorithm  JoinAlgorithm `json:"algorithm,omitempty"`
+	Outer      string        `json:"outer,omitempty"`
+	Inner      string        `json:"inner,omitempty"`
+	Rows       float64       `json:"rows"`
+	Cost       float64       `json:"cost"`
+}
+
+// SetOperation 集合运算，按照列的位置和前面的结果运算，两边的列数和类型必须相同，结果的列名为前面的结果的列名
+type SetOperation struct {
+	Operator SetOperator `json:"operator"`
+	Query    *Query      `json:"query"`
+}
+
+// Correlation 相关子查询的条件: 子查询的列 Column 和外层查询的列 OuterColumn 比较，Operate 只能是 equal / not_equal / greater 等比较符
+type Correlation struct {
+	Column      string         `json:"column"`
+	Operate     DataComparator `json:"operate"`
+	OuterColumn string         `json:"outer_column"`
+}
+
+// SubQueryItem 子查询条件，Query 的结果只能有一列（exists / not_exists 除外）
+// scalar: 外层的列 TargetColumn 和子查询的值使用 Operate 比较，子查询最多返回一行，没有行时为 Null；
+// in / not_in: 外层的列在（不在）子查询的结果中；exists / not_exists: 子查询有（没有）结果
+// 和 SQL 一样，比较的两边有 Null 时不满足条件，not_in 的结果中有 Null 时任何行都不满足条件
+type SubQueryItem struct {
+	Type         SubQueryType   `json:"type"`
+	TargetColumn string         `json:"target_column,omitempty"`
+	Operate      DataComparator `json:"operate,omitempty"`
+	Query        *Query         `json:"query"`
+	Correlation  []*Correlation `json:"correlation,omitempty"`
+}
+
+// CommonTableExpression WITH 定义的临时结果，Name 在之后的 CTE 和查询中可以像表一样使用
+// Recursive 不为空时为 WITH RECURSIVE: 先执行 Query，之后反复执行 Recursive（其中 Name 为上一轮新增的行），直到没有新增的行；
+// UnionAll 为 false 时去掉和之前重复的行
+type CommonTableExpression struct {
+	Name      string `json:"name"`
+	Query     *Query `json:"query"`
+	Recursive *Query `json:"recursive,omitempty"`
+	UnionAll  bool   `json:"union_all,omitempty"`
+}
+
+// WindowFrame 窗口的范围，Start / End 为相对当前行的偏移: 负数为 preceding，0 为 current row，正数为 following，nil 为 unbounded
+// rows 按照行数计算；range 按照排序的值计算，当前行的值相同的行（peer）都在范围内，偏移不为 0 时只能有一个 bigint 类型的排序列
+type WindowFrame struct {
+	Unit  WindowFrameUnit `json:"unit"`
+	Start *int64          `json:"start,omitempty"`
+	End   *int64          `json:"end,omitempty"`
+}
+
+// WindowItem 窗口函数: 按 PartitionBy 分区，分区内按 OrderBy 排序后计算，结果作为新的一列加入每一行
+// Column 为参数的列，row_number / rank / dense_rank 没有参数，count 的 Column 为空时统计行数；
+// lag / lead 取分区中前（后）Offset 行（为 0 时为 1）的值，超出分区时为 Default（为 nil 时为 Null）；
+// Frame 只用于 count / sum / avg / first_value / last_value，为 nil 时有 OrderBy 为 range 从 unbounded preceding 到 current row，否则为整个分区；
+// Alias 为结果的列名，为空时为 函数名(列名)，如: row_number()、sum(amount)
+type WindowItem struct {
+	Function    WindowFunction `json:"function"`
+	Column      string         `json:"column,omitempty"`
+	Offset      int64          `json:"offset,omitempty"`
+	Default     []byte         `json:"default,omitempty"`
+	PartitionBy []string       `json:"partition_by,omitempty"`
+	OrderBy     []*OrderByItem `json:"order_by,omitempty"`
+	Frame       *WindowFrame   `json:"frame,omitempty"`
+	Alias       string         `json:"alias,omitempty"`
+}
+
+// Query 组合查询，按顺序执行:
+// 1. With 定义 CTE；2. 读取 From（表名或者 CTE 的名称）中满足 Where、Predicate、Filter 和 SubQueries 的行；3. Aggregate 聚合；4. Windows 窗口函数；
+// 5. Expressions 计算新的列；6. Columns 投影（为空时为全部的列）；7. 依次执行 SetOperations；8. 按 OrderBy 排序，跳过 Offset 行，最多返回 Limit 行
+type Query struct {
+	With          []*CommonTableExpression `json:"with,omitempty"`
+	From          string                   `json:"from"`
+	Where         []*WherePartItem         `json:"where,omitempty"`
+	Predicate     *WhereNode               `json:"predicate,omitempty"`
+	Filter        *Expression              `json:"filter,omitempty"`
+	SubQueries    []*SubQueryItem          `json:"sub_queries,omitempty"`
+	Aggregate     *AggregateQuery          `json:"aggregate,omitempty"`
+	Windows       []*WindowItem            `json:"windows,omitempty"`
+	Expressions   []*SelectExpression      `json:"expressions,omitempty"`
+	Columns       []string                 `json:"columns,omitempty"`
+	SetOperations []*SetOperation          `json:"set_operations,omitempty"`
+	OrderBy       []*OrderByItem           `json:"order_by,omitempty"`
+	Limit         int64                    `json:"limit,omitempty"`
+	Offset        int64                    `json:"offset,omitempty"`
 }
@@ -1,7 +1,35 @@
 package base
 
+import (
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// validRegexpCacheSize 缓存的正则表达式校验结果的最大个数，Validation 在每一行数据比较前都会调用，避免重复编译
+const validRegexpCacheSize = 1024
+
+var (
+	validRegexpLock  sync.Mutex
+	validRegexpCache = make(map[string]bool)
+)
+
+// validRegexp 正则表达式（RE2 语法）是否可以编译
+func validRegexp(pattern []byte) bool {
+	validRegexpLock.Lock()
+	defer validRegexpLock.Unlock()
+	if valid, ok := validRegexpCache[string(pattern)]; ok {
+		return valid
+	}
+	_, er := regexp.Compile(string(pattern))
+	if len(validRegexpCache) < validRegexpCacheSize {
+		validRegexpCache[string(pattern)] = er == nil
+	}
+	return er == nil
+}
+
 func (item *WherePartItem) Validation() bool {
-	if item.Args == nil {
+	if item.Args == nil || len(item.Placeholders) > 0 {
 		return false
 	}
 	switch item.Operate {
@@ -24,14 +52,111 @@ func (item *WherePartItem) Validation() bool {
 	case DataComparatorBetween:
 		return len(item.Args) == DataComparatorArgsCountBetween
 	case DataComparatorLike:
-		return len(item.Args) == DataComparatorArgsCountLike
+		return len(item.Args) == DataComparatorArgsCountLike || len(item.Args) == DataComparatorArgsCountLikeWithEscape
 	case DataComparatorILike:
-		return len(item.Args) == DataComparatorArgsCountILike
+		return len(item.Args) == DataComparatorArgsCountILike || len(item.Args) == DataComparatorArgsCountLikeWithEscape
 	case DataComparatorIsNull:
 		return len(item.Args) == DataComparatorArgsCountIsNull
 	case DataComparatorIsNotNull:
 		return len(item.Args) == DataComparatorArgsCountIsNotNull
+	case DataComparatorContains:
+		return len(item.Args) == DataComparatorArgsCountContains
+	case DataComparatorRegexp:
+		return len(item.Args) == DataComparatorArgsCountRegexp && validRegexp(item.Args[0])
+	case DataComparatorNotRegexp:
+		return len(item.Args) == DataComparatorArgsCountNotRegexp && validRegexp(item.Args[0])
 	default:
 		return false
 	}
 }
+
+// ColumnAndPath 拆分 TargetColumn 为列名和 json 路径
+// 如 attrs.color 拆分为 attrs 和 color，不包含路径时返回的路径为空
+func (item *WherePartItem) ColumnAndPath() (string, string) {
+	column, path, _ := strings.Cut(item.TargetColumn, SymbolJSONPathSeparator)
+	return column, path
+}
+
+func (node *WhereNode) Validation() bool {
+	if node == nil {
+		return false
+	}
+	switch node.Logic {
+	case "":
+		return node.Item != nil && len(node.Children) == 0 && node.Item.Validation()
+	case WhereLogicAnd, WhereLogicOr:
+		if node.Item != nil || len(node.Children) == 0 {
+			return false
+		}
+	case WhereLogicNot:
+		if node.Item != nil || len(node.Children) != 1 {
+			return false
+		}
+	default:
+		return false
+	}
+	for _, child := range node.Children {
+		if !child.Validation() {
+			return false
+		}
+	}
+	return true
+}
+
+// AndWhereNode 把 and 关系的条件列表和条件树组合为一个条件树，node 可以为 nil；都为空时返回 nil
+func AndWhereNode(items []*WherePartItem, node *WhereNode) *WhereNode {
+	if len(items) == 0 {
+		return node
+	}
+	r := &WhereNode{Logic: WhereLogicAnd, Children: make([]*WhereNode, 0, len(items)+1)}
+	for _, item := range items {
+		r.Children = append(r.Children, &WhereNode{Item: item})
+	}
+	if node != nil {
+		r.Children = append(r.Children, node)
+	}
+	return r
+}
+
+// Items 条件树中的全部单个条件
+func (node *WhereNode) Items() []*WherePartItem {
+	if node == nil {
+		return nil
+	}
+	if node.Item != nil {
+		return []*WherePartItem{node.Item}
+	}
+	r := make([]*WherePartItem, 0)
+	for _, child := range node.Children {
+		r = append(r, child.Items()...)
+	}
+	return r
+}
+
+// Columns 表达式中使用的全部列（可能重复）
+func (expr *Expression) Columns() []string {
+	if expr == nil {
+		return nil
+	}
+	r := make([]string, 0)
+	if expr.Kind == ExpressionKindColumn {
+		r = append(r, expr.Column)
+	}
+	for _, arg := range expr.Args {
+		r = append(r, arg.Columns()...)
+	}
+	return r
+}
+
+// RenameColumn 修改表达式中使用的列名
+func (expr *Expression) RenameColumn(oldName string, newName string) {
+	if expr == nil {
+		return
+	}
+	if expr.Kind == ExpressionKindColumn && expr.Column == oldName {
+		expr.Column = newName
+	}
+	for _, arg := range expr.Args {
+		arg.RenameColumn(oldName, newName)
+	}
+}
@@ -0,0 +1,688 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"ne_database/core/base"
+	"ne_database/core/config"
+	"ne_database/core/tableschema"
+	"ne_database/utils"
+)
+
+// catalogData 持久化的系统目录，保存全部数据库、表结构、序列名和视图，数据库、表结构和视图文件仍然是真实的数据来源
+// 创建、删除数据库，建表、删表、修改表结构，创建、删除序列以及创建、删除视图时同步修改，Init 时按照数据目录中的文件重建
+// 表名、序列名和视图名都是完整的名称（数据库名.名称）；物化视图同时有视图和同名的表
+// 表的统计信息只保存在系统目录中，由 Analyze 生成，删除、清空、重命名表时删除，重建系统目录后需要重新 Analyze
+type catalogData struct {
+	Databases  map[string]string `json:"databases"`            // 数据库名 -> 数据库信息 json
+	Tables     map[string]string `json:"tables"`               // 表名 -> 表结构 json
+	Sequences  map[string]bool   `json:"sequences"`            // 序列名
+	Statistics map[string]string `json:"statistics,omitempty"` // 表名 -> 统计信息 json
+	Views      map[string]string `json:"views,omitempty"`      // 视图名 -> 视图信息 json
+}
+
+// catalogSnapshotData 系统目录的快照，都按照名称排序
+type catalogSnapshotData struct {
+	Databases  []*tableschema.DatabaseInfo
+	Tables     []*tableschema.TableMetaInfo
+	Sequences  []string
+	Statistics []*tableschema.TableStatistics
+	Views      []*tableschema.ViewInfo
+}
+
+// catalogTable 系统目录的虚拟表，只读，数据在查询时由系统目录生成
+// 虚拟表的主键只用于描述列，不保证唯一
+type catalogTable struct {
+	TableInfo *tableschema.TableMetaInfo
+	Rows      func(e *Engine, snapshot *catalogSnapshotData) ([]map[string][]byte, base.StandardError)
+}
+
+func getCatalogFilePath() string {
+	return fmt.Sprintf("%s%s.%s", config.CoreConfig.FileAddr, base.DataIOFileCatalogName, base.DataIOFileCatalogSuffix)
+}
+
+// loadCatalog 读取系统目录，优先使用 Engine 中缓存的系统目录，没有缓存时读取文件，文件不存在时按照表结构文件重建
+// 调用方需要持有 catalogLock，返回的是缓存本身，只能在持有锁时读取，修改需要通过 updateCatalog
+func (e *Engine) loadCatalog() (*catalogData, base.StandardError) {
+	if e.catalog != nil {
+		return e.catalog, nil
+	}
+	r, ok, err := readCatalogFile()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return e.rebuildCatalog()
+	}
+	e.catalog = r
+	e.catalogVer++
+	return r, nil
+}
+
+// readCatalogFile 读取并解析系统目录文件，文件不存在时第二个返回值为 false
+func readCatalogFile() (*catalogData, bool, base.StandardError) {
+	exist, er := utils.FileExist(getCatalogFilePath())
+	if er != nil {
+		errMsg := fmt.Sprintf("检查系统目录文件是否存在报错: %s", er.Error())
+		utils.LogError("[readCatalogFile] " + errMsg)
+		return nil, false, base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeIO, base.ErrorBaseCodeIOError, fmt.Errorf(errMsg))
+	}
+	if !exist {
+		return nil, false, nil
+	}
+	data, er := os.ReadFile(getCatalogFilePath())
+	if er != nil {
+		errMsg := fmt.Sprintf("读取系统目录发生错误: %s", er.Error())
+		utils.LogError("[readCatalogFile] " + errMsg)
+		return nil, false, base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeIO, base.ErrorBaseCodeIOError, fmt.Errorf(errMsg))
+	}
+	r := &catalogData{}
+	er = json.Unmarshal(data, r)
+	if er != nil {
+		errMsg := fmt.Sprintf("解析系统目录发生错误: %s", er.Error())
+		utils.LogError("[readCatalogFile] " + errMsg)
+		return nil, false, base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeIO, base.ErrorBaseCodeInnerDataError, fmt.Errorf(errMsg))
+	}
+	if r.Databases == nil {
+		r.Databases = make(map[string]string)
+	}
+	if r.Tables == nil {
+		r.Tables = make(map[string]string)
+	}
+	if r.Sequences == nil {
+		r.Sequences = make(map[string]bool)
+	}
+	if r.Statistics == nil {
+		r.Statistics = make(map[string]string)
+	}
+	if r.Views == nil {
+		r.Views = make(map[string]string)
+	}
+	return r, true, nil
+}
+
+// saveCatalog 持久化系统目录并更新缓存，没有数据库、表、序列和视图时删除系统目录文件
+// 写入失败时丢弃缓存，下次读取时重新读取文件，调用方需要持有 catalogLock
+func (e *Engine) saveCatalog(data *catalogData) base.StandardError {
+	var er error
+	if len(data.Databases) == 0 && len(data.Tables) == 0 && len(data.Sequences) == 0 && len(data.Views) == 0 {
+		er = os.Remove(getCatalogFilePath())
+		if os.IsNotExist(er) {
+			er = nil
+		}
+	} else {
+		er = utils.WriteFileAtomic(getCatalogFilePath(), []byte(utils.ToJSON(data)), base.DataIOFileTempSuffix)
+	}
+	if er != nil {
+		errMsg := fmt.Sprintf("写入系统目录发生错误: %s", er.Error())
+		utils.LogError("[Engine saveCatalog] " + errMsg)
+		e.catalog = nil
+		e.catalogVer++
+		return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeIO, base.ErrorBaseCodeIOError, fmt.Errorf(errMsg))
+	}
+	e.catalog = data
+	e.catalogVer++
+	return nil
+}
+
+// rebuildCatalog 扫描数据目录以及各个数据库目录中的文件，重建系统目录，调用方需要持有 catalogLock
+// 统计信息没有对应的文件，从原来的系统目录（缓存或者文件）中保留仍然存在的表的统计信息，原来的系统目录无法读取时丢弃
+func (e *Engine) rebuildCatalog() (*catalogData, base.StandardError) {
+	databases, err := e.scanDatabases()
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[rebuildCatalog] scanDatabases错误, %s", err.Error()))
+		return nil, err
+	}
+	previous := e.catalog
+	if previous == nil {
+		data, ok, err := readCatalogFile()
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[rebuildCatalog] 原来的系统目录无法读取，丢弃统计信息, %s", err.Error()))
+		} else if ok {
+			previous = data
+		}
+	}
+	r := &catalogData{Databases: make(map[string]string), Tables: make(map[string]string), Sequences: make(map[string]bool), Statistics: make(map[string]string),
+		Views: make(map[string]string)}
+	for _, database := range databases {
+		if database != "" {
+			databaseInfo, err := e.LoadDatabaseInfo(database)
+			if err != nil {
+				return nil, err
+			}
+			data, err := databaseInfo.DatabaseInfoToJsonByte()
+			if err != nil {
+				return nil, err
+			}
+			r.Databases[database] = string(data)
+		}
+		dirPath := getDatabaseDirPath(database)
+		entries, er := os.ReadDir(dirPath)
+		if er != nil {
+			errMsg := fmt.Sprintf("读取 %s 目录发生错误: %s", dirPath, er.Error())
+			utils.LogError("[Engine rebuildCatalog] " + errMsg)
+			return nil, base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeIO, base.ErrorBaseCodeIOError, fmt.Errorf(errMsg))
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			rawName := entry.Name()
+			if strings.HasSuffix(rawName, "."+base.DataIOFileTableSchemaSuffix) {
+				n := tableschema.QualifiedTableName(database, strings.TrimSuffix(rawName, "."+base.DataIOFileTableSchemaSuffix))
+				tableInfo, err := e.loadTableSchemaInfo(n)
+				if err != nil {
+					return nil, err
+				}
+				data, err := tableInfo.TableMetaInfoToJsonByte()
+				if err != nil {
+					return nil, err
+				}
+				r.Tables[tableInfo.Name] = string(data)
+				if previous != nil {
+					if statistics, ok := previous.Statistics[tableInfo.Name]; ok {
+						r.Statistics[tableInfo.Name] = statistics
+					}
+				}
+			} else if strings.HasSuffix(rawName, "."+base.DataIOFileSequenceSuffix) {
+				r.Sequences[tableschema.QualifiedTableName(database, strings.TrimSuffix(rawName, "."+base.DataIOFileSequenceSuffix))] = true
+			} else if strings.HasSuffix(rawName, "."+base.DataIOFileViewSuffix) {
+				viewInfo, err := e.loadViewInfo(tableschema.QualifiedTableName(database, strings.TrimSuffix(rawName, "."+base.DataIOFileViewSuffix)))
+				if err != nil {
+					return nil, err
+				}
+				data, err := viewInfo.ViewInfoToJsonByte()
+				if err != nil {
+					return nil, err
+				}
+				r.Views[viewInfo.Name] = string(data)
+			}
+		}
+	}
+	err = e.saveCatalog(r)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// updateCatalog 修改系统目录并持久化
+func (e *Engine) updateCatalog(update func(data *catalogData)) base.StandardError {
+	e.catalogLock.Lock()
+	defer e.catalogLock.Unlock()
+	data, err := e.loadCatalog()
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[updateCatalog] loadCatalog错误, %s", err.Error()))
+		return err
+	}
+	update(data)
+	return e.saveCatalog(data)
+}
+
+// readCatalog 持有 catalogLock 读取系统目录，read 中不能修改 data，也不能在返回之后继续使用 data
+func (e *Engine) readCatalog(read func(data *catalogData) base.StandardError) base.StandardError {
+	e.catalogLock.Lock()
+	defer e.catalogLock.Unlock()
+	data, err := e.loadCatalog()
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[readCatalog] loadCatalog错误, %s", err.Error()))
+		return err
+	}
+	return read(data)
+}
+
+// catalogPutTable 在系统目录中写入表结构，tableInfoByte 为表结构的 json
+func (e *Engine) catalogPutTable(tableName string, tableInfoByte []byte) base.StandardError {
+	return e.updateCatalog(func(data *catalogData) {
+		data.Tables[tableName] = string(tableInfoByte)
+	})
+}
+
+// catalogRemoveTable 在系统目录中删除表以及表的统计信息
+func (e *Engine) catalogRemoveTable(tableName string) base.StandardError {
+	return e.updateCatalog(func(data *catalogData) {
+		delete(data.Tables, tableName)
+		delete(data.Statistics, tableName)
+	})
+}
+
+// catalogSetStatistics 在系统目录中写入表的统计信息，statisticsByte 为 nil 时删除
+func (e *Engine) catalogSetStatistics(tableName string, statisticsByte []byte) base.StandardError {
+	return e.updateCatalog(func(data *catalogData) {
+		if statisticsByte == nil {
+			delete(data.Statistics, tableName)
+		} else {
+			data.Statistics[tableName] = string(statisticsByte)
+		}
+	})
+}
+
+// catalogStatistics 读取表的统计信息，没有执行过 Analyze 时第二个返回值为 false
+func (e *Engine) catalogStatistics(tableName string) (*tableschema.TableStatistics, bool, base.StandardError) {
+	var (
+		statisticsJson string
+		ok             bool
+	)
+	err := e.readCatalog(func(data *catalogData) base.StandardError {
+		statisticsJson, ok = data.Statistics[tableName]
+		return nil
+	})
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	stats, err := tableschema.InitTableStatisticsByJson(statisticsJson)
+	if err != nil {
+		return nil, false, err
+	}
+	return stats, true, nil
+}
+
+// catalogVersion 缓存的系统目录的版本，版本相同时系统目录没有变化，版本不同时系统目录可能变化
+func (e *Engine) catalogVersion() uint64 {
+	e.catalogLock.Lock()
+	defer e.catalogLock.Unlock()
+	return e.catalogVer
+}
+
+// catalogFingerprint 表在系统目录中的表结构和视图，任何一个表的结构或者视图的定义变化时结果不同；
+// 第二个返回值为这些表的统计信息，重新 Analyze 或者统计信息被删除时结果不同；同时返回读取时系统目录的版本
+// 用于判断预处理语句缓存的参数类型和执行计划是否失效，tableNames 为带有数据库名的表名或者视图名
+func (e *Engine) catalogFingerprint(tableNames []string) (string, string, uint64, base.StandardError) {
+	var schema, statistics strings.Builder
+	var version uint64
+	err := e.readCatalog(func(data *catalogData) base.StandardError {
+		for _, name := range tableNames {
+			for _, part := range []string{name, data.Tables[name], data.Views[name]} {
+				schema.WriteString(part)
+				schema.WriteByte(0)
+			}
+			for _, part := range []string{name, data.Statistics[name]} {
+				statistics.WriteString(part)
+				statistics.WriteByte(0)
+			}
+		}
+		version = e.catalogVer
+		return nil
+	})
+	if err != nil {
+		return "", "", 0, err
+	}
+	return schema.String(), statistics.String(), version, nil
+}
+
+// catalogSetSequence 在系统目录中添加或者删除序列
+func (e *Engine) catalogSetSequence(sequenceName string, exist bool) base.StandardError {
+	return e.updateCatalog(func(data *catalogData) {
+		if exist {
+			data.Sequences[sequenceName] = true
+		} else {
+			delete(data.Sequences, sequenceName)
+		}
+	})
+}
+
+// catalogSetView 在系统目录中写入视图，viewInfoByte 为 nil 时删除
+func (e *Engine) catalogSetView(viewName string, viewInfoByte []byte) base.StandardError {
+	return e.updateCatalog(func(data *catalogData) {
+		if viewInfoByte == nil {
+			delete(data.Views, viewName)
+		} else {
+			data.Views[viewName] = string(viewInfoByte)
+		}
+	})
+}
+
+// catalogSnapshot 读取系统目录中的全部数据库、表结构、序列名、统计信息和视图
+func (e *Engine) catalogSnapshot() (*catalogSnapshotData, base.StandardError) {
+	r := &catalogSnapshotData{}
+	err := e.readCatalog(func(data *catalogData) base.StandardError {
+		return r.load(data)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// load 解析系统目录中的全部数据库、表结构、序列名、统计信息和视图
+func (r *catalogSnapshotData) load(data *catalogData) base.StandardError {
+	*r = catalogSnapshotData{
+		Databases: make([]*tableschema.DatabaseInfo, 0, len(data.Databases)),
+		Tables:    make([]*tableschema.TableMetaInfo, 0, len(data.Tables)),
+		Sequences: make([]string, 0, len(data.Sequences)),
+	}
+	for _, databaseJson := range data.Databases {
+		databaseInfo, err := tableschema.InitDatabaseInfoByJson(databaseJson)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[catalogSnapshot] InitDatabaseInfoByJson错误, %s", err.Error()))
+			return err
+		}
+		r.Databases = append(r.Databases, databaseInfo)
+	}
+	sort.Slice(r.Databases, func(i, j int) bool { return r.Databases[i].Name < r.Databases[j].Name })
+	for _, tableJson := range data.Tables {
+		tableInfo, err := tableschema.InitTableMetaInfoByJson(tableJson)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[catalogSnapshot] InitTableMetaInfoByJson错误, %s", err.Error()))
+			return err
+		}
+		r.Tables = append(r.Tables, tableInfo)
+	}
+	sort.Slice(r.Tables, func(i, j int) bool { return r.Tables[i].Name < r.Tables[j].Name })
+	for name := range data.Sequences {
+		r.Sequences = append(r.Sequences, name)
+	}
+	sort.Strings(r.Sequences)
+	for _, statisticsJson := range data.Statistics {
+		stats, err := tableschema.InitTableStatisticsByJson(statisticsJson)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[catalogSnapshot] InitTableStatisticsByJson错误, %s", err.Error()))
+			return err
+		}
+		r.Statistics = append(r.Statistics, stats)
+	}
+	sort.Slice(r.Statistics, func(i, j int) bool { return r.Statistics[i].TableName < r.Statistics[j].TableName })
+	for _, viewJson := range data.Views {
+		viewInfo, err := tableschema.InitViewInfoByJson(viewJson)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[catalogSnapshot] InitViewInfoByJson错误, %s", err.Error()))
+			return err
+		}
+		r.Views = append(r.Views, viewInfo)
+	}
+	sort.Slice(r.Views, func(i, j int) bool { return r.Views[i].Name < r.Views[j].Name })
+	return nil
+}
+
+// IsCatalogTable 判断表名是否是系统目录的虚拟表
+func IsCatalogTable(tableName string) bool {
+	_, ok := catalogTables()[tableName]
+	return ok
+}
+
+// catalogTableInfo 虚拟表的表结构，列为 char 或者 bigint
+func catalogTableInfo(name string, columns ...*tableschema.FieldInfo) *tableschema.TableMetaInfo {
+	return &tableschema.TableMetaInfo{
+		Name:                name,
+		PrimaryKeyFieldInfo: columns[0],
+		ValueFieldInfo:      columns[1:],
+		PageSize:            config.CoreConfig.PageSize,
+		StorageType:         base.StorageTypeMemory,
+	}
+}
+
+func catalogCharColumn(name string, length int) *tableschema.FieldInfo {
+	return &tableschema.FieldInfo{Name: name, Length: length, FieldType: tableschema.CharType}
+}
+
+func catalogBigIntColumn(name string) *tableschema.FieldInfo {
+	return &tableschema.FieldInfo{Name: name, Length: base.DataByteLengthInt64, FieldType: tableschema.BigIntType}
+}
+
+// catalogYesNo 系统目录中的布尔值按照 information_schema 的习惯使用 YES / NO
+func catalogYesNo(v bool) []byte {
+	if v {
+		return []byte("YES")
+	}
+	return []byte("NO")
+}
+
+// catalogTables 全部虚拟表，表名 -> 虚拟表
+func catalogTables() map[string]*catalogTable {
+	return map[string]*catalogTable{
+		base.CatalogTableDatabases: {
+			TableInfo: catalogTableInfo(base.CatalogTableDatabases,
+				catalogCharColumn("database_name", 64),
+				catalogBigIntColumn("page_size"),
+				catalogCharColumn("storage_type", 16),
+			),
+			// 不包括默认数据库
+			Rows: func(e *Engine, snapshot *catalogSnapshotData) ([]map[string][]byte, base.StandardError) {
+				r := make([]map[string][]byte, 0, len(snapshot.Databases))
+				for _, d := range snapshot.Databases {
+					r = append(r, map[string][]byte{
+						"database_name": []byte(d.Name),
+						"page_size":     tableschema.BigIntValue(int64(d.PageSize)),
+						"storage_type":  []byte(d.StorageType),
+					})
+				}
+				return r, nil
+			},
+		},
+		base.CatalogTableTables: {
+			TableInfo: catalogTableInfo(base.CatalogTableTables,
+				catalogCharColumn("table_name", 128),
+				catalogCharColumn("table_schema", 64),
+				catalogBigIntColumn("version"),
+				catalogBigIntColumn("page_size"),
+				catalogCharColumn("storage_type", 16),
+				catalogCharColumn("primary_key", 64),
+			),
+			Rows: func(e *Engine, snapshot *catalogSnapshotData) ([]map[string][]byte, base.StandardError) {
+				r := make([]map[string][]byte, 0, len(snapshot.Tables))
+				for _, t := range snapshot.Tables {
+					r = append(r, map[string][]byte{
+						"table_name":   []byte(t.Name),
+						"table_schema": []byte(tableschema.DatabaseName(t.Name)),
+						"version":      tableschema.BigIntValue(int64(t.Version)),
+						"page_size":    tableschema.BigIntValue(int64(t.PageSize)),
+						"storage_type": []byte(t.StorageType),
+						"primary_key":  []byte(t.PrimaryKeyFieldInfo.Name),
+					})
+				}
+				return r, nil
+			},
+		},
+		base.CatalogTableColumns: {
+			TableInfo: catalogTableInfo(base.CatalogTableColumns,
+				catalogCharColumn("table_name", 128),
+				catalogCharColumn("column_name", 64),
+				catalogBigIntColumn("ordinal_position"),
+				catalogCharColumn("data_type", 16),
+				catalogBigIntColumn("length"),
+				catalogCharColumn("is_primary_key", 3),
+				catalogCharColumn("is_auto_increment", 3),
+				catalogCharColumn("column_default", 1024),
+				catalogCharColumn("collation", 32),
+			),
+			Rows: func(e *Engine, snapshot *catalogSnapshotData) ([]map[string][]byte, base.StandardError) {
+				r := make([]map[string][]byte, 0)
+				for _, t := range snapshot.Tables {
+					for i, field := range append([]*tableschema.FieldInfo{t.PrimaryKeyFieldInfo}, t.ValueFieldInfo...) {
+						dataType, err := tableschema.FieldTypeToRaw(field.FieldType)
+						if err != nil {
+							return nil, err
+						}
+						r = append(r, map[string][]byte{
+							"table_name":        []byte(t.Name),
+							"column_name":       []byte(field.Name),
+							"ordinal_position":  tableschema.BigIntValue(int64(i + 1)),
+							"data_type":         []byte(dataType),
+							"length":            tableschema.BigIntValue(int64(field.Length)),
+							"is_primary_key":    catalogYesNo(i == 0),
+							"is_auto_increment": catalogYesNo(field.AutoIncrement),
+							"column_default":    []byte(field.DefaultDefinition()),
+							"collation":         []byte(field.Collation),
+						})
+					}
+				}
+				return r, nil
+			},
+		},
+		base.CatalogTableConstraints: {
+			TableInfo: catalogTableInfo(base.CatalogTableConstraints,
+				catalogCharColumn("table_name", 128),
+				catalogCharColumn("constraint_name", 128),
+				catalogCharColumn("constraint_type", 16),
+				catalogCharColumn("column_name", 64),
+				catalogCharColumn("ref_table", 64),
+				catalogCharColumn("ref_column", 64),
+				catalogCharColumn("definition", 1024),
+			),
+			Rows: func(e *Engine, snapshot *catalogSnapshotData) ([]map[string][]byte, base.StandardError) {
+				r := make([]map[string][]byte, 0)
+				for _, t := range snapshot.Tables {
+					r = append(r, map[string][]byte{
+						"table_name":      []byte(t.Name),
+						"constraint_name": []byte(tableschema.PrimaryKeyIndexName(t.Name)),
+						"constraint_type": []byte(base.ConstraintTypePrimaryKey),
+						"column_name":     []byte(t.PrimaryKeyFieldInfo.Name),
+					})
+					for _, constraint := range t.AllCheckConstraints() {
+						r = append(r, map[string][]byte{
+							"table_name":      []byte(t.Name),
+							"constraint_name": []byte(constraint.Name),
+							"constraint_type": []byte(base.ConstraintTypeCheck),
+							"definition":      []byte(constraint.Definition()),
+						})
+					}
+					for _, fk := range t.ForeignKeys {
+						r = append(r, map[string][]byte{
+							"table_name":      []byte(t.Name),
+							"constraint_name": []byte(fk.Name),
+							"constraint_type": []byte(base.ConstraintTypeForeignKey),
+							"column_name":     []byte(fk.Column),
+							"ref_table":       []byte(fk.RefTable),
+							"ref_column":      []byte(fk.RefColumn),
+							"definition":      []byte(fmt.Sprintf("on_delete: %s, on_update: %s", fk.DeleteAction(), fk.UpdateAction())),
+						})
+					}
+				}
+				return r, nil
+			},
+		},
+		base.CatalogTableIndexes: {
+			TableInfo: catalogTableInfo(base.CatalogTableIndexes,
+				catalogCharColumn("table_name", 128),
+				catalogCharColumn("index_name", 128),
+				catalogCharColumn("column_name", 64),
+				catalogCharColumn("is_unique", 3),
+			),
+			// 目前只有主键对应的B+树索引
+			Rows: func(e *Engine, snapshot *catalogSnapshotData) ([]map[string][]byte, base.StandardError) {
+				r := make([]map[string][]byte, 0, len(snapshot.Tables))
+				for _, t := range snapshot.Tables {
+					r = append(r, map[string][]byte{
+						"table_name":  []byte(t.Name),
+						"index_name":  []byte(tableschema.PrimaryKeyIndexName(t.Name)),
+						"column_name": []byte(t.PrimaryKeyFieldInfo.Name),
+						"is_unique":   catalogYesNo(true),
+					})
+				}
+				return r, nil
+			},
+		},
+		base.CatalogTableSequences: {
+			TableInfo: catalogTableInfo(base.CatalogTableSequences,
+				catalogCharColumn("sequence_name", 128),
+				catalogBigIntColumn("start"),
+				catalogBigIntColumn("increment"),
+				catalogBigIntColumn("cache_size"),
+				catalogBigIntColumn("next"),
+			),
+			Rows: func(e *Engine, snapshot *catalogSnapshotData) ([]map[string][]byte, base.StandardError) {
+				r := make([]map[string][]byte, 0, len(snapshot.Sequences))
+				for _, name := range snapshot.Sequences {
+					info, err := e.loadSequenceInfo(name)
+					if err != nil {
+						return nil, err
+					}
+					r = append(r, map[string][]byte{
+						"sequence_name": []byte(info.Name),
+						"start":         tableschema.BigIntValue(info.Start),
+						"increment":     tableschema.BigIntValue(info.Increment),
+						"cache_size":    tableschema.BigIntValue(int64(info.CacheSize)),
+						"next":          tableschema.BigIntValue(info.Next),
+					})
+				}
+				return r, nil
+			},
+		},
+		base.CatalogTableStatistics: {
+			TableInfo: catalogTableInfo(base.CatalogTableStatistics,
+				catalogCharColumn("table_name", 128),
+				catalogCharColumn("column_name", 64),
+				catalogBigIntColumn("row_count"),
+				catalogBigIntColumn("page_count"),
+				catalogBigIntColumn("distinct_count"),
+				catalogCharColumn("null_fraction", 16),
+				catalogBigIntColumn("histogram_bounds"),
+			),
+			// 每个执行过 Analyze 的表的每一列一行
+			Rows: func(e *Engine, snapshot *catalogSnapshotData) ([]map[string][]byte, base.StandardError) {
+				r := make([]map[string][]byte, 0)
+				for _, stats := range snapshot.Statistics {
+					for _, c := range stats.Columns {
+						r = append(r, map[string][]byte{
+							"table_name":       []byte(stats.TableName),
+							"column_name":      []byte(c.Name),
+							"row_count":        tableschema.BigIntValue(stats.RowCount),
+							"page_count":       tableschema.BigIntValue(stats.PageCount),
+							"distinct_count":   tableschema.BigIntValue(c.DistinctCount),
+							"null_fraction":    []byte(strconv.FormatFloat(c.NullFraction, 'f', 4, 64)),
+							"histogram_bounds": tableschema.BigIntValue(int64(len(c.Histogram))),
+						})
+					}
+				}
+				return r, nil
+			},
+		},
+		base.CatalogTableViews: {
+			TableInfo: catalogTableInfo(base.CatalogTableViews,
+				catalogCharColumn("table_name", 128),
+				catalogCharColumn("table_schema", 64),
+				catalogCharColumn("is_materialized", 3),
+				catalogCharColumn("view_definition", 4096),
+			),
+			Rows: func(e *Engine, snapshot *catalogSnapshotData) ([]map[string][]byte, base.StandardError) {
+				r := make([]map[string][]byte, 0, len(snapshot.Views))
+				for _, v := range snapshot.Views {
+					r = append(r, map[string][]byte{
+						"table_name":      []byte(v.Name),
+						"table_schema":    []byte(tableschema.DatabaseName(v.Name)),
+						"is_materialized": catalogYesNo(v.Materialized),
+						"view_definition": []byte(v.Definition()),
+					})
+				}
+				return r, nil
+			},
+		},
+	}
+}
+
+// selectCatalogTable 查询系统目录的虚拟表，没有值的列为 Null
+func (e *Engine) selectCatalogTable(table *catalogTable, whereArgs []*base.WherePartItem) ([]map[string][]byte, base.StandardError) {
+	snapshot, err := e.catalogSnapshot()
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[selectCatalogTable] catalogSnapshot错误, %s", err.Error()))
+		return nil, err
+	}
+	rows, err := table.Rows(e, snapshot)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[selectCatalogTable] 生成虚拟表<%s>的数据错误, %s", table.TableInfo.Name, err.Error()))
+		return nil, err
+	}
+	regexpCache := tableschema.NewRegexpCache()
+	r := make([]map[string][]byte, 0, len(rows))
+	for _, row := range rows {
+		for _, field := range append([]*tableschema.FieldInfo{table.TableInfo.PrimaryKeyFieldInfo}, table.TableInfo.ValueFieldInfo...) {
+			if _, ok := row[field.Name]; !ok {
+				row[field.Name] = field.FieldType.TrimRaw(field.NullValue())
+			}
+		}
+		match, err := table.TableInfo.MatchWhereParts(whereArgs, row, regexpCache)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[selectCatalogTable] MatchWhereParts错误, %s", err.Error()))
+			return nil, err
+		}
+		if match {
+			r = append(r, row)
+		}
+	}
+	return r, nil
+}
@@ -0,0 +1,169 @@
+package core
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"ne_database/core/base"
+	"ne_database/core/tableschema"
+	"ne_database/utils"
+)
+
+// newTestCatalogTables users 的主键自增，name 有默认值，age 有 CHECK 约束；orders 引用 users
+func newTestCatalogTables() (users, orders *tableschema.TableMetaInfo) {
+	name := testCharField("name", 10)
+	name.DefaultValue = "'guest'"
+	age := testBigIntField("age")
+	age.Check = tableschema.CheckExpression{{
+		{TargetColumn: "age", Operate: base.DataComparatorGreaterAndEqual, Args: [][]byte{testInt64(0)}},
+	}}
+	users = newTestTableInfo("engine_catalog_users", name, age)
+	users.PrimaryKeyFieldInfo.AutoIncrement = true
+	orders = newTestTableInfo("engine_catalog_orders", testBigIntField("user_id"))
+	orders.ForeignKeys = []*tableschema.ForeignKey{{Column: "user_id", RefTable: users.Name, RefColumn: "id"}}
+	return users, orders
+}
+
+// testWhereTable 系统目录中 table_name 等于 tableName 的条件
+func testWhereTable(tableName string) []*base.WherePartItem {
+	return []*base.WherePartItem{{TargetColumn: "table_name", Operate: base.DataComparatorEqual, Args: [][]byte{[]byte(tableName)}}}
+}
+
+// testColumnValues 按结果的顺序取 column 列的值，以逗号连接
+func testColumnValues(rows []map[string][]byte, column string) string {
+	r := make([]string, 0, len(rows))
+	for _, row := range rows {
+		r = append(r, string(row[column]))
+	}
+	return strings.Join(r, ",")
+}
+
+func TestEngine_Select_Catalog(t *testing.T) {
+	users, orders := newTestCatalogTables()
+	e := newTestEngine(t, users, orders)
+
+	sequenceName := tableschema.AutoIncrementSequenceName(users.Name, "id")
+	testCases := []struct {
+		tableName string
+		whereArgs []*base.WherePartItem
+		column    string
+		expect    string
+	}{
+		{base.CatalogTableTables, testWhereTable(users.Name), "primary_key", "id"},
+		{base.CatalogTableTables, testWhereTable(users.Name), "storage_type", string(base.StorageTypeFile)},
+		// 按照列的顺序
+		{base.CatalogTableColumns, testWhereTable(users.Name), "column_name", "id,name,age"},
+		{base.CatalogTableColumns, testWhereTable(users.Name), "column_default", ",'guest',"},
+		{base.CatalogTableColumns, testWhereTable(users.Name), "is_auto_increment", "YES,NO,NO"},
+		{base.CatalogTableColumns, testWhereTable(users.Name), "is_primary_key", "YES,NO,NO"},
+		{base.CatalogTableIndexes, testWhereTable(orders.Name), "table_name", orders.Name},
+		{
+			base.CatalogTableSequences,
+			[]*base.WherePartItem{{TargetColumn: "sequence_name", Operate: base.DataComparatorEqual, Args: [][]byte{[]byte(sequenceName)}}},
+			"sequence_name", sequenceName,
+		},
+	}
+	for i, c := range testCases {
+		_, rows, err := e.Select(c.tableName, c.whereArgs)
+		if err != nil {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+			continue
+		}
+		if r := testColumnValues(rows, c.column); r != c.expect {
+			t.Errorf("case %d: expected %s, but got %s", i, c.expect, r)
+		}
+	}
+
+	// information_schema.constraints: 主键、CHECK、外键
+	_, rows, err := e.Select(base.CatalogTableConstraints, nil)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	constraintTypes := make(map[string]string)
+	for _, row := range rows {
+		constraintTypes[string(row["constraint_name"])] = string(row["constraint_type"])
+	}
+	expectConstraints := map[string]string{
+		tableschema.PrimaryKeyIndexName(users.Name):        base.ConstraintTypePrimaryKey,
+		tableschema.CheckConstraintName(users.Name, "age"): base.ConstraintTypeCheck,
+		tableschema.ForeignKeyName(orders.Name, "user_id"): base.ConstraintTypeForeignKey,
+	}
+	for name, constraintType := range expectConstraints {
+		if constraintTypes[name] != constraintType {
+			t.Errorf("constraint %s: expected %s, but got %s", name, constraintType, constraintTypes[name])
+		}
+	}
+}
+
+func TestEngine_Select_PrimaryKey(t *testing.T) {
+	// 普通表的查询结果包括主键
+	users, _ := newTestCatalogTables()
+	e := newTestEngine(t, users)
+	_, key, err := e.Insert(users.Name, map[string][]byte{"name": []byte("Alice")})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	count, rows, err := e.Select(users.Name, nil)
+	if err != nil || count != 1 || string(rows[0]["id"]) != string(key) || string(rows[0]["name"]) != "Alice" {
+		t.Errorf("Select failed, got %d rows %#v, %v", count, rows, err)
+	}
+}
+
+func TestEngine_Catalog_Rebuild(t *testing.T) {
+	e := newTestEngine(t)
+	// 在删除表之后执行，所有的表删除之后删除系统目录文件
+	t.Cleanup(func() {
+		if exist, _ := utils.FileExist(getCatalogFilePath()); exist {
+			t.Error("catalog file not removed after all tables deleted")
+		}
+	})
+	users, orders := newTestCatalogTables()
+	createTestTables(t, e, users, orders)
+
+	allTable, err := e.AllTable()
+	if _, ok := allTable[orders.Name]; err != nil || !ok {
+		t.Errorf("AllTable failed, %s not found, %v", orders.Name, err)
+		return
+	}
+	// 读取使用缓存的系统目录，不再读取文件，Init 时重建系统目录文件
+	testCases := []struct {
+		action      func() error
+		expectExist bool
+	}{
+		{func() error { return os.Remove(getCatalogFilePath()) }, false},
+		{func() error { return e.Init() }, true},
+	}
+	for i, c := range testCases {
+		if err := c.action(); err != nil {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+			continue
+		}
+		exist, _ := utils.FileExist(getCatalogFilePath())
+		count, _, err := e.Select(base.CatalogTableTables, testWhereTable(orders.Name))
+		if exist != c.expectExist || err != nil || count != 1 {
+			t.Errorf("case %d: file exist %v, got %d rows, %v", i, exist, count, err)
+		}
+	}
+}
+
+func TestEngine_Catalog_ReadOnly(t *testing.T) {
+	e := newTestEngine(t)
+	// 系统目录只读，也不能建同名的表
+	testCases := []func() error{
+		func() error {
+			_, _, err := e.Insert(base.CatalogTableTables, map[string][]byte{"table_name": []byte("x")})
+			return err
+		},
+		func() error {
+			return e.CreateTable(newTestTableInfo(base.CatalogTableTables))
+		},
+	}
+	for i, c := range testCases {
+		if err := c(); err == nil {
+			t.Errorf("case %d: expected error, but got nil", i)
+		}
+	}
+}
@@ -15,6 +15,15 @@ type config struct {
 	Dev      bool   `json:"Dev"`      // 是否处在开发模式
 	PageSize int    `json:"PageSize"` // 数据一页的大小（新建表默认）
 	FileAddr string `json:"FileAddr"` // 数据文件存放目录
+
+	SequenceCacheSize int `json:"SequenceCacheSize"` // 序列每次预先分配的数量（序列没有设置时使用）
+
+	OperatorMemoryRows int `json:"OperatorMemoryRows"` // 聚合、排序时内存中最多保存的行数（分组数），超过时写入临时文件
+	SpillMergeFanIn    int `json:"SpillMergeFanIn"`    // 外部排序归并时最多同时读取的临时文件数量，超过时分多趟归并
+
+	AnalyzeSampleLeaves int `json:"AnalyzeSampleLeaves"` // Analyze 时最多采样的叶子结点数量
+
+	RecursiveCTEMaxDepth int `json:"RecursiveCTEMaxDepth"` // WITH RECURSIVE 最多迭代的次数，超过时报错（防止死循环）
 }
 
 func (c *config) Init() base.StandardError {
@@ -57,6 +66,11 @@ func (c *config) InitToDefault() {
 	c.Dev = false
 	c.PageSize = 64000 // go中是按照byte计算的
 	c.FileAddr = "./"
+	c.SequenceCacheSize = 20
+	c.OperatorMemoryRows = 10000
+	c.SpillMergeFanIn = 64
+	c.AnalyzeSampleLeaves = 100
+	c.RecursiveCTEMaxDepth = 1000
 }
 
 var CoreConfig = config{}
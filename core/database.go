@@ -0,0 +1,267 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"ne_database/core/base"
+	"ne_database/core/config"
+	"ne_database/core/tableschema"
+	"ne_database/utils"
+)
+
+// getDatabaseDirPath 数据库对应的目录，默认数据库为数据目录本身
+func getDatabaseDirPath(database string) string {
+	if database == "" {
+		return config.CoreConfig.FileAddr
+	}
+	return config.CoreConfig.FileAddr + database + "/"
+}
+
+func getDatabaseFilePath(database string) string {
+	return fmt.Sprintf("%s%s.%s", getDatabaseDirPath(database), base.DataIOFileDatabaseName, base.DataIOFileDatabaseSuffix)
+}
+
+// getTableFilePath 表或序列的文件路径，name 为完整的名称（数据库名.名称），文件在数据库对应的目录中
+func getTableFilePath(name string, suffix string) string {
+	database, n := tableschema.SplitTableName(name)
+	return fmt.Sprintf("%s%s.%s", getDatabaseDirPath(database), n, suffix)
+}
+
+// qualifiedName 在当前数据库中解析表名或序列名，已经带有数据库名的名称不变
+func (e *Engine) qualifiedName(name string) string {
+	return tableschema.QualifiedTableName(e.database, name)
+}
+
+// CurrentDatabase 当前数据库，为空时是默认数据库
+func (e *Engine) CurrentDatabase() string {
+	return e.database
+}
+
+// UseDatabase 切换当前数据库，之后没有数据库名的表名和序列名都在该数据库中解析；database 为空时切换到默认数据库
+func (e *Engine) UseDatabase(database string) base.StandardError {
+	if database != "" {
+		exist, err := e.CheckDatabaseExist(database)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[UseDatabase] CheckDatabaseExist错误, %s", err.Error()))
+			return err
+		}
+		if !exist {
+			errMsg := fmt.Sprintf("数据库: %s 不存在", database)
+			utils.LogError("[Engine UseDatabase] " + errMsg)
+			return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+		}
+	}
+	e.database = database
+	return nil
+}
+
+// CheckDatabaseExist 判断数据库是否存在
+func (e *Engine) CheckDatabaseExist(database string) (bool, base.StandardError) {
+	exist, er := utils.FileExist(getDatabaseFilePath(database))
+	if er != nil {
+		errMsg := fmt.Sprintf("检查数据库文件是否存在报错: %s", er.Error())
+		utils.LogError("[Engine CheckDatabaseExist] " + errMsg)
+		return false, base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeIO, base.ErrorBaseCodeIOError, fmt.Errorf(errMsg))
+	}
+	return exist, nil
+}
+
+// LoadDatabaseInfo 读取数据库信息
+func (e *Engine) LoadDatabaseInfo(database string) (*tableschema.DatabaseInfo, base.StandardError) {
+	bytes, er := os.ReadFile(getDatabaseFilePath(database))
+	if er != nil {
+		errMsg := fmt.Sprintf("读取数据库<%s>时发生错误: %s", database, er.Error())
+		utils.LogError("[Engine LoadDatabaseInfo] " + errMsg)
+		return nil, base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeIO, base.ErrorBaseCodeIOError, fmt.Errorf(errMsg))
+	}
+	return tableschema.InitDatabaseInfoByJson(string(bytes))
+}
+
+// CreateDatabase 创建数据库，在数据目录下创建同名的目录并写入数据库配置
+func (e *Engine) CreateDatabase(info *tableschema.DatabaseInfo) base.StandardError {
+	if info == nil {
+		errMsg := "输入的databaseInfo为空"
+		utils.LogError("[Engine CreateDatabase] " + errMsg)
+		return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+	}
+	data, err := info.DatabaseInfoToJsonByte()
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[CreateDatabase] DatabaseInfoToJsonByte错误, %s", err.Error()))
+		return err
+	}
+	exist, err := e.CheckDatabaseExist(info.Name)
+	if err != nil {
+		return err
+	}
+	if exist {
+		errMsg := fmt.Sprintf("数据库: %s 已存在", info.Name)
+		utils.LogError("[Engine CreateDatabase] " + errMsg)
+		return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+	}
+	er := os.MkdirAll(getDatabaseDirPath(info.Name), os.ModePerm)
+	if er == nil {
+		er = utils.WriteFileAtomic(getDatabaseFilePath(info.Name), data, base.DataIOFileTempSuffix)
+	}
+	if er != nil {
+		errMsg := fmt.Sprintf("创建数据库<%s>发生错误: %s", info.Name, er.Error())
+		utils.LogError("[Engine CreateDatabase] " + errMsg)
+		return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeIO, base.ErrorBaseCodeIOError, fmt.Errorf(errMsg))
+	}
+	return e.updateCatalog(func(catalog *catalogData) {
+		catalog.Databases[info.Name] = string(data)
+	})
+}
+
+// DropDatabase 删除数据库，数据库中还有表、序列或视图时不能删除；force 为 true 时先删除其中全部的视图、表和序列
+// 删除的是当前数据库时，切换到默认数据库
+func (e *Engine) DropDatabase(database string, force bool) base.StandardError {
+	exist, err := e.CheckDatabaseExist(database)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[DropDatabase] CheckDatabaseExist错误, %s", err.Error()))
+		return err
+	}
+	if database == "" || !exist {
+		errMsg := fmt.Sprintf("数据库: %s 不存在", database)
+		utils.LogError("[Engine DropDatabase] " + errMsg)
+		return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+	}
+	snapshot, err := e.catalogSnapshot()
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[DropDatabase] catalogSnapshot错误, %s", err.Error()))
+		return err
+	}
+	tables := make([]string, 0)
+	for _, tableInfo := range snapshot.Tables {
+		if tableschema.DatabaseName(tableInfo.Name) == database {
+			tables = append(tables, tableInfo.Name)
+		}
+	}
+	sequences := make([]string, 0)
+	for _, sequenceName := range snapshot.Sequences {
+		if tableschema.DatabaseName(sequenceName) == database {
+			sequences = append(sequences, sequenceName)
+		}
+	}
+	views := make([]string, 0)
+	for _, viewInfo := range snapshot.Views {
+		if tableschema.DatabaseName(viewInfo.Name) == database {
+			views = append(views, viewInfo.Name)
+		}
+	}
+	if !force && len(tables)+len(sequences)+len(views) > 0 {
+		errMsg := fmt.Sprintf("数据库<%s>中还有%d个表、%d个序列和%d个视图，不能删除", database, len(tables), len(sequences), len(views))
+		utils.LogError("[Engine DropDatabase] " + errMsg)
+		return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeInput, base.ErrorBaseCodeConstraintError, fmt.Errorf(errMsg))
+	}
+	// 先删除视图，物化视图的表同时删除
+	for _, viewName := range views {
+		err = e.DropView(viewName)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[DropDatabase] DropView错误, %s", err.Error()))
+			return err
+		}
+	}
+	// 外键只能引用同一个数据库的表，所以删除其中的表不会影响其他数据库
+	for _, tableName := range tables {
+		err = e.DropTableCascade(tableName)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[DropDatabase] DropTableCascade错误, %s", err.Error()))
+			return err
+		}
+	}
+	for _, sequenceName := range sequences {
+		err = e.DropSequence(sequenceName)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[DropDatabase] DropSequence错误, %s", err.Error()))
+			return err
+		}
+	}
+	er := os.RemoveAll(getDatabaseDirPath(database))
+	if er != nil {
+		errMsg := fmt.Sprintf("删除数据库<%s>的目录发生错误: %s", database, er.Error())
+		utils.LogError("[Engine DropDatabase] " + errMsg)
+		return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeIO, base.ErrorBaseCodeIOError, fmt.Errorf(errMsg))
+	}
+	if e.database == database {
+		e.database = ""
+	}
+	return e.updateCatalog(func(catalog *catalogData) {
+		delete(catalog.Databases, database)
+	})
+}
+
+// AllDatabase 从系统目录中读取全部数据库（不包括默认数据库），数据库名 -> 数据库信息
+func (e *Engine) AllDatabase() (map[string]*tableschema.DatabaseInfo, base.StandardError) {
+	snapshot, err := e.catalogSnapshot()
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[AllDatabase] catalogSnapshot错误, %s", err.Error()))
+		return nil, err
+	}
+	r := make(map[string]*tableschema.DatabaseInfo, len(snapshot.Databases))
+	for _, info := range snapshot.Databases {
+		r[info.Name] = info
+	}
+	return r, nil
+}
+
+// nameDatabaseInfo 完整的表名或序列名所在的数据库，默认数据库返回 nil，数据库不存在时报错
+func (e *Engine) nameDatabaseInfo(name string) (*tableschema.DatabaseInfo, base.StandardError) {
+	database := tableschema.DatabaseName(name)
+	if database == "" {
+		return nil, nil
+	}
+	exist, err := e.CheckDatabaseExist(database)
+	if err != nil {
+		return nil, err
+	}
+	if !exist {
+		errMsg := fmt.Sprintf("<%s>所在的数据库: %s 不存在", name, database)
+		utils.LogError("[Engine nameDatabaseInfo] " + errMsg)
+		return nil, base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+	}
+	return e.LoadDatabaseInfo(database)
+}
+
+// fillTableDefault 表没有设置 PageSize / StorageType 时使用所在数据库的配置，数据库也没有设置时使用全局配置
+func (e *Engine) fillTableDefault(tableInfo *tableschema.TableMetaInfo) base.StandardError {
+	databaseInfo, err := e.nameDatabaseInfo(tableInfo.Name)
+	if err != nil {
+		return err
+	}
+	if databaseInfo != nil {
+		databaseInfo.FillTableDefault(tableInfo)
+	}
+	if tableInfo.PageSize == 0 {
+		tableInfo.PageSize = config.CoreConfig.PageSize
+	}
+	if tableInfo.StorageType == "" {
+		tableInfo.StorageType = base.StorageTypeFile
+	}
+	return nil
+}
+
+// scanDatabases 扫描数据目录中的数据库，返回的第一个为默认数据库（空字符串）
+func (e *Engine) scanDatabases() ([]string, base.StandardError) {
+	entries, er := os.ReadDir(config.CoreConfig.FileAddr)
+	if er != nil {
+		errMsg := fmt.Sprintf("读取 %s 目录发生错误: %s", config.CoreConfig.FileAddr, er.Error())
+		utils.LogError("[Engine scanDatabases] " + errMsg)
+		return nil, base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeIO, base.ErrorBaseCodeIOError, fmt.Errorf(errMsg))
+	}
+	r := []string{""}
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		exist, err := e.CheckDatabaseExist(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		if exist {
+			r = append(r, entry.Name())
+		}
+	}
+	return r, nil
+}
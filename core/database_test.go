@@ -0,0 +1,185 @@
+package core
+
+import (
+	"testing"
+
+	"ne_database/core/base"
+	"ne_database/core/config"
+	"ne_database/core/tableschema"
+	"ne_database/utils"
+)
+
+// newTestDatabaseEngine 创建数据库 engine_db_shop（页大小为 4096）和 engine_db_crm，测试结束时删除，并检查系统目录文件已经删除
+func newTestDatabaseEngine(t *testing.T) (e *Engine, shop, crm *tableschema.DatabaseInfo) {
+	t.Helper()
+	e = newTestEngine(t)
+	shop = &tableschema.DatabaseInfo{Name: "engine_db_shop", PageSize: 4096, StorageType: base.StorageTypeFile}
+	crm = &tableschema.DatabaseInfo{Name: "engine_db_crm"}
+	for _, info := range []*tableschema.DatabaseInfo{shop, crm} {
+		if err := e.CreateDatabase(info); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	t.Cleanup(func() {
+		_ = e.DropDatabase(shop.Name, true)
+		_ = e.DropDatabase(crm.Name, true)
+		if exist, _ := utils.FileExist(getCatalogFilePath()); exist {
+			t.Error("catalog file not removed after all databases dropped")
+		}
+	})
+	return e, shop, crm
+}
+
+// newTestDatabaseTableInfo 没有设置 PageSize 和 StorageType 的表，使用数据库的配置
+func newTestDatabaseTableInfo(name string, fields ...*tableschema.FieldInfo) *tableschema.TableMetaInfo {
+	tableInfo := newTestTableInfo(name, fields...)
+	tableInfo.PageSize = 0
+	tableInfo.StorageType = ""
+	return tableInfo
+}
+
+// newTestDatabaseUsersInfo 主键自增的 users 表
+func newTestDatabaseUsersInfo(name string) *tableschema.TableMetaInfo {
+	tableInfo := newTestDatabaseTableInfo(name, testCharField("name", 10))
+	tableInfo.PrimaryKeyFieldInfo.AutoIncrement = true
+	return tableInfo
+}
+
+func TestEngine_CreateDatabase(t *testing.T) {
+	e, shop, _ := newTestDatabaseEngine(t)
+	if err := e.CreateDatabase(&tableschema.DatabaseInfo{Name: shop.Name}); err == nil {
+		t.Error("expected error, but got nil")
+	}
+	allDatabase, err := e.AllDatabase()
+	if err != nil || len(allDatabase) != 2 || allDatabase[shop.Name] == nil {
+		t.Errorf("AllDatabase failed, got %#v, %v", allDatabase, err)
+	}
+}
+
+func TestEngine_UseDatabase(t *testing.T) {
+	e, shop, crm := newTestDatabaseEngine(t)
+	// 不同数据库中的同名表互不影响
+	if err := e.CreateTable(newTestDatabaseUsersInfo(shop.Name + ".users")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if err := e.UseDatabase(crm.Name); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if err := e.CreateTable(newTestDatabaseUsersInfo("users")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	insertTestRows(t, e, "users", map[string][]byte{"name": []byte("Alice")}, map[string][]byte{"name": []byte("Bob")})
+
+	// 没有数据库前缀的表名使用当前数据库，没有设置的 PageSize 使用数据库的配置
+	testCases := []struct {
+		tableName      string
+		expectName     string
+		expectPageSize int
+		expectRows     int64
+	}{
+		{shop.Name + ".users", shop.Name + ".users", shop.PageSize, 0},
+		{"users", crm.Name + ".users", config.CoreConfig.PageSize, 2},
+		{crm.Name + ".users", crm.Name + ".users", config.CoreConfig.PageSize, 2},
+	}
+	for i, c := range testCases {
+		tableInfo, err := e.LoadTableSchemaInfo(c.tableName)
+		if err != nil || tableInfo.Name != c.expectName || tableInfo.PageSize != c.expectPageSize {
+			t.Errorf("case %d: unexpected table schema: %s, %v", i, utils.ToJSON(tableInfo), err)
+			continue
+		}
+		count, _, err := e.Select(c.tableName, nil)
+		if err != nil || count != c.expectRows {
+			t.Errorf("case %d: expected %d rows, but got %d, %v", i, c.expectRows, count, err)
+		}
+	}
+}
+
+func TestEngine_Database_ForeignKey(t *testing.T) {
+	e, shop, crm := newTestDatabaseEngine(t)
+	createTestTables(t, e, newTestDatabaseUsersInfo(crm.Name+".users"), newTestDatabaseUsersInfo(shop.Name+".users"))
+	if err := e.UseDatabase(shop.Name); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	// 外键只能引用同一个数据库的表
+	testCases := []struct {
+		refTable  string
+		expectErr bool
+	}{
+		{crm.Name + ".users", true},
+		{"users", false},
+	}
+	for i, c := range testCases {
+		orders := newTestDatabaseTableInfo("orders", testBigIntField("user_id"))
+		orders.ForeignKeys = []*tableschema.ForeignKey{{Column: "user_id", RefTable: c.refTable, RefColumn: "id"}}
+		err := e.CreateTable(orders)
+		if (err != nil) != c.expectErr {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+		}
+	}
+
+	// 重命名时外键跟随新表名，表名不能移动到其他数据库
+	if err := e.RenameTable("users", "members"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	tableInfo, err := e.LoadTableSchemaInfo("orders")
+	if err != nil || tableInfo.ForeignKeys[0].RefTable != shop.Name+".members" {
+		t.Errorf("RenameTable failed, got %s, %v", utils.ToJSON(tableInfo), err)
+		return
+	}
+	if err = e.RenameTable("members", crm.Name+".members"); err == nil {
+		t.Error("expected error, but got nil")
+		return
+	}
+
+	// 系统目录中按照数据库查询
+	count, rows, err := e.Select(base.CatalogTableTables, []*base.WherePartItem{
+		{TargetColumn: "table_schema", Operate: base.DataComparatorEqual, Args: [][]byte{[]byte(shop.Name)}},
+	})
+	if err != nil || count != 2 || string(rows[0]["table_name"]) != shop.Name+".members" {
+		t.Errorf("Select tables failed, got %d rows %#v, %v", count, rows, err)
+	}
+}
+
+func TestEngine_DropDatabase(t *testing.T) {
+	e, shop, crm := newTestDatabaseEngine(t)
+	createTestTables(t, e, newTestDatabaseUsersInfo(shop.Name+".users"), newTestDatabaseUsersInfo(crm.Name+".users"))
+	insertTestRows(t, e, crm.Name+".users", map[string][]byte{"name": []byte("Alice")})
+	if err := e.UseDatabase(shop.Name); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	// 数据库不为空时不能删除
+	if err := e.DropDatabase(shop.Name, false); err == nil {
+		t.Error("expected error, but got nil")
+		return
+	}
+	if err := e.DropDatabase(shop.Name, true); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	// 删除当前数据库后切换到默认数据库
+	if e.CurrentDatabase() != "" {
+		t.Errorf("DropDatabase failed, current database %s", e.CurrentDatabase())
+		return
+	}
+	if exist, _ := utils.FileExist(getDatabaseDirPath(shop.Name)); exist {
+		t.Error("DropDatabase failed, directory not removed")
+		return
+	}
+	if err := e.UseDatabase(shop.Name); err == nil {
+		t.Error("expected error, but got nil")
+		return
+	}
+	// 其他数据库不受影响
+	count, _, err := e.Select(crm.Name+".users", nil)
+	if err != nil || count != 1 {
+		t.Errorf("Select failed, got %d rows, %v", count, err)
+	}
+}
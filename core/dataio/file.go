@@ -50,6 +50,44 @@ func InitFileManagerData(initData map[int64][]byte, pageSize int) (IOManager, ba
 	return &c, nil
 }
 
+// OpenFileManager 打开已经存在的表数据文件
+func OpenFileManager(baseDir string, tableName string, pageSize int) (IOManager, base.StandardError) {
+	if pageSize <= 0 {
+		utils.LogError(fmt.Sprintf("[OpenFileManager] pageSize小于等于0: %d", pageSize))
+		return nil, base.NewDBError(base.FunctionModelCoreDataIO, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf("pageSize小于等于0: %d", pageSize))
+	}
+	c := FileManager{
+		tableName: tableName,
+		baseDir:   baseDir,
+		pageSize:  pageSize,
+	}
+	err := c.open(c.getTableDataFileAddr())
+	if err != nil {
+		utils.LogError(fmt.Sprintf("[OpenFileManager] 打开文件失败: %s", err.Error()))
+		return nil, err
+	}
+	return &c, nil
+}
+
+// CreateFileManager 创建空的表数据文件，文件已经存在时清空
+func CreateFileManager(baseDir string, tableName string, pageSize int) (IOManager, base.StandardError) {
+	if pageSize <= 0 {
+		utils.LogError(fmt.Sprintf("[CreateFileManager] pageSize小于等于0: %d", pageSize))
+		return nil, base.NewDBError(base.FunctionModelCoreDataIO, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf("pageSize小于等于0: %d", pageSize))
+	}
+	c := FileManager{
+		tableName: tableName,
+		baseDir:   baseDir,
+		pageSize:  pageSize,
+	}
+	err := c.CreateFile(c.getTableDataFileAddr())
+	if err != nil {
+		utils.LogError(fmt.Sprintf("[CreateFileManager] 创建文件失败: %s", err.Error()))
+		return nil, err
+	}
+	return &c, nil
+}
+
 func (c *FileManager) GetPageSize() int {
 	return c.pageSize
 }
@@ -144,6 +182,17 @@ func (c *FileManager) Delete(offset int64) (bool, base.StandardError) {
 	return c.Writer(offset, data)
 }
 
+// Sync 把已经写入的数据落盘
+func (c *FileManager) Sync() base.StandardError {
+	if c.file != nil {
+		err := c.file.Sync()
+		if err != nil {
+			return base.NewDBError(base.FunctionModelCoreDataIO, base.ErrorTypeIO, base.ErrorBaseCodeIOError, err)
+		}
+	}
+	return nil
+}
+
 func (c *FileManager) Close() base.StandardError {
 	if c.file != nil {
 		err := c.file.Close()
@@ -175,3 +224,12 @@ func (c *FileManager) AssignEmptyPage() (int64, base.StandardError) {
 	}
 	return offset, nil
 }
+
+// PageCount 数据文件占用的页数，包括已经删除（清空）的页
+func (c *FileManager) PageCount() (int64, base.StandardError) {
+	fi, er := os.Stat(c.getTableDataFileAddr())
+	if er != nil {
+		return 0, base.NewDBError(base.FunctionModelCoreDataIO, base.ErrorTypeIO, base.ErrorBaseCodeIOError, er)
+	}
+	return (fi.Size() + int64(c.pageSize) - 1) / int64(c.pageSize), nil
+}
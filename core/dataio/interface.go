@@ -12,6 +12,7 @@ type IOManager interface {
 	Delete(offset int64) (bool, base.StandardError)
 	Close() base.StandardError
 	AssignEmptyPage() (int64, base.StandardError)
+	PageCount() (int64, base.StandardError)
 }
 
 type DataManagerFunc func(initData map[int64][]byte, pageSize int) (IOManager, base.StandardError)
@@ -81,3 +81,26 @@ func (c *MemoryManager) AssignEmptyPage() (int64, base.StandardError) {
 	}
 
 }
+
+// PageCount 已经分配的页数
+func (c *MemoryManager) PageCount() (int64, base.StandardError) {
+	return int64(len(c.Storage)), nil
+}
+
+// ToByteData 按 offset 拼接全部页，没有写入数据的页使用 0 填充，用于将内存中的数据写入文件
+func (c *MemoryManager) ToByteData() []byte {
+	if len(c.Storage) == 0 {
+		return make([]byte, 0)
+	}
+	var maxOffset int64
+	for offset := range c.Storage {
+		if offset > maxOffset {
+			maxOffset = offset
+		}
+	}
+	data := make([]byte, maxOffset+int64(c.pageSize))
+	for offset, page := range c.Storage {
+		copy(data[offset:], page)
+	}
+	return data
+}
@@ -3,32 +3,47 @@ package core
 import (
 	"fmt"
 	"os"
-	"strings"
+	"sync"
 
 	"ne_database/core/base"
-	"ne_database/core/config"
+	"ne_database/core/dataio"
 	"ne_database/core/tableschema"
 	"ne_database/utils"
 	"ne_database/utils/set"
 )
 
 type Engine struct {
+	sequenceLock  sync.Mutex
+	sequenceCache map[string]*sequenceCache // 序列名 -> 预先分配的值
+	catalogLock   sync.Mutex                // 系统目录的读写锁
+	catalog       *catalogData              // 缓存的系统目录，由 catalogLock 保护，系统目录的写入都经过 saveCatalog，同时更新缓存
+	catalogVer    uint64                    // 缓存的系统目录的版本，由 catalogLock 保护，catalog 每次变化时加一
+	viewLock      sync.Mutex                // 创建、删除视图以及刷新物化视图的锁
+	database      string                    // 当前数据库，没有数据库名的表名和序列名在其中解析，为空时是默认数据库
 }
 
-// Init 初始化方法
+// Init 初始化方法，处理上次没有完成的表重命名和物化视图的替换，并按照表结构文件重建系统目录
 func (e *Engine) Init() base.StandardError {
-	return nil
+	err := e.recoverJournals()
+	if err != nil {
+		return err
+	}
+	e.catalogLock.Lock()
+	defer e.catalogLock.Unlock()
+	_, err = e.rebuildCatalog()
+	return err
 }
 
 func getTableSchemaFilePath(tableName string) string {
-	return fmt.Sprintf("%s%s.%s", config.CoreConfig.FileAddr, tableName, base.DataIOFileTableSchemaSuffix)
+	return getTableFilePath(tableName, base.DataIOFileTableSchemaSuffix)
 }
 
 func getTableDataFilePath(tableName string) string {
-	return fmt.Sprintf("%s%s.%s", config.CoreConfig.FileAddr, tableName, base.DataIOFileTableDataSuffix)
+	return getTableFilePath(tableName, base.DataIOFileTableDataSuffix)
 }
 
 func (e *Engine) CheckTableExist(tableName string) (bool, base.StandardError) {
+	tableName = e.qualifiedName(tableName)
 	tableSchemaFilePath := getTableSchemaFilePath(tableName)
 	tableDataFilePath := getTableDataFilePath(tableName)
 
@@ -61,6 +76,11 @@ func (e *Engine) CheckTableExist(tableName string) (bool, base.StandardError) {
 }
 
 func (e *Engine) LoadTableSchemaInfo(tableName string) (*tableschema.TableMetaInfo, base.StandardError) {
+	return e.loadTableSchemaInfo(e.qualifiedName(tableName))
+}
+
+// loadTableSchemaInfo 读取表结构，tableName 为完整的表名
+func (e *Engine) loadTableSchemaInfo(tableName string) (*tableschema.TableMetaInfo, base.StandardError) {
 	tableSchemaFilePath := getTableSchemaFilePath(tableName)
 
 	bytes, er := os.ReadFile(tableSchemaFilePath)
@@ -77,17 +97,96 @@ func (e *Engine) LoadTableSchemaInfo(tableName string) (*tableschema.TableMetaIn
 	return tableSchema, nil
 }
 
+// saveTableSchemaInfo 持久化表结构，使用临时文件 + 重命名保证崩溃时不会损坏
+func (e *Engine) saveTableSchemaInfo(tableInfo *tableschema.TableMetaInfo) base.StandardError {
+	data, err := tableInfo.TableMetaInfoToJsonByte()
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[saveTableSchemaInfo] TableMetaInfoToJsonByte错误, %s", err.Error()))
+		return err
+	}
+	er := utils.WriteFileAtomic(getTableSchemaFilePath(tableInfo.Name), data, base.DataIOFileTempSuffix)
+	if er != nil {
+		errMsg := fmt.Sprintf("写入表<%s>的TableSchema发生错误: %s", tableInfo.Name, er.Error())
+		utils.LogError("[Engine saveTableSchemaInfo] " + errMsg)
+		return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeIO, base.ErrorBaseCodeIOError, fmt.Errorf(errMsg))
+	}
+	return e.catalogPutTable(tableInfo.Name, data)
+}
+
+// DeleteTable 删除表，表被其他表的外键引用时不能删除，需要同时去掉这些外键时使用 DropTableCascade
+// 物化视图的表需要通过 DropView 删除
 func (e *Engine) DeleteTable(tableName string) base.StandardError {
+	tableName = e.qualifiedName(tableName)
+	err := e.checkNotView(tableName)
+	if err != nil {
+		return err
+	}
+	return e.deleteTable(tableName, false)
+}
+
+// DropTableCascade 删除表，并去掉其他表中引用该表的外键
+func (e *Engine) DropTableCascade(tableName string) base.StandardError {
+	tableName = e.qualifiedName(tableName)
+	err := e.checkNotView(tableName)
+	if err != nil {
+		return err
+	}
+	return e.deleteTable(tableName, true)
+}
+
+// deleteTable 删除表，tableName 为完整的表名；force 为 true 时去掉其他表中引用该表的外键
+func (e *Engine) deleteTable(tableName string, force bool) base.StandardError {
 	exist, err := e.CheckTableExist(tableName)
 	if err != nil {
 		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[DeleteTable] CheckTableExist错误, %s", err.Error()))
 		return err
 	}
 	if !exist {
-		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Engine.DeleteTable] 表 %s 不存在，所以无需删除", err.Error()))
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Engine.DeleteTable] 表 %s 不存在，所以无需删除", tableName))
 		return nil
 	}
 
+	refs, err := e.referencingForeignKeys(tableName)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[DeleteTable] referencingForeignKeys错误, %s", err.Error()))
+		return err
+	}
+	for _, ref := range refs {
+		if ref.TableInfo.Name == tableName {
+			continue
+		}
+		if !force {
+			errMsg := fmt.Sprintf("表<%s>被表<%s>的外键<%s>引用，不能删除", tableName, ref.TableInfo.Name, ref.ForeignKey.Name)
+			utils.LogError("[Engine DeleteTable] " + errMsg)
+			return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeInput, base.ErrorBaseCodeConstraintError, fmt.Errorf(errMsg))
+		}
+		foreignKeys := make([]*tableschema.ForeignKey, 0, len(ref.TableInfo.ForeignKeys))
+		for _, fk := range ref.TableInfo.ForeignKeys {
+			if fk.RefTable != tableName {
+				foreignKeys = append(foreignKeys, fk)
+			}
+		}
+		ref.TableInfo.ForeignKeys = foreignKeys
+		err = e.saveTableSchemaInfo(ref.TableInfo)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[DeleteTable] 去掉表<%s>的外键错误, %s", ref.TableInfo.Name, err.Error()))
+			return err
+		}
+	}
+
+	tableInfo, err := e.LoadTableSchemaInfo(tableName)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[DeleteTable] LoadTableSchemaInfo错误, %s", err.Error()))
+		return err
+	}
+	if tableInfo.PrimaryKeyFieldInfo.AutoIncrement {
+		err = e.DropSequence(tableschema.AutoIncrementSequenceName(tableName, tableInfo.PrimaryKeyFieldInfo.Name))
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[DeleteTable] 删除自增序列错误, %s", err.Error()))
+			return err
+		}
+	}
+
 	tableSchemaFilePath := getTableSchemaFilePath(tableName)
 	tableDataFilePath := getTableDataFilePath(tableName)
 
@@ -104,54 +203,93 @@ func (e *Engine) DeleteTable(tableName string) base.StandardError {
 		return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeIO, base.ErrorBaseCodeIOError, fmt.Errorf(errMsg))
 	}
 
-	return nil
+	return e.catalogRemoveTable(tableName)
 }
 
-func (e *Engine) AllTable() (map[string]*tableschema.TableMetaInfo, base.StandardError) {
-
-	entries, err := os.ReadDir(config.CoreConfig.FileAddr)
+// TruncateTable 清空表数据，保留表结构和自增主键的序列；其他表中存在引用该表的数据时不能清空
+func (e *Engine) TruncateTable(tableName string) base.StandardError {
+	tableName = e.qualifiedName(tableName)
+	err := e.checkNotView(tableName)
 	if err != nil {
-		errMsg := fmt.Sprintf("读取 %s 目录发生错误: %s", config.CoreConfig.FileAddr, err.Error())
-		utils.LogError("[Engine AllTable] " + errMsg)
-		return nil, base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeIO, base.ErrorBaseCodeIOError, fmt.Errorf(errMsg))
+		return err
+	}
+	exist, err := e.CheckTableExist(tableName)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[TruncateTable] CheckTableExist错误, %s", err.Error()))
+		return err
+	}
+	if !exist {
+		errMsg := fmt.Sprintf("表: %s 不存在", tableName)
+		utils.LogError("[Engine TruncateTable] " + errMsg)
+		return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+	}
+	tableInfo, err := e.LoadTableSchemaInfo(tableName)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[TruncateTable] LoadTableSchemaInfo错误, %s", err.Error()))
+		return err
 	}
 
-	var (
-		tableSet       = set.NewStringsSet()
-		allTableSchema = make(map[string]*tableschema.TableMetaInfo)
-	)
-
-	for _, entry := range entries {
-		if entry != nil {
-			info, err := entry.Info()
+	refs, err := e.referencingForeignKeys(tableName)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[TruncateTable] referencingForeignKeys错误, %s", err.Error()))
+		return err
+	}
+	if len(refs) > 0 {
+		keyList, _, err := e.searchTable(tableName, nil)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[TruncateTable] searchTable错误, %s", err.Error()))
+			return err
+		}
+		for _, ref := range refs {
+			if ref.TableInfo.Name == tableName || len(keyList) == 0 {
+				continue
+			}
+			refKeyList, err := e.referencingRows(ref, keyList, nil)
 			if err != nil {
-				errMsg := fmt.Sprintf("读取 %s 文件info发生错误: %s", config.CoreConfig.FileAddr, err.Error())
-				utils.LogError("[Engine AllTable] " + errMsg)
-				return nil, base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeIO, base.ErrorBaseCodeIOError, fmt.Errorf(errMsg))
+				return err
 			}
-			if !info.IsDir() {
-				// 不是文件夹，且是目标文件
-				rawName := info.Name()
-				if strings.HasSuffix(info.Name(), base.DataIOFileTableSchemaSuffix) {
-					n := strings.Replace(rawName, fmt.Sprintf(".%s", base.DataIOFileTableSchemaSuffix), "", 1)
-					tableSet.Add(n)
-				} else if strings.HasSuffix(info.Name(), base.DataIOFileTableDataSuffix) {
-					n := strings.Replace(rawName, fmt.Sprintf(".%s", base.DataIOFileTableDataSuffix), "", 1)
-					tableSet.Add(n)
-				}
-
+			if len(refKeyList) > 0 {
+				errMsg := fmt.Sprintf("违反外键约束<%s>: 表<%s>中存在引用的数据，不能清空", ref.ForeignKey.Name, ref.TableInfo.Name)
+				utils.LogError("[Engine TruncateTable] " + errMsg)
+				return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeInput, base.ErrorBaseCodeConstraintError, fmt.Errorf(errMsg))
 			}
 		}
 	}
-	for _, n := range tableSet.TotalMember() {
-		if n == "" {
-			continue
-		}
-		schema, er := e.LoadTableSchemaInfo(n)
-		if er != nil {
-			return nil, er
+
+	// 空的数据文件在第一次打开时写入根结点
+	er := utils.WriteFileAtomic(getTableDataFilePath(tableName), nil, base.DataIOFileTempSuffix)
+	if er != nil {
+		errMsg := fmt.Sprintf("清空表<%s>的TableData发生错误: %s", tableName, er.Error())
+		utils.LogError("[Engine TruncateTable] " + errMsg)
+		return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeIO, base.ErrorBaseCodeIOError, fmt.Errorf(errMsg))
+	}
+	err = e.catalogSetStatistics(tableName, nil)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[TruncateTable] 删除统计信息错误, %s", err.Error()))
+		return err
+	}
+	// 已经没有旧版本写入的数据
+	if len(tableInfo.History) > 0 {
+		tableInfo.History = nil
+		err = e.saveTableSchemaInfo(tableInfo)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[TruncateTable] saveTableSchemaInfo错误, %s", err.Error()))
+			return err
 		}
-		allTableSchema[schema.Name] = schema
+	}
+	return nil
+}
+
+// AllTable 从系统目录中读取全部数据库的表结构，完整的表名 -> 表结构
+func (e *Engine) AllTable() (map[string]*tableschema.TableMetaInfo, base.StandardError) {
+	snapshot, err := e.catalogSnapshot()
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[AllTable] catalogSnapshot错误, %s", err.Error()))
+		return nil, err
+	}
+	allTableSchema := make(map[string]*tableschema.TableMetaInfo, len(snapshot.Tables))
+	for _, tableInfo := range snapshot.Tables {
+		allTableSchema[tableInfo.Name] = tableInfo
 	}
 	return allTableSchema, nil
 }
@@ -168,11 +306,26 @@ func (e *Engine) CreateTable(tableInfo *tableschema.TableMetaInfo) base.Standard
 		utils.LogError("[Engine CreateTable] " + errMsg)
 		return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeInput, base.ErrorBaseCodeTableSchemaError, fmt.Errorf(errMsg))
 	}
+	// 表名没有数据库名时建在当前数据库中，没有设置的 PageSize / StorageType 使用数据库的配置
+	tableInfo.Name = e.qualifiedName(tableInfo.Name)
+	err = e.fillTableDefault(tableInfo)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[CreateTable] fillTableDefault错误, %s", err.Error()))
+		return err
+	}
+	// 新建的表从初始版本开始，行数据中记录版本
+	tableInfo.Version = base.TableSchemaVersionInitial
+	tableInfo.History = nil
 	err = tableInfo.Verification()
 	if err != nil {
 		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[CreateTable] 表校验错误, %s", err.Error()))
 		return err
 	}
+	err = e.foreignKeyVerification(tableInfo)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[CreateTable] 外键校验错误, %s", err.Error()))
+		return err
+	}
 
 	tableSchemaFilePath := getTableSchemaFilePath(tableInfo.Name)
 	tableDataFilePath := getTableDataFilePath(tableInfo.Name)
@@ -187,6 +340,30 @@ func (e *Engine) CreateTable(tableInfo *tableschema.TableMetaInfo) base.Standard
 		utils.LogError("[Engine CreateTable] " + errMsg)
 		return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeInput, base.ErrorBaseCodeIOError, fmt.Errorf(errMsg))
 	}
+	exist, err = e.viewExist(tableInfo.Name)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[CreateTable] viewExist错误, %s", err.Error()))
+		return err
+	}
+	if exist {
+		errMsg := fmt.Sprintf("视图: %s 已存在", tableInfo.Name)
+		utils.LogError("[Engine CreateTable] " + errMsg)
+		return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+	}
+
+	autoIncrementSequenceName := tableschema.AutoIncrementSequenceName(tableInfo.Name, tableInfo.PrimaryKeyFieldInfo.Name)
+	if tableInfo.PrimaryKeyFieldInfo.AutoIncrement {
+		exist, err = e.CheckSequenceExist(autoIncrementSequenceName)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[CreateTable] CheckSequenceExist错误, %s", err.Error()))
+			return err
+		}
+		if exist {
+			errMsg := fmt.Sprintf("自增主键对应的序列: %s 已存在", autoIncrementSequenceName)
+			utils.LogError("[Engine CreateTable] " + errMsg)
+			return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeInput, base.ErrorBaseCodeIOError, fmt.Errorf(errMsg))
+		}
+	}
 
 	tableInfoByte, err := tableInfo.TableMetaInfoToJsonByte()
 	if err != nil {
@@ -206,26 +383,415 @@ func (e *Engine) CreateTable(tableInfo *tableschema.TableMetaInfo) base.Standard
 		utils.LogError("[Engine CreateTable] 建表Data错误" + er.Error())
 		return base.NewDBError(base.FunctionModelCoreDataIO, base.ErrorTypeIO, base.ErrorBaseCodeIOError, err)
 	}
+	err = e.catalogPutTable(tableInfo.Name, tableInfoByte)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[CreateTable] 写入系统目录错误, %s", err.Error()))
+		return err
+	}
+
+	if tableInfo.PrimaryKeyFieldInfo.AutoIncrement {
+		err = e.createSequence(tableschema.InitSequenceInfo(autoIncrementSequenceName, 1, 1))
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[CreateTable] 创建自增序列错误, %s", err.Error()))
+			return err
+		}
+	}
 
 	return nil
 }
 
-// Select 表查询
-//func (e *Engine) Select(tableName string, whereArgs []*base.WherePartItem) (int64, [][]byte, base.StandardError) {
-// // TODO
-//}
-
-//// Insert 表插入
-//func (e *Engine) Insert() (int64, base.StandardError) {
-//
-//}
-//
-//// Update 表更新
-//func (e *Engine) Update() (int64, base.StandardError) {
-//
-//}
-//
-//// Delete 表删除
-//func (e *Engine) Delete() (int64, base.StandardError) {
-//
-//}
+// openTable 打开表，返回表对应的B+树，使用完之后需要关闭 DataManager
+func (e *Engine) openTable(tableName string) (*BPlusTree, base.StandardError) {
+	if IsCatalogTable(tableName) {
+		errMsg := fmt.Sprintf("系统目录<%s>是只读的", tableName)
+		utils.LogError("[Engine openTable] " + errMsg)
+		return nil, base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+	}
+	exist, err := e.CheckTableExist(tableName)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[openTable] CheckTableExist错误, %s", err.Error()))
+		return nil, err
+	}
+	if !exist {
+		errMsg := fmt.Sprintf("表: %s 不存在", tableName)
+		utils.LogError("[Engine openTable] " + errMsg)
+		return nil, base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+	}
+	tableInfo, err := e.LoadTableSchemaInfo(tableName)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[openTable] LoadTableSchemaInfo错误, %s", err.Error()))
+		return nil, err
+	}
+
+	dataFileInfo, er := os.Stat(getTableDataFilePath(tableName))
+	if er != nil {
+		errMsg := fmt.Sprintf("读取 %s 的TableData发生错误: %s", tableName, er.Error())
+		utils.LogError("[Engine openTable] " + errMsg)
+		return nil, base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeIO, base.ErrorBaseCodeIOError, fmt.Errorf(errMsg))
+	}
+	database, n := tableschema.SplitTableName(tableName)
+	dataManager, err := dataio.OpenFileManager(getDatabaseDirPath(database), n, tableInfo.PageSize)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[openTable] OpenFileManager错误, %s", err.Error()))
+		return nil, err
+	}
+	// 新建的表数据文件为空，第一次打开时写入根结点
+	tree, err := LoadBPlusTree(tableInfo, dataManager, dataFileInfo.Size() == 0)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[openTable] LoadBPlusTree错误, %s", err.Error()))
+		_ = dataManager.Close()
+		return nil, err
+	}
+	return tree, nil
+}
+
+// Select 表查询，返回满足条件的数量和数据，每行为 列名 -> 值（包括主键），按照主键排序
+// 表名为系统目录的虚拟表（见 IsCatalogTable）时，数据由系统目录生成
+func (e *Engine) Select(tableName string, whereArgs []*base.WherePartItem) (int64, []map[string][]byte, base.StandardError) {
+	tableName = e.qualifiedName(tableName)
+	if table, ok := catalogTables()[tableName]; ok {
+		rows, err := e.selectCatalogTable(table, whereArgs)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Select] selectCatalogTable错误, %s", err.Error()))
+			return 0, nil, err
+		}
+		return int64(len(rows)), rows, nil
+	}
+	tree, err := e.openTable(tableName)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Select] openTable错误, %s", err.Error()))
+		return 0, nil, err
+	}
+	defer tree.DataManager.Close()
+	keyList, valueList, err := tree.Search(whereArgs)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Select] Search错误, %s", err.Error()))
+		return 0, nil, err
+	}
+	for i, key := range keyList {
+		valueList[i][tree.TableInfo.PrimaryKeyFieldInfo.Name] = key
+	}
+	return int64(len(valueList)), valueList, nil
+}
+
+// Insert 表插入，values 为 列名 -> 值，没有提供的列使用默认值（没有默认值时为 Null）
+// 返回插入的数量和主键的值（自增主键没有提供时为生成的值）
+func (e *Engine) Insert(tableName string, values map[string][]byte) (int64, []byte, base.StandardError) {
+	tableName = e.qualifiedName(tableName)
+	err := e.checkNotView(tableName)
+	if err != nil {
+		return 0, nil, err
+	}
+	tree, err := e.openTable(tableName)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Insert] openTable错误, %s", err.Error()))
+		return 0, nil, err
+	}
+	defer tree.DataManager.Close()
+	tableInfo := tree.TableInfo
+
+	row, err := tableInfo.FillDefaultValues(values, e.NextSequenceValue)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Insert] FillDefaultValues错误, %s", err.Error()))
+		return 0, nil, err
+	}
+
+	key, err := tableInfo.PrimaryKeyFieldInfo.FieldType.LengthPadding(row[tableInfo.PrimaryKeyFieldInfo.Name], tableInfo.PrimaryKeyFieldInfo.Length)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Insert] 主键长度错误, %s", err.Error()))
+		return 0, nil, err
+	}
+	keyList, _, err := tree.SearchEqualKey(key)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Insert] SearchEqualKey错误, %s", err.Error()))
+		return 0, nil, err
+	}
+	if len(keyList) > 0 {
+		errMsg := fmt.Sprintf("主键<%s>重复: %s", tableInfo.PrimaryKeyFieldInfo.Name, tableInfo.PrimaryKeyFieldInfo.FieldType.StringValue(key))
+		utils.LogError("[Engine Insert] " + errMsg)
+		return 0, nil, base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+	}
+
+	valueList := make([][]byte, 0, len(tableInfo.ValueFieldInfo))
+	for _, valueInfo := range tableInfo.ValueFieldInfo {
+		value, err := valueInfo.FieldType.LengthPadding(row[valueInfo.Name], valueInfo.Length)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Insert] 列<%s>长度错误, %s", valueInfo.Name, err.Error()))
+			return 0, nil, err
+		}
+		valueList = append(valueList, value)
+		row[valueInfo.Name] = value
+	}
+	row[tableInfo.PrimaryKeyFieldInfo.Name] = key
+	err = tableInfo.CheckRow(row)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Insert] CheckRow错误, %s", err.Error()))
+		return 0, nil, err
+	}
+	err = e.checkForeignKeys(tableInfo, row, nil)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Insert] checkForeignKeys错误, %s", err.Error()))
+		return 0, nil, err
+	}
+
+	err = tree.Insert(key, valueList)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Insert] tree.Insert错误, %s", err.Error()))
+		return 0, nil, err
+	}
+	// 自增主键使用了显式的值时，序列需要越过该值，避免之后生成的值和已有的主键重复
+	if _, ok := values[tableInfo.PrimaryKeyFieldInfo.Name]; ok && tableInfo.PrimaryKeyFieldInfo.AutoIncrement {
+		explicitKey, err := base.ByteListToInt64(key)
+		if err != nil {
+			return 0, nil, err
+		}
+		err = e.advanceSequence(tableschema.AutoIncrementSequenceName(tableName, tableInfo.PrimaryKeyFieldInfo.Name), explicitKey)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Insert] advanceSequence错误, %s", err.Error()))
+			return 0, nil, err
+		}
+	}
+	return 1, key, nil
+}
+
+// Update 表更新，将满足 whereArgs 的数据更新为 values（列名 -> 值），返回更新的数量
+// 更新后的数据需要满足 CHECK 约束和外键，任意一行不满足时不会更新任何数据
+// 修改主键时，引用该表的外键按照 OnUpdate 执行动作；没有事务，级联修改出错时已经执行的修改不会回滚
+func (e *Engine) Update(tableName string, whereArgs []*base.WherePartItem, values map[string][]byte) (int64, base.StandardError) {
+	tableName = e.qualifiedName(tableName)
+	if len(values) == 0 {
+		errMsg := "更新的values为空"
+		utils.LogError("[Engine Update] " + errMsg)
+		return 0, base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+	}
+	err := e.checkNotView(tableName)
+	if err != nil {
+		return 0, err
+	}
+	tree, err := e.openTable(tableName)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Update] openTable错误, %s", err.Error()))
+		return 0, err
+	}
+
+	var refs []*referencingForeignKey
+	if _, ok := values[tree.TableInfo.PrimaryKeyFieldInfo.Name]; ok {
+		refs, err = e.referencingForeignKeys(tableName)
+		if err != nil {
+			_ = tree.DataManager.Close()
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Update] referencingForeignKeys错误, %s", err.Error()))
+			return 0, err
+		}
+	}
+	keyList, newKeyList, err := e.updateTree(tree, whereArgs, values, refs)
+	// 级联动作可能会打开同一个表，需要先关闭
+	_ = tree.DataManager.Close()
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Update] updateTree错误, %s", err.Error()))
+		return 0, err
+	}
+
+	fieldType := tree.TableInfo.PrimaryKeyFieldInfo.FieldType
+	for index, key := range keyList {
+		equal, err := fieldType.Equal(key, newKeyList[index])
+		if err != nil {
+			return 0, err
+		}
+		if equal {
+			continue
+		}
+		err = e.applyParentUpdate(refs, key, newKeyList[index])
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Update] applyParentUpdate错误, %s", err.Error()))
+			return 0, err
+		}
+	}
+	return int64(len(keyList)), nil
+}
+
+// updateTree 在表的B+树中更新数据，返回更新前后的主键；先检查全部数据，再写入
+// refs 为引用该表的外键，修改主键时用于检查 restrict 动作
+func (e *Engine) updateTree(tree *BPlusTree, whereArgs []*base.WherePartItem, values map[string][]byte, refs []*referencingForeignKey) ([][]byte, [][]byte, base.StandardError) {
+	var (
+		tableInfo     = tree.TableInfo
+		pkInfo        = tableInfo.PrimaryKeyFieldInfo
+		paddingValues = make(map[string][]byte, len(values))
+		columns       = set.NewStringsSet()
+		err           base.StandardError
+	)
+	for name, value := range values {
+		valueInfo, ok := tableInfo.FieldInfoByName(name)
+		if !ok {
+			errMsg := fmt.Sprintf("列<%s>不存在", name)
+			utils.LogError("[Engine updateTree] " + errMsg)
+			return nil, nil, base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+		}
+		paddingValues[name], err = valueInfo.FieldType.LengthPadding(value, valueInfo.Length)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[updateTree] 列<%s>长度错误, %s", name, err.Error()))
+			return nil, nil, err
+		}
+		columns.Add(name)
+	}
+	newKey, updateKey := paddingValues[pkInfo.Name]
+
+	keyList, valueList, err := tree.Search(whereArgs)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[updateTree] Search错误, %s", err.Error()))
+		return nil, nil, err
+	}
+	newKeyList := make([][]byte, 0, len(keyList))
+	newRowList := make([]map[string][]byte, 0, len(keyList))
+	for index, key := range keyList {
+		row := make(map[string][]byte, len(valueList[index])+1)
+		for name, value := range valueList[index] {
+			row[name] = value
+		}
+		row[pkInfo.Name] = key
+		for name, value := range paddingValues {
+			row[name] = value
+		}
+		err = tableInfo.CheckRow(row)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[updateTree] CheckRow错误, %s", err.Error()))
+			return nil, nil, err
+		}
+		err = e.checkForeignKeys(tableInfo, row, columns)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[updateTree] checkForeignKeys错误, %s", err.Error()))
+			return nil, nil, err
+		}
+		newKeyList = append(newKeyList, row[pkInfo.Name])
+		newRowList = append(newRowList, row)
+	}
+
+	if !updateKey {
+		for _, key := range keyList {
+			err = tree.Update(key, paddingValues)
+			if err != nil {
+				utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[updateTree] tree.Update错误, %s", err.Error()))
+				return nil, nil, err
+			}
+		}
+		return keyList, newKeyList, nil
+	}
+
+	// 修改主键：新主键只能有一个，并且不能和其他数据重复
+	if len(keyList) > 1 {
+		errMsg := fmt.Sprintf("主键<%s>重复: %s", pkInfo.Name, pkInfo.FieldType.StringValue(newKey))
+		utils.LogError("[Engine updateTree] " + errMsg)
+		return nil, nil, base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+	}
+	if len(keyList) == 0 {
+		return keyList, newKeyList, nil
+	}
+	oldKey := keyList[0]
+	equal, err := pkInfo.FieldType.Equal(oldKey, newKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	if equal {
+		delete(paddingValues, pkInfo.Name)
+		if len(paddingValues) > 0 {
+			err = tree.Update(oldKey, paddingValues)
+			if err != nil {
+				utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[updateTree] tree.Update错误, %s", err.Error()))
+				return nil, nil, err
+			}
+		}
+		return keyList, newKeyList, nil
+	}
+	existKeyList, _, err := tree.SearchEqualKey(newKey)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[updateTree] SearchEqualKey错误, %s", err.Error()))
+		return nil, nil, err
+	}
+	if len(existKeyList) > 0 {
+		errMsg := fmt.Sprintf("主键<%s>重复: %s", pkInfo.Name, pkInfo.FieldType.StringValue(newKey))
+		utils.LogError("[Engine updateTree] " + errMsg)
+		return nil, nil, base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+	}
+	err = e.checkRestrict(tableInfo.Name, refs, keyList, true)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[updateTree] checkRestrict错误, %s", err.Error()))
+		return nil, nil, err
+	}
+
+	newValueList := make([][]byte, 0, len(tableInfo.ValueFieldInfo))
+	for _, valueInfo := range tableInfo.ValueFieldInfo {
+		newValueList = append(newValueList, newRowList[0][valueInfo.Name])
+	}
+	err = tree.Delete(oldKey)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[updateTree] tree.Delete错误, %s", err.Error()))
+		return nil, nil, err
+	}
+	err = tree.Insert(newKey, newValueList)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[updateTree] tree.Insert错误, %s", err.Error()))
+		return nil, nil, err
+	}
+	return keyList, newKeyList, nil
+}
+
+// Delete 表删除，删除满足 whereArgs 的数据，返回删除的数量
+// 引用该表的外键按照 OnDelete 执行动作，restrict 在删除前检查；没有事务，级联删除出错时已经执行的删除不会回滚
+func (e *Engine) Delete(tableName string, whereArgs []*base.WherePartItem) (int64, base.StandardError) {
+	tableName = e.qualifiedName(tableName)
+	err := e.checkNotView(tableName)
+	if err != nil {
+		return 0, err
+	}
+	refs, err := e.referencingForeignKeys(tableName)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Delete] referencingForeignKeys错误, %s", err.Error()))
+		return 0, err
+	}
+	tree, err := e.openTable(tableName)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Delete] openTable错误, %s", err.Error()))
+		return 0, err
+	}
+	keyList, err := e.deleteTree(tree, whereArgs, refs)
+	// 级联动作可能会打开同一个表，需要先关闭
+	_ = tree.DataManager.Close()
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Delete] deleteTree错误, %s", err.Error()))
+		return 0, err
+	}
+	if len(keyList) == 0 {
+		return 0, nil
+	}
+	// 先删除父表数据再处理子表，引用自身的表形成环时也能结束
+	err = e.applyParentDelete(refs, keyList)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Delete] applyParentDelete错误, %s", err.Error()))
+		return 0, err
+	}
+	return int64(len(keyList)), nil
+}
+
+// deleteTree 在表的B+树中删除满足条件的数据，返回删除的主键
+func (e *Engine) deleteTree(tree *BPlusTree, whereArgs []*base.WherePartItem, refs []*referencingForeignKey) ([][]byte, base.StandardError) {
+	keyList, _, err := tree.Search(whereArgs)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[deleteTree] Search错误, %s", err.Error()))
+		return nil, err
+	}
+	if len(keyList) == 0 {
+		return keyList, nil
+	}
+	err = e.checkRestrict(tree.TableInfo.Name, refs, keyList, false)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[deleteTree] checkRestrict错误, %s", err.Error()))
+		return nil, err
+	}
+	for _, key := range keyList {
+		err = tree.Delete(key)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[deleteTree] tree.Delete错误, %s", err.Error()))
+			return nil, err
+		}
+	}
+	return keyList, nil
+}
@@ -3,6 +3,7 @@ package core
 import (
 	"fmt"
 	"os"
+	"strings"
 	"testing"
 
 	"ne_database/core/base"
@@ -349,3 +350,315 @@ func TestEngine_AllTable(t *testing.T) {
 		return
 	}
 }
+
+// newTestTableInfo 测试使用的表结构，主键为 bigint 类型的 id
+func newTestTableInfo(name string, fields ...*tableschema.FieldInfo) *tableschema.TableMetaInfo {
+	return &tableschema.TableMetaInfo{
+		Name:                name,
+		PrimaryKeyFieldInfo: testBigIntField("id"),
+		ValueFieldInfo:      fields,
+		PageSize:            config.CoreConfig.PageSize,
+		StorageType:         base.StorageTypeFile,
+	}
+}
+
+// testBigIntField bigint 类型的列
+func testBigIntField(name string) *tableschema.FieldInfo {
+	return &tableschema.FieldInfo{Name: name, Length: 8, FieldType: tableschema.BigIntType}
+}
+
+// testCharField char 类型的列
+func testCharField(name string, length int) *tableschema.FieldInfo {
+	return &tableschema.FieldInfo{Name: name, Length: length, FieldType: tableschema.CharType}
+}
+
+// newTestEngine 初始化 Engine 并按顺序建表，测试结束时按相反的顺序删除
+func newTestEngine(t *testing.T, tables ...*tableschema.TableMetaInfo) *Engine {
+	t.Helper()
+	e := &Engine{}
+	if err := e.Init(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	createTestTables(t, e, tables...)
+	return e
+}
+
+// createTestTables 按顺序建表，测试结束时按相反的顺序删除，同时去掉其他表引用的外键
+func createTestTables(t *testing.T, e *Engine, tables ...*tableschema.TableMetaInfo) {
+	t.Helper()
+	for _, tableInfo := range tables {
+		if err := e.CreateTable(tableInfo); err != nil {
+			t.Fatalf("create table %s: unexpected error: %v", tableInfo.Name, err)
+		}
+		name := tableInfo.Name
+		t.Cleanup(func() {
+			_ = e.DropTableCascade(name)
+		})
+	}
+}
+
+// insertTestRows 按顺序插入数据
+func insertTestRows(t *testing.T, e *Engine, tableName string, rows ...map[string][]byte) {
+	t.Helper()
+	for i, row := range rows {
+		if _, _, err := e.Insert(tableName, row); err != nil {
+			t.Fatalf("insert row %d into %s: unexpected error: %v", i, tableName, err)
+		}
+	}
+}
+
+// testInt64 bigint 类型的值
+func testInt64(v int64) []byte {
+	r, _ := base.Int64ToByteList(v)
+	return r
+}
+
+// testInt64Values 按结果的顺序取 column 列的 bigint 值，以逗号连接
+func testInt64Values(rows []map[string][]byte, column string) string {
+	r := make([]string, 0, len(rows))
+	for _, row := range rows {
+		r = append(r, tableschema.BigIntType.StringValue(row[column]))
+	}
+	return strings.Join(r, ",")
+}
+
+// testWhereEqual column 等于 value 的条件
+func testWhereEqual(column string, value []byte) []*base.WherePartItem {
+	return []*base.WherePartItem{{TargetColumn: column, Operate: base.DataComparatorEqual, Args: [][]byte{value}}}
+}
+
+// setTestOperatorMemory 修改算子在内存中保存的行数和归并的路数，返回恢复原来配置的函数
+func setTestOperatorMemory(memoryRows int, fanIn int) func() {
+	oldMemoryRows, oldFanIn := config.CoreConfig.OperatorMemoryRows, config.CoreConfig.SpillMergeFanIn
+	config.CoreConfig.OperatorMemoryRows, config.CoreConfig.SpillMergeFanIn = memoryRows, fanIn
+	return func() {
+		config.CoreConfig.OperatorMemoryRows, config.CoreConfig.SpillMergeFanIn = oldMemoryRows, oldFanIn
+	}
+}
+
+// checkNoSpillFiles 算子结束之后临时文件都已经删除
+func checkNoSpillFiles(t *testing.T) {
+	t.Helper()
+	entries, _ := os.ReadDir(config.CoreConfig.FileAddr)
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), "."+base.DataIOFileSpillSuffix) {
+			t.Errorf("spill file %s not removed", entry.Name())
+		}
+	}
+}
+
+func TestEngine_CreateTable_DefaultValue(t *testing.T) {
+	testCases := []struct {
+		field     *tableschema.FieldInfo
+		expectErr bool
+	}{
+		{&tableschema.FieldInfo{Name: "age", Length: 8, FieldType: tableschema.BigIntType, DefaultValue: "18"}, false},
+		// 默认值和列类型不匹配
+		{&tableschema.FieldInfo{Name: "age", Length: 8, FieldType: tableschema.BigIntType, DefaultValue: "abc"}, true},
+		{&tableschema.FieldInfo{Name: "name", Length: 20, FieldType: tableschema.CharType, DefaultValue: "'guest'"}, false},
+		// char 列放不下 CURRENT_TIMESTAMP 生成的文本
+		{&tableschema.FieldInfo{Name: "created_at", Length: 10, FieldType: tableschema.CharType, DefaultValue: "CURRENT_TIMESTAMP"}, true},
+		{&tableschema.FieldInfo{Name: "created_at", Length: 19, FieldType: tableschema.CharType, DefaultValue: "CURRENT_TIMESTAMP"}, false},
+	}
+
+	e := newTestEngine(t)
+	for i, c := range testCases {
+		tableInfo := newTestTableInfo("engine_default_value_users", c.field)
+		err := e.CreateTable(tableInfo)
+		if err == nil {
+			_ = e.DeleteTable(tableInfo.Name)
+		}
+		if (err != nil) != c.expectErr {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+		}
+	}
+}
+
+func TestEngine_Insert(t *testing.T) {
+	name := testCharField("name", 4*5) // 假设最长5字
+	name.DefaultValue = "'guest'"
+	age := testBigIntField("age")
+	age.DefaultValue = "18"
+	tableInfo := newTestTableInfo("engine_insert_users", name, age, testCharField("remark", 20))
+	e := newTestEngine(t, tableInfo)
+
+	// 按顺序插入，expect 为插入之后该行的 name 和 age
+	testCases := []struct {
+		row       map[string][]byte
+		expectErr bool
+		expect    string
+	}{
+		{map[string][]byte{"id": testInt64(1), "name": []byte("Alice"), "age": testInt64(30)}, false, "Alice,30"},
+		// 没有提供的列使用默认值
+		{map[string][]byte{"id": testInt64(2)}, false, "guest,18"},
+		// 主键重复
+		{map[string][]byte{"id": testInt64(1)}, true, ""},
+		// 主键没有默认值
+		{map[string][]byte{"name": []byte("Bob")}, true, ""},
+	}
+
+	for i, c := range testCases {
+		count, _, err := e.Insert(tableInfo.Name, c.row)
+		if c.expectErr {
+			if err == nil {
+				t.Errorf("case %d: expected error, but got nil", i)
+			}
+			continue
+		}
+		if err != nil || count != 1 {
+			t.Errorf("case %d: unexpected result: %d, %v", i, count, err)
+			continue
+		}
+		_, rows, err := e.Select(tableInfo.Name, testWhereEqual("id", c.row["id"]))
+		if err != nil || len(rows) != 1 {
+			t.Errorf("case %d: unexpected result: %d, %v", i, len(rows), err)
+			continue
+		}
+		r := tableschema.CharType.StringValue(rows[0]["name"]) + "," + tableschema.BigIntType.StringValue(rows[0]["age"])
+		if r != c.expect {
+			t.Errorf("case %d: expected %s, but got %s", i, c.expect, r)
+		}
+	}
+}
+
+// newTestCheckTableInfo qty 列有列级 CHECK: qty > 0，表级 CHECK status_values: status in ('new', 'paid')
+func newTestCheckTableInfo(name string) *tableschema.TableMetaInfo {
+	qty := testBigIntField("qty")
+	qty.DefaultValue = "1"
+	qty.Check = tableschema.CheckExpression{{
+		{TargetColumn: "qty", Operate: base.DataComparatorGreater, Args: [][]byte{testInt64(0)}},
+	}}
+	status := testCharField("status", 10)
+	status.DefaultValue = "'new'"
+	tableInfo := newTestTableInfo(name, qty, status)
+	tableInfo.CheckConstraints = []*tableschema.CheckConstraint{
+		{
+			Name: "status_values",
+			Expression: tableschema.CheckExpression{{
+				{TargetColumn: "status", Operate: base.DataComparatorIn, Args: [][]byte{[]byte("new"), []byte("paid")}},
+			}},
+		},
+		{
+			Name: "qty_limit",
+			Predicate: &base.WhereNode{Logic: base.WhereLogicNot, Children: []*base.WhereNode{
+				{Item: &base.WherePartItem{TargetColumn: "qty", Operate: base.DataComparatorGreater, Args: [][]byte{testInt64(100)}}},
+			}},
+		},
+	}
+	return tableInfo
+}
+
+func TestEngine_Insert_Check(t *testing.T) {
+	tableInfo := newTestCheckTableInfo("engine_check_insert_orders")
+	e := newTestEngine(t, tableInfo)
+
+	// expectErr 为违反的约束名，为空时插入成功
+	testCases := []struct {
+		row       map[string][]byte
+		expectErr string
+	}{
+		{map[string][]byte{"id": testInt64(1)}, ""},
+		{map[string][]byte{"id": testInt64(2), "qty": testInt64(-1)}, "engine_check_insert_orders_qty_check"},
+		{map[string][]byte{"id": testInt64(3), "status": []byte("refund")}, "status_values"},
+		{map[string][]byte{"id": testInt64(4), "qty": testInt64(5), "status": []byte("paid")}, ""},
+		{map[string][]byte{"id": testInt64(5), "qty": testInt64(200)}, "qty_limit"},
+		// 条件的结果为 Null 时满足约束
+		{map[string][]byte{"id": testInt64(6), "qty": testInt64(0)}, ""},
+		{map[string][]byte{"id": testInt64(7), "status": []byte{}}, ""},
+	}
+	for i, c := range testCases {
+		_, _, err := e.Insert(tableInfo.Name, c.row)
+		if c.expectErr == "" {
+			if err != nil {
+				t.Errorf("case %d: unexpected error: %v", i, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), c.expectErr) {
+			t.Errorf("case %d: expected CHECK error %s, but got %v", i, c.expectErr, err)
+		}
+	}
+}
+
+func TestEngine_Update_Check(t *testing.T) {
+	tableInfo := newTestCheckTableInfo("engine_check_orders")
+	e := newTestEngine(t, tableInfo)
+	insertTestRows(t, e, tableInfo.Name, map[string][]byte{"id": testInt64(1)})
+
+	// 按顺序更新 id 为 1 的行，expect 为更新之后的 qty 和 status
+	testCases := []struct {
+		update    map[string][]byte
+		expectErr string
+		expect    string
+	}{
+		{map[string][]byte{"status": []byte("refund")}, "status_values", "1,new"},
+		{map[string][]byte{"qty": testInt64(-1)}, "engine_check_orders_qty_check", "1,new"},
+		{map[string][]byte{"qty": testInt64(101)}, "qty_limit", "1,new"},
+		{map[string][]byte{"status": []byte("paid")}, "", "1,paid"},
+		{map[string][]byte{"qty": testInt64(3)}, "", "3,paid"},
+	}
+	whereArgs := testWhereEqual("id", testInt64(1))
+	for i, c := range testCases {
+		count, err := e.Update(tableInfo.Name, whereArgs, c.update)
+		if c.expectErr == "" && (err != nil || count != 1) {
+			t.Errorf("case %d: unexpected result: %d, %v", i, count, err)
+			continue
+		}
+		if c.expectErr != "" && (err == nil || !strings.Contains(err.Error(), c.expectErr)) {
+			t.Errorf("case %d: expected CHECK error %s, but got %v", i, c.expectErr, err)
+			continue
+		}
+		_, rows, err := e.Select(tableInfo.Name, whereArgs)
+		if err != nil || len(rows) != 1 {
+			t.Errorf("case %d: unexpected result: %d, %v", i, len(rows), err)
+			continue
+		}
+		r := tableschema.BigIntType.StringValue(rows[0]["qty"]) + "," + tableschema.CharType.StringValue(rows[0]["status"])
+		if r != c.expect {
+			t.Errorf("case %d: expected %s, but got %s", i, c.expect, r)
+		}
+	}
+}
+
+func TestEngine_TruncateTable(t *testing.T) {
+	users := newTestTableInfo("engine_truncate_users", testCharField("name", 10))
+	users.PrimaryKeyFieldInfo.AutoIncrement = true
+	orders := newTestTableInfo("engine_truncate_orders", testBigIntField("user_id"))
+	orders.ForeignKeys = []*tableschema.ForeignKey{{Column: "user_id", RefTable: users.Name, RefColumn: "id"}}
+	e := newTestEngine(t, users, orders)
+	insertTestRows(t, e, users.Name, map[string][]byte{"name": []byte("Alice")}, map[string][]byte{"name": []byte("Bob")})
+	insertTestRows(t, e, orders.Name, map[string][]byte{"id": testInt64(2), "user_id": testInt64(2)})
+
+	// 按顺序清空，expectRows 为清空之后表中的行数，表不存在时为 -1
+	testCases := []struct {
+		tableName  string
+		expectErr  bool
+		expectRows int64
+	}{
+		// 子表中存在引用的数据
+		{users.Name, true, 2},
+		{orders.Name, false, 0},
+		{users.Name, false, 0},
+		{"engine_truncate_not_exist", true, -1},
+	}
+	for i, c := range testCases {
+		err := e.TruncateTable(c.tableName)
+		if (err != nil) != c.expectErr {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+			continue
+		}
+		if c.expectRows < 0 {
+			continue
+		}
+		count, _, err := e.Select(c.tableName, nil)
+		if err != nil || count != c.expectRows {
+			t.Errorf("case %d: expected %d rows, but got %d, %v", i, c.expectRows, count, err)
+		}
+	}
+
+	// 表结构保留，自增序列不重置
+	_, key, err := e.Insert(users.Name, map[string][]byte{"name": []byte("Cindy")})
+	if err != nil || tableschema.BigIntType.StringValue(key) != "3" {
+		t.Errorf("unexpected auto increment id: %s, %v", tableschema.BigIntType.StringValue(key), err)
+	}
+}
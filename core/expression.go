@@ -0,0 +1,107 @@
+package core
+
+import (
+	"fmt"
+
+	"ne_database/core/base"
+	"ne_database/core/tableschema"
+	"ne_database/utils"
+)
+
+func expressionError(errMsg string) base.StandardError {
+	utils.LogError("[expression] " + errMsg)
+	return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+}
+
+// filterSource 只读取满足条件的行，条件的结果为 Null 时不满足
+func filterSource(source rowSource, condition *tableschema.CompiledExpression) rowSource {
+	return func(fn func(row map[string][]byte) (bool, base.StandardError)) base.StandardError {
+		return source(func(row map[string][]byte) (bool, base.StandardError) {
+			match, err := condition.Match(row)
+			if err != nil || !match {
+				return err == nil, err
+			}
+			return fn(row)
+		})
+	}
+}
+
+// filterRelation 按表达式过滤内存中的数据
+func filterRelation(r *relation, filter *base.Expression) (*relation, base.StandardError) {
+	condition, err := tableschema.CompileCondition(r.tableInfo, filter)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[filterRelation] CompileCondition错误, %s", err.Error()))
+		return nil, err
+	}
+	result := &relation{tableInfo: r.tableInfo, rows: make([]map[string][]byte, 0)}
+	err = filterSource(r.source(), condition)(func(row map[string][]byte) (bool, base.StandardError) {
+		result.rows = append(result.rows, row)
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// expressionRelation 计算每一行的表达式，作为新的列加入结果
+// 结果为 bigint 时列的类型为 bigint，为 char 或者 bool 时为 char（bool 为 true / false），长度为结果的最大长度
+func expressionRelation(r *relation, items []*base.SelectExpression) (*relation, base.StandardError) {
+	fields := r.fields()
+	compiled := make([]*tableschema.CompiledExpression, 0, len(items))
+	for _, item := range items {
+		if item == nil || item.Alias == "" {
+			return nil, expressionError("表达式的列名为空")
+		}
+		if _, ok := relationInfo(r.tableInfo.Name, fields).FieldInfoByName(item.Alias); ok {
+			return nil, expressionError(fmt.Sprintf("表达式的列名<%s>和已有的列重复", item.Alias))
+		}
+		c, err := tableschema.CompileExpression(r.tableInfo, item.Expression)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[expressionRelation] 表达式<%s>错误, %s", item.Alias, err.Error()))
+			return nil, err
+		}
+		compiled = append(compiled, c)
+		fields = append(fields, &tableschema.FieldInfo{Name: item.Alias, Length: 1, FieldType: tableschema.CharType})
+	}
+
+	valueFields := fields[len(fields)-len(items):]
+	for i, c := range compiled {
+		if c.Type() == base.ExpressionTypeBigInt {
+			valueFields[i].Length, valueFields[i].FieldType = base.DataByteLengthInt64, tableschema.BigIntType
+		}
+	}
+	values := make([][]*tableschema.ExpressionValue, len(r.rows))
+	for i, row := range r.rows {
+		values[i] = make([]*tableschema.ExpressionValue, len(compiled))
+		for k, c := range compiled {
+			v, err := c.Evaluate(row)
+			if err != nil {
+				utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[expressionRelation] 计算表达式<%s>错误, %s", items[k].Alias, err.Error()))
+				return nil, err
+			}
+			values[i][k] = v
+			if field := valueFields[k]; field.FieldType == tableschema.CharType && !v.IsNull() && len(v.StringValue()) > field.Length {
+				field.Length = len(v.StringValue())
+			}
+		}
+	}
+
+	result := &relation{tableInfo: relationInfo(r.tableInfo.Name, fields), rows: make([]map[string][]byte, 0, len(r.rows))}
+	for i, row := range r.rows {
+		// 输入的行可能被 CTE 共享，复制之后再加入结果的列
+		copied := make(map[string][]byte, len(row)+len(items))
+		for k, v := range row {
+			copied[k] = v
+		}
+		for k, field := range valueFields {
+			value, err := field.ExpressionValueToByte(values[i][k])
+			if err != nil {
+				return nil, err
+			}
+			copied[field.Name] = field.FieldType.TrimRaw(value)
+		}
+		result.rows = append(result.rows, copied)
+	}
+	return result, nil
+}
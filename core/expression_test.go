@@ -0,0 +1,240 @@
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"ne_database/core/base"
+	"ne_database/core/tableschema"
+)
+
+// testColumnExpression 读取列的表达式
+func testColumnExpression(name string) *base.Expression {
+	return &base.Expression{Kind: base.ExpressionKindColumn, Column: name}
+}
+
+// testLiteral 常量表达式
+func testLiteral(value string, valueType base.ExpressionType) *base.Expression {
+	return &base.Expression{Kind: base.ExpressionKindLiteral, Value: value, Type: valueType}
+}
+
+// testOperator 运算符表达式
+func testOperator(operator base.ExpressionOperator, args ...*base.Expression) *base.Expression {
+	return &base.Expression{Kind: base.ExpressionKindOperator, Operator: operator, Args: args}
+}
+
+// testFunction 函数表达式
+func testFunction(function base.ExpressionFunction, args ...*base.Expression) *base.Expression {
+	return &base.Expression{Kind: base.ExpressionKindFunction, Function: function, Args: args}
+}
+
+// testCastChar 转换为 char 的表达式
+func testCastChar(arg *base.Expression) *base.Expression {
+	return &base.Expression{Kind: base.ExpressionKindFunction, Function: base.ExpressionFunctionCast, Type: base.ExpressionTypeChar, Args: []*base.Expression{arg}}
+}
+
+// newTestExpressionEngine 用户表，age 为 0、name 为空时是 Null
+func newTestExpressionEngine(t *testing.T) (*Engine, *tableschema.TableMetaInfo) {
+	t.Helper()
+	users := newTestTableInfo("engine_expression_users", testCharField("name", 32), testBigIntField("age"), testCharField("created", 19))
+	e := newTestEngine(t, users)
+	data := []struct {
+		id      int64
+		name    string
+		age     int64
+		created string
+	}{
+		{1, "Alice", 30, "2024-01-15 10:30:00"},
+		{2, "bob", 0, "2024-03-01 00:00:00"},
+		{3, "Carol", 17, "2023-12-31 23:59:59"},
+		{4, "", 45, "2024-02-29 12:00:00"},
+	}
+	for _, d := range data {
+		insertTestRows(t, e, users.Name, map[string][]byte{"id": testInt64(d.id), "name": []byte(d.name), "age": testInt64(d.age), "created": []byte(d.created)})
+	}
+	return e, users
+}
+
+func TestEngine_Expression_Filter(t *testing.T) {
+	e, users := newTestExpressionEngine(t)
+	// 结果为 Null 的行不满足
+	testCases := []struct {
+		filter *base.Expression
+		expect string
+	}{
+		{testOperator(base.ExpressionOperatorGreater,
+			testOperator(base.ExpressionOperatorAdd, testColumnExpression("age"), testLiteral("5", base.ExpressionTypeBigInt)),
+			testLiteral("30", base.ExpressionTypeBigInt)), "1,4"},
+		{testOperator(base.ExpressionOperatorOr, testOperator(base.ExpressionOperatorIsNull, testColumnExpression("age")),
+			testOperator(base.ExpressionOperatorLike, testFunction(base.ExpressionFunctionLower, testColumnExpression("name")), testLiteral("c%", base.ExpressionTypeChar))), "2,3"},
+		{testOperator(base.ExpressionOperatorNot,
+			testOperator(base.ExpressionOperatorGreaterAndEqual, testColumnExpression("age"), testLiteral("18", base.ExpressionTypeBigInt))), "3"},
+		{testOperator(base.ExpressionOperatorIn, testFunction(base.ExpressionFunctionMonth, testColumnExpression("created")),
+			testLiteral("1", base.ExpressionTypeBigInt), testLiteral("2", base.ExpressionTypeBigInt)), "1,4"},
+	}
+	for i, c := range testCases {
+		_, rows, err := e.Query(&base.Query{From: users.Name, Filter: c.filter, OrderBy: []*base.OrderByItem{{Column: "id"}}})
+		if err != nil {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+			continue
+		}
+		if r := testInt64Values(rows, "id"); r != c.expect {
+			t.Errorf("case %d: expected %s, but got %s", i, c.expect, r)
+		}
+	}
+
+	// SelectQuery 中 Where 和 Filter 同时使用
+	_, rows, err := e.SelectQuery(users.Name, &base.SelectQuery{
+		Where:  []*base.WherePartItem{{TargetColumn: "id", Operate: base.DataComparatorGreater, Args: [][]byte{testInt64(1)}}},
+		Filter: testOperator(base.ExpressionOperatorEqual, testFunction(base.ExpressionFunctionYear, testColumnExpression("created")), testLiteral("2024", base.ExpressionTypeBigInt)),
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if r := testInt64Values(rows, "id"); r != "2,4" {
+		t.Errorf("expected 2,4, but got %s", r)
+	}
+}
+
+func TestEngine_Expression_Select(t *testing.T) {
+	e, users := newTestExpressionEngine(t)
+	adult := testOperator(base.ExpressionOperatorGreaterAndEqual, testColumnExpression("age"), testLiteral("18", base.ExpressionTypeBigInt))
+	testCases := []struct {
+		expression *base.Expression
+		expect     string // 按照 id 排序后每一行的结果，Null 为空
+	}{
+		{&base.Expression{Kind: base.ExpressionKindCase, Args: []*base.Expression{
+			adult, testLiteral("adult", base.ExpressionTypeChar),
+			testOperator(base.ExpressionOperatorLess, testColumnExpression("age"), testLiteral("18", base.ExpressionTypeBigInt)), testLiteral("minor", base.ExpressionTypeChar),
+			testLiteral("unknown", base.ExpressionTypeChar),
+		}}, "adult,unknown,minor,adult"},
+		{testFunction(base.ExpressionFunctionCoalesce, testFunction(base.ExpressionFunctionUpper, testColumnExpression("name")), testLiteral("-", base.ExpressionTypeChar)), "ALICE,BOB,CAROL,-"},
+		{adult, "true,,false,true"},
+		{testFunction(base.ExpressionFunctionConcat, testColumnExpression("name"), testLiteral("-", base.ExpressionTypeChar), testCastChar(testColumnExpression("age"))), "Alice-30,,Carol-17,"},
+		{testFunction(base.ExpressionFunctionSubstr, testColumnExpression("name"), testLiteral("2", base.ExpressionTypeBigInt), testLiteral("3", base.ExpressionTypeBigInt)), "lic,ob,aro,"},
+		{testFunction(base.ExpressionFunctionDate,
+			testFunction(base.ExpressionFunctionDateAdd, testColumnExpression("created"), testLiteral("86400", base.ExpressionTypeBigInt))), "2024-01-16,2024-03-02,2024-01-01,2024-03-01"},
+	}
+	for i, c := range testCases {
+		_, rows, err := e.Query(&base.Query{
+			From:        users.Name,
+			Expressions: []*base.SelectExpression{{Alias: "result", Expression: c.expression}},
+			Columns:     []string{"id", "result"},
+			OrderBy:     []*base.OrderByItem{{Column: "id"}},
+		})
+		if err != nil {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+			continue
+		}
+		r := make([]string, 0, len(rows))
+		for _, row := range rows {
+			r = append(r, string(tableschema.CharType.TrimRaw(row["result"])))
+		}
+		if strings.Join(r, ",") != c.expect {
+			t.Errorf("case %d: expected %s, but got %s", i, c.expect, strings.Join(r, ","))
+		}
+	}
+
+	// bigint 的结果可以用来排序，统计 2024-01-01 之后的天数
+	_, rows, err := e.Query(&base.Query{
+		From: users.Name,
+		Expressions: []*base.SelectExpression{{Alias: "days", Expression: testOperator(base.ExpressionOperatorDivide,
+			testFunction(base.ExpressionFunctionDateDiff, testColumnExpression("created"), testLiteral("2024-01-01", base.ExpressionTypeChar)),
+			testLiteral("86400", base.ExpressionTypeBigInt))}},
+		OrderBy: []*base.OrderByItem{{Column: "days", Desc: true}},
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if ids, days := testInt64Values(rows, "id"), testInt64Values(rows, "days"); ids != "2,4,1,3" || days != "60,59,14,0" {
+		t.Errorf("expected 2,4,1,3 with 60,59,14,0 days, but got %s with %s days", ids, days)
+	}
+}
+
+func TestEngine_Expression_Error(t *testing.T) {
+	e, users := newTestExpressionEngine(t)
+	testCases := []*base.Query{
+		// 条件需要是 bool
+		{From: users.Name, Filter: testOperator(base.ExpressionOperatorAdd, testColumnExpression("age"), testLiteral("1", base.ExpressionTypeBigInt))},
+		{From: users.Name, Filter: testOperator(base.ExpressionOperatorEqual, testColumnExpression("missing"), testLiteral("1", base.ExpressionTypeBigInt))},
+		{From: users.Name, Filter: testOperator(base.ExpressionOperatorEqual, testColumnExpression("name"), testColumnExpression("age"))},
+		// 别名和表中的列重名
+		{From: users.Name, Expressions: []*base.SelectExpression{{Alias: "age", Expression: testColumnExpression("name")}}},
+		{From: users.Name, Expressions: []*base.SelectExpression{{Alias: "result", Expression: testOperator(base.ExpressionOperatorDivide,
+			testColumnExpression("age"), testLiteral("0", base.ExpressionTypeBigInt))}}},
+		{From: users.Name, Expressions: []*base.SelectExpression{{Alias: "result", Expression: testFunction("md5", testColumnExpression("name"))}}},
+	}
+	for i, query := range testCases {
+		if _, _, err := e.Query(query); err == nil {
+			t.Errorf("case %d: expected error, but got nil", i)
+		}
+	}
+}
+
+func TestEngine_Insert_DefaultExpression(t *testing.T) {
+	amount := testBigIntField("amount")
+	amount.DefaultExpression = testOperator(base.ExpressionOperatorMultiply, testLiteral("10", base.ExpressionTypeBigInt), testLiteral("3", base.ExpressionTypeBigInt))
+	tag := testCharField("tag", 16)
+	tag.DefaultExpression = testFunction(base.ExpressionFunctionConcat, testLiteral("t-", base.ExpressionTypeChar),
+		testCastChar(testOperator(base.ExpressionOperatorAdd, testLiteral("1", base.ExpressionTypeBigInt), testLiteral("1", base.ExpressionTypeBigInt))))
+	orders := newTestTableInfo("engine_expression_orders", amount, tag)
+	// 使用表达式的 CHECK 约束
+	orders.CheckConstraints = []*tableschema.CheckConstraint{{
+		Name: "engine_expression_orders_check",
+		Condition: testOperator(base.ExpressionOperatorAnd,
+			testOperator(base.ExpressionOperatorGreater, testColumnExpression("amount"), testLiteral("0", base.ExpressionTypeBigInt)),
+			testOperator(base.ExpressionOperatorLessAndEqual, testFunction(base.ExpressionFunctionLength, testColumnExpression("tag")), testLiteral("8", base.ExpressionTypeBigInt))),
+	}}
+	e := newTestEngine(t, orders)
+
+	testCases := []struct {
+		row       map[string][]byte
+		expectErr bool
+		expect    string // 插入之后的 amount 和 tag
+	}{
+		{map[string][]byte{"id": testInt64(1)}, false, "30,t-2"},
+		// 条件的结果为 Null 时满足约束
+		{map[string][]byte{"id": testInt64(2), "amount": testInt64(0)}, false, "0,t-2"},
+		{map[string][]byte{"id": testInt64(3), "amount": testInt64(-1)}, true, ""},
+		{map[string][]byte{"id": testInt64(4), "tag": []byte("abcdefghij")}, true, ""},
+	}
+	for i, c := range testCases {
+		_, _, err := e.Insert(orders.Name, c.row)
+		if (err != nil) != c.expectErr {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+			continue
+		}
+		if c.expectErr {
+			continue
+		}
+		_, rows, err := e.Select(orders.Name, testWhereEqual("id", c.row["id"]))
+		if err != nil || len(rows) != 1 {
+			t.Errorf("case %d: unexpected result: %d, %v", i, len(rows), err)
+			continue
+		}
+		r := testInt64Values(rows, "amount") + "," + string(tableschema.CharType.TrimRaw(rows[0]["tag"]))
+		if r != c.expect {
+			t.Errorf("case %d: expected %s, but got %s", i, c.expect, r)
+		}
+	}
+}
+
+func TestEngine_CreateTable_DefaultExpression(t *testing.T) {
+	testCases := []*tableschema.FieldInfo{
+		// bigint 的列不能使用 char 的结果
+		{Name: "v", Length: 8, FieldType: tableschema.BigIntType, DefaultExpression: testFunction(base.ExpressionFunctionNow)},
+		// 默认值表达式不能使用列
+		{Name: "v", Length: 8, FieldType: tableschema.BigIntType, DefaultExpression: testColumnExpression("id")},
+		// 默认值和默认值表达式不能同时设置
+		{Name: "v", Length: 8, FieldType: tableschema.BigIntType, DefaultValue: "1", DefaultExpression: testLiteral("1", base.ExpressionTypeBigInt)},
+	}
+	e := newTestEngine(t)
+	for i, field := range testCases {
+		if err := e.CreateTable(newTestTableInfo("engine_expression_error", field)); err == nil {
+			_ = e.DeleteTable("engine_expression_error")
+			t.Errorf("case %d: expected error, but got nil", i)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package core
+
+import (
+	"fmt"
+
+	"ne_database/core/base"
+	"ne_database/core/tableschema"
+	"ne_database/utils"
+	"ne_database/utils/set"
+)
+
+// referencingForeignKey 引用某个表的外键，TableInfo 为外键所在的子表
+type referencingForeignKey struct {
+	TableInfo  *tableschema.TableMetaInfo
+	ForeignKey *tableschema.ForeignKey
+}
+
+// foreignKeyVerification 建表时校验外键引用的表，引用自身时使用 tableInfo
+func (e *Engine) foreignKeyVerification(tableInfo *tableschema.TableMetaInfo) base.StandardError {
+	for _, fk := range tableInfo.ForeignKeys {
+		refInfo := tableInfo
+		if fk.RefTable != tableInfo.Name {
+			exist, err := e.CheckTableExist(fk.RefTable)
+			if err != nil {
+				utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[foreignKeyVerification] CheckTableExist错误, %s", err.Error()))
+				return err
+			}
+			if !exist {
+				errMsg := fmt.Sprintf("外键<%s>引用的表<%s>不存在", fk.Name, fk.RefTable)
+				utils.LogError("[Engine foreignKeyVerification] " + errMsg)
+				return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeInput, base.ErrorBaseCodeTableSchemaError, fmt.Errorf(errMsg))
+			}
+			refInfo, err = e.LoadTableSchemaInfo(fk.RefTable)
+			if err != nil {
+				utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[foreignKeyVerification] LoadTableSchemaInfo错误, %s", err.Error()))
+				return err
+			}
+		}
+		err := fk.ReferenceVerification(tableInfo, refInfo)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[foreignKeyVerification] ReferenceVerification错误, %s", err.Error()))
+			return err
+		}
+	}
+	return nil
+}
+
+// referencingForeignKeys 查找引用 tableName 的全部外键，包括表引用自身的外键
+func (e *Engine) referencingForeignKeys(tableName string) ([]*referencingForeignKey, base.StandardError) {
+	allTable, err := e.AllTable()
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[referencingForeignKeys] AllTable错误, %s", err.Error()))
+		return nil, err
+	}
+	r := make([]*referencingForeignKey, 0)
+	for _, tableInfo := range allTable {
+		for _, fk := range tableInfo.ForeignKeys {
+			if fk.RefTable == tableName {
+				r = append(r, &referencingForeignKey{TableInfo: tableInfo, ForeignKey: fk})
+			}
+		}
+	}
+	return r, nil
+}
+
+// searchTable 打开表查询满足条件的数据，返回主键和对应的值
+func (e *Engine) searchTable(tableName string, whereArgs []*base.WherePartItem) ([][]byte, []map[string][]byte, base.StandardError) {
+	tree, err := e.openTable(tableName)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[searchTable] openTable错误, %s", err.Error()))
+		return nil, nil, err
+	}
+	defer tree.DataManager.Close()
+	return tree.Search(whereArgs)
+}
+
+// checkForeignKeys 检查写入的数据中外键列的值在引用的表中存在，columns 为本次写入的列，为 nil 时检查全部外键
+func (e *Engine) checkForeignKeys(tableInfo *tableschema.TableMetaInfo, row map[string][]byte, columns *set.StringsSet) base.StandardError {
+	for _, fk := range tableInfo.ForeignKeys {
+		if columns != nil && !columns.Contain(fk.Column) {
+			continue
+		}
+		field, _ := tableInfo.FieldInfoByName(fk.Column)
+		value := row[fk.Column]
+		isNull, err := field.FieldType.IsNull(field.FieldType.TrimRaw(value))
+		if err != nil {
+			return err
+		}
+		if isNull {
+			continue
+		}
+		// 引用自身的同一行
+		if fk.RefTable == tableInfo.Name {
+			equal, err := field.FieldType.Equal(value, row[tableInfo.PrimaryKeyFieldInfo.Name])
+			if err != nil {
+				return err
+			}
+			if equal {
+				continue
+			}
+		}
+		keyList, _, err := e.searchTable(fk.RefTable, []*base.WherePartItem{
+			{TargetColumn: fk.RefColumn, Operate: base.DataComparatorEqual, Args: [][]byte{value}},
+		})
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[checkForeignKeys] searchTable错误, %s", err.Error()))
+			return err
+		}
+		if len(keyList) == 0 {
+			errMsg := fmt.Sprintf("违反外键约束<%s>: 表<%s>中不存在<%s>", fk.Name, fk.RefTable, field.FieldType.StringValue(value))
+			utils.LogError("[Engine checkForeignKeys] " + errMsg)
+			return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeInput, base.ErrorBaseCodeConstraintError, fmt.Errorf(errMsg))
+		}
+	}
+	return nil
+}
+
+// referencingRows 查找子表中引用了 keys 的数据的主键，excludeKeys 为同一个表中本次一起删除或修改的数据，不算作引用
+func (e *Engine) referencingRows(ref *referencingForeignKey, keys [][]byte, excludeKeys [][]byte) ([][]byte, base.StandardError) {
+	keyList, _, err := e.searchTable(ref.TableInfo.Name, []*base.WherePartItem{
+		{TargetColumn: ref.ForeignKey.Column, Operate: base.DataComparatorIn, Args: keys},
+	})
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[referencingRows] searchTable错误, %s", err.Error()))
+		return nil, err
+	}
+	if len(excludeKeys) == 0 {
+		return keyList, nil
+	}
+	fieldType := ref.TableInfo.PrimaryKeyFieldInfo.FieldType
+	r := make([][]byte, 0, len(keyList))
+	for _, key := range keyList {
+		match, err := tableschema.MatchMetaType(fieldType, key, base.DataComparatorIn, excludeKeys, nil)
+		if err != nil {
+			return nil, err
+		}
+		if !match {
+			r = append(r, key)
+		}
+	}
+	return r, nil
+}
+
+// checkRestrict 检查 restrict 动作的外键，子表中存在引用 keys 的数据时报错
+// update 为 true 时检查 OnUpdate，否则检查 OnDelete
+func (e *Engine) checkRestrict(tableName string, refs []*referencingForeignKey, keys [][]byte, update bool) base.StandardError {
+	for _, ref := range refs {
+		action := ref.ForeignKey.DeleteAction()
+		if update {
+			action = ref.ForeignKey.UpdateAction()
+		}
+		if action != base.ForeignKeyActionRestrict {
+			continue
+		}
+		var excludeKeys [][]byte
+		if ref.TableInfo.Name == tableName {
+			excludeKeys = keys
+		}
+		keyList, err := e.referencingRows(ref, keys, excludeKeys)
+		if err != nil {
+			return err
+		}
+		if len(keyList) > 0 {
+			errMsg := fmt.Sprintf("违反外键约束<%s>: 表<%s>中存在引用的数据", ref.ForeignKey.Name, ref.TableInfo.Name)
+			utils.LogError("[Engine checkRestrict] " + errMsg)
+			return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeInput, base.ErrorBaseCodeConstraintError, fmt.Errorf(errMsg))
+		}
+	}
+	return nil
+}
+
+// applyParentDelete 父表数据删除之后，对子表执行 cascade / set null 动作
+func (e *Engine) applyParentDelete(refs []*referencingForeignKey, keys [][]byte) base.StandardError {
+	for _, ref := range refs {
+		whereArgs := []*base.WherePartItem{{TargetColumn: ref.ForeignKey.Column, Operate: base.DataComparatorIn, Args: keys}}
+		var err base.StandardError
+		switch ref.ForeignKey.DeleteAction() {
+		case base.ForeignKeyActionCascade:
+			_, err = e.Delete(ref.TableInfo.Name, whereArgs)
+		case base.ForeignKeyActionSetNull:
+			field, _ := ref.TableInfo.FieldInfoByName(ref.ForeignKey.Column)
+			_, err = e.Update(ref.TableInfo.Name, whereArgs, map[string][]byte{field.Name: field.NullValue()})
+		}
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[applyParentDelete] 外键<%s>执行动作错误, %s", ref.ForeignKey.Name, err.Error()))
+			return err
+		}
+	}
+	return nil
+}
+
+// applyParentUpdate 父表主键由 oldKey 修改为 newKey 之后，对子表执行 cascade / set null 动作
+func (e *Engine) applyParentUpdate(refs []*referencingForeignKey, oldKey []byte, newKey []byte) base.StandardError {
+	for _, ref := range refs {
+		whereArgs := []*base.WherePartItem{{TargetColumn: ref.ForeignKey.Column, Operate: base.DataComparatorEqual, Args: [][]byte{oldKey}}}
+		var err base.StandardError
+		switch ref.ForeignKey.UpdateAction() {
+		case base.ForeignKeyActionCascade:
+			_, err = e.Update(ref.TableInfo.Name, whereArgs, map[string][]byte{ref.ForeignKey.Column: newKey})
+		case base.ForeignKeyActionSetNull:
+			field, _ := ref.TableInfo.FieldInfoByName(ref.ForeignKey.Column)
+			_, err = e.Update(ref.TableInfo.Name, whereArgs, map[string][]byte{field.Name: field.NullValue()})
+		}
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[applyParentUpdate] 外键<%s>执行动作错误, %s", ref.ForeignKey.Name, err.Error()))
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"ne_database/core/base"
+	"ne_database/core/tableschema"
+)
+
+// newTestForeignKeyTables order_items 引用 orders，删除和更新时级联；
+// order_notes 引用 orders，删除时设为 Null，更新时 restrict
+func newTestForeignKeyTables() (orders, items, notes *tableschema.TableMetaInfo) {
+	status := testCharField("status", 10)
+	status.DefaultValue = "'new'"
+	orders = newTestTableInfo("engine_fk_orders", status)
+	items = newTestTableInfo("engine_fk_order_items", testBigIntField("order_id"))
+	items.ForeignKeys = []*tableschema.ForeignKey{
+		{Column: "order_id", RefTable: orders.Name, RefColumn: "id", OnDelete: base.ForeignKeyActionCascade, OnUpdate: base.ForeignKeyActionCascade},
+	}
+	notes = newTestTableInfo("engine_fk_order_notes", testBigIntField("order_id"))
+	notes.ForeignKeys = []*tableschema.ForeignKey{
+		{Name: "notes_order", Column: "order_id", RefTable: orders.Name, RefColumn: "id", OnDelete: base.ForeignKeyActionSetNull},
+	}
+	return orders, items, notes
+}
+
+// newTestForeignKeyEngine 建表并插入 orders 1, 2；order_items 10 -> 1, 11 -> 2；order_notes 20 -> 1, 21 -> Null
+func newTestForeignKeyEngine(t *testing.T) (e *Engine, orders, items, notes *tableschema.TableMetaInfo) {
+	t.Helper()
+	orders, items, notes = newTestForeignKeyTables()
+	e = newTestEngine(t, orders, items, notes)
+	insertTestRows(t, e, orders.Name, map[string][]byte{"id": testInt64(1)}, map[string][]byte{"id": testInt64(2)})
+	insertTestRows(t, e, items.Name,
+		map[string][]byte{"id": testInt64(10), "order_id": testInt64(1)},
+		map[string][]byte{"id": testInt64(11), "order_id": testInt64(2)},
+	)
+	insertTestRows(t, e, notes.Name, map[string][]byte{"id": testInt64(20), "order_id": testInt64(1)}, map[string][]byte{"id": testInt64(21)})
+	return e, orders, items, notes
+}
+
+func TestEngine_CreateTable_ForeignKey(t *testing.T) {
+	_, items, _ := newTestForeignKeyTables()
+	e := newTestEngine(t)
+	// 引用的表不存在
+	if err := e.CreateTable(items); err == nil {
+		_ = e.DeleteTable(items.Name)
+		t.Error("expected error, but got nil")
+	}
+}
+
+func TestEngine_Insert_ForeignKey(t *testing.T) {
+	e, _, items, _ := newTestForeignKeyEngine(t)
+
+	// 按顺序执行，update 为 nil 时插入 row，否则按 row 的 id 更新
+	testCases := []struct {
+		row       map[string][]byte
+		update    map[string][]byte
+		expectErr bool
+	}{
+		{map[string][]byte{"id": testInt64(12), "order_id": testInt64(2)}, nil, false},
+		// 引用的数据不存在
+		{map[string][]byte{"id": testInt64(13), "order_id": testInt64(3)}, nil, true},
+		// 引用的列为 Null 时不检查
+		{map[string][]byte{"id": testInt64(14)}, nil, false},
+		{map[string][]byte{"id": testInt64(11)}, map[string][]byte{"order_id": testInt64(3)}, true},
+		{map[string][]byte{"id": testInt64(11)}, map[string][]byte{"order_id": testInt64(1)}, false},
+	}
+	for i, c := range testCases {
+		var err error
+		if c.update == nil {
+			_, _, err = e.Insert(items.Name, c.row)
+		} else {
+			_, err = e.Update(items.Name, testWhereEqual("id", c.row["id"]), c.update)
+		}
+		if (err != nil) != c.expectErr {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+			continue
+		}
+		if err != nil && !strings.Contains(err.Error(), "engine_fk_order_items_order_id_fkey") {
+			t.Errorf("case %d: expected foreign key error, but got %v", i, err)
+		}
+	}
+}
+
+func TestEngine_Update_ForeignKey(t *testing.T) {
+	testCases := []struct {
+		name      string
+		id        int64
+		newID     int64
+		expectErr string
+		// expectItems 更新之后 order_items 11 引用的 order_id
+		expectItems string
+	}{
+		// order_notes 的 OnUpdate 为 restrict
+		{"restrict", 1, 5, "notes_order", "2"},
+		// order_items 的 OnUpdate 为 cascade
+		{"cascade", 2, 6, "", "6"},
+	}
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			e, orders, items, _ := newTestForeignKeyEngine(t)
+			count, err := e.Update(orders.Name, testWhereEqual("id", testInt64(c.id)), map[string][]byte{"id": testInt64(c.newID)})
+			if c.expectErr == "" && (err != nil || count != 1) {
+				t.Errorf("unexpected result: %d, %v", count, err)
+				return
+			}
+			if c.expectErr != "" && (err == nil || !strings.Contains(err.Error(), c.expectErr)) {
+				t.Errorf("expected restrict error, but got %v", err)
+				return
+			}
+			_, rows, err := e.Select(items.Name, testWhereEqual("id", testInt64(11)))
+			if err != nil || testInt64Values(rows, "order_id") != c.expectItems {
+				t.Errorf("expected %s, but got %s, %v", c.expectItems, testInt64Values(rows, "order_id"), err)
+			}
+		})
+	}
+}
+
+func TestEngine_Delete_ForeignKey(t *testing.T) {
+	e, orders, items, notes := newTestForeignKeyEngine(t)
+	count, err := e.Delete(orders.Name, testWhereEqual("id", testInt64(1)))
+	if err != nil || count != 1 {
+		t.Errorf("unexpected result: %d, %v", count, err)
+		return
+	}
+
+	// order_items 级联删除，order_notes 设为 Null
+	testCases := []struct {
+		table  string
+		expect string
+	}{
+		{items.Name, "11:2"},
+		{notes.Name, "20:0,21:0"},
+	}
+	for i, c := range testCases {
+		_, rows, err := e.Select(c.table, nil)
+		if err != nil {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+			continue
+		}
+		r := make([]string, 0, len(rows))
+		for _, row := range rows {
+			r = append(r, tableschema.BigIntType.StringValue(row["id"])+":"+tableschema.BigIntType.StringValue(row["order_id"]))
+		}
+		if strings.Join(r, ",") != c.expect {
+			t.Errorf("case %d: expected %s, but got %s", i, c.expect, strings.Join(r, ","))
+		}
+	}
+}
+
+func TestEngine_DropTableCascade(t *testing.T) {
+	e, orders, items, notes := newTestForeignKeyEngine(t)
+	// 被引用的表不能删除
+	if err := e.DeleteTable(orders.Name); err == nil {
+		t.Error("expected error, but got nil")
+		return
+	}
+	// 强制删除时去掉引用的外键
+	if err := e.DropTableCascade(orders.Name); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	for i, name := range []string{items.Name, notes.Name} {
+		tableInfo, err := e.LoadTableSchemaInfo(name)
+		if err != nil || len(tableInfo.ForeignKeys) != 0 {
+			t.Errorf("case %d: unexpected foreign keys: %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,557 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"ne_database/core/base"
+	"ne_database/core/tableschema"
+	"ne_database/utils"
+	"ne_database/utils/set"
+)
+
+// 哈希连接写入临时文件时两边的行分别写入各自的分区（见 hashPartitions）
+const (
+	joinBuildSide = 0
+	joinProbeSide = 1
+)
+
+// joinSide 参与连接的一边，keyFields 为 On 中该表的列，顺序和 On 相同
+type joinSide struct {
+	alias     string
+	tree      *BPlusTree
+	where     []*base.WherePartItem
+	layout    *rowSorter // 行和 spillRecord 之间的转换
+	keyFields []*tableschema.FieldInfo
+	estimate  *tableEstimate
+	access    *accessPath
+}
+
+func (s *joinSide) primaryKey() *tableschema.FieldInfo {
+	return s.tree.TableInfo.PrimaryKeyFieldInfo
+}
+
+// scan 按照主键的顺序扫描满足 Where 的行（包括主键）
+func (s *joinSide) scan(whereArgs []*base.WherePartItem, fn func(row map[string][]byte) (bool, base.StandardError)) base.StandardError {
+	return s.tree.Scan(whereArgs, func(key []byte, values map[string][]byte) (bool, base.StandardError) {
+		values[s.primaryKey().Name] = key
+		return fn(values)
+	})
+}
+
+// key 连接的 key，连接的列中有 Null 时返回 false，不和任何行匹配
+func (s *joinSide) key(row map[string][]byte) ([]byte, bool, base.StandardError) {
+	values := make([][]byte, len(s.keyFields))
+	for i, field := range s.keyFields {
+		isNull, err := field.FieldType.IsNull(row[field.Name])
+		if err != nil {
+			return nil, false, err
+		}
+		if isNull {
+			return nil, false, nil
+		}
+		values[i] = row[field.Name]
+	}
+	return compositeIndexKey(s.keyFields, values), true, nil
+}
+
+// record 连接的 key 和行组成的 spillRecord
+func (s *joinSide) record(key []byte, row map[string][]byte) spillRecord {
+	return append(spillRecord{key}, s.layout.record(row)...)
+}
+
+// output 把一行写入结果，列名为 别名.列名，row 为 nil 时全部为 Null
+func (s *joinSide) output(r map[string][]byte, row map[string][]byte) {
+	for _, field := range s.layout.columns {
+		if row == nil {
+			r[s.alias+"."+field.Name] = field.FieldType.TrimRaw(field.NullValue())
+		} else {
+			r[s.alias+"."+field.Name] = row[field.Name]
+		}
+	}
+}
+
+// joinExecutor 连接的执行计划，右连接交换两边后按照左连接执行，outer 为外表（驱动表）
+type joinExecutor struct {
+	outer     *joinSide
+	inner     *joinSide
+	joinType  base.JoinType
+	outerJoin bool // 外表没有匹配的行时也输出
+	rows      []map[string][]byte
+}
+
+func joinError(errMsg string) base.StandardError {
+	utils.LogError("[joinExecutor] " + errMsg)
+	return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+}
+
+func (j *joinExecutor) emit(outerRow map[string][]byte, innerRow map[string][]byte) {
+	r := make(map[string][]byte, len(j.outer.layout.columns)+len(j.inner.layout.columns))
+	j.outer.output(r, outerRow)
+	j.inner.output(r, innerRow)
+	j.rows = append(j.rows, r)
+}
+
+// primaryKeyPair On 中 outer 和 inner 的列分别为主键（needOuter 为 false 时只要求 inner）的位置，没有时为 -1
+func (j *joinExecutor) primaryKeyPair(needOuter bool) int {
+	for i := range j.inner.keyFields {
+		if j.inner.keyFields[i] != j.inner.primaryKey() {
+			continue
+		}
+		if !needOuter || j.outer.keyFields[i] == j.outer.primaryKey() {
+			return i
+		}
+	}
+	return -1
+}
+
+// plan 按照代价选择连接算法和外表: inner / cross 连接比较两种连接顺序，left 连接的外表固定为左表（right 连接已经交换为左连接）
+// 归并连接要求两边都按照主键连接，索引嵌套循环要求内表按照主键连接，cross 连接只能使用嵌套循环；代价相同时按照这个顺序优先
+// algorithm 不为空时只使用指定的算法，不适用时报错；返回后 outer / inner 为选择的连接顺序
+func (j *joinExecutor) plan(algorithm base.JoinAlgorithm) (*base.QueryPlan, base.StandardError) {
+	algorithms := []base.JoinAlgorithm{base.JoinAlgorithmMerge, base.JoinAlgorithmIndexNestedLoop, base.JoinAlgorithmHash, base.JoinAlgorithmNestedLoop}
+	if algorithm != "" {
+		supported := false
+		for _, a := range algorithms {
+			supported = supported || a == algorithm
+		}
+		if !supported {
+			return nil, joinError(fmt.Sprintf("不支持的连接算法: %s", algorithm))
+		}
+		algorithms = []base.JoinAlgorithm{algorithm}
+	}
+	orders := [][2]*joinSide{{j.outer, j.inner}}
+	if j.joinType == base.JoinTypeInner || j.joinType == base.JoinTypeCross {
+		orders = append(orders, [2]*joinSide{j.inner, j.outer})
+	}
+	var (
+		best      *base.QueryPlan
+		bestOrder [2]*joinSide
+	)
+	for _, order := range orders {
+		j.outer, j.inner = order[0], order[1]
+		for _, a := range algorithms {
+			cost, ok := j.cost(a)
+			if !ok || (best != nil && cost >= best.Cost) {
+				continue
+			}
+			best = &base.QueryPlan{Algorithm: a, Outer: j.outer.alias, Inner: j.inner.alias, Rows: j.estimateRows(), Cost: cost}
+			bestOrder = order
+		}
+	}
+	j.outer, j.inner = orders[0][0], orders[0][1]
+	if best == nil {
+		return nil, joinError(fmt.Sprintf("连接算法<%s>不适用于该连接", algorithm))
+	}
+	j.outer, j.inner = bestOrder[0], bestOrder[1]
+	return best, nil
+}
+
+// nestedLoopJoin 嵌套循环连接，外表的每一行都重新扫描一次内表
+func (j *joinExecutor) nestedLoopJoin() base.StandardError {
+	return j.outer.scan(j.outer.where, func(outerRow map[string][]byte) (bool, base.StandardError) {
+		outerKey, ok, err := j.outer.key(outerRow)
+		if err != nil {
+			return false, err
+		}
+		matched := false
+		if ok {
+			err = j.inner.scan(j.inner.where, func(innerRow map[string][]byte) (bool, base.StandardError) {
+				innerKey, ok, err := j.inner.key(innerRow)
+				if err != nil {
+					return false, err
+				}
+				if ok && bytes.Equal(outerKey, innerKey) {
+					j.emit(outerRow, innerRow)
+					matched = true
+				}
+				return true, nil
+			})
+			if err != nil {
+				return false, err
+			}
+		}
+		if !matched && j.outerJoin {
+			j.emit(outerRow, nil)
+		}
+		return true, nil
+	})
+}
+
+// indexNestedLoopJoin 索引嵌套循环连接，外表的每一行使用 SearchEqualKey 在内表的主键中查找
+func (j *joinExecutor) indexNestedLoopJoin() base.StandardError {
+	pair := j.primaryKeyPair(false)
+	innerPrimaryKey := j.inner.primaryKey()
+	regexpCache := tableschema.NewRegexpCache()
+	return j.outer.scan(j.outer.where, func(outerRow map[string][]byte) (bool, base.StandardError) {
+		outerKey, ok, err := j.outer.key(outerRow)
+		if err != nil {
+			return false, err
+		}
+		matched := false
+		if ok {
+			// 值的长度超过内表主键的长度时不可能匹配
+			key, err := innerPrimaryKey.FieldType.LengthPadding(outerRow[j.outer.keyFields[pair].Name], innerPrimaryKey.Length)
+			if err == nil {
+				keyList, valueList, err := j.inner.tree.SearchEqualKey(key)
+				if err != nil {
+					return false, err
+				}
+				for i, innerRow := range valueList {
+					innerRow[innerPrimaryKey.Name] = keyList[i]
+					match, err := j.inner.tree.TableInfo.MatchWhereParts(j.inner.where, innerRow, regexpCache)
+					if err != nil {
+						return false, err
+					}
+					if !match {
+						continue
+					}
+					innerKey, ok, err := j.inner.key(innerRow)
+					if err != nil {
+						return false, err
+					}
+					if ok && bytes.Equal(outerKey, innerKey) {
+						j.emit(outerRow, innerRow)
+						matched = true
+					}
+				}
+			}
+		}
+		if !matched && j.outerJoin {
+			j.emit(outerRow, nil)
+		}
+		return true, nil
+	})
+}
+
+// probe 在内表的哈希表中查找外表的一行
+func (j *joinExecutor) probe(table map[string][]spillRecord, outerKey []byte, ok bool, outerRow map[string][]byte) {
+	matched := false
+	if ok {
+		for _, record := range table[string(outerKey)] {
+			j.emit(outerRow, j.inner.layout.row(record[1:]))
+			matched = true
+		}
+	}
+	if !matched && j.outerJoin {
+		j.emit(outerRow, nil)
+	}
+}
+
+// hashJoin 哈希连接，使用内表建立哈希表，扫描外表查找
+// 内表的行数超过内存限制时（见 config.OperatorMemoryRows），两边的行按照 key 的哈希值分区写入临时文件，再逐个分区连接
+func (j *joinExecutor) hashJoin() base.StandardError {
+	limit := operatorMemoryRows()
+	table := make(map[string][]spillRecord)
+	size := 0
+	var partitions *hashPartitions
+	defer func() {
+		if partitions != nil {
+			partitions.Close()
+		}
+	}()
+	err := j.inner.scan(j.inner.where, func(row map[string][]byte) (bool, base.StandardError) {
+		key, ok, err := j.inner.key(row)
+		if err != nil || !ok {
+			return err == nil, err
+		}
+		record := j.inner.record(key, row)
+		if partitions != nil {
+			return true, partitions.add(joinBuildSide, record)
+		}
+		table[string(key)] = append(table[string(key)], record)
+		size++
+		if size <= limit {
+			return true, nil
+		}
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[hashJoin] 内表<%s>超过内存限制<%d>，分区写入临时文件", j.inner.alias, limit))
+		partitions = newHashPartitions(2, 0)
+		for _, records := range table {
+			for _, r := range records {
+				err := partitions.add(joinBuildSide, r)
+				if err != nil {
+					return false, err
+				}
+			}
+		}
+		table = nil
+		return true, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if partitions == nil {
+		return j.outer.scan(j.outer.where, func(row map[string][]byte) (bool, base.StandardError) {
+			key, ok, err := j.outer.key(row)
+			if err != nil {
+				return false, err
+			}
+			j.probe(table, key, ok, row)
+			return true, nil
+		})
+	}
+	err = j.outer.scan(j.outer.where, func(row map[string][]byte) (bool, base.StandardError) {
+		key, ok, err := j.outer.key(row)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			j.probe(nil, nil, false, row)
+			return true, nil
+		}
+		return true, partitions.add(joinProbeSide, j.outer.record(key, row))
+	})
+	if err != nil {
+		return err
+	}
+	err = partitions.finish()
+	if err != nil {
+		return err
+	}
+	for partition := 0; partition < hashPartitionCount; partition++ {
+		table = make(map[string][]spillRecord)
+		err = partitions.each(joinBuildSide, partition, func(record spillRecord) base.StandardError {
+			table[string(record[0])] = append(table[string(record[0])], record)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		err = partitions.each(joinProbeSide, partition, func(record spillRecord) base.StandardError {
+			j.probe(table, record[0], true, j.outer.layout.row(record[1:]))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeJoin 归并连接，两边都按照主键连接，B+树按照主键的顺序扫描，输入已经有序并且 key 唯一
+// 外表按顺序扫描，内表每次读取主键大于上一次读取的最多 config.OperatorMemoryRows 行
+func (j *joinExecutor) mergeJoin() base.StandardError {
+	pair := j.primaryKeyPair(true)
+	fieldType := j.outer.primaryKey().FieldType
+	innerName := j.inner.primaryKey().Name
+	limit := operatorMemoryRows()
+	var (
+		buffer  []map[string][]byte
+		pos     int
+		lastKey []byte
+		done    bool
+	)
+	// current 内表当前的行，读取完时返回 nil
+	current := func() (map[string][]byte, base.StandardError) {
+		if pos < len(buffer) || done {
+			if pos < len(buffer) {
+				return buffer[pos], nil
+			}
+			return nil, nil
+		}
+		whereArgs := j.inner.where
+		if lastKey != nil {
+			whereArgs = append(append([]*base.WherePartItem{}, j.inner.where...),
+				&base.WherePartItem{TargetColumn: innerName, Operate: base.DataComparatorGreater, Args: [][]byte{lastKey}})
+		}
+		buffer, pos = buffer[:0], 0
+		err := j.inner.scan(whereArgs, func(row map[string][]byte) (bool, base.StandardError) {
+			buffer = append(buffer, row)
+			return len(buffer) < limit, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(buffer) < limit {
+			done = true
+		}
+		if len(buffer) == 0 {
+			return nil, nil
+		}
+		lastKey = buffer[len(buffer)-1][innerName]
+		return buffer[0], nil
+	}
+
+	return j.outer.scan(j.outer.where, func(outerRow map[string][]byte) (bool, base.StandardError) {
+		outerKey, ok, err := j.outer.key(outerRow)
+		if err != nil {
+			return false, err
+		}
+		matched := false
+		for ok {
+			innerRow, err := current()
+			if err != nil {
+				return false, err
+			}
+			if innerRow == nil {
+				break
+			}
+			c, err := compareFieldValue(fieldType, innerRow[innerName], outerRow[j.outer.keyFields[pair].Name])
+			if err != nil {
+				return false, err
+			}
+			if c > 0 {
+				break
+			}
+			pos++
+			if c < 0 {
+				continue
+			}
+			innerKey, ok, err := j.inner.key(innerRow)
+			if err != nil {
+				return false, err
+			}
+			if ok && bytes.Equal(outerKey, innerKey) {
+				j.emit(outerRow, innerRow)
+				matched = true
+			}
+			break
+		}
+		if !matched && j.outerJoin {
+			j.emit(outerRow, nil)
+		}
+		return true, nil
+	})
+}
+
+// newJoinSide 打开参与连接的表，alias 为空时使用表名（不包括数据库名）
+// 结果的列名为 别名.列名，别名不能包含分隔符，否则无法区分别名和列名
+func (e *Engine) newJoinSide(table *base.JoinTable) (*joinSide, base.StandardError) {
+	if table == nil || table.Name == "" {
+		return nil, joinError("连接的表为空")
+	}
+	if strings.Contains(table.Alias, base.DatabaseNameSeparator) {
+		return nil, joinError(fmt.Sprintf("连接的表的别名<%s>不能包含<%s>", table.Alias, base.DatabaseNameSeparator))
+	}
+	tableName := e.qualifiedName(table.Name)
+	tree, err := e.openTable(tableName)
+	if err != nil {
+		return nil, err
+	}
+	layout, err := newRowSorter(tree.TableInfo, nil)
+	if err != nil {
+		tree.DataManager.Close()
+		return nil, err
+	}
+	estimate, err := e.estimateTable(tree)
+	if err != nil {
+		tree.DataManager.Close()
+		return nil, err
+	}
+	access, err := estimateAccessPath(tree.TableInfo, estimate, table.Where)
+	if err != nil {
+		tree.DataManager.Close()
+		return nil, err
+	}
+	alias := table.Alias
+	if alias == "" {
+		_, alias = tableschema.SplitTableName(tableName)
+	}
+	return &joinSide{alias: alias, tree: tree, where: table.Where, layout: layout, estimate: estimate, access: access}, nil
+}
+
+// newJoinExecutor 校验连接查询并打开两边的表，使用完之后需要关闭两边的 DataManager
+func (e *Engine) newJoinExecutor(query *base.JoinQuery) (*joinExecutor, base.StandardError) {
+	if query == nil {
+		return nil, joinError("连接查询为空")
+	}
+	switch query.Type {
+	case base.JoinTypeInner, base.JoinTypeLeft, base.JoinTypeRight:
+		if len(query.On) == 0 {
+			return nil, joinError(fmt.Sprintf("%s 连接需要连接条件", query.Type))
+		}
+	case base.JoinTypeCross:
+		if len(query.On) > 0 {
+			return nil, joinError("cross 连接不能有连接条件")
+		}
+	default:
+		return nil, joinError(fmt.Sprintf("不支持的连接类型: %s", query.Type))
+	}
+	left, err := e.newJoinSide(query.Left)
+	if err != nil {
+		return nil, err
+	}
+	right, err := e.newJoinSide(query.Right)
+	if err != nil {
+		left.tree.DataManager.Close()
+		return nil, err
+	}
+	j := &joinExecutor{outer: left, inner: right, joinType: query.Type, outerJoin: query.Type == base.JoinTypeLeft}
+	closeAll := func() {
+		left.tree.DataManager.Close()
+		right.tree.DataManager.Close()
+	}
+	if left.alias == right.alias {
+		closeAll()
+		return nil, joinError(fmt.Sprintf("连接的两个表的别名相同: %s", left.alias))
+	}
+	existColumn := set.NewStringsSet()
+	for _, on := range query.On {
+		if on == nil {
+			closeAll()
+			return nil, joinError("连接条件为空")
+		}
+		leftField, ok := left.tree.TableInfo.FieldInfoByName(on.LeftColumn)
+		if !ok {
+			closeAll()
+			return nil, joinError(fmt.Sprintf("连接条件的列<%s.%s>不存在", left.alias, on.LeftColumn))
+		}
+		rightField, ok := right.tree.TableInfo.FieldInfoByName(on.RightColumn)
+		if !ok {
+			closeAll()
+			return nil, joinError(fmt.Sprintf("连接条件的列<%s.%s>不存在", right.alias, on.RightColumn))
+		}
+		if leftField.FieldType.GetType() != rightField.FieldType.GetType() {
+			closeAll()
+			return nil, joinError(fmt.Sprintf("连接条件的列<%s.%s>和<%s.%s>类型不同", left.alias, on.LeftColumn, right.alias, on.RightColumn))
+		}
+		if existColumn.Contain(on.LeftColumn + "=" + on.RightColumn) {
+			closeAll()
+			return nil, joinError(fmt.Sprintf("连接条件<%s.%s = %s.%s>重复", left.alias, on.LeftColumn, right.alias, on.RightColumn))
+		}
+		existColumn.Add(on.LeftColumn + "=" + on.RightColumn)
+		left.keyFields = append(left.keyFields, leftField)
+		right.keyFields = append(right.keyFields, rightField)
+	}
+	if query.Type == base.JoinTypeRight {
+		j.outer, j.inner = right, left
+		j.outerJoin = true
+	}
+	return j, nil
+}
+
+// Join 连接两个表，返回结果的行数和每一行: 别名.列名 -> 值；连接算法和连接顺序见 joinExecutor.plan
+func (e *Engine) Join(query *base.JoinQuery) (int64, []map[string][]byte, base.StandardError) {
+	j, err := e.newJoinExecutor(query)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Join] newJoinExecutor错误, %s", err.Error()))
+		return 0, nil, err
+	}
+	defer j.outer.tree.DataManager.Close()
+	defer j.inner.tree.DataManager.Close()
+	plan, err := j.plan(query.Algorithm)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Join] plan错误, %s", err.Error()))
+		return 0, nil, err
+	}
+	algorithm := plan.Algorithm
+	utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Join] %s 连接，外表<%s>，内表<%s>，使用 %s，估计代价 %.2f", query.Type, plan.Outer, plan.Inner, algorithm, plan.Cost))
+	j.rows = make([]map[string][]byte, 0)
+	switch algorithm {
+	case base.JoinAlgorithmNestedLoop:
+		err = j.nestedLoopJoin()
+	case base.JoinAlgorithmIndexNestedLoop:
+		err = j.indexNestedLoopJoin()
+	case base.JoinAlgorithmHash:
+		err = j.hashJoin()
+	case base.JoinAlgorithmMerge:
+		err = j.mergeJoin()
+	}
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Join] %s错误, %s", algorithm, err.Error()))
+		return 0, nil, err
+	}
+	return int64(len(j.rows)), j.rows, nil
+}
@@ -0,0 +1,144 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"ne_database/core/base"
+	"ne_database/core/config"
+	"ne_database/core/tableschema"
+)
+
+// newTestJoinEngine 用户、部门和用户资料三张表，dept_id 为 0 时是 Null
+func newTestJoinEngine(t *testing.T) (*Engine, *tableschema.TableMetaInfo, *tableschema.TableMetaInfo) {
+	t.Helper()
+	users := newTestTableInfo("engine_join_users", testBigIntField("dept_id"))
+	depts := newTestTableInfo("engine_join_depts", testBigIntField("budget"))
+	profiles := newTestTableInfo("engine_join_profiles", testBigIntField("age"))
+	e := newTestEngine(t, users, depts, profiles)
+	data := map[*tableschema.TableMetaInfo][][2]int64{
+		users:    {{1, 1}, {2, 2}, {3, 1}, {4, 0}, {5, 9}},
+		depts:    {{1, 100}, {2, 200}, {3, 300}},
+		profiles: {{1, 20}, {3, 30}, {5, 50}, {6, 60}},
+	}
+	for info, rows := range data {
+		for _, d := range rows {
+			insertTestRows(t, e, info.Name, map[string][]byte{"id": testInt64(d[0]), info.ValueFieldInfo[0].Name: testInt64(d[1])})
+		}
+	}
+	return e, users, depts
+}
+
+// testJoinPairs 每一行为 左表的id-右表的id，Null 为 0，排序后以逗号连接
+func testJoinPairs(rows []map[string][]byte, left string, right string) string {
+	r := make([]string, 0, len(rows))
+	for _, row := range rows {
+		leftID, _ := base.ByteListToInt64(row[left+".id"])
+		rightID, _ := base.ByteListToInt64(row[right+".id"])
+		r = append(r, fmt.Sprintf("%d-%d", leftID, rightID))
+	}
+	sort.Strings(r)
+	return strings.Join(r, ",")
+}
+
+func TestEngine_Join(t *testing.T) {
+	e, users, _ := newTestJoinEngine(t)
+	byDept := []*base.JoinOn{{LeftColumn: "dept_id", RightColumn: "id"}}
+	byID := []*base.JoinOn{{LeftColumn: "id", RightColumn: "id"}}
+	testCases := []struct {
+		right      string
+		joinType   base.JoinType
+		on         []*base.JoinOn
+		algorithms []base.JoinAlgorithm // 第一个为执行计划选择的算法（没有统计信息）
+		expect     string
+	}{
+		{"engine_join_depts", base.JoinTypeInner, byDept,
+			[]base.JoinAlgorithm{base.JoinAlgorithmHash, base.JoinAlgorithmIndexNestedLoop, base.JoinAlgorithmNestedLoop}, "1-1,2-2,3-1"},
+		{"engine_join_depts", base.JoinTypeLeft, byDept,
+			[]base.JoinAlgorithm{base.JoinAlgorithmHash, base.JoinAlgorithmIndexNestedLoop, base.JoinAlgorithmNestedLoop}, "1-1,2-2,3-1,4-0,5-0"},
+		{"engine_join_depts", base.JoinTypeRight, byDept,
+			[]base.JoinAlgorithm{base.JoinAlgorithmHash, base.JoinAlgorithmNestedLoop}, "0-3,1-1,2-2,3-1"},
+		// 两边都按照连接列有序时使用归并连接
+		{"engine_join_profiles", base.JoinTypeLeft, byID,
+			[]base.JoinAlgorithm{base.JoinAlgorithmMerge, base.JoinAlgorithmIndexNestedLoop, base.JoinAlgorithmHash, base.JoinAlgorithmNestedLoop}, "1-1,2-0,3-3,4-0,5-5"},
+		{"engine_join_profiles", base.JoinTypeRight, byID,
+			[]base.JoinAlgorithm{base.JoinAlgorithmMerge, base.JoinAlgorithmHash}, "0-6,1-1,3-3,5-5"},
+	}
+	for i, c := range testCases {
+		query := &base.JoinQuery{
+			Left:  &base.JoinTable{Name: users.Name, Alias: "u"},
+			Right: &base.JoinTable{Name: c.right, Alias: "r"},
+			Type:  c.joinType,
+			On:    c.on,
+		}
+		j, err := e.newJoinExecutor(query)
+		if err != nil {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+			continue
+		}
+		plan, err := j.plan("")
+		j.outer.tree.DataManager.Close()
+		j.inner.tree.DataManager.Close()
+		if err != nil || plan.Algorithm != c.algorithms[0] {
+			t.Errorf("case %d: plan expected %s, but got %#v, %v", i, c.algorithms[0], plan, err)
+			continue
+		}
+		// 内存限制为 2 行时哈希连接写入临时文件，归并连接分批读取内表
+		for _, memoryRows := range []int{config.CoreConfig.OperatorMemoryRows, 2} {
+			for _, algorithm := range c.algorithms {
+				query.Algorithm = algorithm
+				restore := setTestOperatorMemory(memoryRows, config.CoreConfig.SpillMergeFanIn)
+				_, rows, err := e.Join(query)
+				restore()
+				if err != nil {
+					t.Errorf("case %d %s: unexpected error: %v", i, algorithm, err)
+					continue
+				}
+				if r := testJoinPairs(rows, "u", "r"); r != c.expect {
+					t.Errorf("case %d %s (memory rows %d): expected %s, but got %s", i, algorithm, memoryRows, c.expect, r)
+				}
+			}
+		}
+	}
+	checkNoSpillFiles(t)
+}
+
+func TestEngine_Join_Cross(t *testing.T) {
+	// 别名默认为表名；连接前按照 Where 过滤
+	e, users, depts := newTestJoinEngine(t)
+	count, rows, err := e.Join(&base.JoinQuery{
+		Left:  &base.JoinTable{Name: users.Name, Where: []*base.WherePartItem{{TargetColumn: "id", Operate: base.DataComparatorLessAndEqual, Args: [][]byte{testInt64(2)}}}},
+		Right: &base.JoinTable{Name: depts.Name},
+		Type:  base.JoinTypeCross,
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if r := testJoinPairs(rows, users.Name, depts.Name); count != 6 || r != "1-1,1-2,1-3,2-1,2-2,2-3" {
+		t.Errorf("expected 6 rows, but got %d rows: %s", count, r)
+	}
+}
+
+func TestEngine_Join_Error(t *testing.T) {
+	e, users, depts := newTestJoinEngine(t)
+	left, right := &base.JoinTable{Name: users.Name}, &base.JoinTable{Name: depts.Name}
+	byID := []*base.JoinOn{{LeftColumn: "id", RightColumn: "id"}}
+	testCases := []*base.JoinQuery{
+		// cross 连接不能有连接条件
+		{Left: left, Right: right, Type: base.JoinTypeCross, On: byID},
+		{Left: left, Right: right, Type: base.JoinTypeInner},
+		{Left: left, Right: right, Type: base.JoinTypeInner, On: []*base.JoinOn{{LeftColumn: "unknown", RightColumn: "id"}}},
+		// 自连接需要不同的别名
+		{Left: left, Right: &base.JoinTable{Name: users.Name}, Type: base.JoinTypeInner, On: byID},
+		// 没有按照连接列排序时不能使用归并连接
+		{Left: left, Right: right, Type: base.JoinTypeInner, On: []*base.JoinOn{{LeftColumn: "dept_id", RightColumn: "budget"}}, Algorithm: base.JoinAlgorithmMerge},
+	}
+	for i, query := range testCases {
+		if _, _, err := e.Join(query); err == nil {
+			t.Errorf("case %d: expected error, but got nil", i)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package core
+
+import (
+	"fmt"
+	"math"
+
+	"ne_database/core/base"
+	"ne_database/core/config"
+	"ne_database/core/tableschema"
+	"ne_database/utils"
+)
+
+// 代价模型的常量，代价的单位为读取一页
+const (
+	costCPURow  = 0.01 // 处理一行
+	costHashRow = 0.02 // 建立或者查找哈希表的一行
+	// defaultDistinctCount 没有统计信息时连接的列的不同值的数量
+	defaultDistinctCount = 200
+)
+
+// tableEstimate 表的估计
+// 有统计信息时按照当前的页数等比例估计行数；没有时假设每页是半满的叶子结点
+type tableEstimate struct {
+	rows   float64
+	pages  float64
+	height float64
+	stats  *tableschema.TableStatistics // 没有执行过 Analyze 时为 nil
+}
+
+// accessPath 单表的访问路径: 全表扫描，或者条件包含主键的范围时只扫描范围内的叶子结点
+type accessPath struct {
+	path  string
+	pages float64 // 读取的页数
+	rows  float64 // 满足条件的行数
+	cost  float64
+}
+
+// Analyze 采样表的叶子结点生成统计信息（行数、每列的不同值数量、Null 的比例和等深直方图）并保存在系统目录中
+// 叶子结点不超过 config.AnalyzeSampleLeaves 个时读取全部，否则均匀地选取叶子结点，按照采样的平均行数估计总行数
+func (e *Engine) Analyze(tableName string) (*tableschema.TableStatistics, base.StandardError) {
+	tableName = e.qualifiedName(tableName)
+	tree, err := e.openTable(tableName)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Analyze] openTable错误, %s", err.Error()))
+		return nil, err
+	}
+	defer tree.DataManager.Close()
+	leaves, height, err := tree.LeafOffsets()
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Analyze] LeafOffsets错误, %s", err.Error()))
+		return nil, err
+	}
+	pageCount, err := tree.DataManager.PageCount()
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Analyze] PageCount错误, %s", err.Error()))
+		return nil, err
+	}
+
+	sample := leaves
+	if limit := config.CoreConfig.AnalyzeSampleLeaves; limit > 0 && len(leaves) > limit {
+		sample = make([]int64, 0, limit)
+		for i := 0; i < limit; i++ {
+			sample = append(sample, leaves[i*len(leaves)/limit])
+		}
+	}
+	columns := append([]*tableschema.FieldInfo{tree.TableInfo.PrimaryKeyFieldInfo}, tree.TableInfo.ValueFieldInfo...)
+	values := make([][][]byte, len(columns))
+	sampledRows := 0
+	for _, offset := range sample {
+		node, err := tree.OffsetLoadNode(offset)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Analyze] OffsetLoadNode错误, %s", err.Error()))
+			return nil, err
+		}
+		for i, key := range node.KeysValueList {
+			values[0] = append(values[0], key.Value)
+			for j, field := range tree.TableInfo.ValueFieldInfo {
+				var value []byte
+				if v, ok := node.DataValues[i][field.Name]; ok && v != nil {
+					value = v.Value
+				}
+				values[j+1] = append(values[j+1], value)
+			}
+			sampledRows++
+		}
+	}
+	rowCount := int64(sampledRows)
+	if len(sample) < len(leaves) {
+		rowCount = int64(math.Round(float64(sampledRows) / float64(len(sample)) * float64(len(leaves))))
+	}
+
+	stats := &tableschema.TableStatistics{
+		TableName:   tableName,
+		RowCount:    rowCount,
+		PageCount:   pageCount,
+		LeafCount:   int64(len(leaves)),
+		Height:      height,
+		SampledRows: int64(sampledRows),
+	}
+	for i, field := range columns {
+		c, err := tableschema.BuildColumnStatistics(field, values[i], rowCount, base.StatisticsHistogramBucketCount)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Analyze] BuildColumnStatistics错误, %s", err.Error()))
+			return nil, err
+		}
+		stats.Columns = append(stats.Columns, c)
+	}
+	data, err := stats.TableStatisticsToJsonByte()
+	if err != nil {
+		return nil, err
+	}
+	err = e.catalogSetStatistics(tableName, data)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Analyze] catalogSetStatistics错误, %s", err.Error()))
+		return nil, err
+	}
+	return stats, nil
+}
+
+// LoadTableStatistics 读取表的统计信息，没有执行过 Analyze 时第二个返回值为 false
+func (e *Engine) LoadTableStatistics(tableName string) (*tableschema.TableStatistics, bool, base.StandardError) {
+	return e.catalogStatistics(e.qualifiedName(tableName))
+}
+
+// estimateTable 估计表的行数、页数和B+树的高度，页数来自 IOManager
+func (e *Engine) estimateTable(tree *BPlusTree) (*tableEstimate, base.StandardError) {
+	pageCount, err := tree.DataManager.PageCount()
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[estimateTable] PageCount错误, %s", err.Error()))
+		return nil, err
+	}
+	stats, ok, err := e.catalogStatistics(tree.TableInfo.Name)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[estimateTable] catalogStatistics错误, %s", err.Error()))
+		return nil, err
+	}
+	r := &tableEstimate{pages: math.Max(1, float64(pageCount))}
+	if ok && stats.PageCount > 0 {
+		r.stats = stats
+		r.rows = float64(stats.RowCount) * r.pages / float64(stats.PageCount)
+		r.height = float64(stats.Height)
+	} else {
+		r.rows = r.pages * float64(tree.LeafOrder) / 2
+		r.height = 1 + math.Ceil(math.Log(r.pages)/math.Log(float64(tree.IndexOrder)))
+	}
+	r.height = math.Max(1, r.height)
+	return r, nil
+}
+
+// estimateAccessPath 估计可用的访问路径的代价，返回代价最小的一个，代价相同时优先全表扫描
+// 全表扫描顺序读取全部的页；主键范围扫描先从根结点找到范围的起点，再读取范围内的叶子结点，范围覆盖几乎全部数据时代价比全表扫描高
+func estimateAccessPath(tableInfo *tableschema.TableMetaInfo, estimate *tableEstimate, whereArgs []*base.WherePartItem) (*accessPath, base.StandardError) {
+	keyRange, err := tableInfo.PrimaryKeyRange(whereArgs)
+	if err != nil {
+		return nil, err
+	}
+	if keyRange.Empty {
+		return &accessPath{path: base.AccessPathPrimaryKeyRange, pages: estimate.height, cost: estimate.height}, nil
+	}
+	rows := estimate.rows * tableschema.Selectivity(tableInfo, estimate.stats, whereArgs)
+	best := &accessPath{
+		path:  base.AccessPathFullScan,
+		pages: estimate.pages,
+		rows:  rows,
+		cost:  estimate.pages + estimate.rows*costCPURow,
+	}
+	if keyRange.Min != nil || keyRange.Max != nil {
+		keyItems := make([]*base.WherePartItem, 0)
+		for _, item := range whereArgs {
+			if item.TargetColumn == tableInfo.PrimaryKeyFieldInfo.Name {
+				keyItems = append(keyItems, item)
+			}
+		}
+		fraction := tableschema.Selectivity(tableInfo, estimate.stats, keyItems)
+		pages := estimate.height - 1 + math.Max(1, estimate.pages*fraction)
+		rangeScan := &accessPath{
+			path:  base.AccessPathPrimaryKeyRange,
+			pages: pages,
+			rows:  rows,
+			cost:  pages + estimate.rows*fraction*costCPURow,
+		}
+		if rangeScan.cost < best.cost {
+			best = rangeScan
+		}
+	}
+	return best, nil
+}
+
+// Explain 估计单表查询的访问路径、结果行数和代价
+func (e *Engine) Explain(tableName string, whereArgs []*base.WherePartItem) (*base.QueryPlan, base.StandardError) {
+	tableName = e.qualifiedName(tableName)
+	tree, err := e.openTable(tableName)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Explain] openTable错误, %s", err.Error()))
+		return nil, err
+	}
+	defer tree.DataManager.Close()
+	estimate, err := e.estimateTable(tree)
+	if err != nil {
+		return nil, err
+	}
+	access, err := estimateAccessPath(tree.TableInfo, estimate, whereArgs)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Explain] estimateAccessPath错误, %s", err.Error()))
+		return nil, err
+	}
+	return &base.QueryPlan{AccessPath: access.path, Rows: access.rows, Cost: access.cost}, nil
+}
+
+// ExplainJoin 估计连接的执行计划，见 joinExecutor.plan
+func (e *Engine) ExplainJoin(query *base.JoinQuery) (*base.QueryPlan, base.StandardError) {
+	j, err := e.newJoinExecutor(query)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[ExplainJoin] newJoinExecutor错误, %s", err.Error()))
+		return nil, err
+	}
+	defer j.outer.tree.DataManager.Close()
+	defer j.inner.tree.DataManager.Close()
+	return j.plan(query.Algorithm)
+}
+
+// distinctCount 连接的列的不同值的数量，主键为行数
+func (s *joinSide) distinctCount(field *tableschema.FieldInfo) float64 {
+	if field == s.primaryKey() {
+		return math.Max(1, s.estimate.rows)
+	}
+	if s.estimate.stats != nil {
+		if c, ok := s.estimate.stats.Column(field.Name); ok && c.DistinctCount > 0 {
+			return float64(c.DistinctCount)
+		}
+	}
+	return math.Min(math.Max(1, s.estimate.rows), defaultDistinctCount)
+}
+
+// estimateRows 估计连接结果的行数: 两边的行数相乘，每个连接条件除以两边不同值数量中较大的一个
+func (j *joinExecutor) estimateRows() float64 {
+	r := j.outer.access.rows * j.inner.access.rows
+	for i := range j.outer.keyFields {
+		r /= math.Max(j.outer.distinctCount(j.outer.keyFields[i]), j.inner.distinctCount(j.inner.keyFields[i]))
+	}
+	if j.outerJoin {
+		r = math.Max(r, j.outer.access.rows)
+	}
+	return r
+}
+
+// cost 按照当前的外表和内表估计连接算法的代价，算法不适用时第二个返回值为 false
+func (j *joinExecutor) cost(algorithm base.JoinAlgorithm) (float64, bool) {
+	outer, inner := j.outer.access, j.inner.access
+	switch algorithm {
+	case base.JoinAlgorithmNestedLoop:
+		return outer.cost + outer.rows*inner.cost, true
+	case base.JoinAlgorithmIndexNestedLoop:
+		if j.joinType == base.JoinTypeCross || j.primaryKeyPair(false) < 0 {
+			return 0, false
+		}
+		return outer.cost + outer.rows*(j.inner.estimate.height+costCPURow), true
+	case base.JoinAlgorithmHash:
+		if j.joinType == base.JoinTypeCross {
+			return 0, false
+		}
+		r := outer.cost + inner.cost + (outer.rows+inner.rows)*costHashRow
+		// 超过内存限制时两边都写入临时文件再读取
+		if inner.rows > float64(operatorMemoryRows()) {
+			r += 2 * (outer.pages + inner.pages)
+		}
+		return r, true
+	case base.JoinAlgorithmMerge:
+		if j.joinType == base.JoinTypeCross || j.primaryKeyPair(true) < 0 {
+			return 0, false
+		}
+		return outer.cost + inner.cost + (outer.rows+inner.rows)*costCPURow, true
+	}
+	return 0, false
+}
@@ -0,0 +1,189 @@
+package core
+
+import (
+	"math"
+	"testing"
+
+	"ne_database/core/base"
+	"ne_database/core/config"
+	"ne_database/core/tableschema"
+	"ne_database/utils"
+)
+
+// testAnalyzeRowCount 统计信息测试中每张表的行数
+const testAnalyzeRowCount = int64(300)
+
+// newTestAnalyzeEngine 客户和订单两张表，页比较小，让表有多个叶子结点；region 为 0 时是 Null
+func newTestAnalyzeEngine(t *testing.T) (*Engine, *tableschema.TableMetaInfo, *tableschema.TableMetaInfo) {
+	t.Helper()
+	customers := newTestTableInfo("engine_analyze_customers", testBigIntField("region"))
+	customers.PageSize = 512
+	orders := newTestTableInfo("engine_analyze_orders", testBigIntField("customer_id"))
+	orders.PageSize = 512
+	e := newTestEngine(t, customers, orders)
+	for i := int64(1); i <= testAnalyzeRowCount; i++ {
+		insertTestRows(t, e, customers.Name, map[string][]byte{"id": testInt64(i), "region": testInt64(i % 10)})
+		insertTestRows(t, e, orders.Name, map[string][]byte{"id": testInt64(i), "customer_id": testInt64(i*7%testAnalyzeRowCount + 1)})
+	}
+	return e, customers, orders
+}
+
+// analyzeTestTables 收集全部表的统计信息
+func analyzeTestTables(t *testing.T, e *Engine, tables ...*tableschema.TableMetaInfo) {
+	t.Helper()
+	for _, tableInfo := range tables {
+		if _, err := e.Analyze(tableInfo.Name); err != nil {
+			t.Fatalf("analyze %s: unexpected error: %v", tableInfo.Name, err)
+		}
+	}
+}
+
+func TestEngine_Analyze(t *testing.T) {
+	e, customers, _ := newTestAnalyzeEngine(t)
+	_, ok, err := e.LoadTableStatistics(customers.Name)
+	if err != nil || ok {
+		t.Errorf("LoadTableStatistics before Analyze expected not found, but got %v, %v", ok, err)
+		return
+	}
+	stats, err := e.Analyze(customers.Name)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	region, _ := stats.Column("region")
+	id, _ := stats.Column("id")
+	if stats.RowCount != testAnalyzeRowCount || stats.LeafCount < 2 || stats.Height < 2 || stats.PageCount < stats.LeafCount ||
+		id.DistinctCount != testAnalyzeRowCount || region.DistinctCount != 9 || math.Abs(region.NullFraction-0.1) > 0.001 {
+		t.Errorf("unexpected statistics: %s", utils.ToJSON(stats))
+	}
+	// 每一列一行
+	count, _, err := e.Select(base.CatalogTableStatistics, testWhereEqual("table_name", []byte(customers.Name)))
+	if err != nil || count != 2 {
+		t.Errorf("Select statistics expected 2 rows, but got %d rows, %v", count, err)
+	}
+
+	// 只采样部分叶子结点时估计总行数
+	sampleLeaves := config.CoreConfig.AnalyzeSampleLeaves
+	config.CoreConfig.AnalyzeSampleLeaves = 3
+	stats, err = e.Analyze(customers.Name)
+	config.CoreConfig.AnalyzeSampleLeaves = sampleLeaves
+	if err != nil || stats.SampledRows >= testAnalyzeRowCount || math.Abs(float64(stats.RowCount-testAnalyzeRowCount)) > float64(testAnalyzeRowCount)/5 {
+		t.Errorf("Analyze with sample failed, got %s, %v", utils.ToJSON(stats), err)
+	}
+}
+
+func TestEngine_LoadTableStatistics(t *testing.T) {
+	e, customers, _ := newTestAnalyzeEngine(t)
+	stats, err := e.Analyze(customers.Name)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	// 重新 Init 时重建系统目录，保留统计信息
+	if err = e.Init(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	restarted := &Engine{}
+	if err = restarted.Init(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	for i, engine := range []*Engine{e, restarted} {
+		reloaded, ok, err := engine.LoadTableStatistics(customers.Name)
+		if err != nil || !ok || utils.ToJSON(reloaded) != utils.ToJSON(stats) {
+			t.Errorf("case %d: LoadTableStatistics failed, got %v, %v", i, ok, err)
+		}
+	}
+
+	// 清空表之后统计信息失效
+	if err = e.TruncateTable(customers.Name); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	_, ok, err := e.LoadTableStatistics(customers.Name)
+	if err != nil || ok {
+		t.Errorf("LoadTableStatistics after TruncateTable expected not found, but got %v, %v", ok, err)
+	}
+}
+
+func TestEngine_Explain(t *testing.T) {
+	e, customers, _ := newTestAnalyzeEngine(t)
+	analyzeTestTables(t, e, customers)
+	testCases := []struct {
+		where      []*base.WherePartItem
+		accessPath string
+		rows       float64
+	}{
+		{[]*base.WherePartItem{{TargetColumn: "id", Operate: base.DataComparatorLessAndEqual, Args: [][]byte{testInt64(10)}}},
+			base.AccessPathPrimaryKeyRange, 10},
+		{testWhereEqual("region", testInt64(3)), base.AccessPathFullScan, 30},
+		// 主键的范围覆盖全部数据时，范围扫描还需要从根结点查找起点，按照代价选择全表扫描
+		{[]*base.WherePartItem{{TargetColumn: "id", Operate: base.DataComparatorGreaterAndEqual, Args: [][]byte{testInt64(1)}}},
+			base.AccessPathFullScan, float64(testAnalyzeRowCount)},
+	}
+	var rangeCost float64
+	for i, c := range testCases {
+		plan, err := e.Explain(customers.Name, c.where)
+		if err != nil {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+			continue
+		}
+		if plan.AccessPath != c.accessPath || math.Abs(plan.Rows-c.rows) > 5 {
+			t.Errorf("case %d: expected %s with %v rows, but got %#v", i, c.accessPath, c.rows, plan)
+		}
+		// 主键范围扫描的代价小于全表扫描
+		if i == 0 {
+			rangeCost = plan.Cost
+		} else if plan.Cost <= rangeCost {
+			t.Errorf("case %d: expected cost greater than %v, but got %v", i, rangeCost, plan.Cost)
+		}
+	}
+}
+
+func TestEngine_ExplainJoin(t *testing.T) {
+	e, customers, orders := newTestAnalyzeEngine(t)
+	analyzeTestTables(t, e, customers, orders)
+	fewRows := []*base.WherePartItem{{TargetColumn: "id", Operate: base.DataComparatorLessAndEqual, Args: [][]byte{testInt64(3)}}}
+	byID := []*base.JoinOn{{LeftColumn: "id", RightColumn: "id"}}
+	byCustomer := []*base.JoinOn{{LeftColumn: "id", RightColumn: "customer_id"}}
+	testCases := []struct {
+		query     *base.JoinQuery
+		algorithm base.JoinAlgorithm
+		outer     string
+		count     int64
+	}{
+		// 两边都按照主键连接，全部数据时使用归并连接
+		{&base.JoinQuery{
+			Left: &base.JoinTable{Name: customers.Name, Alias: "c"}, Right: &base.JoinTable{Name: orders.Name, Alias: "o"},
+			Type: base.JoinTypeInner, On: byID,
+		}, base.JoinAlgorithmMerge, "c", testAnalyzeRowCount},
+		// 外表只有几行时在内表的主键中查找
+		{&base.JoinQuery{
+			Left: &base.JoinTable{Name: customers.Name, Alias: "c", Where: fewRows}, Right: &base.JoinTable{Name: orders.Name, Alias: "o"},
+			Type: base.JoinTypeInner, On: byID,
+		}, base.JoinAlgorithmIndexNestedLoop, "c", 3},
+		// 交换连接顺序，使用过滤后的 orders 作为外表
+		{&base.JoinQuery{
+			Left: &base.JoinTable{Name: customers.Name, Alias: "c"}, Right: &base.JoinTable{Name: orders.Name, Alias: "o", Where: fewRows},
+			Type: base.JoinTypeInner, On: byCustomer,
+		}, base.JoinAlgorithmIndexNestedLoop, "o", 3},
+		// 左连接不能交换连接顺序
+		{&base.JoinQuery{
+			Left: &base.JoinTable{Name: customers.Name, Alias: "c"}, Right: &base.JoinTable{Name: orders.Name, Alias: "o", Where: fewRows},
+			Type: base.JoinTypeLeft, On: byCustomer,
+		}, base.JoinAlgorithmHash, "c", testAnalyzeRowCount},
+	}
+	for i, c := range testCases {
+		plan, err := e.ExplainJoin(c.query)
+		if err != nil || plan.Algorithm != c.algorithm || plan.Outer != c.outer {
+			t.Errorf("case %d: expected %s with outer %s, but got %#v, %v", i, c.algorithm, c.outer, plan, err)
+			continue
+		}
+		count, _, err := e.Join(c.query)
+		if err != nil || count != c.count {
+			t.Errorf("case %d: expected %d rows, but got %d, %v", i, c.count, count, err)
+		}
+	}
+}
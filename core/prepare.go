@@ -0,0 +1,719 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"ne_database/core/base"
+	"ne_database/core/tableschema"
+	"ne_database/utils"
+)
+
+func prepareError(errMsg string) base.StandardError {
+	utils.LogError("[Prepare] " + errMsg)
+	return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+}
+
+// stmtSlot 查询中的一个参数占位符: item 的第 index 个参数，或者表达式 expr
+type stmtSlot struct {
+	placeholder string
+	item        *base.WherePartItem
+	index       int
+	from        string // 条件所在的查询的 From
+	typed       bool   // 条件的列可以通过 From 的表结构确定类型（From 不是 CTE，不是 Having）
+	expr        *base.Expression
+}
+
+// stmtWalker 按固定的顺序收集查询中的参数占位符和用到的表，结构相同的查询得到的顺序相同
+type stmtWalker struct {
+	slots   []*stmtSlot
+	sources []*base.Query // From 为表、视图或者系统目录的虚拟表（不是 CTE）的查询
+}
+
+// walkQuery ctes 为查询中可以使用的 CTE 名称
+func (w *stmtWalker) walkQuery(query *base.Query, ctes []string) {
+	if query == nil {
+		return
+	}
+	scope := append([]string{}, ctes...)
+	for _, cte := range query.With {
+		if cte == nil {
+			continue
+		}
+		w.walkQuery(cte.Query, scope)
+		scope = append(scope, cte.Name)
+		w.walkQuery(cte.Recursive, scope)
+	}
+	fromCTE := false
+	for _, name := range scope {
+		fromCTE = fromCTE || name == query.From
+	}
+	if !fromCTE {
+		w.sources = append(w.sources, query)
+	}
+
+	items := append(append([]*base.WherePartItem{}, query.Where...), query.Predicate.Items()...)
+	if query.Aggregate != nil {
+		items = append(items, query.Aggregate.Where...)
+	}
+	w.walkItems(items, query.From, !fromCTE)
+	if query.Aggregate != nil {
+		w.walkItems(query.Aggregate.Having, query.From, false)
+	}
+	w.walkExpression(query.Filter)
+	for _, item := range query.SubQueries {
+		if item != nil {
+			w.walkQuery(item.Query, scope)
+		}
+	}
+	for _, item := range query.Expressions {
+		if item != nil {
+			w.walkExpression(item.Expression)
+		}
+	}
+	for _, op := range query.SetOperations {
+		if op != nil {
+			w.walkQuery(op.Query, scope)
+		}
+	}
+}
+
+func (w *stmtWalker) walkItems(items []*base.WherePartItem, from string, typed bool) {
+	for _, item := range items {
+		if item == nil {
+			continue
+		}
+		for index, placeholder := range item.Placeholders {
+			if placeholder != "" {
+				w.slots = append(w.slots, &stmtSlot{placeholder: placeholder, item: item, index: index, from: from, typed: typed})
+			}
+		}
+	}
+}
+
+func (w *stmtWalker) walkExpression(expr *base.Expression) {
+	if expr == nil {
+		return
+	}
+	if expr.Kind == base.ExpressionKindParameter {
+		w.slots = append(w.slots, &stmtSlot{placeholder: expr.Value, expr: expr})
+	}
+	for _, arg := range expr.Args {
+		w.walkExpression(arg)
+	}
+}
+
+// parameterNumbers 每个占位符对应的参数序号（从 0 开始）和参数的个数
+// ? 按出现的顺序编号，$n 为第 n 个参数，两种占位符不能混用；使用 $n 时 1 ~ n 的每个参数都需要被使用
+func parameterNumbers(slots []*stmtSlot) ([]int, int, base.StandardError) {
+	numbers := make([]int, len(slots))
+	count, positional, numbered := 0, false, false
+	for i, slot := range slots {
+		if slot.placeholder == base.SymbolParameterPositional {
+			positional = true
+			numbers[i] = i
+			count = i + 1
+			continue
+		}
+		numbered = true
+		n, er := strconv.Atoi(strings.TrimPrefix(slot.placeholder, base.SymbolParameterNumberedPrefix))
+		if !strings.HasPrefix(slot.placeholder, base.SymbolParameterNumberedPrefix) || er != nil || n < 1 {
+			return nil, 0, prepareError(fmt.Sprintf("参数占位符<%s>错误", slot.placeholder))
+		}
+		numbers[i] = n - 1
+		if n > count {
+			count = n
+		}
+	}
+	if positional && numbered {
+		return nil, 0, prepareError(fmt.Sprintf("参数占位符 %s 和 %sn 不能混用", base.SymbolParameterPositional, base.SymbolParameterNumberedPrefix))
+	}
+	used := make([]bool, count)
+	for _, n := range numbers {
+		used[n] = true
+	}
+	for n, ok := range used {
+		if !ok {
+			return nil, 0, prepareError(fmt.Sprintf("参数%s%d没有被使用", base.SymbolParameterNumberedPrefix, n+1))
+		}
+	}
+	return numbers, count, nil
+}
+
+// cloneQuery 深拷贝查询，预处理和创建视图时保存的查询不受调用方之后修改的影响
+func cloneQuery(query *base.Query) (*base.Query, base.StandardError) {
+	r := &base.Query{}
+	er := json.Unmarshal([]byte(utils.ToJSON(query)), r)
+	if er != nil {
+		return nil, prepareError(fmt.Sprintf("复制查询错误: %s", er.Error()))
+	}
+	return r, nil
+}
+
+// stmtBinding 预处理语句缓存的参数信息
+type stmtBinding struct {
+	fingerprint string                   // 确定参数类型时用到的表和视图的结构，见 Engine.catalogFingerprint
+	numbers     []int                    // 每个占位符对应的参数序号
+	fields      []*tableschema.FieldInfo // 条件中的占位符对应的列，表达式中的参数为 nil
+	paramCount  int
+}
+
+// stmtPlan 预处理语句缓存的执行计划: 模板中 From 为表（包括物化视图）的每个查询的访问路径
+// 只使用没有参数的条件估计；主键上的条件带有参数或者条件树中有主键的条件时使用主键范围扫描，扫描的范围在执行时按照绑定的参数确定
+type stmtPlan struct {
+	fingerprint string                          // 估计时用到的表和视图的结构，见 Engine.catalogFingerprint
+	statistics  string                          // 估计时用到的表的统计信息，见 Engine.catalogFingerprint
+	access      map[*base.Query]*base.QueryPlan // 模板中的查询 -> 访问路径
+}
+
+// Stmt 预处理语句，查询中的参数占位符在执行时绑定，见 base.WherePartItem.Placeholders 和 base.ExpressionKindParameter
+// 缓存参数的序号和类型以及执行计划，用到的表或者视图的结构变化之后重新确定参数，结构或者统计信息变化之后重新生成执行计划
+// 没有 SQL 解析，查询使用 base.Query 描述；可以并发执行
+type Stmt struct {
+	engine  *Engine
+	query   *base.Query   // 查询的模板，不会被修改
+	slots   []*stmtSlot   // 模板中的参数占位符，顺序见 Engine.Prepare
+	sources []*base.Query // 模板中 From 不是 CTE 的查询
+	tables  []string      // 用到的表和视图（包括视图中用到的），带有数据库名
+	lock    sync.Mutex
+	binding *stmtBinding
+	plan    *stmtPlan
+	version uint64 // 上次检查 binding 和 plan 时系统目录的版本，版本不变时不需要重新读取 fingerprint
+}
+
+// Prepare 预处理查询，校验参数占位符并确定参数的类型
+// 接收的是 base.Query 而不是 SQL 文本，参数占位符为条件的 Placeholders 或者 parameter 类型的表达式
+// 使用 ? 时参数按照占位符在查询中的顺序编号: 先是 With 中的每个 CTE（Query 之后是 Recursive），
+// 之后依次为 Where、Predicate（深度优先）、Aggregate.Where、Aggregate.Having、Filter（深度优先）、SubQueries、Expressions，最后是 SetOperations；
+// CTE、子查询和集合运算中的查询按照同样的顺序展开，一个条件中有多个占位符时按照 Args 的顺序
+func (e *Engine) Prepare(query *base.Query) (*Stmt, base.StandardError) {
+	if query == nil {
+		return nil, prepareError("查询为空")
+	}
+	template, err := cloneQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	w := &stmtWalker{}
+	w.walkQuery(template, nil)
+	names := make([]string, 0, len(w.sources))
+	for _, source := range w.sources {
+		names = append(names, e.qualifiedName(source.From))
+	}
+	// 视图用到的表的结构变化时同样需要重新确定参数的类型
+	tables, err := e.viewDependencies(names)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Prepare] viewDependencies错误, %s", err.Error()))
+		return nil, err
+	}
+	s := &Stmt{engine: e, query: template, slots: w.slots, sources: w.sources, tables: tables}
+	_, _, err = s.current()
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Prepare] 确定参数的类型和执行计划错误, %s", err.Error()))
+		return nil, err
+	}
+	return s, nil
+}
+
+// current 缓存的参数信息和执行计划，系统目录的版本变化时检查用到的表或者视图:
+// 结构变化时重新生成参数信息，结构或者统计信息变化时重新生成执行计划
+func (s *Stmt) current() (*stmtBinding, *stmtPlan, base.StandardError) {
+	version := s.engine.catalogVersion()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.binding != nil && s.plan != nil && s.version == version {
+		return s.binding, s.plan, nil
+	}
+	fingerprint, statistics, version, err := s.engine.catalogFingerprint(s.tables)
+	if err != nil {
+		return nil, nil, err
+	}
+	if s.binding == nil || s.binding.fingerprint != fingerprint {
+		binding, err := s.buildBinding(fingerprint)
+		if err != nil {
+			return nil, nil, err
+		}
+		s.binding = binding
+	}
+	if s.plan == nil || s.plan.fingerprint != fingerprint || s.plan.statistics != statistics {
+		plan, err := s.buildPlan(fingerprint, statistics)
+		if err != nil {
+			return nil, nil, err
+		}
+		s.plan = plan
+	}
+	s.version = version
+	return s.binding, s.plan, nil
+}
+
+func (s *Stmt) buildBinding(fingerprint string) (*stmtBinding, base.StandardError) {
+	numbers, count, err := parameterNumbers(s.slots)
+	if err != nil {
+		return nil, err
+	}
+	binding := &stmtBinding{fingerprint: fingerprint, numbers: numbers, fields: make([]*tableschema.FieldInfo, len(s.slots)), paramCount: count}
+	// 同一个 From 的多个占位符只读取一次表结构
+	relations := make(map[string]*tableschema.TableMetaInfo)
+	for i, slot := range s.slots {
+		if slot.expr != nil {
+			switch slot.expr.Type {
+			case base.ExpressionTypeBigInt, base.ExpressionTypeChar, base.ExpressionTypeBool:
+			default:
+				return nil, prepareError(fmt.Sprintf("参数<%s>的类型<%s>错误", slot.placeholder, slot.expr.Type))
+			}
+			continue
+		}
+		if !slot.typed {
+			return nil, prepareError(fmt.Sprintf("条件<%s>中的参数<%s>无法确定类型，From 为 CTE 或者在 Having 中时请使用表达式的参数", slot.item.TargetColumn, slot.placeholder))
+		}
+		fromName := s.engine.qualifiedName(slot.from)
+		tableInfo, ok := relations[fromName]
+		if !ok {
+			tableInfo, err = s.engine.stmtRelationInfo(fromName)
+			if err != nil {
+				utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Stmt.buildBinding] stmtRelationInfo错误, %s", err.Error()))
+				return nil, err
+			}
+			relations[fromName] = tableInfo
+		}
+		column, path := slot.item.ColumnAndPath()
+		if path != "" {
+			return nil, prepareError(fmt.Sprintf("带有json路径的条件<%s>不能使用参数", slot.item.TargetColumn))
+		}
+		field, ok := tableInfo.FieldInfoByName(column)
+		if !ok {
+			return nil, prepareError(fmt.Sprintf("表<%s>中不存在列<%s>", slot.from, column))
+		}
+		binding.fields[i] = field
+	}
+	return binding, nil
+}
+
+// buildPlan 估计模板中 From 为表的每个查询的访问路径，见 stmtPlan
+func (s *Stmt) buildPlan(fingerprint string, statistics string) (*stmtPlan, base.StandardError) {
+	plan := &stmtPlan{fingerprint: fingerprint, statistics: statistics, access: make(map[*base.Query]*base.QueryPlan)}
+	for _, source := range s.sources {
+		fromName := s.engine.qualifiedName(source.From)
+		if _, ok := catalogTables()[fromName]; ok {
+			continue
+		}
+		view, isView, err := s.engine.lookupView(fromName)
+		if err != nil {
+			return nil, err
+		}
+		if isView && !view.Materialized {
+			continue
+		}
+		access, err := s.engine.planAccessPath(fromName, source)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Stmt.buildPlan] planAccessPath错误, %s", err.Error()))
+			return nil, err
+		}
+		plan.access[source] = access
+	}
+	return plan, nil
+}
+
+// planAccessPath 按照当前的统计信息估计查询的访问路径，只使用没有参数的条件，见 stmtPlan
+func (e *Engine) planAccessPath(tableName string, query *base.Query) (*base.QueryPlan, base.StandardError) {
+	tree, err := e.openTable(tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer tree.DataManager.Close()
+	estimate, err := e.estimateTable(tree)
+	if err != nil {
+		return nil, err
+	}
+	keyName := tree.TableInfo.PrimaryKeyFieldInfo.Name
+	items := make([]*base.WherePartItem, 0, len(query.Where))
+	keyParameter := false
+	for _, item := range query.Where {
+		switch {
+		case item == nil:
+		case len(item.Placeholders) == 0:
+			items = append(items, item)
+		case item.TargetColumn == keyName:
+			keyParameter = true
+		}
+	}
+	for _, item := range query.Predicate.Items() {
+		keyParameter = keyParameter || (item != nil && item.TargetColumn == keyName)
+	}
+	access, err := estimateAccessPath(tree.TableInfo, estimate, items)
+	if err != nil {
+		return nil, err
+	}
+	r := &base.QueryPlan{AccessPath: access.path, Rows: access.rows, Cost: access.cost}
+	if keyParameter {
+		r.AccessPath = base.AccessPathPrimaryKeyRange
+	}
+	return r, nil
+}
+
+// stmtRelationInfo 表、物化视图、普通视图或者系统目录的虚拟表的列，tableName 为完整的名称；普通视图不执行查询，见 Engine.viewSchema
+func (e *Engine) stmtRelationInfo(tableName string) (*tableschema.TableMetaInfo, base.StandardError) {
+	if table, ok := catalogTables()[tableName]; ok {
+		return table.TableInfo, nil
+	}
+	view, ok, err := e.lookupView(tableName)
+	if err != nil {
+		return nil, err
+	}
+	if ok && !view.Materialized {
+		return e.viewSchema(view)
+	}
+	return e.loadTableSchemaInfo(tableName)
+}
+
+// NumParams 参数的个数
+func (s *Stmt) NumParams() (int, base.StandardError) {
+	binding, _, err := s.current()
+	if err != nil {
+		return 0, err
+	}
+	return binding.paramCount, nil
+}
+
+// Plan 缓存的执行计划中最外层查询的访问路径，From 为 CTE、普通视图或者系统目录的虚拟表时为 nil
+// 执行时按照这个访问路径读取表，表结构或者统计信息变化之后重新估计，见 stmtPlan
+func (s *Stmt) Plan() (*base.QueryPlan, base.StandardError) {
+	_, plan, err := s.current()
+	if err != nil {
+		return nil, err
+	}
+	return plan.access[s.query], nil
+}
+
+// Bind 绑定参数，返回可以直接执行的查询，返回的查询和模板共享没有参数的部分，不能修改
+// 参数可以是 nil（Null）、[]byte（储存值，直接使用）、string（可读值）、int / int32 / int64 或者 bool；
+// 条件中的参数按照列的类型转换为储存值（like / ilike / regexp 的参数为原始字符串），表达式中的参数按照声明的类型转换
+func (s *Stmt) Bind(args ...interface{}) (*base.Query, base.StandardError) {
+	query, _, err := s.bind(args...)
+	return query, err
+}
+
+// bind 绑定参数，同时返回绑定之后的查询 -> 执行计划中的访问路径
+func (s *Stmt) bind(args ...interface{}) (*base.Query, map[*base.Query]string, base.StandardError) {
+	binding, plan, err := s.current()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(args) != binding.paramCount {
+		return nil, nil, prepareError(fmt.Sprintf("参数的个数应该为%d，实际为%d", binding.paramCount, len(args)))
+	}
+	b := &stmtBinder{args: make(map[*base.WherePartItem][][]byte), exprs: make(map[*base.Expression]*base.Expression),
+		access: plan.access, accessPaths: make(map[*base.Query]string)}
+	for i, slot := range s.slots {
+		arg := args[binding.numbers[i]]
+		if slot.expr != nil {
+			b.exprs[slot.expr], err = bindExpressionParameter(slot.expr, arg)
+		} else {
+			bound, ok := b.args[slot.item]
+			if !ok {
+				bound = make([][]byte, max(len(slot.item.Args), len(slot.item.Placeholders)))
+				copy(bound, slot.item.Args)
+				b.args[slot.item] = bound
+			}
+			bound[slot.index], err = bindWhereParameter(binding.fields[i], slot.item.Operate, arg)
+		}
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Stmt.Bind] 绑定第%d个参数错误, %s", binding.numbers[i]+1, err.Error()))
+			return nil, nil, err
+		}
+	}
+	return b.query(s.query), b.accessPaths, nil
+}
+
+// Execute 绑定参数并按照缓存的执行计划执行查询，结果和 Engine.Query 相同
+func (s *Stmt) Execute(args ...interface{}) (int64, []map[string][]byte, base.StandardError) {
+	query, accessPaths, err := s.bind(args...)
+	if err != nil {
+		return 0, nil, err
+	}
+	r, err := s.engine.runQuery(&queryScope{accessPaths: accessPaths}, query)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Stmt.Execute] runQuery错误, %s", err.Error()))
+		return 0, nil, err
+	}
+	return int64(len(r.rows)), r.rows, nil
+}
+
+// stmtBinder 按照绑定的参数复制查询的模板，只复制从查询到占位符的路径上的结构，其他部分和模板共享（执行查询时不会修改查询）
+type stmtBinder struct {
+	args        map[*base.WherePartItem][][]byte      // 模板中带有占位符的条件 -> 绑定之后的 Args
+	exprs       map[*base.Expression]*base.Expression // 模板中的参数 -> 绑定之后的字面值
+	access      map[*base.Query]*base.QueryPlan       // 模板中的查询 -> 访问路径，见 stmtPlan
+	accessPaths map[*base.Query]string                // 绑定之后的查询 -> 访问路径
+}
+
+func (b *stmtBinder) query(query *base.Query) *base.Query {
+	if query == nil {
+		return nil
+	}
+	r := *query
+	if access, ok := b.access[query]; ok {
+		b.accessPaths[&r] = access.AccessPath
+	}
+	if len(query.With) > 0 {
+		r.With = make([]*base.CommonTableExpression, len(query.With))
+		for i, cte := range query.With {
+			if cte != nil {
+				c := *cte
+				c.Query, c.Recursive = b.query(cte.Query), b.query(cte.Recursive)
+				r.With[i] = &c
+			}
+		}
+	}
+	r.Where = b.items(query.Where)
+	r.Predicate = b.node(query.Predicate)
+	if query.Aggregate != nil {
+		aggregate := *query.Aggregate
+		aggregate.Where, aggregate.Having = b.items(aggregate.Where), b.items(aggregate.Having)
+		r.Aggregate = &aggregate
+	}
+	r.Filter = b.expression(query.Filter)
+	if len(query.SubQueries) > 0 {
+		r.SubQueries = make([]*base.SubQueryItem, len(query.SubQueries))
+		for i, item := range query.SubQueries {
+			if item != nil {
+				c := *item
+				c.Query = b.query(item.Query)
+				r.SubQueries[i] = &c
+			}
+		}
+	}
+	if len(query.Expressions) > 0 {
+		r.Expressions = make([]*base.SelectExpression, len(query.Expressions))
+		for i, item := range query.Expressions {
+			if item != nil {
+				c := *item
+				c.Expression = b.expression(item.Expression)
+				r.Expressions[i] = &c
+			}
+		}
+	}
+	if len(query.SetOperations) > 0 {
+		r.SetOperations = make([]*base.SetOperation, len(query.SetOperations))
+		for i, op := range query.SetOperations {
+			if op != nil {
+				c := *op
+				c.Query = b.query(op.Query)
+				r.SetOperations[i] = &c
+			}
+		}
+	}
+	return &r
+}
+
+func (b *stmtBinder) items(items []*base.WherePartItem) []*base.WherePartItem {
+	if len(items) == 0 {
+		return items
+	}
+	r := make([]*base.WherePartItem, len(items))
+	for i, item := range items {
+		r[i] = b.item(item)
+	}
+	return r
+}
+
+func (b *stmtBinder) item(item *base.WherePartItem) *base.WherePartItem {
+	args, ok := b.args[item]
+	if !ok {
+		return item
+	}
+	r := *item
+	r.Args, r.Placeholders = args, nil
+	return &r
+}
+
+func (b *stmtBinder) node(node *base.WhereNode) *base.WhereNode {
+	if node == nil {
+		return nil
+	}
+	r := *node
+	r.Item = b.item(node.Item)
+	if len(node.Children) > 0 {
+		r.Children = make([]*base.WhereNode, len(node.Children))
+		for i, child := range node.Children {
+			r.Children[i] = b.node(child)
+		}
+	}
+	return &r
+}
+
+// expression 没有参数的表达式直接使用模板中的表达式
+func (b *stmtBinder) expression(expr *base.Expression) *base.Expression {
+	if expr == nil {
+		return nil
+	}
+	if bound, ok := b.exprs[expr]; ok {
+		return bound
+	}
+	var args []*base.Expression
+	for i, arg := range expr.Args {
+		bound := b.expression(arg)
+		if bound != arg && args == nil {
+			args = append(make([]*base.Expression, 0, len(expr.Args)), expr.Args[:i]...)
+		}
+		if args != nil {
+			args = append(args, bound)
+		}
+	}
+	if args == nil {
+		return expr
+	}
+	r := *expr
+	r.Args = args
+	return &r
+}
+
+func parameterInt(arg interface{}) (int64, bool) {
+	switch v := arg.(type) {
+	case int:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	}
+	return 0, false
+}
+
+// parameterString 参数的可读值
+func parameterString(arg interface{}) (string, base.StandardError) {
+	if i, ok := parameterInt(arg); ok {
+		return strconv.FormatInt(i, 10), nil
+	}
+	switch v := arg.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+	return "", prepareError(fmt.Sprintf("不支持的参数类型: %T", arg))
+}
+
+// bindWhereParameter 把条件中的参数转换为列的储存值
+func bindWhereParameter(field *tableschema.FieldInfo, operate base.DataComparator, arg interface{}) ([]byte, base.StandardError) {
+	switch v := arg.(type) {
+	case nil:
+		return field.NullValue(), nil
+	case []byte:
+		return v, nil
+	}
+	text, err := parameterString(arg)
+	if err != nil {
+		return nil, err
+	}
+	switch operate {
+	case base.DataComparatorLike, base.DataComparatorILike, base.DataComparatorRegexp, base.DataComparatorNotRegexp:
+		return []byte(text), nil
+	}
+	var value []byte
+	if i, ok := parameterInt(arg); ok && field.FieldType.GetType() == base.DBDataTypeBigInt {
+		value, err = base.Int64ToByteList(i)
+	} else {
+		value, err = field.FieldType.StringToByte(text)
+	}
+	if err == nil {
+		_, err = field.FieldType.LengthPadding(value, field.Length)
+	}
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[bindWhereParameter] 列<%s>的参数<%s>错误, %s", field.Name, text, err.Error()))
+		return nil, err
+	}
+	return value, nil
+}
+
+// bindExpressionParameter 把表达式中的参数替换为声明的类型的字面值，返回新的表达式，不修改模板
+func bindExpressionParameter(expr *base.Expression, arg interface{}) (*base.Expression, base.StandardError) {
+	r := *expr
+	if arg == nil {
+		r.Kind, r.Value, r.Type = base.ExpressionKindLiteral, "", base.ExpressionTypeNull
+		return &r, nil
+	}
+	text, err := parameterString(arg)
+	if err != nil {
+		return nil, err
+	}
+	r.Kind, r.Value = base.ExpressionKindLiteral, text
+	_, err = tableschema.CompileExpression(nil, &r)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Session 客户端连接的会话，保存预处理语句，语句的句柄只在会话内有效，供网络协议使用
+type Session struct {
+	engine     *Engine
+	lock       sync.Mutex
+	lastHandle uint32
+	statements map[uint32]*Stmt
+}
+
+// NewSession 创建会话
+func (e *Engine) NewSession() *Session {
+	return &Session{engine: e, statements: make(map[uint32]*Stmt)}
+}
+
+// Prepare 预处理查询，返回语句的句柄和参数的个数
+func (s *Session) Prepare(query *base.Query) (uint32, int, base.StandardError) {
+	stmt, err := s.engine.Prepare(query)
+	if err != nil {
+		return 0, 0, err
+	}
+	count, err := stmt.NumParams()
+	if err != nil {
+		return 0, 0, err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.lastHandle++
+	s.statements[s.lastHandle] = stmt
+	return s.lastHandle, count, nil
+}
+
+// statement 句柄对应的语句
+func (s *Session) statement(handle uint32) (*Stmt, base.StandardError) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	stmt, ok := s.statements[handle]
+	if !ok {
+		return nil, prepareError(fmt.Sprintf("预处理语句<%d>不存在", handle))
+	}
+	return stmt, nil
+}
+
+// Execute 执行句柄对应的语句
+func (s *Session) Execute(handle uint32, args ...interface{}) (int64, []map[string][]byte, base.StandardError) {
+	stmt, err := s.statement(handle)
+	if err != nil {
+		return 0, nil, err
+	}
+	return stmt.Execute(args...)
+}
+
+// CloseStatement 关闭句柄对应的语句
+func (s *Session) CloseStatement(handle uint32) base.StandardError {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, ok := s.statements[handle]; !ok {
+		return prepareError(fmt.Sprintf("预处理语句<%d>不存在", handle))
+	}
+	delete(s.statements, handle)
+	return nil
+}
+
+// Close 关闭会话中的全部语句
+func (s *Session) Close() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.statements = make(map[uint32]*Stmt)
+}
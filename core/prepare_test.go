@@ -0,0 +1,249 @@
+package core
+
+import (
+	"fmt"
+	"testing"
+
+	"ne_database/core/base"
+	"ne_database/core/tableschema"
+	"ne_database/utils"
+)
+
+// newTestPrepareEngine id 为 1 ~ 50，name 为 n0 ~ n4，score 为 id*10
+func newTestPrepareEngine(t *testing.T) (*Engine, *tableschema.TableMetaInfo) {
+	t.Helper()
+	items := newTestTableInfo("engine_prepare_items", testCharField("name", 8), testBigIntField("score"))
+	e := newTestEngine(t, items)
+	for i := int64(1); i <= 50; i++ {
+		insertTestRows(t, e, items.Name, map[string][]byte{"id": testInt64(i), "name": []byte(fmt.Sprintf("n%d", i%5)), "score": testInt64(i * 10)})
+	}
+	return e, items
+}
+
+// testParameter 表达式中的参数
+func testParameter(placeholder string, valueType base.ExpressionType) *base.Expression {
+	return &base.Expression{Kind: base.ExpressionKindParameter, Value: placeholder, Type: valueType}
+}
+
+// testWhereParameter column 和参数比较的条件
+func testWhereParameter(column string, operate base.DataComparator, placeholders ...string) *base.WherePartItem {
+	return &base.WherePartItem{TargetColumn: column, Operate: operate, Placeholders: placeholders}
+}
+
+func TestStmt_Execute(t *testing.T) {
+	e, items := newTestPrepareEngine(t)
+	// ? 按出现的顺序编号
+	between := &base.Query{
+		From: items.Name,
+		Where: []*base.WherePartItem{
+			testWhereParameter("id", base.DataComparatorBetween, "?", "?"),
+			testWhereParameter("name", base.DataComparatorEqual, "?"),
+		},
+		OrderBy: []*base.OrderByItem{{Column: "id"}},
+	}
+	// $n 可以重复使用，表达式中的参数按照声明的类型转换
+	numbered := &base.Query{
+		From:  items.Name,
+		Where: []*base.WherePartItem{testWhereParameter("id", base.DataComparatorLessAndEqual, "$1")},
+		Filter: &base.Expression{Kind: base.ExpressionKindOperator, Operator: base.ExpressionOperatorGreaterAndEqual, Args: []*base.Expression{
+			{Kind: base.ExpressionKindColumn, Column: "score"},
+			{Kind: base.ExpressionKindOperator, Operator: base.ExpressionOperatorMultiply, Args: []*base.Expression{
+				testParameter("$1", base.ExpressionTypeBigInt), testParameter("$2", base.ExpressionTypeBigInt)}},
+		}},
+		OrderBy: []*base.OrderByItem{{Column: "id"}},
+	}
+	testCases := []struct {
+		query     *base.Query
+		numParams int
+		args      [][]interface{}
+		expect    []string // 每组参数的结果，为空时期望报错
+	}{
+		// 同一个语句绑定不同的参数，模板不会被修改
+		{between, 3, [][]interface{}{{1, int64(20), "n3"}, {40, 50, []byte("n0")}, {1, 2}, {1, 2, 3.5}}, []string{"3,8,13,18", "40,45,50", "", ""}},
+		{numbered, 2, [][]interface{}{{10, 9}}, []string{"9,10"}},
+	}
+	for i, c := range testCases {
+		stmt, err := e.Prepare(c.query)
+		if err != nil {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+			continue
+		}
+		if n, _ := stmt.NumParams(); n != c.numParams {
+			t.Errorf("case %d: expected %d params, got %d", i, c.numParams, n)
+		}
+		for j, args := range c.args {
+			_, rows, err := stmt.Execute(args...)
+			if c.expect[j] == "" {
+				if err == nil {
+					t.Errorf("case %d-%d: expected error, but got nil", i, j)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("case %d-%d: unexpected error: %v", i, j, err)
+				continue
+			}
+			if r := testInt64Values(rows, "id"); r != c.expect[j] {
+				t.Errorf("case %d-%d: expected %s, but got %s", i, j, c.expect[j], r)
+			}
+		}
+		// 未绑定参数的模板不能直接执行
+		if _, _, err = e.Query(stmt.query); err == nil {
+			t.Errorf("case %d: expected error for unbound params", i)
+		}
+	}
+}
+
+func TestEngine_Prepare_Error(t *testing.T) {
+	e, items := newTestPrepareEngine(t)
+	testCases := []*base.Query{
+		// ? 和 $n 混用
+		{From: items.Name, Where: []*base.WherePartItem{testWhereParameter("id", base.DataComparatorBetween, "?", "$1")}},
+		// $1 没有被使用
+		{From: items.Name, Where: []*base.WherePartItem{testWhereParameter("id", base.DataComparatorEqual, "$2")}},
+		// From 为 CTE 时条件中的参数无法确定类型
+		{With: []*base.CommonTableExpression{{Name: "all_items", Query: &base.Query{From: items.Name}}}, From: "all_items",
+			Where: []*base.WherePartItem{testWhereParameter("id", base.DataComparatorEqual, "?")}},
+		{From: items.Name, Filter: testParameter("?", base.ExpressionTypeNull)},
+		{From: items.Name, Where: []*base.WherePartItem{testWhereParameter("unknown", base.DataComparatorEqual, "?")}},
+	}
+	for i, query := range testCases {
+		if _, err := e.Prepare(query); err == nil {
+			t.Errorf("case %d: expected error, but got nil", i)
+		}
+	}
+}
+
+func TestStmt_Plan(t *testing.T) {
+	e, items := newTestPrepareEngine(t)
+	// 统计信息不影响参数的类型，Analyze 之后继续使用缓存的参数信息，访问路径的估计按照新的统计信息
+	stmt, err := e.Prepare(&base.Query{From: items.Name, Where: []*base.WherePartItem{
+		{TargetColumn: "score", Operate: base.DataComparatorGreater, Args: [][]byte{testInt64(400)}},
+		testWhereParameter("name", base.DataComparatorEqual, "?"),
+	}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	binding, version := stmt.binding, stmt.version
+	if _, err = e.Analyze(items.Name); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	plan, err := stmt.Plan()
+	if err != nil || plan == nil {
+		t.Fatalf("expected plan for table, got %#v, %v", plan, err)
+	}
+	if _, err = stmt.NumParams(); err != nil || stmt.binding != binding || stmt.version == version {
+		t.Errorf("expected binding to be reused after analyze, err %v", err)
+	}
+	// 执行计划在统计信息不变时被缓存，重新 Analyze 之后重新估计
+	if cached, err := stmt.Plan(); err != nil || cached != plan {
+		t.Errorf("expected cached plan, got %#v, %v", cached, err)
+	}
+	insertTestRows(t, e, items.Name, map[string][]byte{"id": testInt64(51), "name": []byte("n9"), "score": testInt64(0)})
+	if cached, err := stmt.Plan(); err != nil || cached != plan {
+		t.Errorf("expected cached plan after insert, got %#v, %v", cached, err)
+	}
+	if _, err = e.Analyze(items.Name); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if replanned, err := stmt.Plan(); err != nil || replanned == plan {
+		t.Errorf("expected new plan after analyze, got %#v, %v", replanned, err)
+	}
+}
+
+func TestStmt_Plan_AccessPath(t *testing.T) {
+	e, items := newTestPrepareEngine(t)
+	if _, err := e.Analyze(items.Name); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// 主键的范围覆盖全部数据时执行计划为全表扫描；主键上的条件带有参数时为主键范围扫描，范围按照参数确定
+	testCases := []struct {
+		where  *base.WherePartItem
+		args   []interface{}
+		path   string
+		expect string
+	}{
+		{&base.WherePartItem{TargetColumn: "id", Operate: base.DataComparatorGreaterAndEqual, Args: [][]byte{testInt64(1)}}, nil, base.AccessPathFullScan, "5,10,15,20,25,30,35,40,45,50"},
+		{testWhereParameter("id", base.DataComparatorLessAndEqual, "?"), []interface{}{12}, base.AccessPathPrimaryKeyRange, "5,10"},
+	}
+	for i, c := range testCases {
+		stmt, err := e.Prepare(&base.Query{From: items.Name, Where: append([]*base.WherePartItem{c.where}, testWhereEqual("name", []byte("n0"))...)})
+		if err != nil {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+			continue
+		}
+		plan, err := stmt.Plan()
+		if err != nil || plan == nil || plan.AccessPath != c.path {
+			t.Errorf("case %d: expected %s, got %#v, %v", i, c.path, plan, err)
+			continue
+		}
+		_, rows, err := stmt.Execute(c.args...)
+		if err != nil {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+			continue
+		}
+		if r := testInt64Values(rows, "id"); r != c.expect {
+			t.Errorf("case %d: expected %s, but got %s", i, c.expect, r)
+		}
+	}
+}
+
+func TestStmt_Bind(t *testing.T) {
+	e, items := newTestPrepareEngine(t)
+	stmt, err := e.Prepare(&base.Query{From: items.Name, Where: []*base.WherePartItem{
+		{TargetColumn: "score", Operate: base.DataComparatorGreater, Args: [][]byte{testInt64(400)}},
+		testWhereParameter("name", base.DataComparatorEqual, "?"),
+	}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// 绑定时只复制带有参数的条件，没有参数的条件和模板共享，模板不被修改
+	bound, err := stmt.Bind("n1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bound.Where[0] != stmt.query.Where[0] || bound.Where[1] == stmt.query.Where[1] || len(bound.Where[1].Placeholders) != 0 ||
+		len(stmt.query.Where[1].Args) != 0 || len(stmt.query.Where[1].Placeholders) != 1 {
+		t.Errorf("unexpected bound query: %s, template: %s", utils.ToJSON(bound), utils.ToJSON(stmt.query))
+	}
+	_, rows, err := stmt.Execute("n1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r := testInt64Values(rows, "id"); r != "41,46" {
+		t.Errorf("expected 41,46, but got %s", r)
+	}
+}
+
+func TestSession_Statement(t *testing.T) {
+	e, items := newTestPrepareEngine(t)
+	session := e.NewSession()
+	defer session.Close()
+	handle, count, err := session.Prepare(&base.Query{From: items.Name,
+		Where: []*base.WherePartItem{testWhereParameter("name", base.DataComparatorEqual, "?")}})
+	if err != nil || count != 1 {
+		t.Fatalf("unexpected error: %v, count %d", err, count)
+	}
+	_, rows, err := session.Execute(handle, "n2")
+	if err != nil || len(rows) != 10 {
+		t.Fatalf("unexpected error: %v, rows %d", err, len(rows))
+	}
+
+	// 表结构变化之后重新生成执行计划，用到的列被删除时报错
+	err = e.AlterTable(items.Name, []*tableschema.AlterTableItem{{Action: base.AlterTableActionDropColumn, Column: "name"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err = session.Execute(handle, "n2"); err == nil {
+		t.Error("expected error after column dropped")
+	}
+	if err = session.CloseStatement(handle); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, _, err = session.Execute(handle, "n2"); err == nil {
+		t.Error("expected error for closed statement")
+	}
+	if err = session.CloseStatement(handle); err == nil {
+		t.Error("expected error for closing unknown statement")
+	}
+}
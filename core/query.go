@@ -0,0 +1,793 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+
+	"ne_database/core/base"
+	"ne_database/core/config"
+	"ne_database/core/tableschema"
+	"ne_database/utils"
+	"ne_database/utils/set"
+)
+
+// relation 组合查询的中间结果，保存在内存中
+// tableInfo 只用于列的类型、条件和排序，第一列作为主键（不要求唯一）；rows 为 列名 -> 值
+type relation struct {
+	tableInfo *tableschema.TableMetaInfo
+	rows      []map[string][]byte
+}
+
+func (r *relation) fields() []*tableschema.FieldInfo {
+	return append([]*tableschema.FieldInfo{r.tableInfo.PrimaryKeyFieldInfo}, r.tableInfo.ValueFieldInfo...)
+}
+
+func (r *relation) source() rowSource {
+	return func(fn func(row map[string][]byte) (bool, base.StandardError)) base.StandardError {
+		for _, row := range r.rows {
+			next, err := fn(row)
+			if err != nil || !next {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func relationInfo(name string, fields []*tableschema.FieldInfo) *tableschema.TableMetaInfo {
+	return &tableschema.TableMetaInfo{Name: name, PrimaryKeyFieldInfo: fields[0], ValueFieldInfo: fields[1:]}
+}
+
+// queryScope CTE 的作用域，内层 With 定义的 CTE 覆盖外层同名的 CTE
+// 展开视图时使用新的作用域（没有 parent），views 为正在展开的视图，见 Engine.runView
+// accessPaths 为预处理语句缓存的执行计划中每个查询的访问路径，只在最外层的作用域中，见 Stmt.Execute
+type queryScope struct {
+	parent      *queryScope
+	relations   map[string]*relation
+	views       []string
+	accessPaths map[*base.Query]string
+}
+
+func (s *queryScope) lookup(name string) (*relation, bool) {
+	for ; s != nil; s = s.parent {
+		if r, ok := s.relations[name]; ok {
+			return r, true
+		}
+	}
+	return nil, false
+}
+
+// accessPath 执行计划中查询的访问路径，没有执行计划时为空
+func (s *queryScope) accessPath(query *base.Query) string {
+	for ; s != nil; s = s.parent {
+		if path, ok := s.accessPaths[query]; ok {
+			return path
+		}
+	}
+	return ""
+}
+
+// expandingViews 正在展开的视图，由外到内
+func (s *queryScope) expandingViews() []string {
+	for ; s != nil; s = s.parent {
+		if s.views != nil {
+			return s.views
+		}
+	}
+	return nil
+}
+
+func queryError(errMsg string) base.StandardError {
+	utils.LogError("[Query] " + errMsg)
+	return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+}
+
+// Query 执行组合查询（子查询、CTE、集合运算），返回结果的行数和每一行（列名 -> 值），见 base.Query
+// 中间结果保存在内存中；From 为表时 Where 在扫描B+树时过滤，可以使用主键的范围；From 为视图时执行视图的查询
+func (e *Engine) Query(query *base.Query) (int64, []map[string][]byte, base.StandardError) {
+	r, err := e.runQuery(nil, query)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[Query] runQuery错误, %s", err.Error()))
+		return 0, nil, err
+	}
+	return int64(len(r.rows)), r.rows, nil
+}
+
+func (e *Engine) runQuery(scope *queryScope, query *base.Query) (*relation, base.StandardError) {
+	if query == nil {
+		return nil, queryError("查询为空")
+	}
+	scope, err := e.withScope(scope, query.With)
+	if err != nil {
+		return nil, err
+	}
+	return e.runQueryBody(scope, query, nil, nil)
+}
+
+// withScope 按顺序执行 With 中的 CTE，后面的 CTE 可以使用前面的
+func (e *Engine) withScope(scope *queryScope, with []*base.CommonTableExpression) (*queryScope, base.StandardError) {
+	if len(with) == 0 {
+		return scope, nil
+	}
+	r := &queryScope{parent: scope, relations: make(map[string]*relation)}
+	for _, cte := range with {
+		if cte == nil || cte.Name == "" || cte.Query == nil {
+			return nil, queryError("CTE 的名称或者查询为空")
+		}
+		if _, ok := r.relations[cte.Name]; ok {
+			return nil, queryError(fmt.Sprintf("CTE<%s>重复", cte.Name))
+		}
+		rel, err := e.runCTE(r, cte)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[withScope] 执行CTE<%s>错误, %s", cte.Name, err.Error()))
+			return nil, err
+		}
+		r.relations[cte.Name] = rel
+	}
+	return r, nil
+}
+
+// runCTE 执行 CTE；递归的 CTE 每一轮只把上一轮新增的行作为 Name 的数据，最多迭代 config.RecursiveCTEMaxDepth 次
+func (e *Engine) runCTE(scope *queryScope, cte *base.CommonTableExpression) (*relation, base.StandardError) {
+	r, err := e.runQuery(scope, cte.Query)
+	if err != nil || cte.Recursive == nil {
+		return r, err
+	}
+	fields := r.fields()
+	seen := make(map[string]bool)
+	distinct := func(rows []map[string][]byte) []map[string][]byte {
+		if cte.UnionAll {
+			return rows
+		}
+		added := make([]map[string][]byte, 0, len(rows))
+		for _, row := range rows {
+			key := string(rowIndexKey(fields, row))
+			if !seen[key] {
+				seen[key] = true
+				added = append(added, row)
+			}
+		}
+		return added
+	}
+	r.rows = distinct(r.rows)
+	working := r.rows
+	for depth := 0; len(working) > 0; depth++ {
+		if depth >= config.CoreConfig.RecursiveCTEMaxDepth {
+			return nil, queryError(fmt.Sprintf("递归的CTE<%s>超过最大迭代次数<%d>", cte.Name, config.CoreConfig.RecursiveCTEMaxDepth))
+		}
+		inner := &queryScope{parent: scope, relations: map[string]*relation{cte.Name: {tableInfo: r.tableInfo, rows: working}}}
+		step, err := e.runQuery(inner, cte.Recursive)
+		if err != nil {
+			return nil, err
+		}
+		rows, err := alignRelation(r, step)
+		if err != nil {
+			return nil, err
+		}
+		working = distinct(rows)
+		r.rows = append(r.rows, working...)
+	}
+	return r, nil
+}
+
+// runQueryBody 执行 With 之外的部分；extraWhere 和 extraColumns 为相关子查询附加的条件和结果的列
+func (e *Engine) runQueryBody(scope *queryScope, query *base.Query, extraWhere []*base.WherePartItem, extraColumns []string) (*relation, base.StandardError) {
+	if query.From == "" {
+		return nil, queryError("查询的 From 为空")
+	}
+	if query.Limit < 0 || query.Offset < 0 {
+		return nil, queryError(fmt.Sprintf("Limit: %d 或 Offset: %d 小于0", query.Limit, query.Offset))
+	}
+	// 相关子查询附加的条件可能使用主键，这时不使用执行计划中的全表扫描
+	fullScan := len(extraWhere) == 0 && scope.accessPath(query) == base.AccessPathFullScan
+	r, err := e.scanRelation(scope, query.From, append(append([]*base.WherePartItem{}, query.Where...), extraWhere...), query.Predicate, fullScan)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[runQueryBody] scanRelation错误, %s", err.Error()))
+		return nil, err
+	}
+	if query.Filter != nil {
+		r, err = filterRelation(r, query.Filter)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[runQueryBody] filterRelation错误, %s", err.Error()))
+			return nil, err
+		}
+	}
+	for _, item := range query.SubQueries {
+		r, err = e.filterSubQuery(scope, r, item)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[runQueryBody] filterSubQuery错误, %s", err.Error()))
+			return nil, err
+		}
+	}
+	r, err = shapeRelation(r, query, extraColumns)
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range query.SetOperations {
+		if op == nil {
+			return nil, queryError("集合运算为空")
+		}
+		right, err := e.runQuery(scope, op.Query)
+		if err != nil {
+			return nil, err
+		}
+		r, err = setOperation(r, right, op.Operator)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(query.OrderBy) == 0 && query.Limit == 0 && query.Offset == 0 {
+		return r, nil
+	}
+	sorter, err := newRowSorter(r.tableInfo, query.OrderBy)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := sorter.run(r.source(), len(query.OrderBy) == 0, query.Offset, query.Limit)
+	if err != nil {
+		return nil, err
+	}
+	return &relation{tableInfo: r.tableInfo, rows: rows}, nil
+}
+
+// scanRelation 读取 CTE、系统目录的虚拟表、视图或者表中满足条件的行，物化视图读取对应的表
+// fullScan 为 true 时表不使用主键的范围，见 BPlusTree.ScanAll
+func (e *Engine) scanRelation(scope *queryScope, name string, whereArgs []*base.WherePartItem, predicate *base.WhereNode, fullScan bool) (*relation, base.StandardError) {
+	if rel, ok := scope.lookup(name); ok {
+		return filterWhereNode(rel, base.AndWhereNode(whereArgs, predicate))
+	}
+	tableName := e.qualifiedName(name)
+	if table, ok := catalogTables()[tableName]; ok {
+		rows, err := e.selectCatalogTable(table, whereArgs)
+		if err != nil {
+			return nil, err
+		}
+		return filterWhereNode(&relation{tableInfo: table.TableInfo, rows: rows}, predicate)
+	}
+	view, ok, err := e.lookupView(tableName)
+	if err != nil {
+		return nil, err
+	}
+	if ok && !view.Materialized {
+		rel, err := e.runView(scope, view)
+		if err != nil {
+			return nil, err
+		}
+		return filterWhereNode(rel, base.AndWhereNode(whereArgs, predicate))
+	}
+	tree, err := e.openTable(tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer tree.DataManager.Close()
+	r := &relation{tableInfo: tree.TableInfo, rows: make([]map[string][]byte, 0)}
+	collect := func(key []byte, values map[string][]byte) (bool, base.StandardError) {
+		values[tree.TableInfo.PrimaryKeyFieldInfo.Name] = key
+		r.rows = append(r.rows, values)
+		return true, nil
+	}
+	switch {
+	case fullScan:
+		err = tree.ScanAll(base.AndWhereNode(whereArgs, predicate), collect)
+	case predicate == nil:
+		err = tree.Scan(whereArgs, collect)
+	default:
+		err = tree.ScanNode(base.AndWhereNode(whereArgs, predicate), collect)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// filterWhereNode 按条件树过滤内存中的数据，node 为 nil 时为全部的数据
+func filterWhereNode(r *relation, node *base.WhereNode) (*relation, base.StandardError) {
+	regexpCache := tableschema.NewRegexpCache()
+	result := &relation{tableInfo: r.tableInfo, rows: make([]map[string][]byte, 0)}
+	for _, row := range r.rows {
+		match := true
+		if node != nil {
+			var err base.StandardError
+			match, err = r.tableInfo.MatchWhereNode(node, row, regexpCache)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if match {
+			result.rows = append(result.rows, row)
+		}
+	}
+	return result, nil
+}
+
+// shapeRelation 聚合、窗口函数、表达式和投影，extraColumns 加入分组的列和结果的列
+func shapeRelation(r *relation, query *base.Query, extraColumns []string) (*relation, base.StandardError) {
+	var err base.StandardError
+	if query.Aggregate != nil {
+		aggregateQuery := *query.Aggregate
+		aggregateQuery.GroupBy = appendMissing(aggregateQuery.GroupBy, extraColumns)
+		r, err = aggregateRelation(r, &aggregateQuery)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, item := range query.Windows {
+		r, err = windowRelation(r, item)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(query.Expressions) > 0 {
+		r, err = expressionRelation(r, query.Expressions)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(query.Columns) > 0 {
+		r, err = projectRelation(r, appendMissing(append([]string{}, query.Columns...), extraColumns))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func appendMissing(columns []string, extra []string) []string {
+	exist := set.NewStringsSet(columns...)
+	for _, column := range extra {
+		if !exist.Contain(column) {
+			exist.Add(column)
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// aggregateRelation 聚合内存中的数据，只使用哈希聚合；count_distinct 和 Engine.Aggregate 一样使用外部排序去重，结果按照分组的 key 排序
+func aggregateRelation(r *relation, query *base.AggregateQuery) (*relation, base.StandardError) {
+	a, err := newAggregator(r.tableInfo, query)
+	if err != nil {
+		return nil, err
+	}
+	if a.hasCountDistinct() {
+		a.distinct = newExternalSorter(compareSpillRecords, operatorMemoryRows())
+		defer a.distinct.Close()
+	}
+	regexpCache := tableschema.NewRegexpCache()
+	groups := make(map[string]*aggregateGroup)
+	for _, row := range r.rows {
+		match, err := r.tableInfo.MatchWhereParts(query.Where, row, regexpCache)
+		if err != nil {
+			return nil, err
+		}
+		if !match {
+			continue
+		}
+		record := a.record(row[r.tableInfo.PrimaryKeyFieldInfo.Name], row)
+		g, ok := groups[string(record[0])]
+		if !ok {
+			g = a.newGroup(record)
+			groups[string(record[0])] = g
+		}
+		err = a.accumulate(g, record)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(query.GroupBy) == 0 && len(groups) == 0 {
+		groups[""] = a.newGroup(spillRecord{nil})
+	}
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	counter, err := a.newDistinctCounter()
+	if err != nil {
+		return nil, err
+	}
+	defer counter.Close()
+	result := &relation{tableInfo: a.resultInfo, rows: make([]map[string][]byte, 0, len(keys))}
+	for _, key := range keys {
+		err = counter.fill(groups[key])
+		if err != nil {
+			return nil, err
+		}
+		row, match, err := a.result(groups[key])
+		if err != nil {
+			return nil, err
+		}
+		if match {
+			result.rows = append(result.rows, row)
+		}
+	}
+	return result, nil
+}
+
+func projectRelation(r *relation, columns []string) (*relation, base.StandardError) {
+	fields := make([]*tableschema.FieldInfo, 0, len(columns))
+	exist := set.NewStringsSet()
+	for _, column := range columns {
+		field, ok := r.tableInfo.FieldInfoByName(column)
+		if !ok {
+			return nil, queryError(fmt.Sprintf("列<%s>不存在", column))
+		}
+		if exist.Contain(column) {
+			return nil, queryError(fmt.Sprintf("列<%s>重复", column))
+		}
+		exist.Add(column)
+		fields = append(fields, field)
+	}
+	result := &relation{tableInfo: relationInfo(r.tableInfo.Name, fields), rows: make([]map[string][]byte, 0, len(r.rows))}
+	for _, row := range r.rows {
+		values := make(map[string][]byte, len(fields))
+		for _, field := range fields {
+			values[field.Name] = row[field.Name]
+		}
+		result.rows = append(result.rows, values)
+	}
+	return result, nil
+}
+
+// rowIndexKey 一行中全部列组合成的 key，用于集合运算去重
+func rowIndexKey(fields []*tableschema.FieldInfo, row map[string][]byte) []byte {
+	values := make([][]byte, 0, len(fields))
+	for _, field := range fields {
+		values = append(values, row[field.Name])
+	}
+	return compositeIndexKey(fields, values)
+}
+
+// alignRelation 按照列的位置把 right 的行转换为 left 的列名，两边的列数和类型必须相同
+func alignRelation(left *relation, right *relation) ([]map[string][]byte, base.StandardError) {
+	leftFields, rightFields := left.fields(), right.fields()
+	if len(leftFields) != len(rightFields) {
+		return nil, queryError(fmt.Sprintf("集合运算两边的列数不同: %d, %d", len(leftFields), len(rightFields)))
+	}
+	for i := range leftFields {
+		if leftFields[i].FieldType.GetType() != rightFields[i].FieldType.GetType() {
+			return nil, queryError(fmt.Sprintf("集合运算第%d列的类型不同: %s, %s", i+1, leftFields[i].FieldType.GetType(), rightFields[i].FieldType.GetType()))
+		}
+	}
+	rows := make([]map[string][]byte, 0, len(right.rows))
+	for _, row := range right.rows {
+		values := make(map[string][]byte, len(leftFields))
+		for i, field := range leftFields {
+			values[field.Name] = row[rightFields[i].Name]
+		}
+		rows = append(rows, values)
+	}
+	return rows, nil
+}
+
+// setOperation 集合运算，按照列的 IndexKey 判断两行是否相同；结果保持行第一次出现的顺序
+func setOperation(left *relation, right *relation, operator base.SetOperator) (*relation, base.StandardError) {
+	rightRows, err := alignRelation(left, right)
+	if err != nil {
+		return nil, err
+	}
+	fields := left.fields()
+	r := &relation{tableInfo: left.tableInfo, rows: make([]map[string][]byte, 0)}
+	if operator == base.SetOperatorUnionAll {
+		r.rows = append(append(r.rows, left.rows...), rightRows...)
+		return r, nil
+	}
+	rightKeys := make(map[string]bool)
+	switch operator {
+	case base.SetOperatorUnion:
+	case base.SetOperatorIntersect, base.SetOperatorExcept:
+		for _, row := range rightRows {
+			rightKeys[string(rowIndexKey(fields, row))] = true
+		}
+		rightRows = nil
+	default:
+		return nil, queryError(fmt.Sprintf("不支持的集合运算: %s", operator))
+	}
+	seen := make(map[string]bool)
+	for _, row := range append(append([]map[string][]byte{}, left.rows...), rightRows...) {
+		key := string(rowIndexKey(fields, row))
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if operator == base.SetOperatorUnion || rightKeys[key] == (operator == base.SetOperatorIntersect) {
+			r.rows = append(r.rows, row)
+		}
+	}
+	return r, nil
+}
+
+// subQueryFilter 子查询条件的执行计划
+// 没有相关条件时子查询只执行一次；相关条件都是 equal 并且没有 Limit / Offset / Windows 时去掉相关条件执行一次，
+// 把相关的列加入结果（有聚合时加入分组），按照相关的列建立哈希表（去相关）；否则对外层每一组不同的值执行一次
+type subQueryFilter struct {
+	item         *base.SubQueryItem
+	targetField  *tableschema.FieldInfo   // 外层的列，exists / not_exists 为 nil
+	outerFields  []*tableschema.FieldInfo // 相关条件中外层的列
+	innerFields  []*tableschema.FieldInfo // 相关条件中子查询的列
+	valueName    string                   // 子查询结果的列名
+	empty        *relation                // 子查询的数据为空时的结果
+	results      map[string]*relation     // 相关的值的 key -> 子查询的结果
+	decorrelated bool
+}
+
+// filterSubQuery 过滤满足子查询条件的行
+func (e *Engine) filterSubQuery(scope *queryScope, r *relation, item *base.SubQueryItem) (*relation, base.StandardError) {
+	f, err := e.newSubQueryFilter(scope, r, item)
+	if err != nil {
+		return nil, err
+	}
+	result := &relation{tableInfo: r.tableInfo, rows: make([]map[string][]byte, 0, len(r.rows))}
+	for _, row := range r.rows {
+		sub, err := f.subQueryResult(e, scope, row)
+		if err != nil {
+			return nil, err
+		}
+		match, err := f.match(row, sub)
+		if err != nil {
+			return nil, err
+		}
+		if match {
+			result.rows = append(result.rows, row)
+		}
+	}
+	return result, nil
+}
+
+func (e *Engine) newSubQueryFilter(scope *queryScope, r *relation, item *base.SubQueryItem) (*subQueryFilter, base.StandardError) {
+	if item == nil || item.Query == nil {
+		return nil, queryError("子查询为空")
+	}
+	f := &subQueryFilter{item: item}
+	switch item.Type {
+	case base.SubQueryTypeScalar:
+		if !comparisonOperate(item.Operate) {
+			return nil, queryError(fmt.Sprintf("标量子查询不支持比较符: %s", item.Operate))
+		}
+		fallthrough
+	case base.SubQueryTypeIn, base.SubQueryTypeNotIn:
+		field, ok := r.tableInfo.FieldInfoByName(item.TargetColumn)
+		if !ok {
+			return nil, queryError(fmt.Sprintf("子查询条件的列<%s>不存在", item.TargetColumn))
+		}
+		f.targetField = field
+	case base.SubQueryTypeExists, base.SubQueryTypeNotExists:
+	default:
+		return nil, queryError(fmt.Sprintf("不支持的子查询类型: %s", item.Type))
+	}
+
+	// 没有相关条件
+	if len(item.Correlation) == 0 {
+		sub, err := e.runQuery(scope, item.Query)
+		if err != nil {
+			return nil, err
+		}
+		err = f.setValueField(sub)
+		if err != nil {
+			return nil, err
+		}
+		f.results = map[string]*relation{"": sub}
+		return f, nil
+	}
+
+	if len(item.Query.SetOperations) > 0 {
+		return nil, queryError("相关子查询不支持集合运算")
+	}
+	inner, err := e.withScope(scope, item.Query.With)
+	if err != nil {
+		return nil, err
+	}
+	sourceInfo, err := e.relationInfo(inner, item.Query.From)
+	if err != nil {
+		return nil, err
+	}
+	decorrelate := item.Query.Limit == 0 && item.Query.Offset == 0 && len(item.Query.Windows) == 0
+	for _, c := range item.Correlation {
+		if c == nil {
+			return nil, queryError("相关子查询的条件为空")
+		}
+		if !comparisonOperate(c.Operate) {
+			return nil, queryError(fmt.Sprintf("相关子查询的条件不支持比较符: %s", c.Operate))
+		}
+		outerField, ok := r.tableInfo.FieldInfoByName(c.OuterColumn)
+		if !ok {
+			return nil, queryError(fmt.Sprintf("相关子查询外层的列<%s>不存在", c.OuterColumn))
+		}
+		innerField, ok := sourceInfo.FieldInfoByName(c.Column)
+		if !ok {
+			return nil, queryError(fmt.Sprintf("相关子查询的列<%s>不存在", c.Column))
+		}
+		f.outerFields = append(f.outerFields, outerField)
+		f.innerFields = append(f.innerFields, innerField)
+		decorrelate = decorrelate && c.Operate == base.DataComparatorEqual
+	}
+	f.empty, err = shapeRelation(&relation{tableInfo: sourceInfo}, item.Query, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = f.setValueField(f.empty)
+	if err != nil {
+		return nil, err
+	}
+	f.results = make(map[string]*relation)
+	if !decorrelate {
+		return f, nil
+	}
+
+	columns := make([]string, 0, len(item.Correlation))
+	for _, c := range item.Correlation {
+		columns = append(columns, c.Column)
+	}
+	sub, err := e.runQueryBody(inner, item.Query, nil, columns)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range sub.rows {
+		values := make([][]byte, 0, len(f.innerFields))
+		for _, field := range f.innerFields {
+			value := row[field.Name]
+			isNull, err := field.FieldType.IsNull(value)
+			if err != nil {
+				return nil, err
+			}
+			if isNull {
+				values = nil
+				break
+			}
+			values = append(values, value)
+		}
+		if values == nil {
+			continue
+		}
+		key := string(compositeIndexKey(f.innerFields, values))
+		if _, ok := f.results[key]; !ok {
+			f.results[key] = &relation{tableInfo: sub.tableInfo, rows: make([]map[string][]byte, 0)}
+		}
+		f.results[key].rows = append(f.results[key].rows, row)
+	}
+	f.decorrelated = true
+	return f, nil
+}
+
+// comparisonOperate 是否为只有一个参数的比较符
+func comparisonOperate(operate base.DataComparator) bool {
+	switch operate {
+	case base.DataComparatorEqual, base.DataComparatorNotEqual, base.DataComparatorGreater,
+		base.DataComparatorGreaterAndEqual, base.DataComparatorLess, base.DataComparatorLessAndEqual:
+		return true
+	}
+	return false
+}
+
+// relationInfo 表、虚拟表或者 CTE 的列
+func (e *Engine) relationInfo(scope *queryScope, name string) (*tableschema.TableMetaInfo, base.StandardError) {
+	if rel, ok := scope.lookup(name); ok {
+		return rel.tableInfo, nil
+	}
+	tableName := e.qualifiedName(name)
+	if table, ok := catalogTables()[tableName]; ok {
+		return table.TableInfo, nil
+	}
+	view, ok, err := e.lookupView(tableName)
+	if err != nil {
+		return nil, err
+	}
+	if ok && !view.Materialized {
+		rel, err := e.runView(scope, view)
+		if err != nil {
+			return nil, err
+		}
+		return rel.tableInfo, nil
+	}
+	return e.loadTableSchemaInfo(tableName)
+}
+
+// setValueField 检查子查询结果的列数，exists / not_exists 不限制
+func (f *subQueryFilter) setValueField(sub *relation) base.StandardError {
+	if f.targetField == nil {
+		return nil
+	}
+	fields := sub.fields()
+	if len(fields) != 1 {
+		return queryError(fmt.Sprintf("子查询的结果只能有一列，实际为%d列", len(fields)))
+	}
+	if fields[0].FieldType.GetType() != f.targetField.FieldType.GetType() {
+		return queryError(fmt.Sprintf("子查询结果的类型<%s>和列<%s>的类型<%s>不同", fields[0].FieldType.GetType(), f.targetField.Name, f.targetField.FieldType.GetType()))
+	}
+	f.valueName = fields[0].Name
+	return nil
+}
+
+// subQueryResult 外层的一行对应的子查询结果
+func (f *subQueryFilter) subQueryResult(e *Engine, scope *queryScope, row map[string][]byte) (*relation, base.StandardError) {
+	if len(f.outerFields) == 0 {
+		return f.results[""], nil
+	}
+	values := make([][]byte, 0, len(f.outerFields))
+	for _, field := range f.outerFields {
+		value := row[field.Name]
+		isNull, err := field.FieldType.IsNull(value)
+		if err != nil {
+			return nil, err
+		}
+		// 和 Null 比较的相关条件不满足，子查询的数据为空
+		if isNull {
+			return f.empty, nil
+		}
+		values = append(values, field.FieldType.TrimRaw(value))
+	}
+	key := string(compositeIndexKey(f.innerFields, values))
+	if r, ok := f.results[key]; ok {
+		return r, nil
+	}
+	// 去相关之后没有结果的值，子查询的数据为空
+	if f.decorrelated {
+		return f.empty, nil
+	}
+
+	extraWhere := make([]*base.WherePartItem, 0, len(f.item.Correlation))
+	for i, c := range f.item.Correlation {
+		extraWhere = append(extraWhere, &base.WherePartItem{TargetColumn: c.Column, Operate: c.Operate, Args: [][]byte{values[i]}})
+	}
+	inner, err := e.withScope(scope, f.item.Query.With)
+	if err != nil {
+		return nil, err
+	}
+	r, err := e.runQueryBody(inner, f.item.Query, extraWhere, nil)
+	if err != nil {
+		return nil, err
+	}
+	f.results[key] = r
+	return r, nil
+}
+
+// match 外层的一行是否满足子查询条件
+func (f *subQueryFilter) match(row map[string][]byte, sub *relation) (bool, base.StandardError) {
+	switch f.item.Type {
+	case base.SubQueryTypeExists:
+		return len(sub.rows) > 0, nil
+	case base.SubQueryTypeNotExists:
+		return len(sub.rows) == 0, nil
+	}
+	fieldType := f.targetField.FieldType
+	target := fieldType.TrimRaw(row[f.targetField.Name])
+	isNull, err := fieldType.IsNull(target)
+	if err != nil || isNull {
+		return false, err
+	}
+	if f.item.Type == base.SubQueryTypeScalar {
+		if len(sub.rows) > 1 {
+			return false, queryError(fmt.Sprintf("标量子查询返回了%d行", len(sub.rows)))
+		}
+		if len(sub.rows) == 0 {
+			return false, nil
+		}
+		value := fieldType.TrimRaw(sub.rows[0][f.valueName])
+		isNull, err = fieldType.IsNull(value)
+		if err != nil || isNull {
+			return false, err
+		}
+		return tableschema.MatchMetaType(fieldType, target, f.item.Operate, [][]byte{value}, nil)
+	}
+
+	hasNull := false
+	for _, subRow := range sub.rows {
+		value := fieldType.TrimRaw(subRow[f.valueName])
+		isNull, err = fieldType.IsNull(value)
+		if err != nil {
+			return false, err
+		}
+		if isNull {
+			hasNull = true
+			continue
+		}
+		equal, err := fieldType.Equal(target, value)
+		if err != nil {
+			return false, err
+		}
+		if equal {
+			return f.item.Type == base.SubQueryTypeIn, nil
+		}
+	}
+	return f.item.Type == base.SubQueryTypeNotIn && !hasNull, nil
+}
@@ -0,0 +1,217 @@
+package core
+
+import (
+	"testing"
+
+	"ne_database/core/base"
+	"ne_database/core/tableschema"
+)
+
+// newTestQueryEngine 客户、订单和员工三张表，0 为 Null；employees 中 9 和 10 互为上级
+func newTestQueryEngine(t *testing.T) (*Engine, *tableschema.TableMetaInfo, *tableschema.TableMetaInfo, *tableschema.TableMetaInfo) {
+	t.Helper()
+	customers := newTestTableInfo("engine_query_customers", testBigIntField("region"))
+	orders := newTestTableInfo("engine_query_orders", testBigIntField("customer_id"), testBigIntField("amount"))
+	employees := newTestTableInfo("engine_query_employees", testBigIntField("manager_id"))
+	e := newTestEngine(t, customers, orders, employees)
+	data := map[*tableschema.TableMetaInfo][][]int64{
+		customers: {{1, 1}, {2, 1}, {3, 2}, {4, 2}, {5, 0}},
+		orders:    {{1, 1, 50}, {2, 1, 150}, {3, 2, 100}, {4, 3, 20}, {5, 3, 30}, {6, 3, 70}, {7, 0, 500}},
+		employees: {{1, 0}, {2, 1}, {3, 1}, {4, 2}, {5, 4}, {6, 3}, {7, 0}, {8, 7}, {9, 10}, {10, 9}},
+	}
+	for info, rows := range data {
+		for _, d := range rows {
+			row := map[string][]byte{"id": testInt64(d[0])}
+			for i, field := range info.ValueFieldInfo {
+				row[field.Name] = testInt64(d[i+1])
+			}
+			insertTestRows(t, e, info.Name, row)
+		}
+	}
+	return e, customers, orders, employees
+}
+
+// testQueryCase 查询结果中 column 列的值按照结果的顺序以逗号连接
+type testQueryCase struct {
+	query  *base.Query
+	column string
+	expect string
+}
+
+// checkTestQueries 依次执行查询并比较结果
+func checkTestQueries(t *testing.T, e *Engine, testCases []testQueryCase) {
+	t.Helper()
+	for i, c := range testCases {
+		count, rows, err := e.Query(c.query)
+		if err != nil {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+			continue
+		}
+		if r := testInt64Values(rows, c.column); count != int64(len(rows)) || r != c.expect {
+			t.Errorf("case %d: expected %s, but got %d rows: %s", i, c.expect, count, r)
+		}
+	}
+}
+
+func TestEngine_Query_SubQuery(t *testing.T) {
+	e, customers, orders, _ := newTestQueryEngine(t)
+	ordersOf := func(where ...*base.WherePartItem) *base.Query {
+		return &base.Query{From: orders.Name, Where: where, Columns: []string{"customer_id"}}
+	}
+	bigOrder := &base.WherePartItem{TargetColumn: "amount", Operate: base.DataComparatorGreaterAndEqual, Args: [][]byte{testInt64(100)}}
+	hasCustomer := &base.WherePartItem{TargetColumn: "customer_id", Operate: base.DataComparatorIsNotNull, Args: [][]byte{}}
+	sameCustomer := []*base.Correlation{{Column: "customer_id", Operate: base.DataComparatorEqual, OuterColumn: "id"}}
+	checkTestQueries(t, e, []testQueryCase{
+		{&base.Query{From: customers.Name, SubQueries: []*base.SubQueryItem{
+			{Type: base.SubQueryTypeIn, TargetColumn: "id", Query: ordersOf(bigOrder)},
+		}}, "id", "1,2"},
+		// 子查询的结果中有 Null 时 not in 不满足
+		{&base.Query{From: customers.Name, SubQueries: []*base.SubQueryItem{
+			{Type: base.SubQueryTypeNotIn, TargetColumn: "id", Query: ordersOf()},
+		}}, "id", ""},
+		{&base.Query{From: customers.Name, SubQueries: []*base.SubQueryItem{
+			{Type: base.SubQueryTypeNotIn, TargetColumn: "id", Query: ordersOf(hasCustomer)},
+		}}, "id", "4,5"},
+		{&base.Query{From: customers.Name, SubQueries: []*base.SubQueryItem{
+			{Type: base.SubQueryTypeExists, Query: ordersOf(bigOrder), Correlation: sameCustomer},
+		}}, "id", "1,2"},
+		{&base.Query{From: customers.Name, SubQueries: []*base.SubQueryItem{
+			{Type: base.SubQueryTypeNotExists, Query: ordersOf(), Correlation: sameCustomer},
+		}}, "id", "4,5"},
+		// 不是 equal 的相关条件对每一行执行子查询
+		{&base.Query{From: customers.Name, SubQueries: []*base.SubQueryItem{
+			{Type: base.SubQueryTypeExists, Query: ordersOf(), Correlation: []*base.Correlation{{Column: "customer_id", Operate: base.DataComparatorGreater, OuterColumn: "id"}}},
+		}}, "id", "1,2"},
+		// 金额大于该客户平均金额的订单
+		{&base.Query{From: orders.Name, SubQueries: []*base.SubQueryItem{
+			{Type: base.SubQueryTypeScalar, TargetColumn: "amount", Operate: base.DataComparatorGreater, Query: &base.Query{
+				From:      orders.Name,
+				Aggregate: &base.AggregateQuery{Aggregates: []*base.AggregateItem{{Function: base.AggregateFunctionAvg, Column: "amount"}}},
+			}, Correlation: []*base.Correlation{{Column: "customer_id", Operate: base.DataComparatorEqual, OuterColumn: "customer_id"}}},
+		}}, "id", "2,6"},
+		{&base.Query{From: customers.Name, SubQueries: []*base.SubQueryItem{
+			{Type: base.SubQueryTypeScalar, TargetColumn: "id", Operate: base.DataComparatorLess, Query: &base.Query{
+				From:      orders.Name,
+				Where:     []*base.WherePartItem{hasCustomer},
+				Aggregate: &base.AggregateQuery{Aggregates: []*base.AggregateItem{{Function: base.AggregateFunctionMax, Column: "customer_id"}}},
+			}},
+		}}, "id", "1,2"},
+	})
+}
+
+func TestEngine_Query_SetOperation(t *testing.T) {
+	e, customers, orders, _ := newTestQueryEngine(t)
+	customerIDs := &base.Query{From: orders.Name, Columns: []string{"customer_id"}}
+	hasCustomer := &base.Query{From: orders.Name, Columns: []string{"customer_id"}, Where: []*base.WherePartItem{
+		{TargetColumn: "customer_id", Operate: base.DataComparatorIsNotNull, Args: [][]byte{}},
+	}}
+	bigOrders := &base.Query{From: orders.Name, Columns: []string{"customer_id"}, Where: []*base.WherePartItem{
+		{TargetColumn: "amount", Operate: base.DataComparatorGreaterAndEqual, Args: [][]byte{testInt64(100)}},
+		{TargetColumn: "customer_id", Operate: base.DataComparatorIsNotNull, Args: [][]byte{}},
+	}}
+	region2 := testWhereEqual("region", testInt64(2))
+	checkTestQueries(t, e, []testQueryCase{
+		{&base.Query{From: customers.Name, Where: region2, Columns: []string{"id"},
+			SetOperations: []*base.SetOperation{{Operator: base.SetOperatorUnion, Query: hasCustomer}}}, "id", "3,4,1,2"},
+		{&base.Query{From: customers.Name, Where: region2, Columns: []string{"id"},
+			SetOperations: []*base.SetOperation{{Operator: base.SetOperatorUnionAll, Query: bigOrders}}}, "id", "3,4,1,2"},
+		{&base.Query{From: customers.Name, Columns: []string{"id"},
+			SetOperations: []*base.SetOperation{{Operator: base.SetOperatorIntersect, Query: customerIDs}}}, "id", "1,2,3"},
+		{&base.Query{From: customers.Name, Columns: []string{"id"},
+			SetOperations: []*base.SetOperation{{Operator: base.SetOperatorExcept, Query: customerIDs}},
+			OrderBy:       []*base.OrderByItem{{Column: "id", Desc: true}}}, "id", "5,4"},
+	})
+}
+
+func TestEngine_Query_With(t *testing.T) {
+	e, customers, orders, employees := newTestQueryEngine(t)
+	bigOrder := &base.WherePartItem{TargetColumn: "amount", Operate: base.DataComparatorGreaterAndEqual, Args: [][]byte{testInt64(100)}}
+	hasCustomer := &base.WherePartItem{TargetColumn: "customer_id", Operate: base.DataComparatorIsNotNull, Args: [][]byte{}}
+	checkTestQueries(t, e, []testQueryCase{
+		{&base.Query{
+			With: []*base.CommonTableExpression{
+				{Name: "big_orders", Query: &base.Query{From: orders.Name, Where: []*base.WherePartItem{bigOrder}}},
+				{Name: "big_customers", Query: &base.Query{From: "big_orders", Where: []*base.WherePartItem{hasCustomer}, Columns: []string{"customer_id"}}},
+			},
+			From:       customers.Name,
+			SubQueries: []*base.SubQueryItem{{Type: base.SubQueryTypeIn, TargetColumn: "id", Query: &base.Query{From: "big_customers"}}},
+		}, "id", "1,2"},
+		// 内存中的聚合和 Engine.Aggregate 一样通过外部排序对 count_distinct 去重，Null 不计入
+		{&base.Query{
+			With: []*base.CommonTableExpression{{Name: "all_orders", Query: &base.Query{From: orders.Name}}},
+			From: "all_orders",
+			Aggregate: &base.AggregateQuery{GroupBy: []string{"customer_id"}, Aggregates: []*base.AggregateItem{
+				{Function: base.AggregateFunctionCountDistinct, Column: "customer_id", Alias: "customers"},
+			}},
+		}, "customers", "0,1,1,1"},
+		// 2 的全部下级
+		{&base.Query{
+			With: []*base.CommonTableExpression{{
+				Name:  "subordinates",
+				Query: &base.Query{From: employees.Name, Where: testWhereEqual("id", testInt64(2))},
+				Recursive: &base.Query{From: employees.Name, SubQueries: []*base.SubQueryItem{
+					{Type: base.SubQueryTypeIn, TargetColumn: "manager_id", Query: &base.Query{From: "subordinates", Columns: []string{"id"}}},
+				}},
+			}},
+			From: "subordinates",
+		}, "id", "2,4,5"},
+		// union 去重后循环也会结束
+		{&base.Query{
+			With: []*base.CommonTableExpression{{
+				Name:  "chain",
+				Query: &base.Query{From: employees.Name, Where: testWhereEqual("id", testInt64(9))},
+				Recursive: &base.Query{From: employees.Name, SubQueries: []*base.SubQueryItem{
+					{Type: base.SubQueryTypeIn, TargetColumn: "id", Query: &base.Query{From: "chain", Columns: []string{"manager_id"}}},
+				}},
+			}},
+			From: "chain",
+		}, "id", "9,10"},
+	})
+}
+
+func TestEngine_Query_Error(t *testing.T) {
+	e, customers, orders, employees := newTestQueryEngine(t)
+	customerIDs := &base.Query{From: orders.Name, Columns: []string{"customer_id"}}
+	testCases := []*base.Query{
+		// 标量子查询返回多行
+		{From: customers.Name, SubQueries: []*base.SubQueryItem{
+			{Type: base.SubQueryTypeScalar, TargetColumn: "id", Operate: base.DataComparatorEqual, Query: customerIDs},
+		}},
+		// 子查询和集合操作的结果只能有一列或者列数相同
+		{From: customers.Name, SubQueries: []*base.SubQueryItem{
+			{Type: base.SubQueryTypeIn, TargetColumn: "id", Query: &base.Query{From: orders.Name}},
+		}},
+		{From: customers.Name, SetOperations: []*base.SetOperation{{Operator: base.SetOperatorUnion, Query: customerIDs}}},
+		// union all 的循环超过递归的层数
+		{
+			With: []*base.CommonTableExpression{{
+				Name:     "chain",
+				UnionAll: true,
+				Query:    &base.Query{From: employees.Name, Where: testWhereEqual("id", testInt64(9))},
+				Recursive: &base.Query{From: employees.Name, SubQueries: []*base.SubQueryItem{
+					{Type: base.SubQueryTypeIn, TargetColumn: "id", Query: &base.Query{From: "chain", Columns: []string{"manager_id"}}},
+				}},
+			}},
+			From: "chain",
+		},
+	}
+	for i, query := range testCases {
+		if _, _, err := e.Query(query); err == nil {
+			t.Errorf("case %d: expected error, but got nil", i)
+		}
+	}
+}
+
+func TestEngine_Query_Predicate(t *testing.T) {
+	// CTE 和外层查询都可以使用条件树
+	e, items := newTestWhereNodeEngine(t)
+	checkTestQueries(t, e, []testQueryCase{
+		{&base.Query{
+			With: []*base.CommonTableExpression{{Name: "small", Query: &base.Query{From: items.Name, Predicate: testWhereOr(
+				testWhereNode("id", base.DataComparatorLessAndEqual, testInt64(3)), testWhereNode("id", base.DataComparatorEqual, testInt64(30)))}}},
+			From:      "small",
+			Predicate: testWhereNot(testWhereNode("name", base.DataComparatorEqual, []byte("n2"))),
+			OrderBy:   []*base.OrderByItem{{Column: "id"}},
+		}, "id", "1,3,30"},
+	})
+}
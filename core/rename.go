@@ -0,0 +1,165 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"ne_database/core/base"
+	"ne_database/core/tableschema"
+	"ne_database/utils"
+)
+
+// renameJournal 重命名表的进度记录，在移动文件之前写入，全部完成后删除
+// 自增主键的序列名跟随表名修改时，同时记录原来和新的序列名
+type renameJournal struct {
+	From        string `json:"from"`
+	To          string `json:"to"`
+	Sequence    string `json:"sequence,omitempty"`
+	NewSequence string `json:"new_sequence,omitempty"`
+}
+
+func getRenameJournalFilePath(tableName string) string {
+	return getTableFilePath(tableName, base.DataIOFileRenameJournalSuffix)
+}
+
+// RenameTable 修改表名，同时修改引用该表的外键以及自增主键的序列名，不重写表数据
+func (e *Engine) RenameTable(tableName string, newName string) base.StandardError {
+	return e.AlterTable(tableName, []*tableschema.AlterTableItem{
+		{Action: base.AlterTableActionRenameTable, NewName: newName},
+	})
+}
+
+// moveTableFiles 把表 oldInfo.Name 的文件移动为 newInfo.Name 对应的文件，表结构写入 newInfo
+// 步骤为: 写入进度记录、写入新的表结构、重命名数据文件和自增序列、删除原来的表结构、删除进度记录
+// 中途崩溃时由 recoverJournals 处理: 新的表结构已经写入时继续完成，否则放弃这次重命名
+func (e *Engine) moveTableFiles(oldInfo *tableschema.TableMetaInfo, newInfo *tableschema.TableMetaInfo) base.StandardError {
+	tableName := oldInfo.Name
+	journal := &renameJournal{From: tableName, To: newInfo.Name}
+	if oldInfo.PrimaryKeyFieldInfo.AutoIncrement {
+		journal.Sequence = tableschema.AutoIncrementSequenceName(tableName, oldInfo.PrimaryKeyFieldInfo.Name)
+		journal.NewSequence = tableschema.AutoIncrementSequenceName(newInfo.Name, newInfo.PrimaryKeyFieldInfo.Name)
+	}
+	journalPath := getRenameJournalFilePath(tableName)
+	er := utils.WriteFileAtomic(journalPath, []byte(utils.ToJSON(journal)), base.DataIOFileTempSuffix)
+	if er != nil {
+		errMsg := fmt.Sprintf("写入表<%s>的重命名记录发生错误: %s", tableName, er.Error())
+		utils.LogError("[Engine moveTableFiles] " + errMsg)
+		return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeIO, base.ErrorBaseCodeIOError, fmt.Errorf(errMsg))
+	}
+	err := e.saveTableSchemaInfo(newInfo)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[moveTableFiles] saveTableSchemaInfo错误, %s", err.Error()))
+		_ = os.Remove(journalPath)
+		return err
+	}
+	return e.finishRename(journal)
+}
+
+// finishRename 新的表结构写入之后，完成剩余的重命名步骤，重复执行不影响结果
+func (e *Engine) finishRename(journal *renameJournal) base.StandardError {
+	tableName, newName := journal.From, journal.To
+	renameError := func(errMsg string) base.StandardError {
+		utils.LogError("[Engine finishRename] " + errMsg)
+		return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeIO, base.ErrorBaseCodeIOError, fmt.Errorf(errMsg))
+	}
+	exist, er := utils.FileExist(getTableDataFilePath(tableName))
+	if er != nil {
+		return renameError(fmt.Sprintf("检查表<%s>的TableData发生错误: %s", tableName, er.Error()))
+	}
+	if exist {
+		er = os.Rename(getTableDataFilePath(tableName), getTableDataFilePath(newName))
+		if er != nil {
+			return renameError(fmt.Sprintf("重命名表<%s>的TableData发生错误: %s", tableName, er.Error()))
+		}
+	}
+	if journal.Sequence != "" && journal.Sequence != journal.NewSequence {
+		exist, err := e.sequenceExist(journal.Sequence)
+		if err != nil {
+			return err
+		}
+		if exist {
+			err = e.renameSequence(journal.Sequence, journal.NewSequence)
+			if err != nil {
+				utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[finishRename] renameSequence错误, %s", err.Error()))
+				return err
+			}
+		}
+	}
+	er = os.Remove(getTableSchemaFilePath(tableName))
+	if er != nil && !os.IsNotExist(er) {
+		return renameError(fmt.Sprintf("删除表<%s>的TableSchema发生错误: %s", tableName, er.Error()))
+	}
+	err := e.catalogRemoveTable(tableName)
+	if err != nil {
+		return err
+	}
+	er = os.Remove(getRenameJournalFilePath(tableName))
+	if er != nil && !os.IsNotExist(er) {
+		return renameError(fmt.Sprintf("删除表<%s>的重命名记录发生错误: %s", tableName, er.Error()))
+	}
+	return nil
+}
+
+// recoverJournals 处理上次没有完成的表重命名和表数据的替换，包括各个数据库中的表
+func (e *Engine) recoverJournals() base.StandardError {
+	databases, err := e.scanDatabases()
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[recoverJournals] scanDatabases错误, %s", err.Error()))
+		return err
+	}
+	for _, database := range databases {
+		dirPath := getDatabaseDirPath(database)
+		entries, er := os.ReadDir(dirPath)
+		if er != nil {
+			errMsg := fmt.Sprintf("读取 %s 目录发生错误: %s", dirPath, er.Error())
+			utils.LogError("[Engine recoverJournals] " + errMsg)
+			return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeIO, base.ErrorBaseCodeIOError, fmt.Errorf(errMsg))
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			if strings.HasSuffix(entry.Name(), "."+base.DataIOFileRenameJournalSuffix) {
+				err = e.recoverRenameJournal(dirPath + entry.Name())
+			} else if strings.HasSuffix(entry.Name(), "."+base.DataIOFileRefreshJournalSuffix) {
+				err = e.recoverRefreshJournal(dirPath + entry.Name())
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// recoverRenameJournal 处理一个重命名记录: 新的表结构已经写入时继续完成，否则放弃这次重命名
+func (e *Engine) recoverRenameJournal(journalPath string) base.StandardError {
+	data, er := os.ReadFile(journalPath)
+	if er != nil {
+		errMsg := fmt.Sprintf("读取 %s 发生错误: %s", journalPath, er.Error())
+		utils.LogError("[Engine recoverRenameJournal] " + errMsg)
+		return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeIO, base.ErrorBaseCodeIOError, fmt.Errorf(errMsg))
+	}
+	journal := &renameJournal{}
+	er = json.Unmarshal(data, journal)
+	if er != nil {
+		errMsg := fmt.Sprintf("解析 %s 发生错误: %s", journalPath, er.Error())
+		utils.LogError("[Engine recoverRenameJournal] " + errMsg)
+		return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeIO, base.ErrorBaseCodeInnerDataError, fmt.Errorf(errMsg))
+	}
+	exist, er := utils.FileExist(getTableSchemaFilePath(journal.To))
+	if er != nil {
+		errMsg := fmt.Sprintf("检查表<%s>的TableSchema发生错误: %s", journal.To, er.Error())
+		utils.LogError("[Engine recoverRenameJournal] " + errMsg)
+		return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeIO, base.ErrorBaseCodeIOError, fmt.Errorf(errMsg))
+	}
+	if !exist {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[recoverRenameJournal] 放弃表<%s>的重命名", journal.From))
+		_ = os.Remove(journalPath)
+		return nil
+	}
+	utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[recoverRenameJournal] 继续完成表<%s>重命名为<%s>", journal.From, journal.To))
+	return e.finishRename(journal)
+}
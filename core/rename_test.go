@@ -0,0 +1,104 @@
+package core
+
+import (
+	"testing"
+
+	"ne_database/core/base"
+	"ne_database/core/tableschema"
+	"ne_database/utils"
+)
+
+func TestEngine_RenameTable(t *testing.T) {
+	tableInfo := newTestTableInfo("engine_rename_users", testCharField("name", 10))
+	tableInfo.PrimaryKeyFieldInfo.AutoIncrement = true
+	e := newTestEngine(t, tableInfo)
+	insertTestRows(t, e, tableInfo.Name, map[string][]byte{"name": []byte("Alice")})
+
+	newName := "engine_rename_members"
+	if err := e.RenameTable(tableInfo.Name, newName); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	defer e.DropTableCascade(newName)
+	if exist, _ := e.CheckTableExist(tableInfo.Name); exist {
+		t.Error("RenameTable failed, old table still exists")
+		return
+	}
+	// 只修改表名时不处理数据，版本不变
+	newInfo, err := e.LoadTableSchemaInfo(newName)
+	if err != nil || newInfo.Name != newName || newInfo.Version != base.TableSchemaVersionInitial {
+		t.Errorf("RenameTable failed, got %s, %v", utils.ToJSON(newInfo), err)
+		return
+	}
+	_, rows, err := e.Select(newName, nil)
+	if err != nil || len(rows) != 1 || string(rows[0]["name"]) != "Alice" {
+		t.Errorf("RenameTable failed, got %d rows, %v", len(rows), err)
+		return
+	}
+	// 自增序列跟随新表名
+	if exist, _ := e.CheckSequenceExist(tableschema.AutoIncrementSequenceName(newName, "id")); !exist {
+		t.Error("RenameTable failed, sequence not renamed")
+	}
+}
+
+func TestEngine_RenameTable_Recover(t *testing.T) {
+	testCases := []struct {
+		name string
+		// schemaWritten 崩溃之前新的表结构是否已经写入
+		schemaWritten bool
+		// expectTable 恢复之后存在的表
+		expectTable string
+	}{
+		// 新的表结构已经写入时继续完成重命名
+		{"finish", true, "engine_rename_recover_accounts"},
+		// 新的表结构没有写入时放弃重命名
+		{"abandon", false, "engine_rename_recover_users"},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			tableInfo := newTestTableInfo("engine_rename_recover_users", testCharField("name", 10))
+			tableInfo.PrimaryKeyFieldInfo.AutoIncrement = true
+			e := newTestEngine(t, tableInfo)
+			newName := "engine_rename_recover_accounts"
+			defer e.DropTableCascade(newName)
+
+			journal := &renameJournal{
+				From:        tableInfo.Name,
+				To:          newName,
+				Sequence:    tableschema.AutoIncrementSequenceName(tableInfo.Name, "id"),
+				NewSequence: tableschema.AutoIncrementSequenceName(newName, "id"),
+			}
+			er := utils.WriteFileAtomic(getRenameJournalFilePath(tableInfo.Name), []byte(utils.ToJSON(journal)), base.DataIOFileTempSuffix)
+			if er != nil {
+				t.Errorf("unexpected error: %v", er)
+				return
+			}
+			if c.schemaWritten {
+				newInfo, _ := tableInfo.Clone()
+				newInfo.Name = newName
+				if err := e.saveTableSchemaInfo(newInfo); err != nil {
+					t.Errorf("unexpected error: %v", err)
+					return
+				}
+			}
+			if err := e.Init(); err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+
+			allTable, err := e.AllTable()
+			if _, ok := allTable[c.expectTable]; err != nil || !ok || len(allTable) != 1 {
+				t.Errorf("expected only %s, but got %d tables, %v", c.expectTable, len(allTable), err)
+				return
+			}
+			if exist, _ := e.CheckSequenceExist(tableschema.AutoIncrementSequenceName(c.expectTable, "id")); !exist {
+				t.Error("expected sequence to follow the table name")
+				return
+			}
+			if exist, _ := utils.FileExist(getRenameJournalFilePath(tableInfo.Name)); exist {
+				t.Error("expected rename journal to be removed")
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package core
+
+import (
+	"fmt"
+
+	"ne_database/core/base"
+	"ne_database/core/tableschema"
+	"ne_database/utils"
+)
+
+// canAlterLazily 判断修改表结构后能否不重写已有的数据
+// 除了表结构本身的条件之外，旧版本的页在转换为当前版本后需要仍然能放入一页，所以叶子结点的阶数不能变小:
+// 新增列或者加长列使行变长时，只有页内的空余仍然能放下变长后的行时才在读取时转换，否则重写数据；
+// 写回转换后的结点时不会分裂结点，已经写满的旧版本结点转换后会超过一页
+func canAlterLazily(oldInfo *tableschema.TableMetaInfo, newInfo *tableschema.TableMetaInfo, source map[string]string) (bool, base.StandardError) {
+	if !oldInfo.CanUpgradeLazily(newInfo, source) {
+		return false, nil
+	}
+	oldLeafOrder, _, err := TreeOrder(oldInfo)
+	if err != nil {
+		return false, err
+	}
+	newLeafOrder, _, err := TreeOrder(newInfo)
+	if err != nil {
+		return false, err
+	}
+	return newLeafOrder >= oldLeafOrder, nil
+}
+
+// UpgradeRows 把旧版本写入的叶子结点按照当前版本重写，最多重写 maxPages 个结点，maxPages 小于等于 0 时不限制
+// 返回重写的行数，以及是否还有没有重写的旧版本数据
+func (tree *BPlusTree) UpgradeRows(maxPages int) (int64, bool, base.StandardError) {
+	var (
+		rows    int64
+		pages   int
+		curNode = tree.Root
+		err     base.StandardError
+	)
+	if !tree.TableInfo.RowVersioned() {
+		return 0, false, nil
+	}
+	// 1. 查找最左边的叶子节点
+	for !curNode.IsLeaf {
+		curNode, err = tree.OffsetLoadNode(curNode.KeysOffsetList[0])
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreBPlusTree))(fmt.Sprintf("[BPlusTree.UpgradeRows] tree.OffsetLoadNode 错误: %s", err.Error()))
+			return 0, false, err
+		}
+	}
+
+	// 2. 依次重写包含旧版本数据的叶子节点，加载时数据已经转换为当前版本
+	for {
+		var oldRows int64
+		for _, version := range curNode.DataVersions {
+			if version != tree.TableInfo.Version {
+				oldRows++
+			}
+		}
+		if oldRows > 0 {
+			if maxPages > 0 && pages >= maxPages {
+				return rows, true, nil
+			}
+			nodeByte, err := curNode.NodeToByteData(tree.TableInfo)
+			if err != nil {
+				utils.LogDev(string(base.FunctionModelCoreBPlusTree))(fmt.Sprintf("[BPlusTree.UpgradeRows] curNode.NodeToByteData 错误: %s", err.Error()))
+				return rows, true, err
+			}
+			success, err := tree.DataManager.Writer(curNode.Offset, nodeByte)
+			if err != nil {
+				utils.LogDev(string(base.FunctionModelCoreBPlusTree))(fmt.Sprintf("[BPlusTree.UpgradeRows] Writer 错误: %s", err.Error()))
+				return rows, true, err
+			}
+			if !success {
+				errMsg := fmt.Sprintf("写入offset <%d>失败", curNode.Offset)
+				utils.LogError(fmt.Sprintf("[BPlusTree.UpgradeRows] %s", errMsg))
+				return rows, true, base.NewDBError(base.FunctionModelCoreBPlusTree, base.ErrorTypeIO, base.ErrorBaseCodeIOError, fmt.Errorf(errMsg))
+			}
+			rows += oldRows
+			pages++
+		}
+		if curNode.AfterNodeOffset == base.OffsetNull {
+			break
+		}
+		curNode, err = tree.OffsetLoadNode(curNode.AfterNodeOffset)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreBPlusTree))(fmt.Sprintf("[BPlusTree.UpgradeRows] tree.OffsetLoadNode 错误: %s", err.Error()))
+			return rows, true, err
+		}
+	}
+	return rows, false, nil
+}
+
+// UpgradeTableRows 把表中旧版本写入的数据重写为当前版本，每次最多重写 maxPages 个叶子结点，返回重写的行数
+// 可以在后台分批调用，需要和其他写操作串行执行；全部重写完成后清空表结构中的旧版本
+func (e *Engine) UpgradeTableRows(tableName string, maxPages int) (int64, base.StandardError) {
+	tableName = e.qualifiedName(tableName)
+	tree, err := e.openTable(tableName)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[UpgradeTableRows] openTable错误, %s", err.Error()))
+		return 0, err
+	}
+	if len(tree.TableInfo.History) == 0 {
+		tree.DataManager.Close()
+		return 0, nil
+	}
+	rows, remaining, err := tree.UpgradeRows(maxPages)
+	tree.DataManager.Close()
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[UpgradeTableRows] UpgradeRows错误, %s", err.Error()))
+		return rows, err
+	}
+	if remaining {
+		return rows, nil
+	}
+	tree.TableInfo.History = nil
+	err = e.saveTableSchemaInfo(tree.TableInfo)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[UpgradeTableRows] saveTableSchemaInfo错误, %s", err.Error()))
+		return rows, err
+	}
+	return rows, nil
+}
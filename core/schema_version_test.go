@@ -0,0 +1,166 @@
+package core
+
+import (
+	"fmt"
+	"testing"
+
+	"ne_database/core/base"
+	"ne_database/core/tableschema"
+)
+
+// newTestLazyAlterEngine 页大小为 pageSize 的表，name 和 remark 都是 char(10)，有 total 行数据，占用多个叶子结点
+func newTestLazyAlterEngine(t *testing.T, name string, pageSize int, total int) (*Engine, *tableschema.TableMetaInfo) {
+	t.Helper()
+	tableInfo := newTestTableInfo(name, testCharField("name", 10), testCharField("remark", 10))
+	tableInfo.PrimaryKeyFieldInfo.AutoIncrement = true
+	tableInfo.PageSize = pageSize
+	e := newTestEngine(t, tableInfo)
+	for i := 0; i < total; i++ {
+		insertTestRows(t, e, tableInfo.Name, map[string][]byte{"name": []byte(fmt.Sprintf("user-%d", i)), "remark": []byte("r")})
+	}
+	return e, tableInfo
+}
+
+func TestEngine_AlterTable_Lazy(t *testing.T) {
+	level := &tableschema.FieldInfo{Name: "level", Length: 8, FieldType: tableschema.BigIntType, DefaultValue: "3"}
+	note := testBigIntField("note")
+	// 叶子结点的阶数为 3，新增一个 bigint 列之后仍然为 3 的页大小
+	headerLength := base.DataByteLengthOffset + 1 + base.DataByteLengthInt64 + base.DataByteLengthOffset
+	slackPageSize := headerLength + 3*(8+10+10+8+base.DataByteLengthRowVersion)
+	testCases := []struct {
+		name     string
+		pageSize int
+		items    []*tableschema.AlterTableItem
+		// expectLazy 是否只修改表结构，已有的数据在读取时转换
+		expectLazy bool
+		// expect 修改之后第一行的数据，按列的顺序以逗号连接，Null 为 Null
+		expect string
+	}{
+		// 行没有变长
+		{
+			"drop and add", 256,
+			[]*tableschema.AlterTableItem{
+				{Action: base.AlterTableActionDropColumn, Column: "remark"},
+				{Action: base.AlterTableActionRenameColumn, Column: "name", NewName: "nickname"},
+				{Action: base.AlterTableActionAddColumn, Field: level},
+			},
+			true, "user-0,3",
+		},
+		{
+			"modify length", 256,
+			[]*tableschema.AlterTableItem{{Action: base.AlterTableActionModifyLength, Column: "remark", Length: 5}},
+			false, "user-0,r",
+		},
+		// 行变长，页内的空余能放下变长后的行
+		{
+			"add nullable column with page slack", slackPageSize,
+			[]*tableschema.AlterTableItem{{Action: base.AlterTableActionAddColumn, Field: note}},
+			true, "user-0,r,Null",
+		},
+		// 行变长，叶子结点的阶数变小
+		{
+			"add nullable column", 256,
+			[]*tableschema.AlterTableItem{{Action: base.AlterTableActionAddColumn, Field: note}},
+			false, "user-0,r,Null",
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			e, tableInfo := newTestLazyAlterEngine(t, "engine_lazy_alter_users", c.pageSize, 30)
+			if err := e.AlterTable(tableInfo.Name, c.items); err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			newInfo, err := e.LoadTableSchemaInfo(tableInfo.Name)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if newInfo.Version != 2 || (len(newInfo.History) == 1) != c.expectLazy {
+				t.Errorf("expected lazy %v, but got version %d, %d history", c.expectLazy, newInfo.Version, len(newInfo.History))
+				return
+			}
+			_, rows, err := e.Select(tableInfo.Name, nil)
+			if err != nil || len(rows) != 30 || len(rows[0]) != len(newInfo.ValueFieldInfo)+1 {
+				t.Errorf("unexpected result: %d, %v", len(rows), err)
+				return
+			}
+			r := ""
+			for i, field := range newInfo.ValueFieldInfo {
+				if i > 0 {
+					r += ","
+				}
+				if isNull, _ := field.FieldType.IsNull(rows[0][field.Name]); isNull {
+					r += "Null"
+				} else {
+					r += field.FieldType.StringValue(rows[0][field.Name])
+				}
+			}
+			if r != c.expect {
+				t.Errorf("expected %s, but got %s", c.expect, r)
+				return
+			}
+			// 转换之后的结点可以继续写入
+			insertTestRows(t, e, tableInfo.Name, map[string][]byte{})
+			count, _, err := e.Select(tableInfo.Name, nil)
+			if err != nil || count != 31 {
+				t.Errorf("unexpected result after insert: %d, %v", count, err)
+			}
+		})
+	}
+}
+
+func TestEngine_UpgradeTableRows(t *testing.T) {
+	total := 30
+	e, tableInfo := newTestLazyAlterEngine(t, "engine_upgrade_rows_users", 256, total)
+	err := e.AlterTable(tableInfo.Name, []*tableschema.AlterTableItem{
+		{Action: base.AlterTableActionDropColumn, Column: "remark"},
+		{Action: base.AlterTableActionAddColumn, Field: &tableschema.FieldInfo{Name: "level", Length: 8, FieldType: tableschema.BigIntType, DefaultValue: "3"}},
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	// 新旧版本的数据可以一起读写，插入时写入的结点已经是新版本
+	insertTestRows(t, e, tableInfo.Name, map[string][]byte{"name": []byte("new")})
+
+	// 分批重写旧版本的数据，全部完成后清空旧版本
+	testCases := []struct {
+		maxPages      int
+		expectHistory int
+	}{
+		{1, 1},
+		{0, 0},
+		// 已经没有旧版本的数据
+		{0, 0},
+	}
+	upgraded := int64(0)
+	for i, c := range testCases {
+		rows, err := e.UpgradeTableRows(tableInfo.Name, c.maxPages)
+		if err != nil || (i == 0 && rows == 0) {
+			t.Errorf("case %d: unexpected result: %d, %v", i, rows, err)
+			continue
+		}
+		upgraded += rows
+		newInfo, err := e.LoadTableSchemaInfo(tableInfo.Name)
+		if err != nil || len(newInfo.History) != c.expectHistory {
+			t.Errorf("case %d: expected %d history, but got %d, %v", i, c.expectHistory, len(newInfo.History), err)
+			continue
+		}
+		_, result, err := e.Select(tableInfo.Name, nil)
+		if err != nil || len(result) != total+1 {
+			t.Errorf("case %d: unexpected result: %d, %v", i, len(result), err)
+			continue
+		}
+		for j, row := range result[:total] {
+			if string(row["name"]) != fmt.Sprintf("user-%d", j) || tableschema.BigIntType.StringValue(row["level"]) != "3" || len(row) != 3 {
+				t.Errorf("case %d: row %d: got %#v", i, j, row)
+				break
+			}
+		}
+	}
+	if upgraded > int64(total) {
+		t.Errorf("expected at most %d rows upgraded, but got %d", total, upgraded)
+	}
+}
@@ -0,0 +1,258 @@
+package core
+
+import (
+	"fmt"
+	"os"
+
+	"ne_database/core/base"
+	"ne_database/core/config"
+	"ne_database/core/tableschema"
+	"ne_database/utils"
+)
+
+// sequenceCache 序列预先分配的值，remaining 为还可以使用的数量
+type sequenceCache struct {
+	next      int64
+	increment int64
+	remaining int
+}
+
+func getSequenceFilePath(sequenceName string) string {
+	return getTableFilePath(sequenceName, base.DataIOFileSequenceSuffix)
+}
+
+// CheckSequenceExist 判断序列是否存在
+func (e *Engine) CheckSequenceExist(sequenceName string) (bool, base.StandardError) {
+	return e.sequenceExist(e.qualifiedName(sequenceName))
+}
+
+// sequenceExist 判断序列是否存在，sequenceName 为完整的序列名
+func (e *Engine) sequenceExist(sequenceName string) (bool, base.StandardError) {
+	exist, er := utils.FileExist(getSequenceFilePath(sequenceName))
+	if er != nil {
+		errMsg := fmt.Sprintf("检查序列文件是否存在报错: %s", er.Error())
+		utils.LogError("[Engine CheckSequenceExist] " + errMsg)
+		return false, base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeIO, base.ErrorBaseCodeIOError, fmt.Errorf(errMsg))
+	}
+	return exist, nil
+}
+
+// LoadSequenceInfo 读取序列信息
+func (e *Engine) LoadSequenceInfo(sequenceName string) (*tableschema.SequenceInfo, base.StandardError) {
+	return e.loadSequenceInfo(e.qualifiedName(sequenceName))
+}
+
+// loadSequenceInfo 读取序列信息，sequenceName 为完整的序列名
+func (e *Engine) loadSequenceInfo(sequenceName string) (*tableschema.SequenceInfo, base.StandardError) {
+	bytes, er := os.ReadFile(getSequenceFilePath(sequenceName))
+	if er != nil {
+		errMsg := fmt.Sprintf("读取序列<%s>时发生错误: %s", sequenceName, er.Error())
+		utils.LogError("[Engine LoadSequenceInfo] " + errMsg)
+		return nil, base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeIO, base.ErrorBaseCodeIOError, fmt.Errorf(errMsg))
+	}
+	return tableschema.InitSequenceInfoByJson(string(bytes))
+}
+
+// saveSequenceInfo 持久化序列信息，使用临时文件 + 重命名保证崩溃时不会损坏
+func (e *Engine) saveSequenceInfo(info *tableschema.SequenceInfo) base.StandardError {
+	data, err := info.SequenceInfoToJsonByte()
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[saveSequenceInfo] SequenceInfoToJsonByte错误, %s", err.Error()))
+		return err
+	}
+	er := utils.WriteFileAtomic(getSequenceFilePath(info.Name), data, base.DataIOFileTempSuffix)
+	if er != nil {
+		errMsg := fmt.Sprintf("写入序列<%s>时发生错误: %s", info.Name, er.Error())
+		utils.LogError("[Engine saveSequenceInfo] " + errMsg)
+		return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeIO, base.ErrorBaseCodeIOError, fmt.Errorf(errMsg))
+	}
+	return nil
+}
+
+// CreateSequence 创建序列，序列名没有数据库名时建在当前数据库中
+// 自增主键的序列名是保留的，只能由建表创建
+func (e *Engine) CreateSequence(info *tableschema.SequenceInfo) base.StandardError {
+	if info == nil {
+		errMsg := "输入的sequenceInfo为空"
+		utils.LogError("[Engine CreateSequence] " + errMsg)
+		return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+	}
+	if tableschema.IsAutoIncrementSequenceName(info.Name) {
+		errMsg := fmt.Sprintf("序列名<%s>不能以%s结尾，该后缀保留给自增主键的序列", info.Name, base.AutoIncrementSequenceSeparator+base.AutoIncrementSequenceSuffix)
+		utils.LogError("[Engine CreateSequence] " + errMsg)
+		return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+	}
+	return e.createSequence(info)
+}
+
+// createSequence 创建序列，不检查保留的序列名
+func (e *Engine) createSequence(info *tableschema.SequenceInfo) base.StandardError {
+	info.Name = e.qualifiedName(info.Name)
+	err := info.Verification()
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[CreateSequence] 序列校验错误, %s", err.Error()))
+		return err
+	}
+	_, err = e.nameDatabaseInfo(info.Name)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[CreateSequence] nameDatabaseInfo错误, %s", err.Error()))
+		return err
+	}
+
+	e.sequenceLock.Lock()
+	defer e.sequenceLock.Unlock()
+	exist, err := e.sequenceExist(info.Name)
+	if err != nil {
+		return err
+	}
+	if exist {
+		errMsg := fmt.Sprintf("序列: %s 已存在", info.Name)
+		utils.LogError("[Engine CreateSequence] " + errMsg)
+		return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+	}
+	err = e.saveSequenceInfo(info)
+	if err != nil {
+		return err
+	}
+	return e.catalogSetSequence(info.Name, true)
+}
+
+// DropSequence 删除序列，序列不存在时不做处理
+func (e *Engine) DropSequence(sequenceName string) base.StandardError {
+	sequenceName = e.qualifiedName(sequenceName)
+	e.sequenceLock.Lock()
+	defer e.sequenceLock.Unlock()
+	delete(e.sequenceCache, sequenceName)
+
+	exist, err := e.sequenceExist(sequenceName)
+	if err != nil || !exist {
+		return err
+	}
+	er := os.Remove(getSequenceFilePath(sequenceName))
+	if er != nil {
+		errMsg := fmt.Sprintf("删除序列<%s>发生错误: %s", sequenceName, er.Error())
+		utils.LogError("[Engine DropSequence] " + errMsg)
+		return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeIO, base.ErrorBaseCodeIOError, fmt.Errorf(errMsg))
+	}
+	return e.catalogSetSequence(sequenceName, false)
+}
+
+// NextSequenceValue 获取序列的下一个值
+// 预先分配的值用完时，一次分配 CacheSize 个值并先持久化，之后的值直接从内存中获取
+func (e *Engine) NextSequenceValue(sequenceName string) (int64, base.StandardError) {
+	sequenceName = e.qualifiedName(sequenceName)
+	e.sequenceLock.Lock()
+	defer e.sequenceLock.Unlock()
+
+	if e.sequenceCache == nil {
+		e.sequenceCache = make(map[string]*sequenceCache)
+	}
+	cache, ok := e.sequenceCache[sequenceName]
+	if !ok || cache.remaining == 0 {
+		exist, err := e.sequenceExist(sequenceName)
+		if err != nil {
+			return 0, err
+		}
+		if !exist {
+			errMsg := fmt.Sprintf("序列: %s 不存在", sequenceName)
+			utils.LogError("[Engine NextSequenceValue] " + errMsg)
+			return 0, base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+		}
+		info, err := e.loadSequenceInfo(sequenceName)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[NextSequenceValue] loadSequenceInfo错误, %s", err.Error()))
+			return 0, err
+		}
+		cacheSize := info.CacheSize
+		if cacheSize == 0 {
+			cacheSize = config.CoreConfig.SequenceCacheSize
+		}
+		if cacheSize <= 0 {
+			cacheSize = 1
+		}
+		cache = &sequenceCache{
+			next:      info.Next,
+			increment: info.Increment,
+			remaining: cacheSize,
+		}
+		info.Next += info.Increment * int64(cacheSize)
+		err = e.saveSequenceInfo(info)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[NextSequenceValue] saveSequenceInfo错误, %s", err.Error()))
+			return 0, err
+		}
+		e.sequenceCache[sequenceName] = cache
+	}
+
+	value := cache.next
+	cache.next += cache.increment
+	cache.remaining--
+	return value, nil
+}
+
+// advanceSequence 确保序列之后生成的值都在 value 之后（步长为负数时在 value 之前），用于自增主键插入了显式的值
+// 预先分配的值已经越过 value 时不做处理，否则丢弃预先分配的值，并把 Next 设置为 value 加上步长
+func (e *Engine) advanceSequence(sequenceName string, value int64) base.StandardError {
+	e.sequenceLock.Lock()
+	defer e.sequenceLock.Unlock()
+
+	passed := func(next int64, increment int64) bool {
+		if increment > 0 {
+			return next > value
+		}
+		return next < value
+	}
+	if cache, ok := e.sequenceCache[sequenceName]; ok && cache.remaining > 0 {
+		if passed(cache.next, cache.increment) {
+			return nil
+		}
+		delete(e.sequenceCache, sequenceName)
+	}
+	info, err := e.loadSequenceInfo(sequenceName)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[advanceSequence] loadSequenceInfo错误, %s", err.Error()))
+		return err
+	}
+	if passed(info.Next, info.Increment) {
+		return nil
+	}
+	info.Next = value + info.Increment
+	err = e.saveSequenceInfo(info)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[advanceSequence] saveSequenceInfo错误, %s", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// renameSequence 修改序列名，已经预先分配的值保留
+func (e *Engine) renameSequence(sequenceName string, newSequenceName string) base.StandardError {
+	e.sequenceLock.Lock()
+	defer e.sequenceLock.Unlock()
+
+	info, err := e.loadSequenceInfo(sequenceName)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[renameSequence] loadSequenceInfo错误, %s", err.Error()))
+		return err
+	}
+	info.Name = newSequenceName
+	err = e.saveSequenceInfo(info)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[renameSequence] saveSequenceInfo错误, %s", err.Error()))
+		return err
+	}
+	er := os.Remove(getSequenceFilePath(sequenceName))
+	if er != nil {
+		errMsg := fmt.Sprintf("删除序列<%s>发生错误: %s", sequenceName, er.Error())
+		utils.LogError("[Engine renameSequence] " + errMsg)
+		return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeIO, base.ErrorBaseCodeIOError, fmt.Errorf(errMsg))
+	}
+	if cache, ok := e.sequenceCache[sequenceName]; ok {
+		e.sequenceCache[newSequenceName] = cache
+		delete(e.sequenceCache, sequenceName)
+	}
+	return e.updateCatalog(func(data *catalogData) {
+		delete(data.Sequences, sequenceName)
+		data.Sequences[newSequenceName] = true
+	})
+}
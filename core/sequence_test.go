@@ -0,0 +1,155 @@
+package core
+
+import (
+	"testing"
+
+	"ne_database/core/tableschema"
+)
+
+func TestEngine_CreateSequence(t *testing.T) {
+	e := newTestEngine(t)
+	if err := e.CreateSequence(tableschema.InitSequenceInfo("engine_create_seq", 1, 1)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	defer e.DropSequence("engine_create_seq")
+
+	testCases := []struct {
+		name      string
+		expectErr bool
+	}{
+		{"engine_create_seq_other", false},
+		// 重名
+		{"engine_create_seq", true},
+		// 自增主键的序列名是保留的
+		{tableschema.AutoIncrementSequenceName("engine_other", "id"), true},
+	}
+	for i, c := range testCases {
+		err := e.CreateSequence(tableschema.InitSequenceInfo(c.name, 1, 1))
+		if err == nil && !c.expectErr {
+			_ = e.DropSequence(c.name)
+		}
+		if (err != nil) != c.expectErr {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+		}
+	}
+
+	// 表名和列名中带有分隔符时也不会和其他表的序列重名
+	if tableschema.AutoIncrementSequenceName("a$b", "c") == tableschema.AutoIncrementSequenceName("a", "b$c") {
+		t.Error("AutoIncrementSequenceName collision")
+	}
+}
+
+func TestEngine_NextSequenceValue(t *testing.T) {
+	e := newTestEngine(t)
+	sequenceInfo := tableschema.InitSequenceInfo("engine_test_seq", 10, 5)
+	sequenceInfo.CacheSize = 3
+	if err := e.CreateSequence(sequenceInfo); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	defer e.DropSequence(sequenceInfo.Name)
+
+	// 模拟崩溃重启: 新的 Engine 跳过已经分配但没有使用的值，不会重复
+	restarted := &Engine{}
+	testCases := []struct {
+		engine    *Engine
+		name      string
+		expectErr bool
+		expect    int64
+	}{
+		{e, sequenceInfo.Name, false, 10},
+		{e, sequenceInfo.Name, false, 15},
+		{restarted, sequenceInfo.Name, false, 25},
+		{e, "engine_test_seq_not_exist", true, 0},
+	}
+	for i, c := range testCases {
+		value, err := c.engine.NextSequenceValue(c.name)
+		if (err != nil) != c.expectErr {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+			continue
+		}
+		if value != c.expect {
+			t.Errorf("case %d: expected %d but got %d", i, c.expect, value)
+		}
+		if i == 1 {
+			// 预先分配的值在分配时已经持久化
+			persisted, err := e.LoadSequenceInfo(sequenceInfo.Name)
+			if err != nil || persisted.Next != 25 {
+				t.Errorf("case %d: unexpected persisted sequence: %v, %v", i, persisted, err)
+			}
+		}
+	}
+}
+
+func TestEngine_Insert_AutoIncrement(t *testing.T) {
+	orderNo := testBigIntField("order_no")
+	orderNo.DefaultValue = "NEXTVAL(engine_order_no_seq)"
+	tableInfo := newTestTableInfo("engine_auto_increment_orders", orderNo)
+	tableInfo.PrimaryKeyFieldInfo.AutoIncrement = true
+
+	e := newTestEngine(t)
+	if err := e.CreateSequence(tableschema.InitSequenceInfo("engine_order_no_seq", 1000, 1)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	defer e.DropSequence("engine_order_no_seq")
+	createTestTables(t, e, tableInfo)
+
+	// 按顺序插入，id 为 0 时使用生成的主键，expect 为插入的主键和 order_no
+	testCases := []struct {
+		id     int64
+		expect string
+	}{
+		{0, "1,1000"},
+		{0, "2,1001"},
+		{0, "3,1002"},
+		// 显式插入主键之后，序列越过该值，之后生成的主键不会重复
+		{100, "100,1003"},
+		{0, "101,1004"},
+		// 显式的值小于序列时不影响序列
+		{50, "50,1005"},
+		{0, "102,1006"},
+	}
+	for i, c := range testCases {
+		row := map[string][]byte{}
+		if c.id != 0 {
+			row["id"] = testInt64(c.id)
+		}
+		_, key, err := e.Insert(tableInfo.Name, row)
+		if err != nil {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+			continue
+		}
+		_, rows, err := e.Select(tableInfo.Name, testWhereEqual("id", key))
+		if err != nil || len(rows) != 1 {
+			t.Errorf("case %d: unexpected result: %d, %v", i, len(rows), err)
+			continue
+		}
+		r := tableschema.BigIntType.StringValue(key) + "," + tableschema.BigIntType.StringValue(rows[0]["order_no"])
+		if r != c.expect {
+			t.Errorf("case %d: expected %s, but got %s", i, c.expect, r)
+		}
+	}
+}
+
+func TestEngine_CreateTable_AutoIncrement(t *testing.T) {
+	// 自增只能是 bigint 类型的主键
+	value := testBigIntField("value")
+	value.AutoIncrement = true
+	charKey := newTestTableInfo("engine_auto_increment_error")
+	charKey.PrimaryKeyFieldInfo = testCharField("id", 8)
+	charKey.PrimaryKeyFieldInfo.AutoIncrement = true
+
+	testCases := []*tableschema.TableMetaInfo{
+		newTestTableInfo("engine_auto_increment_error", value),
+		charKey,
+	}
+	e := newTestEngine(t)
+	for i, tableInfo := range testCases {
+		if err := e.CreateTable(tableInfo); err == nil {
+			_ = e.DeleteTable(tableInfo.Name)
+			t.Errorf("case %d: expected error, but got nil", i)
+		}
+	}
+}
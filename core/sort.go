@@ -0,0 +1,334 @@
+package core
+
+import (
+	"container/heap"
+	"fmt"
+	"sort"
+
+	"ne_database/core/base"
+	"ne_database/core/tableschema"
+	"ne_database/utils"
+	"ne_database/utils/set"
+)
+
+// rowSource 按照顺序把每一行（列名 -> 值，包括主键）交给 fn，fn 返回 false 时停止
+type rowSource func(fn func(row map[string][]byte) (bool, base.StandardError)) base.StandardError
+
+// rowSorter 排序算子，行在算子中按照 主键、其他列 的顺序保存为 spillRecord
+// 列之间使用 FieldType 的 Less / Greater 比较，带排序规则的字符列按照排序规则排序；排序是稳定的，比较相等的行保持输入的顺序
+type rowSorter struct {
+	columns    []*tableschema.FieldInfo
+	orderBy    []*base.OrderByItem
+	orderIndex []int              // 排序的列在 columns 中的位置
+	err        base.StandardError // 比较时的第一个错误，排序和堆的比较函数不能返回错误，排序之后检查
+}
+
+func sortError(errMsg string) base.StandardError {
+	utils.LogError("[rowSorter] " + errMsg)
+	return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+}
+
+func newRowSorter(tableInfo *tableschema.TableMetaInfo, orderBy []*base.OrderByItem) (*rowSorter, base.StandardError) {
+	s := &rowSorter{
+		columns: append([]*tableschema.FieldInfo{tableInfo.PrimaryKeyFieldInfo}, tableInfo.ValueFieldInfo...),
+		orderBy: orderBy,
+	}
+	existColumn := set.NewStringsSet()
+	for _, item := range orderBy {
+		if item == nil {
+			return nil, sortError("排序的列为空")
+		}
+		if existColumn.Contain(item.Column) {
+			return nil, sortError(fmt.Sprintf("排序的列<%s>重复", item.Column))
+		}
+		existColumn.Add(item.Column)
+		index := -1
+		for i, field := range s.columns {
+			if field.Name == item.Column {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return nil, sortError(fmt.Sprintf("排序的列<%s>不存在", item.Column))
+		}
+		s.orderIndex = append(s.orderIndex, index)
+	}
+	return s, nil
+}
+
+// primaryKeyOrder 按照主键顺序扫描时是否已经满足排序要求: 没有排序，或者第一个排序的列为主键（主键唯一，之后的列不影响顺序）
+// 第二个返回值为是否需要按照主键倒序扫描
+func primaryKeyOrder(tableInfo *tableschema.TableMetaInfo, orderBy []*base.OrderByItem) (bool, bool) {
+	if len(orderBy) == 0 {
+		return true, false
+	}
+	if orderBy[0] == nil || orderBy[0].Column != tableInfo.PrimaryKeyFieldInfo.Name {
+		return false, false
+	}
+	return true, orderBy[0].Desc
+}
+
+// compareFieldValue 比较两个值，返回 -1 / 0 / 1
+func compareFieldValue(fieldType tableschema.MetaType, data1 []byte, data2 []byte) (int, base.StandardError) {
+	less, err := fieldType.Less(data1, data2)
+	if err != nil {
+		return 0, err
+	}
+	if less {
+		return -1, nil
+	}
+	greater, err := fieldType.Greater(data1, data2)
+	if err != nil {
+		return 0, err
+	}
+	if greater {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// compare 比较两行，出错时记录第一个错误（见 rowSorter.err），这两行视为相等
+func (s *rowSorter) compare(a spillRecord, b spillRecord) int {
+	for i, item := range s.orderBy {
+		index := s.orderIndex[i]
+		c, err := compareFieldValue(s.columns[index].FieldType, a[index], b[index])
+		if err != nil {
+			if s.err == nil {
+				s.err = err
+			}
+			return 0
+		}
+		if item.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func (s *rowSorter) record(row map[string][]byte) spillRecord {
+	r := make(spillRecord, len(s.columns))
+	for i, field := range s.columns {
+		r[i] = row[field.Name]
+	}
+	return r
+}
+
+func (s *rowSorter) row(record spillRecord) map[string][]byte {
+	r := make(map[string][]byte, len(s.columns))
+	for i, field := range s.columns {
+		r[field.Name] = record[i]
+	}
+	return r
+}
+
+// run 排序后跳过 offset 行，最多返回 limit 行（为 0 时不限制）
+// ordered 为 true 时输入已经有序，直接读取需要的行后停止；有 limit 并且 offset + limit 不超过内存限制时使用堆只保留前 N 行；
+// 否则使用外部排序，超过内存限制时写入临时文件
+func (s *rowSorter) run(source rowSource, ordered bool, offset int64, limit int64) ([]map[string][]byte, base.StandardError) {
+	r := make([]map[string][]byte, 0)
+	skipped := int64(0)
+	emit := func(row map[string][]byte) bool {
+		if skipped < offset {
+			skipped++
+			return true
+		}
+		r = append(r, row)
+		return limit == 0 || int64(len(r)) < limit
+	}
+	if ordered {
+		err := source(func(row map[string][]byte) (bool, base.StandardError) {
+			return emit(row), nil
+		})
+		return r, err
+	}
+
+	memoryRows := int64(operatorMemoryRows())
+	if limit > 0 && limit <= memoryRows && offset <= memoryRows-limit {
+		items, err := s.topN(source, offset+limit)
+		if err == nil {
+			err = s.err
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			if !emit(s.row(item.record)) {
+				break
+			}
+		}
+		return r, nil
+	}
+
+	sorter := newExternalSorter(s.compare, int(memoryRows))
+	defer sorter.Close()
+	err := source(func(row map[string][]byte) (bool, base.StandardError) {
+		return true, sorter.Add(s.record(row))
+	})
+	if err != nil {
+		return nil, err
+	}
+	if sorter.Spilled() {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[rowSorter.run] 排序超过内存限制<%d>，使用临时文件", memoryRows))
+	}
+	err = sorter.Each(func(record spillRecord) (bool, base.StandardError) {
+		return s.err == nil && emit(s.row(record)), s.err
+	})
+	if err == nil {
+		err = s.err
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// topN 只保留排序后的前 n 行，返回有序的结果
+func (s *rowSorter) topN(source rowSource, n int64) ([]*topNItem, base.StandardError) {
+	h := &topNHeap{compare: s.compare}
+	seq := int64(0)
+	err := source(func(row map[string][]byte) (bool, base.StandardError) {
+		heap.Push(h, &topNItem{seq: seq, record: s.record(row)})
+		seq++
+		if int64(h.Len()) > n {
+			heap.Pop(h)
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(h.items, func(i, j int) bool {
+		return h.Less(j, i)
+	})
+	return h.items, nil
+}
+
+type topNItem struct {
+	seq    int64 // 输入的顺序，比较相等时先输入的在前
+	record spillRecord
+}
+
+// topNHeap 大顶堆，堆顶为当前排在最后的一行
+type topNHeap struct {
+	compare func(a spillRecord, b spillRecord) int
+	items   []*topNItem
+}
+
+func (h *topNHeap) Len() int {
+	return len(h.items)
+}
+
+func (h *topNHeap) Less(i, j int) bool {
+	c := h.compare(h.items[i].record, h.items[j].record)
+	if c != 0 {
+		return c > 0
+	}
+	return h.items[i].seq > h.items[j].seq
+}
+
+func (h *topNHeap) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+}
+
+func (h *topNHeap) Push(x interface{}) {
+	h.items = append(h.items, x.(*topNItem))
+}
+
+func (h *topNHeap) Pop() interface{} {
+	item := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return item
+}
+
+// SelectQuery 带排序和分页的查询，返回的每一行包括主键
+// 没有排序或者按照主键排序时直接按照B+树的顺序（主键倒序时从最右边的叶子结点倒序）扫描，读取到 Offset + Limit 行后停止；其他排序见 rowSorter.run
+func (e *Engine) SelectQuery(tableName string, query *base.SelectQuery) (int64, []map[string][]byte, base.StandardError) {
+	if query == nil {
+		query = &base.SelectQuery{}
+	}
+	if query.Limit < 0 || query.Offset < 0 {
+		errMsg := fmt.Sprintf("Limit: %d 或 Offset: %d 小于0", query.Limit, query.Offset)
+		utils.LogError("[Engine SelectQuery] " + errMsg)
+		return 0, nil, base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+	}
+	tableName = e.qualifiedName(tableName)
+
+	var (
+		tableInfo *tableschema.TableMetaInfo
+		source    rowSource
+		ordered   bool
+	)
+	if table, ok := catalogTables()[tableName]; ok {
+		rows, err := e.selectCatalogTable(table, query.Where)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[SelectQuery] selectCatalogTable错误, %s", err.Error()))
+			return 0, nil, err
+		}
+		if query.Predicate != nil {
+			r, err := filterWhereNode(&relation{tableInfo: table.TableInfo, rows: rows}, query.Predicate)
+			if err != nil {
+				return 0, nil, err
+			}
+			rows = r.rows
+		}
+		// 虚拟表的数据不保证按照主键的顺序
+		tableInfo = table.TableInfo
+		source = func(fn func(row map[string][]byte) (bool, base.StandardError)) base.StandardError {
+			for _, row := range rows {
+				next, err := fn(row)
+				if err != nil || !next {
+					return err
+				}
+			}
+			return nil
+		}
+	} else {
+		tree, err := e.openTable(tableName)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[SelectQuery] openTable错误, %s", err.Error()))
+			return 0, nil, err
+		}
+		defer tree.DataManager.Close()
+		tableInfo = tree.TableInfo
+		var reverse bool
+		ordered, reverse = primaryKeyOrder(tableInfo, query.OrderBy)
+		source = func(fn func(row map[string][]byte) (bool, base.StandardError)) base.StandardError {
+			scanFn := func(key []byte, values map[string][]byte) (bool, base.StandardError) {
+				values[tableInfo.PrimaryKeyFieldInfo.Name] = key
+				return fn(values)
+			}
+			if reverse {
+				return tree.ScanNodeReverse(base.AndWhereNode(query.Where, query.Predicate), scanFn)
+			}
+			if query.Predicate != nil {
+				return tree.ScanNode(base.AndWhereNode(query.Where, query.Predicate), scanFn)
+			}
+			return tree.Scan(query.Where, scanFn)
+		}
+	}
+
+	if query.Filter != nil {
+		condition, err := tableschema.CompileCondition(tableInfo, query.Filter)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[SelectQuery] CompileCondition错误, %s", err.Error()))
+			return 0, nil, err
+		}
+		source = filterSource(source, condition)
+	}
+
+	sorter, err := newRowSorter(tableInfo, query.OrderBy)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[SelectQuery] newRowSorter错误, %s", err.Error()))
+		return 0, nil, err
+	}
+	rows, err := sorter.run(source, ordered, query.Offset, query.Limit)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[SelectQuery] 排序错误, %s", err.Error()))
+		return 0, nil, err
+	}
+	return int64(len(rows)), rows, nil
+}
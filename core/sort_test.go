@@ -0,0 +1,279 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"ne_database/core/base"
+	"ne_database/core/tableschema"
+	"ne_database/utils"
+)
+
+// newTestSortEngine 成绩表，score 有相同的值用来检查排序是否稳定
+func newTestSortEngine(t *testing.T) (*Engine, *tableschema.TableMetaInfo) {
+	t.Helper()
+	tableInfo := newTestTableInfo("engine_select_query_scores", testCharField("name", 10), testBigIntField("score"))
+	e := newTestEngine(t, tableInfo)
+	data := []struct {
+		id    int64
+		name  string
+		score int64
+	}{{1, "e", 80}, {2, "b", 95}, {3, "g", -5}, {4, "a", 80}, {5, "f", 60}, {6, "c", 95}, {7, "d", 80}}
+	for _, d := range data {
+		insertTestRows(t, e, tableInfo.Name, map[string][]byte{"id": testInt64(d.id), "name": []byte(d.name), "score": testInt64(d.score)})
+	}
+	return e, tableInfo
+}
+
+func TestEngine_SelectQuery(t *testing.T) {
+	e, tableInfo := newTestSortEngine(t)
+
+	byScore := []*base.OrderByItem{{Column: "score", Desc: true}, {Column: "name"}}
+	byScoreStable := []*base.OrderByItem{{Column: "score"}}
+	testCases := []struct {
+		query *base.SelectQuery
+		// memoryRows 大于 0 时限制算子在内存中的行数，每次只归并两个有序段
+		memoryRows int
+		expect     string
+	}{
+		// 多列排序，比较相等时保持主键的顺序
+		{&base.SelectQuery{OrderBy: byScore}, 0, "2,6,4,7,1,5,3"},
+		{&base.SelectQuery{OrderBy: byScoreStable}, 0, "3,5,1,4,7,2,6"},
+		// 前 N 行使用堆，超过内存限制时使用外部排序，结果相同
+		{&base.SelectQuery{OrderBy: byScore, Limit: 3, Offset: 2}, 0, "4,7,1"},
+		{&base.SelectQuery{OrderBy: byScore, Limit: 3, Offset: 2}, 2, "4,7,1"},
+		// 分多趟归并，仍然是稳定的
+		{&base.SelectQuery{OrderBy: byScore}, 2, "2,6,4,7,1,5,3"},
+		{&base.SelectQuery{OrderBy: byScoreStable}, 2, "3,5,1,4,7,2,6"},
+		// 按照主键排序时直接使用B+树的顺序
+		{&base.SelectQuery{
+			Where:   []*base.WherePartItem{{TargetColumn: "id", Operate: base.DataComparatorGreaterAndEqual, Args: [][]byte{testInt64(3)}}},
+			OrderBy: []*base.OrderByItem{{Column: "id"}}, Limit: 2, Offset: 1,
+		}, 0, "4,5"},
+		{&base.SelectQuery{OrderBy: []*base.OrderByItem{{Column: "id", Desc: true}}, Limit: 2}, 0, "7,6"},
+		{&base.SelectQuery{
+			Where:     []*base.WherePartItem{{TargetColumn: "id", Operate: base.DataComparatorLess, Args: [][]byte{testInt64(6)}}},
+			Predicate: &base.WhereNode{Item: &base.WherePartItem{TargetColumn: "score", Operate: base.DataComparatorGreaterAndEqual, Args: [][]byte{testInt64(3)}}},
+			OrderBy:   []*base.OrderByItem{{Column: "id", Desc: true}}, Limit: 2, Offset: 1,
+		}, 0, "4,2"},
+	}
+	for i, c := range testCases {
+		restore := func() {}
+		if c.memoryRows > 0 {
+			restore = setTestOperatorMemory(c.memoryRows, 2)
+		}
+		_, rows, err := e.SelectQuery(tableInfo.Name, c.query)
+		restore()
+		if err != nil {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+			continue
+		}
+		if r := testInt64Values(rows, "id"); r != c.expect {
+			t.Errorf("case %d: expected %s, but got %s", i, c.expect, r)
+		}
+	}
+	checkNoSpillFiles(t)
+}
+
+func TestEngine_SelectQuery_Error(t *testing.T) {
+	e, tableInfo := newTestSortEngine(t)
+	testCases := []*base.SelectQuery{
+		{OrderBy: []*base.OrderByItem{{Column: "unknown"}}},
+		{OrderBy: []*base.OrderByItem{{Column: "name"}, {Column: "name", Desc: true}}},
+		{Limit: -1},
+	}
+	for i, query := range testCases {
+		if _, _, err := e.SelectQuery(tableInfo.Name, query); err == nil {
+			t.Errorf("case %d: expected error, but got nil", i)
+		}
+	}
+}
+
+func TestEngine_SelectQuery_Catalog(t *testing.T) {
+	// 系统目录的虚拟表也可以排序
+	e, tableInfo := newTestSortEngine(t)
+	_, rows, err := e.SelectQuery(base.CatalogTableColumns, &base.SelectQuery{
+		Where:   testWhereEqual("table_name", []byte(tableInfo.Name)),
+		OrderBy: []*base.OrderByItem{{Column: "column_name", Desc: true}},
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if r := testColumnValues(rows, "column_name"); r != "score,name,id" {
+		t.Errorf("expected score,name,id, but got %s", r)
+	}
+}
+
+func TestPrimaryKeyOrder(t *testing.T) {
+	tableInfo := newTestTableInfo("engine_select_query_scores", testCharField("name", 10), testBigIntField("score"))
+	// 主键倒序时倒序扫描叶子结点
+	testCases := []struct {
+		orderBy []*base.OrderByItem
+		ordered bool
+		reverse bool
+	}{
+		{nil, true, false},
+		{[]*base.OrderByItem{{Column: "id"}, {Column: "name"}}, true, false},
+		{[]*base.OrderByItem{{Column: "id", Desc: true}, {Column: "name"}}, true, true},
+		{[]*base.OrderByItem{{Column: "score", Desc: true}, {Column: "name"}}, false, false},
+	}
+	for i, c := range testCases {
+		ordered, reverse := primaryKeyOrder(tableInfo, c.orderBy)
+		if ordered != c.ordered || reverse != c.reverse {
+			t.Errorf("case %d: primaryKeyOrder(%s) expected %v %v, but got %v %v", i, utils.ToJSON(c.orderBy), c.ordered, c.reverse, ordered, reverse)
+		}
+	}
+}
+
+func TestRowSorter_CompareError(t *testing.T) {
+	// 比较出错时返回错误，不视为相等
+	jsonInfo := newTestTableInfo("engine_select_query_json", &tableschema.FieldInfo{Name: "attrs", Length: 20, FieldType: tableschema.JSONType})
+	sorter, err := newRowSorter(jsonInfo, []*base.OrderByItem{{Column: "attrs"}})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	_, err = sorter.run(func(fn func(row map[string][]byte) (bool, base.StandardError)) base.StandardError {
+		for _, attrs := range []string{"{\"a\":1}", "{bad"} {
+			next, err := fn(map[string][]byte{"attrs": []byte(attrs)})
+			if err != nil || !next {
+				return err
+			}
+		}
+		return nil
+	}, false, 0, 0)
+	if err == nil {
+		t.Error("expected compare error, but got nil")
+	}
+}
+
+func TestEngine_SelectQuery_Reverse(t *testing.T) {
+	tableInfo := newTestTableInfo("engine_select_query_reverse", testCharField("name", 10))
+	tableInfo.PrimaryKeyFieldInfo.AutoIncrement = true
+	tableInfo.PageSize = 256
+	e := newTestEngine(t, tableInfo)
+	for i := 0; i < 60; i++ {
+		insertTestRows(t, e, tableInfo.Name, map[string][]byte{"name": []byte(fmt.Sprintf("user-%d", i))})
+	}
+	for _, id := range []int64{1, 17, 18, 40, 60} {
+		if _, err := e.Delete(tableInfo.Name, testWhereEqual("id", testInt64(id))); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// 多个叶子结点时，倒序扫描的结果和升序扫描的结果相反
+	testCases := [][]*base.WherePartItem{
+		nil,
+		{{TargetColumn: "id", Operate: base.DataComparatorLess, Args: [][]byte{testInt64(40)}}},
+		{{TargetColumn: "id", Operate: base.DataComparatorGreater, Args: [][]byte{testInt64(10)}}, {TargetColumn: "id", Operate: base.DataComparatorLessAndEqual, Args: [][]byte{testInt64(50)}}},
+		{{TargetColumn: "id", Operate: base.DataComparatorGreaterAndEqual, Args: [][]byte{testInt64(70)}}},
+	}
+	for i, where := range testCases {
+		_, ascRows, err := e.SelectQuery(tableInfo.Name, &base.SelectQuery{Where: where})
+		if err != nil {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+			continue
+		}
+		_, descRows, err := e.SelectQuery(tableInfo.Name, &base.SelectQuery{Where: where, OrderBy: []*base.OrderByItem{{Column: "id", Desc: true}}})
+		if err != nil {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+			continue
+		}
+		for j, k := 0, len(descRows)-1; j < k; j, k = j+1, k-1 {
+			descRows[j], descRows[k] = descRows[k], descRows[j]
+		}
+		if asc, desc := testInt64Values(ascRows, "id"), testInt64Values(descRows, "id"); asc != desc {
+			t.Errorf("case %d: expected reverse of %s, but got reverse of %s", i, asc, desc)
+		}
+	}
+
+	// 主键上的 or 条件按照范围的倒序扫描，读取到 Limit 行后停止
+	_, rows, err := e.SelectQuery(tableInfo.Name, &base.SelectQuery{
+		Predicate: &base.WhereNode{Logic: base.WhereLogicOr, Children: []*base.WhereNode{
+			{Item: &base.WherePartItem{TargetColumn: "id", Operate: base.DataComparatorLess, Args: [][]byte{testInt64(5)}}},
+			{Item: &base.WherePartItem{TargetColumn: "id", Operate: base.DataComparatorGreater, Args: [][]byte{testInt64(55)}}},
+		}},
+		OrderBy: []*base.OrderByItem{{Column: "id", Desc: true}},
+		Limit:   6,
+	})
+	if err != nil || testInt64Values(rows, "id") != "59,58,57,56,4,3" {
+		t.Errorf("reverse scan with or failed, got %s, %v", testInt64Values(rows, "id"), err)
+	}
+}
+
+// testWhereNode 单个条件的结点
+func testWhereNode(column string, operate base.DataComparator, args ...[]byte) *base.WhereNode {
+	return &base.WhereNode{Item: &base.WherePartItem{TargetColumn: column, Operate: operate, Args: args}}
+}
+
+// testWhereOr 子结点之间为 or 的结点
+func testWhereOr(children ...*base.WhereNode) *base.WhereNode {
+	return &base.WhereNode{Logic: base.WhereLogicOr, Children: children}
+}
+
+// testWhereNot 对子结点取反的结点
+func testWhereNot(child *base.WhereNode) *base.WhereNode {
+	return &base.WhereNode{Logic: base.WhereLogicNot, Children: []*base.WhereNode{child}}
+}
+
+// newTestWhereNodeEngine 50 行数据，name 为 n0 ~ n4
+func newTestWhereNodeEngine(t *testing.T) (*Engine, *tableschema.TableMetaInfo) {
+	t.Helper()
+	items := newTestTableInfo("engine_where_node_items", testCharField("name", 8))
+	e := newTestEngine(t, items)
+	for i := int64(1); i <= 50; i++ {
+		insertTestRows(t, e, items.Name, map[string][]byte{"id": testInt64(i), "name": []byte(fmt.Sprintf("n%d", i%5))})
+	}
+	return e, items
+}
+
+func TestEngine_SelectQuery_Predicate(t *testing.T) {
+	e, items := newTestWhereNodeEngine(t)
+	testCases := []struct {
+		query     *base.SelectQuery
+		expectErr bool
+		expect    string
+	}{
+		// 主键上的 or 合并为多个范围，结果仍然按照主键的顺序
+		{&base.SelectQuery{Predicate: testWhereOr(
+			testWhereNode("id", base.DataComparatorGreater, testInt64(48)),
+			testWhereNode("id", base.DataComparatorLess, testInt64(3)),
+			testWhereNode("id", base.DataComparatorIn, testInt64(20), testInt64(10)),
+			testWhereNode("id", base.DataComparatorBetween, testInt64(9), testInt64(10)),
+		)}, false, "1,2,9,10,20,49,50"},
+		// 包含其他列时扫描全部的数据
+		{&base.SelectQuery{Predicate: testWhereOr(
+			testWhereNode("id", base.DataComparatorEqual, testInt64(1)),
+			testWhereNode("name", base.DataComparatorEqual, []byte("n0")),
+		)}, false, "1,5,10,15,20,25,30,35,40,45,50"},
+		// Where 和 Predicate 之间是 and 的关系
+		{&base.SelectQuery{
+			Where:     []*base.WherePartItem{{TargetColumn: "id", Operate: base.DataComparatorLessAndEqual, Args: [][]byte{testInt64(10)}}},
+			Predicate: testWhereNot(testWhereNode("name", base.DataComparatorEqual, []byte("n0"))),
+		}, false, "1,2,3,4,6,7,8,9"},
+		{&base.SelectQuery{
+			Predicate: testWhereOr(testWhereNode("id", base.DataComparatorLess, testInt64(5)), testWhereNode("id", base.DataComparatorGreater, testInt64(45))),
+			OrderBy:   []*base.OrderByItem{{Column: "id", Desc: true}}, Offset: 1, Limit: 3,
+		}, false, "49,48,47"},
+		// not 需要一个子结点
+		{&base.SelectQuery{Predicate: &base.WhereNode{Logic: base.WhereLogicNot}}, true, ""},
+	}
+	for i, c := range testCases {
+		// 条件树可以序列化为 JSON
+		query := &base.SelectQuery{}
+		if err := json.Unmarshal([]byte(utils.ToJSON(c.query)), query); err != nil {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+			continue
+		}
+		_, rows, err := e.SelectQuery(items.Name, query)
+		if (err != nil) != c.expectErr {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+			continue
+		}
+		if r := testInt64Values(rows, "id"); !c.expectErr && r != c.expect {
+			t.Errorf("case %d: expected %s, but got %s", i, c.expect, r)
+		}
+	}
+}
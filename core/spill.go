@@ -0,0 +1,476 @@
+package core
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"sort"
+
+	"ne_database/core/base"
+	"ne_database/core/config"
+	"ne_database/utils"
+)
+
+// spillRecord 算子之间传递、写入临时文件的一行数据，按照列的顺序保存
+type spillRecord [][]byte
+
+// operatorMemoryRows 算子在内存中最多保存的行数，配置小于等于0时为1
+func operatorMemoryRows() int {
+	if config.CoreConfig.OperatorMemoryRows <= 0 {
+		return 1
+	}
+	return config.CoreConfig.OperatorMemoryRows
+}
+
+// writeSpillRecord 写入一行: 4字节的列数，之后每列为 4字节的长度 + 数据
+func writeSpillRecord(w io.Writer, record spillRecord) error {
+	buf := make([]byte, base.DataByteLengthUint32)
+	binary.BigEndian.PutUint32(buf, uint32(len(record)))
+	_, er := w.Write(buf)
+	if er != nil {
+		return er
+	}
+	for _, column := range record {
+		binary.BigEndian.PutUint32(buf, uint32(len(column)))
+		_, er = w.Write(buf)
+		if er != nil {
+			return er
+		}
+		_, er = w.Write(column)
+		if er != nil {
+			return er
+		}
+	}
+	return nil
+}
+
+// readSpillRecord 读取一行，文件结束时返回 nil, io.EOF
+func readSpillRecord(r io.Reader) (spillRecord, error) {
+	buf := make([]byte, base.DataByteLengthUint32)
+	_, er := io.ReadFull(r, buf)
+	if er != nil {
+		return nil, er
+	}
+	record := make(spillRecord, binary.BigEndian.Uint32(buf))
+	for i := range record {
+		_, er = io.ReadFull(r, buf)
+		if er != nil {
+			return nil, er
+		}
+		record[i] = make([]byte, binary.BigEndian.Uint32(buf))
+		_, er = io.ReadFull(r, record[i])
+		if er != nil {
+			return nil, er
+		}
+	}
+	return record, nil
+}
+
+func spillError(funcName string, errMsg string) base.StandardError {
+	utils.LogError(fmt.Sprintf("[%s] %s", funcName, errMsg))
+	return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeIO, base.ErrorBaseCodeIOError, fmt.Errorf(errMsg))
+}
+
+// spillMergeFanIn 外部排序一次归并最多读取的有序段数量，配置小于2时为2
+func spillMergeFanIn() int {
+	if config.CoreConfig.SpillMergeFanIn < 2 {
+		return 2
+	}
+	return config.CoreConfig.SpillMergeFanIn
+}
+
+// externalSorter 外部排序，内存中最多保存 limit 行，超过时排序后写入一个临时文件（有序段），读取时多路归并
+// 有序段超过 spillMergeFanIn 时先分多趟归并，同时打开的临时文件数量有上限
+// 排序是稳定的: 比较相等的行保持加入的顺序；使用完之后需要调用 Close 删除临时文件
+type externalSorter struct {
+	compare func(a spillRecord, b spillRecord) int
+	limit   int
+	buffer  []spillRecord
+	runs    []string
+}
+
+func newExternalSorter(compare func(a spillRecord, b spillRecord) int, limit int) *externalSorter {
+	if limit <= 0 {
+		limit = 1
+	}
+	return &externalSorter{compare: compare, limit: limit}
+}
+
+// Spilled 是否已经写入过临时文件
+func (s *externalSorter) Spilled() bool {
+	return len(s.runs) > 0
+}
+
+// Add 加入一行
+func (s *externalSorter) Add(record spillRecord) base.StandardError {
+	s.buffer = append(s.buffer, record)
+	if len(s.buffer) >= s.limit {
+		return s.flush()
+	}
+	return nil
+}
+
+func (s *externalSorter) sortBuffer() {
+	sort.SliceStable(s.buffer, func(i, j int) bool {
+		return s.compare(s.buffer[i], s.buffer[j]) < 0
+	})
+}
+
+// writeRun 创建一个新的有序段，write 依次写入其中的行，返回临时文件的路径，出错时删除临时文件
+func writeRun(write func(w io.Writer) error) (string, base.StandardError) {
+	f, er := os.CreateTemp(config.CoreConfig.FileAddr, "sort_*."+base.DataIOFileSpillSuffix)
+	if er != nil {
+		return "", spillError("writeRun", fmt.Sprintf("创建临时文件发生错误: %s", er.Error()))
+	}
+	w := bufio.NewWriter(f)
+	er = write(w)
+	if er == nil {
+		er = w.Flush()
+	}
+	closeEr := f.Close()
+	if er == nil {
+		er = closeEr
+	}
+	if er != nil {
+		_ = os.Remove(f.Name())
+		return "", spillError("writeRun", fmt.Sprintf("写入临时文件 %s 发生错误: %s", f.Name(), er.Error()))
+	}
+	return f.Name(), nil
+}
+
+// flush 把内存中的行排序后写入一个新的有序段
+func (s *externalSorter) flush() base.StandardError {
+	if len(s.buffer) == 0 {
+		return nil
+	}
+	s.sortBuffer()
+	path, err := writeRun(func(w io.Writer) error {
+		for _, record := range s.buffer {
+			er := writeSpillRecord(w, record)
+			if er != nil {
+				return er
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.runs = append(s.runs, path)
+	s.buffer = nil
+	return nil
+}
+
+// mergePass 把相邻的每 fanIn 个有序段归并为一个新的有序段，新的有序段保持原来的先后顺序，排序仍然是稳定的
+func (s *externalSorter) mergePass(fanIn int) base.StandardError {
+	merged := make([]string, 0, (len(s.runs)+fanIn-1)/fanIn)
+	for i := 0; i < len(s.runs); i += fanIn {
+		it, err := mergeRuns(s.runs[i:min(i+fanIn, len(s.runs))], s.compare)
+		if err != nil {
+			s.runs = append(s.runs, merged...)
+			return err
+		}
+		var readErr base.StandardError
+		path, err := writeRun(func(w io.Writer) error {
+			for {
+				record, ok, err := it.Next()
+				if err != nil || !ok {
+					readErr = err
+					return nil
+				}
+				er := writeSpillRecord(w, record)
+				if er != nil {
+					return er
+				}
+			}
+		})
+		it.Close()
+		if err == nil && readErr != nil {
+			_ = os.Remove(path)
+			err = readErr
+		}
+		if err != nil {
+			s.runs = append(s.runs, merged...)
+			return err
+		}
+		merged = append(merged, path)
+	}
+	for _, path := range s.runs {
+		_ = os.Remove(path)
+	}
+	s.runs = merged
+	return nil
+}
+
+// Iterator 按照顺序逐行读取，只能调用一次，之后不能再加入；返回的 sortedIterator 需要先于 externalSorter 关闭
+func (s *externalSorter) Iterator() (*sortedIterator, base.StandardError) {
+	if !s.Spilled() {
+		s.sortBuffer()
+		return &sortedIterator{buffer: s.buffer}, nil
+	}
+	err := s.flush()
+	if err != nil {
+		return nil, err
+	}
+	fanIn := spillMergeFanIn()
+	for len(s.runs) > fanIn {
+		err = s.mergePass(fanIn)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return mergeRuns(s.runs, s.compare)
+}
+
+// Each 按照顺序把每一行交给 fn，fn 返回 false 时停止；只能调用一次
+func (s *externalSorter) Each(fn func(record spillRecord) (bool, base.StandardError)) base.StandardError {
+	it, err := s.Iterator()
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+	for {
+		record, ok, err := it.Next()
+		if err != nil || !ok {
+			return err
+		}
+		next, err := fn(record)
+		if err != nil || !next {
+			return err
+		}
+	}
+}
+
+// Close 删除临时文件
+func (s *externalSorter) Close() {
+	for _, path := range s.runs {
+		_ = os.Remove(path)
+	}
+	s.runs = nil
+	s.buffer = nil
+}
+
+// sortedIterator 外部排序结果的迭代器: 没有写入临时文件时读取内存中排好序的行，否则多路归并有序段
+type sortedIterator struct {
+	buffer []spillRecord
+	files  []*os.File
+	heap   *spillMergeHeap
+	last   *spillMergeItem // 上一次返回的行所在的有序段，下一次读取时前进
+}
+
+// mergeRuns 打开有序段并多路归并，有序段的数量由调用方限制
+func mergeRuns(paths []string, compare func(a spillRecord, b spillRecord) int) (*sortedIterator, base.StandardError) {
+	it := &sortedIterator{heap: &spillMergeHeap{compare: compare}}
+	for i, path := range paths {
+		f, er := os.Open(path)
+		if er != nil {
+			it.Close()
+			return nil, spillError("mergeRuns", fmt.Sprintf("打开临时文件 %s 发生错误: %s", path, er.Error()))
+		}
+		it.files = append(it.files, f)
+		item := &spillMergeItem{run: i, reader: bufio.NewReader(f)}
+		ok, err := item.next()
+		if err != nil {
+			it.Close()
+			return nil, err
+		}
+		if ok {
+			it.heap.items = append(it.heap.items, item)
+		}
+	}
+	heap.Init(it.heap)
+	return it, nil
+}
+
+// Next 读取下一行，没有更多的行时第二个返回值为 false
+func (it *sortedIterator) Next() (spillRecord, bool, base.StandardError) {
+	if it.heap == nil {
+		if len(it.buffer) == 0 {
+			return nil, false, nil
+		}
+		record := it.buffer[0]
+		it.buffer = it.buffer[1:]
+		return record, true, nil
+	}
+	if it.last != nil {
+		ok, err := it.last.next()
+		if err != nil {
+			return nil, false, err
+		}
+		if ok {
+			heap.Fix(it.heap, 0)
+		} else {
+			heap.Pop(it.heap)
+		}
+		it.last = nil
+	}
+	if it.heap.Len() == 0 {
+		return nil, false, nil
+	}
+	it.last = it.heap.items[0]
+	return it.last.record, true, nil
+}
+
+// Close 关闭打开的有序段
+func (it *sortedIterator) Close() {
+	for _, f := range it.files {
+		_ = f.Close()
+	}
+	it.files = nil
+	it.buffer = nil
+}
+
+// hashPartitionCount 哈希连接、哈希聚合写入临时文件时的分区数量
+const hashPartitionCount = 16
+
+// hashPartitions 按照 key（spillRecord 的第一列）的哈希值分区写入的临时文件，每一边（如哈希连接的内表和外表）各有 hashPartitionCount 个文件
+// level 为分区的层数，分区之后再次分区时使用不同的层数，哈希值不同，同一个分区中的行可以分到不同的分区
+type hashPartitions struct {
+	level   int
+	files   [][hashPartitionCount]*os.File
+	writers [][hashPartitionCount]*bufio.Writer
+}
+
+func newHashPartitions(sides int, level int) *hashPartitions {
+	return &hashPartitions{
+		level:   level,
+		files:   make([][hashPartitionCount]*os.File, sides),
+		writers: make([][hashPartitionCount]*bufio.Writer, sides),
+	}
+}
+
+func (p *hashPartitions) index(key []byte) int {
+	h := fnv.New32a()
+	if p.level > 0 {
+		_, _ = h.Write([]byte{byte(p.level)})
+	}
+	_, _ = h.Write(key)
+	return int(h.Sum32() % hashPartitionCount)
+}
+
+func (p *hashPartitions) add(side int, record spillRecord) base.StandardError {
+	partition := p.index(record[0])
+	if p.files[side][partition] == nil {
+		f, er := os.CreateTemp(config.CoreConfig.FileAddr, "hash_*."+base.DataIOFileSpillSuffix)
+		if er != nil {
+			return spillError("hashPartitions.add", fmt.Sprintf("创建临时文件发生错误: %s", er.Error()))
+		}
+		p.files[side][partition] = f
+		p.writers[side][partition] = bufio.NewWriter(f)
+	}
+	er := writeSpillRecord(p.writers[side][partition], record)
+	if er != nil {
+		return spillError("hashPartitions.add", fmt.Sprintf("写入临时文件 %s 发生错误: %s", p.files[side][partition].Name(), er.Error()))
+	}
+	return nil
+}
+
+// finish 写入完成，之后可以读取
+func (p *hashPartitions) finish() base.StandardError {
+	for side := range p.writers {
+		for _, w := range p.writers[side] {
+			if w == nil {
+				continue
+			}
+			er := w.Flush()
+			if er != nil {
+				return spillError("hashPartitions.finish", fmt.Sprintf("写入临时文件发生错误: %s", er.Error()))
+			}
+		}
+	}
+	return nil
+}
+
+// each 依次读取一个分区中的行，fn 返回错误时停止
+func (p *hashPartitions) each(side int, partition int, fn func(record spillRecord) base.StandardError) base.StandardError {
+	f := p.files[side][partition]
+	if f == nil {
+		return nil
+	}
+	_, er := f.Seek(0, io.SeekStart)
+	if er != nil {
+		return spillError("hashPartitions.each", fmt.Sprintf("读取临时文件 %s 发生错误: %s", f.Name(), er.Error()))
+	}
+	reader := bufio.NewReader(f)
+	for {
+		record, er := readSpillRecord(reader)
+		if er == io.EOF {
+			return nil
+		}
+		if er != nil {
+			return spillError("hashPartitions.each", fmt.Sprintf("读取临时文件 %s 发生错误: %s", f.Name(), er.Error()))
+		}
+		err := fn(record)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Close 关闭并删除临时文件
+func (p *hashPartitions) Close() {
+	for side := range p.files {
+		for _, f := range p.files[side] {
+			if f == nil {
+				continue
+			}
+			_ = f.Close()
+			_ = os.Remove(f.Name())
+		}
+	}
+}
+
+// spillMergeItem 归并时每个有序段当前的行
+type spillMergeItem struct {
+	run    int
+	reader *bufio.Reader
+	record spillRecord
+}
+
+func (item *spillMergeItem) next() (bool, base.StandardError) {
+	record, er := readSpillRecord(item.reader)
+	if er == io.EOF {
+		return false, nil
+	}
+	if er != nil {
+		return false, spillError("spillMergeItem.next", fmt.Sprintf("读取临时文件发生错误: %s", er.Error()))
+	}
+	item.record = record
+	return true, nil
+}
+
+// spillMergeHeap 多路归并的小顶堆，比较相等时先写入的有序段在前，保证排序稳定
+type spillMergeHeap struct {
+	compare func(a spillRecord, b spillRecord) int
+	items   []*spillMergeItem
+}
+
+func (h *spillMergeHeap) Len() int {
+	return len(h.items)
+}
+
+func (h *spillMergeHeap) Less(i, j int) bool {
+	c := h.compare(h.items[i].record, h.items[j].record)
+	if c != 0 {
+		return c < 0
+	}
+	return h.items[i].run < h.items[j].run
+}
+
+func (h *spillMergeHeap) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+}
+
+func (h *spillMergeHeap) Push(x interface{}) {
+	h.items = append(h.items, x.(*spillMergeItem))
+}
+
+func (h *spillMergeHeap) Pop() interface{} {
+	item := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return item
+}
@@ -0,0 +1,180 @@
+package tableschema
+
+import (
+	"fmt"
+
+	"ne_database/core/base"
+	"ne_database/utils"
+)
+
+// AlterTableItem ALTER TABLE 的单个操作
+// add_column 使用 Field；drop_column 使用 Column；rename_column 使用 Column 和 NewName；
+// modify_length 使用 Column 和 Length，只支持 char 类型的值；rename_table 使用 NewName
+type AlterTableItem struct {
+	Action  string     `json:"action"`
+	Column  string     `json:"column,omitempty"`
+	NewName string     `json:"new_name,omitempty"`
+	Field   *FieldInfo `json:"field,omitempty"`
+	Length  int        `json:"length,omitempty"`
+}
+
+// Clone 通过 json 深拷贝表结构
+func (info *TableMetaInfo) Clone() (*TableMetaInfo, base.StandardError) {
+	jsonByte, err := info.TableMetaInfoToJsonByte()
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[TableMetaInfo.Clone] TableMetaInfoToJsonByte出错, %s", err.Error()))
+		return nil, err
+	}
+	return InitTableMetaInfoByJson(string(jsonByte))
+}
+
+// renameCheckColumn 修改 CHECK 约束的条件中使用的列名，带有 json 路径的条件保留路径
+func renameCheckColumn(items []*base.WherePartItem, oldName string, newName string) {
+	for _, item := range items {
+		column, path := item.ColumnAndPath()
+		if column != oldName {
+			continue
+		}
+		item.TargetColumn = newName
+		if path != "" {
+			item.TargetColumn = newName + base.SymbolJSONPathSeparator + path
+		}
+	}
+}
+
+// Alter 按顺序执行 ALTER TABLE 的操作，返回修改后的表结构（不修改 info）
+// 第二个返回值为 新列名 -> 原来的列名，新增的列不在其中，用于转换已有的数据
+// 删除的列被表级 CHECK 约束使用时报错，列上的外键和列级 CHECK 约束随列一起删除
+func (info *TableMetaInfo) Alter(items []*AlterTableItem) (*TableMetaInfo, map[string]string, base.StandardError) {
+	alterError := func(errMsg string) base.StandardError {
+		utils.LogError("[TableMetaInfo.Alter] " + errMsg)
+		return base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeInput, base.ErrorBaseCodeTableSchemaError, fmt.Errorf(errMsg))
+	}
+	if len(items) == 0 {
+		return nil, nil, alterError("ALTER TABLE 操作为空")
+	}
+	r, err := info.Clone()
+	if err != nil {
+		return nil, nil, err
+	}
+	source := make(map[string]string, len(r.ValueFieldInfo)+1)
+	for _, field := range append([]*FieldInfo{r.PrimaryKeyFieldInfo}, r.ValueFieldInfo...) {
+		source[field.Name] = field.Name
+	}
+
+	for _, item := range items {
+		if item == nil {
+			return nil, nil, alterError("ALTER TABLE 操作为空")
+		}
+		switch item.Action {
+		case base.AlterTableActionAddColumn:
+			if item.Field == nil || item.Field.Name == "" {
+				return nil, nil, alterError("新增的列为空")
+			}
+			if _, ok := r.FieldInfoByName(item.Field.Name); ok {
+				return nil, nil, alterError(fmt.Sprintf("列<%s>已存在", item.Field.Name))
+			}
+			field := *item.Field
+			r.ValueFieldInfo = append(r.ValueFieldInfo, &field)
+		case base.AlterTableActionDropColumn:
+			if item.Column == r.PrimaryKeyFieldInfo.Name {
+				return nil, nil, alterError(fmt.Sprintf("不能删除主键<%s>", item.Column))
+			}
+			if _, ok := r.FieldInfoByName(item.Column); !ok {
+				return nil, nil, alterError(fmt.Sprintf("列<%s>不存在", item.Column))
+			}
+			for _, constraint := range r.CheckConstraints {
+				for _, i := range constraint.Items() {
+					if column, _ := i.ColumnAndPath(); column == item.Column {
+						return nil, nil, alterError(fmt.Sprintf("列<%s>被CHECK约束<%s>使用，不能删除", item.Column, constraint.Name))
+					}
+				}
+				for _, column := range constraint.Condition.Columns() {
+					if column == item.Column {
+						return nil, nil, alterError(fmt.Sprintf("列<%s>被CHECK约束<%s>使用，不能删除", item.Column, constraint.Name))
+					}
+				}
+			}
+			valueFieldInfo := make([]*FieldInfo, 0, len(r.ValueFieldInfo))
+			for _, field := range r.ValueFieldInfo {
+				if field.Name != item.Column {
+					valueFieldInfo = append(valueFieldInfo, field)
+				}
+			}
+			r.ValueFieldInfo = valueFieldInfo
+			foreignKeys := make([]*ForeignKey, 0, len(r.ForeignKeys))
+			for _, fk := range r.ForeignKeys {
+				if fk.Column != item.Column {
+					foreignKeys = append(foreignKeys, fk)
+				}
+			}
+			r.ForeignKeys = foreignKeys
+			delete(source, item.Column)
+		case base.AlterTableActionRenameColumn:
+			field, ok := r.FieldInfoByName(item.Column)
+			if !ok {
+				return nil, nil, alterError(fmt.Sprintf("列<%s>不存在", item.Column))
+			}
+			if item.NewName == "" {
+				return nil, nil, alterError("新列名为空")
+			}
+			if _, ok := r.FieldInfoByName(item.NewName); ok {
+				return nil, nil, alterError(fmt.Sprintf("列<%s>已存在", item.NewName))
+			}
+			field.Name = item.NewName
+			renameCheckColumn(field.Check.Items(), item.Column, item.NewName)
+			for _, constraint := range r.CheckConstraints {
+				renameCheckColumn(constraint.Items(), item.Column, item.NewName)
+				constraint.Condition.RenameColumn(item.Column, item.NewName)
+			}
+			for _, fk := range r.ForeignKeys {
+				if fk.Column == item.Column {
+					fk.Column = item.NewName
+				}
+				if fk.RefTable == r.Name && fk.RefColumn == item.Column {
+					fk.RefColumn = item.NewName
+				}
+			}
+			if name, ok := source[item.Column]; ok {
+				source[item.NewName] = name
+				delete(source, item.Column)
+			}
+		case base.AlterTableActionModifyLength:
+			if item.Column == r.PrimaryKeyFieldInfo.Name {
+				return nil, nil, alterError(fmt.Sprintf("不能修改主键<%s>的长度", item.Column))
+			}
+			field, ok := r.FieldInfoByName(item.Column)
+			if !ok {
+				return nil, nil, alterError(fmt.Sprintf("列<%s>不存在", item.Column))
+			}
+			if field.FieldType.GetType() != base.DBDataTypeChar || item.Length <= 0 {
+				return nil, nil, alterError(fmt.Sprintf("只能修改char类型的列长度，并且长度需要大于0: %s, %d", item.Column, item.Length))
+			}
+			field.Length = item.Length
+		case base.AlterTableActionRenameTable:
+			if item.NewName == "" {
+				return nil, nil, alterError("新表名为空")
+			}
+			// 新表名没有数据库名时在原来的数据库中，不能移动到其他数据库
+			newName := QualifiedTableName(DatabaseName(r.Name), item.NewName)
+			if DatabaseName(newName) != DatabaseName(r.Name) {
+				return nil, nil, alterError(fmt.Sprintf("表<%s>不能移动到其他数据库: %s", r.Name, newName))
+			}
+			for _, fk := range r.ForeignKeys {
+				if fk.RefTable == r.Name {
+					fk.RefTable = newName
+				}
+			}
+			r.Name = newName
+		default:
+			return nil, nil, alterError(fmt.Sprintf("不支持的 ALTER TABLE 操作: %s", item.Action))
+		}
+	}
+
+	err = r.Verification()
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[TableMetaInfo.Alter] 修改后的表校验错误, %s", err.Error()))
+		return nil, nil, err
+	}
+	return r, source, nil
+}
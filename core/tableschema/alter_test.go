@@ -0,0 +1,56 @@
+package tableschema
+
+import (
+	"testing"
+
+	"ne_database/core/base"
+)
+
+func TestTableMetaInfo_Alter(t *testing.T) {
+	tableInfo := checkTestTableInfo()
+	newInfo, source, err := tableInfo.Alter([]*AlterTableItem{
+		{Action: base.AlterTableActionRenameColumn, Column: "status", NewName: "state"},
+		{Action: base.AlterTableActionAddColumn, Field: &FieldInfo{Name: "remark", Length: 10, FieldType: CharType}},
+		{Action: base.AlterTableActionDropColumn, Column: "qty"},
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if tableInfo.ValueFieldInfo[2].Name != "status" {
+		t.Error("Alter should not modify the original table info")
+		return
+	}
+	if source["state"] != "status" || source["discount"] != "discount" {
+		t.Errorf("Alter source failed, got %#v", source)
+		return
+	}
+	if _, ok := source["remark"]; ok {
+		t.Error("Alter source failed, added column should not have source")
+		return
+	}
+	if _, ok := source["qty"]; ok {
+		t.Error("Alter source failed, dropped column should not have source")
+		return
+	}
+	if newInfo.CheckConstraints[0].Expression[0][0].TargetColumn != "state" {
+		t.Error("Alter failed, CHECK constraint not renamed")
+		return
+	}
+
+	testCases := [][]*AlterTableItem{
+		nil,
+		{{Action: base.AlterTableActionDropColumn, Column: "id"}},
+		{{Action: base.AlterTableActionDropColumn, Column: "status"}},
+		{{Action: base.AlterTableActionRenameColumn, Column: "qty", NewName: "discount"}},
+		{{Action: base.AlterTableActionModifyLength, Column: "qty", Length: 20}},
+		{{Action: base.AlterTableActionAddColumn, Field: &FieldInfo{Name: "qty", Length: 8, FieldType: BigIntType}}},
+		{{Action: "truncate"}},
+	}
+	for i, c := range testCases {
+		_, _, err = tableInfo.Alter(c)
+		if err == nil {
+			t.Errorf("case %d: expected error, but got nil", i)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package tableschema
+
+import (
+	"fmt"
+
+	"ne_database/core/base"
+	"ne_database/utils"
+	"ne_database/utils/set"
+)
+
+// CheckExpression CHECK 约束的条件，外层之间是 or 的关系，内层之间是 and 的关系
+// 如: [[a > 0, a < 10], [a = -1]] 为 (a > 0 and a < 10) or a = -1；需要 not 时使用 CheckConstraint 的 Predicate
+type CheckExpression [][]*base.WherePartItem
+
+// Validation 条件校验，不能为空，每个 and 的条件列表也不能为空
+func (expr CheckExpression) Validation() bool {
+	if len(expr) == 0 {
+		return false
+	}
+	for _, items := range expr {
+		if len(items) == 0 {
+			return false
+		}
+		for _, item := range items {
+			if item == nil || !item.Validation() {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Items 条件中的全部单个条件
+func (expr CheckExpression) Items() []*base.WherePartItem {
+	r := make([]*base.WherePartItem, 0)
+	for _, items := range expr {
+		r = append(r, items...)
+	}
+	return r
+}
+
+// WhereNode 转换为 or 的条件树，每个子节点为 and 的条件
+func (expr CheckExpression) WhereNode() *base.WhereNode {
+	r := &base.WhereNode{Logic: base.WhereLogicOr, Children: make([]*base.WhereNode, 0, len(expr))}
+	for _, items := range expr {
+		r.Children = append(r.Children, base.AndWhereNode(items, nil))
+	}
+	return r
+}
+
+// CheckConstraint 表级 CHECK 约束，写入的数据需要满足 Expression、Predicate 或者 Condition（只能设置一个）
+// Predicate 为条件树，可以使用 not；三种方式的结果为 Null 时都视为满足，和 SQL 一致
+// Expression 和 Predicate 中除了 is_null、is_not_null，使用的列为 Null 时条件的结果为 Null，按照三值逻辑组合
+type CheckConstraint struct {
+	Name       string           `json:"name"`
+	Expression CheckExpression  `json:"expression"`
+	Predicate  *base.WhereNode  `json:"predicate,omitempty"`
+	Condition  *base.Expression `json:"condition,omitempty"`
+}
+
+// CheckConstraintName 列级 CHECK 约束的名称，为: 表名_列名_check
+func CheckConstraintName(tableName string, columnName string) string {
+	return fmt.Sprintf("%s_%s_%s", tableName, columnName, base.CheckConstraintSuffix)
+}
+
+// AllCheckConstraints 表的全部 CHECK 约束，列级约束在前，表级约束在后
+func (info *TableMetaInfo) AllCheckConstraints() []*CheckConstraint {
+	r := make([]*CheckConstraint, 0, len(info.CheckConstraints))
+	fields := append([]*FieldInfo{info.PrimaryKeyFieldInfo}, info.ValueFieldInfo...)
+	for _, field := range fields {
+		if field != nil && field.Check != nil {
+			r = append(r, &CheckConstraint{Name: CheckConstraintName(info.Name, field.Name), Expression: field.Check})
+		}
+	}
+	return append(r, info.CheckConstraints...)
+}
+
+// checkConstraintVerification 建表时校验 CHECK 约束
+// 约束名不能为空或重复，表达式中的列需要存在，列级约束只能使用本列；每个条件都会对全 Null 的数据判断一次，用来提前发现参数错误
+func (info *TableMetaInfo) checkConstraintVerification() base.StandardError {
+	constraintError := func(errMsg string) base.StandardError {
+		utils.LogError("[checkConstraintVerification] " + errMsg)
+		return base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeType, base.ErrorBaseCodeTableSchemaError, fmt.Errorf(errMsg))
+	}
+
+	nullRow := make(map[string][]byte, len(info.ValueFieldInfo)+1)
+	fields := append([]*FieldInfo{info.PrimaryKeyFieldInfo}, info.ValueFieldInfo...)
+	for _, field := range fields {
+		nullRow[field.Name] = field.NullValue()
+	}
+	columnByConstraint := make(map[string]string)
+	for _, field := range fields {
+		if field.Check != nil {
+			columnByConstraint[CheckConstraintName(info.Name, field.Name)] = field.Name
+		}
+	}
+
+	existName := set.NewStringsSet()
+	cache := NewRegexpCache()
+	for _, constraint := range info.AllCheckConstraints() {
+		if constraint == nil || constraint.Name == "" {
+			return constraintError("CHECK约束名为空")
+		}
+		if existName.Contain(constraint.Name) {
+			return constraintError(fmt.Sprintf("CHECK约束名<%s>重复", constraint.Name))
+		}
+		existName.Add(constraint.Name)
+		count := 0
+		for _, ok := range []bool{constraint.Expression != nil, constraint.Predicate != nil, constraint.Condition != nil} {
+			if ok {
+				count++
+			}
+		}
+		if count > 1 {
+			return constraintError(fmt.Sprintf("CHECK约束<%s>只能设置Expression、Predicate和Condition中的一个", constraint.Name))
+		}
+		if constraint.Condition != nil {
+			_, err := CompileCondition(info, constraint.Condition)
+			if err != nil {
+				utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[checkConstraintVerification] CHECK约束<%s>的条件错误: %s", constraint.Name, err.Error()))
+				return err
+			}
+			continue
+		}
+		if (constraint.Predicate != nil && !constraint.Predicate.Validation()) || (constraint.Predicate == nil && !constraint.Expression.Validation()) {
+			return constraintError(fmt.Sprintf("CHECK约束<%s>的表达式不合法: %s", constraint.Name, constraint.Definition()))
+		}
+		for _, item := range constraint.Items() {
+			column, _ := item.ColumnAndPath()
+			if _, ok := info.FieldInfoByName(column); !ok {
+				return constraintError(fmt.Sprintf("CHECK约束<%s>使用的列<%s>不存在", constraint.Name, column))
+			}
+			if ownColumn, ok := columnByConstraint[constraint.Name]; ok && column != ownColumn {
+				return constraintError(fmt.Sprintf("列级CHECK约束<%s>只能使用列<%s>，不能使用列<%s>", constraint.Name, ownColumn, column))
+			}
+			_, err := info.MatchWherePartItem(item, nullRow, cache)
+			if err != nil {
+				utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[checkConstraintVerification] CHECK约束<%s>的条件错误: %s", constraint.Name, err.Error()))
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// CheckRow 判断写入的一行数据是否满足全部 CHECK 约束，不满足时返回的错误中包含约束名
+// row 为 列名 -> 值，需要包含主键和全部的值
+func (info *TableMetaInfo) CheckRow(row map[string][]byte) base.StandardError {
+	cache := NewRegexpCache()
+	for _, constraint := range info.AllCheckConstraints() {
+		match, err := constraint.match(info, row, cache)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[TableMetaInfo.CheckRow] CHECK约束<%s>判断错误: %s", constraint.Name, err.Error()))
+			return err
+		}
+		if !match {
+			errMsg := fmt.Sprintf("违反CHECK约束<%s>", constraint.Name)
+			utils.LogError("[TableMetaInfo.CheckRow] " + errMsg)
+			return base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeInput, base.ErrorBaseCodeConstraintError, fmt.Errorf(errMsg))
+		}
+	}
+	return nil
+}
+
+// Definition 约束的定义，为 Expression、Predicate 或者 Condition 的 JSON
+func (constraint *CheckConstraint) Definition() string {
+	if constraint.Condition != nil {
+		return utils.ToJSON(constraint.Condition)
+	}
+	if constraint.Predicate != nil {
+		return utils.ToJSON(constraint.Predicate)
+	}
+	return utils.ToJSON(constraint.Expression)
+}
+
+// Items Expression 或者 Predicate 中的全部单个条件
+func (constraint *CheckConstraint) Items() []*base.WherePartItem {
+	if constraint.Predicate != nil {
+		return constraint.Predicate.Items()
+	}
+	return constraint.Expression.Items()
+}
+
+// match 一行数据是否满足约束，结果为 Null 时满足
+func (constraint *CheckConstraint) match(info *TableMetaInfo, row map[string][]byte, cache RegexpCache) (bool, base.StandardError) {
+	if constraint.Condition == nil {
+		node := constraint.Predicate
+		if node == nil {
+			node = constraint.Expression.WhereNode()
+		}
+		match, unknown, err := info.matchCheckNode(node, row, cache)
+		if err != nil {
+			return false, err
+		}
+		return match || unknown, nil
+	}
+	condition, err := CompileCondition(info, constraint.Condition)
+	if err != nil {
+		return false, err
+	}
+	v, err := condition.Evaluate(row)
+	if err != nil {
+		return false, err
+	}
+	return v.IsNull() || v.Bool, nil
+}
+
+// matchCheckNode 按照 SQL 的三值逻辑判断条件树，unknown 为 true 时结果为 Null
+// and 有 false 时为 false，or 有 true 时为 true，否则有 Null 时为 Null；not Null 仍然为 Null
+func (info *TableMetaInfo) matchCheckNode(node *base.WhereNode, row map[string][]byte, cache RegexpCache) (bool, bool, base.StandardError) {
+	switch node.Logic {
+	case base.WhereLogicAnd, base.WhereLogicOr:
+		shortCircuit := node.Logic == base.WhereLogicOr
+		unknown := false
+		for _, child := range node.Children {
+			match, childUnknown, err := info.matchCheckNode(child, row, cache)
+			if err != nil {
+				return false, false, err
+			}
+			if childUnknown {
+				unknown = true
+				continue
+			}
+			if match == shortCircuit {
+				return shortCircuit, false, nil
+			}
+		}
+		if unknown {
+			return false, true, nil
+		}
+		return !shortCircuit, false, nil
+	case base.WhereLogicNot:
+		match, unknown, err := info.matchCheckNode(node.Children[0], row, cache)
+		if err != nil || unknown {
+			return false, unknown, err
+		}
+		return !match, false, nil
+	default:
+		item := node.Item
+		if item.Operate != base.DataComparatorIsNull && item.Operate != base.DataComparatorIsNotNull {
+			column, _ := item.ColumnAndPath()
+			field, fieldOk := info.FieldInfoByName(column)
+			value, valueOk := row[column]
+			if fieldOk && valueOk {
+				isNull, err := field.FieldType.IsNull(field.FieldType.TrimRaw(value))
+				if err != nil {
+					utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[TableMetaInfo.matchCheckNode.IsNull] err: %s", err.Error()))
+					return false, false, err
+				}
+				if isNull {
+					return false, true, nil
+				}
+			}
+		}
+		match, err := info.MatchWherePartItem(item, row, cache)
+		return match, false, err
+	}
+}
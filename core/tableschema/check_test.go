@@ -0,0 +1,191 @@
+package tableschema
+
+import (
+	"strings"
+	"testing"
+
+	"ne_database/core/base"
+)
+
+func checkTestTableInfo() *TableMetaInfo {
+	zero, _ := base.Int64ToByteList(0)
+	fifty, _ := base.Int64ToByteList(50)
+	hundred, _ := base.Int64ToByteList(100)
+	return &TableMetaInfo{
+		Name: "orders",
+		PrimaryKeyFieldInfo: &FieldInfo{
+			Name:      "id",
+			Length:    8,
+			FieldType: BigIntType,
+		},
+		ValueFieldInfo: []*FieldInfo{
+			{
+				Name:      "qty",
+				Length:    8,
+				FieldType: BigIntType,
+				Check: CheckExpression{{
+					{TargetColumn: "qty", Operate: base.DataComparatorGreater, Args: [][]byte{zero}},
+				}},
+			},
+			{Name: "discount", Length: 8, FieldType: BigIntType},
+			{Name: "status", Length: 10, FieldType: CharType},
+		},
+		PageSize:    1000,
+		StorageType: base.StorageTypeMemory,
+		CheckConstraints: []*CheckConstraint{
+			{
+				Name: "status_discount",
+				// status in (new, paid, vip) and (status = vip or discount not in (100))
+				Expression: CheckExpression{
+					{
+						{TargetColumn: "status", Operate: base.DataComparatorIn, Args: [][]byte{[]byte("new"), []byte("paid"), []byte("vip")}},
+						{TargetColumn: "status", Operate: base.DataComparatorEqual, Args: [][]byte{[]byte("vip")}},
+					},
+					{
+						{TargetColumn: "status", Operate: base.DataComparatorIn, Args: [][]byte{[]byte("new"), []byte("paid"), []byte("vip")}},
+						{TargetColumn: "discount", Operate: base.DataComparatorNotIn, Args: [][]byte{hundred}},
+					},
+				},
+			},
+			{
+				Name: "new_discount",
+				// not (status = new and discount > 50)
+				Predicate: &base.WhereNode{Logic: base.WhereLogicNot, Children: []*base.WhereNode{
+					base.AndWhereNode([]*base.WherePartItem{
+						{TargetColumn: "status", Operate: base.DataComparatorEqual, Args: [][]byte{[]byte("new")}},
+						{TargetColumn: "discount", Operate: base.DataComparatorGreater, Args: [][]byte{fifty}},
+					}, nil),
+				}},
+			},
+		},
+	}
+}
+
+func TestTableMetaInfo_CheckRow(t *testing.T) {
+	tableInfo := checkTestTableInfo()
+	err := tableInfo.Verification()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	id, _ := base.Int64ToByteList(1)
+	qty, _ := base.Int64ToByteList(2)
+	minus, _ := base.Int64ToByteList(-1)
+	zero, _ := base.Int64ToByteList(0)
+	sixty, _ := base.Int64ToByteList(60)
+	hundred, _ := base.Int64ToByteList(100)
+	// bigint 的 0 和空的 char 为 Null
+	testCases := []struct {
+		qty        []byte
+		discount   []byte
+		status     string
+		constraint string
+	}{
+		{qty, sixty, "paid", ""},
+		{qty, hundred, "vip", ""},
+		{minus, zero, "new", "orders_qty_check"},
+		{qty, zero, "refund", "status_discount"},
+		{qty, hundred, "paid", "status_discount"},
+		{qty, sixty, "new", "new_discount"},
+		// 使用的列为 Null 时条件的结果为 Null，视为满足
+		{zero, sixty, "paid", ""},
+		{qty, hundred, "", ""},
+		// not 的子条件为 Null 时结果仍然为 Null
+		{qty, zero, "new", ""},
+	}
+	for i, c := range testCases {
+		status, _ := CharType.LengthPadding([]byte(c.status), 10)
+		err = tableInfo.CheckRow(map[string][]byte{"id": id, "qty": c.qty, "discount": c.discount, "status": status})
+		if c.constraint == "" {
+			if err != nil {
+				t.Errorf("case %d: unexpected error: %v", i, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), c.constraint) {
+			t.Errorf("case %d: expected error with constraint %s, but got %v", i, c.constraint, err)
+		}
+	}
+
+	// 储存后可以还原
+	jsonByte, err := tableInfo.TableMetaInfoToJsonByte()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	loadInfo, err := InitTableMetaInfoByJson(string(jsonByte))
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if !loadInfo.CompareTableInfo(tableInfo) {
+		t.Error("CheckConstraints json failed")
+		return
+	}
+}
+
+func TestTableMetaInfo_CheckConstraintVerification(t *testing.T) {
+	// 使用不存在的列
+	tableInfo := checkTestTableInfo()
+	tableInfo.CheckConstraints[0].Expression[0][0].TargetColumn = "price"
+	if tableInfo.Verification() == nil {
+		t.Error("expected error, but got nil")
+	}
+
+	// 列级约束使用其他列
+	tableInfo = checkTestTableInfo()
+	tableInfo.ValueFieldInfo[0].Check[0][0].TargetColumn = "discount"
+	if tableInfo.Verification() == nil {
+		t.Error("expected error, but got nil")
+	}
+
+	// 约束名重复
+	tableInfo = checkTestTableInfo()
+	tableInfo.CheckConstraints[0].Name = "orders_qty_check"
+	if tableInfo.Verification() == nil {
+		t.Error("expected error, but got nil")
+	}
+
+	// and 的条件列表不能为空
+	tableInfo = checkTestTableInfo()
+	tableInfo.CheckConstraints[0].Expression = append(tableInfo.CheckConstraints[0].Expression, []*base.WherePartItem{})
+	if tableInfo.Verification() == nil {
+		t.Error("expected error, but got nil")
+	}
+
+	// 参数错误在建表时发现
+	tableInfo = checkTestTableInfo()
+	tableInfo.CheckConstraints = append(tableInfo.CheckConstraints, &CheckConstraint{
+		Name:       "status_pattern",
+		Expression: CheckExpression{{{TargetColumn: "status", Operate: base.DataComparatorRegexp, Args: [][]byte{[]byte("(new")}}}},
+	})
+	if tableInfo.Verification() == nil {
+		t.Error("expected error, but got nil")
+	}
+}
+
+func TestTableMetaInfo_CheckConstraintVerification_Predicate(t *testing.T) {
+	zero, _ := base.Int64ToByteList(0)
+	testCases := []*CheckConstraint{
+		// not 只能有一个子节点
+		{Name: "not_children", Predicate: &base.WhereNode{Logic: base.WhereLogicNot, Children: []*base.WhereNode{
+			{Item: &base.WherePartItem{TargetColumn: "qty", Operate: base.DataComparatorGreater, Args: [][]byte{zero}}},
+			{Item: &base.WherePartItem{TargetColumn: "discount", Operate: base.DataComparatorGreater, Args: [][]byte{zero}}},
+		}}},
+		// 使用不存在的列
+		{Name: "unknown_column", Predicate: &base.WhereNode{Logic: base.WhereLogicNot, Children: []*base.WhereNode{
+			{Item: &base.WherePartItem{TargetColumn: "price", Operate: base.DataComparatorGreater, Args: [][]byte{zero}}},
+		}}},
+		// 只能设置 Expression、Predicate 和 Condition 中的一个
+		{Name: "both", Expression: CheckExpression{{{TargetColumn: "qty", Operate: base.DataComparatorGreater, Args: [][]byte{zero}}}},
+			Predicate: &base.WhereNode{Item: &base.WherePartItem{TargetColumn: "qty", Operate: base.DataComparatorGreater, Args: [][]byte{zero}}}},
+	}
+	for i, constraint := range testCases {
+		tableInfo := checkTestTableInfo()
+		tableInfo.CheckConstraints = append(tableInfo.CheckConstraints, constraint)
+		if tableInfo.Verification() == nil {
+			t.Errorf("case %d: expected error, but got nil", i)
+		}
+	}
+}
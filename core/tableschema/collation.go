@@ -0,0 +1,91 @@
+package tableschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+
+	"ne_database/core/base"
+	"ne_database/utils"
+	"ne_database/utils/set"
+)
+
+// unicodeCollatorPool collate.Collator 不是并发安全的，这里使用 pool 复用
+var unicodeCollatorPool = sync.Pool{
+	New: func() interface{} {
+		return collate.New(language.Und)
+	},
+}
+
+// CollationVerification 校验排序规则是否支持，空值等同于 base.CollationBinary
+func CollationVerification(collation base.Collation) base.StandardError {
+	totalCollation := set.NewStringsSet("", string(base.CollationBinary), string(base.CollationCaseInsensitive), string(base.CollationUnicode))
+	if !totalCollation.Contain(string(collation)) {
+		utils.LogError(fmt.Sprintf("[CollationVerification] 不支持的排序规则: %s", collation))
+		return base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf("不支持的排序规则: %s", collation))
+	}
+	return nil
+}
+
+// foldCase Unicode case folding，比 strings.ToLower 更适合用于忽略大小写的比较（如: ß 和 SS）
+func foldCase(data []byte) []byte {
+	return cases.Fold().Bytes(data)
+}
+
+// collationCompare 按排序规则比较两个值，返回 -1 / 0 / 1
+func collationCompare(collation base.Collation, data1 []byte, data2 []byte) int {
+	switch collation {
+	case base.CollationCaseInsensitive:
+		return bytes.Compare(foldCase(data1), foldCase(data2))
+	case base.CollationUnicode:
+		c := unicodeCollatorPool.Get().(*collate.Collator)
+		defer unicodeCollatorPool.Put(c)
+		return c.Compare(data1, data2)
+	default:
+		return bytes.Compare(data1, data2)
+	}
+}
+
+// collationKey 按排序规则生成可以直接按字节比较的 key
+// 对任意两个值，bytes.Compare(collationKey(a), collationKey(b)) 和 collationCompare(a, b) 的结果一致
+func collationKey(collation base.Collation, data []byte) []byte {
+	switch collation {
+	case base.CollationCaseInsensitive:
+		return foldCase(data)
+	case base.CollationUnicode:
+		c := unicodeCollatorPool.Get().(*collate.Collator)
+		defer unicodeCollatorPool.Put(c)
+		buf := collate.Buffer{}
+		key := c.Key(&buf, data)
+		return append(make([]byte, 0, len(key)), key...)
+	default:
+		return data
+	}
+}
+
+// IndexKey 获取值用于索引、排序、分组的 key
+// 相等（Equal）的两个值得到的 key 一定相同，对于带排序规则的字符类型，key 之间按字节比较的结果和 Greater / Less 一致
+func IndexKey(fieldType MetaType, data []byte) []byte {
+	data = fieldType.TrimRaw(data)
+	switch t := fieldType.(type) {
+	case charType:
+		return collationKey(t.collation, data)
+	case jsonType:
+		// json 对象的键重新排序，保证语义相同的文档 key 相同
+		value, err := decodeJSONValue(data)
+		if err != nil {
+			return data
+		}
+		key, er := json.Marshal(value)
+		if er != nil {
+			return data
+		}
+		return key
+	}
+	return data
+}
@@ -0,0 +1,134 @@
+package tableschema
+
+import (
+	"bytes"
+	"sort"
+	"testing"
+
+	"ne_database/core/base"
+)
+
+func TestCharType_Collation(t *testing.T) {
+	binary := NewCharType(base.CollationBinary)
+	caseInsensitive := NewCharType(base.CollationCaseInsensitive)
+	unicode := NewCharType(base.CollationUnicode)
+
+	if binary != CharType {
+		t.Error("binary collation should be same as CharType")
+		return
+	}
+
+	// 按字节比较时大小写不同
+	equal, _ := binary.Equal([]byte("Straße"), []byte("STRASSE"))
+	if equal {
+		t.Error("binary collation expected not equal")
+		return
+	}
+	// case folding 之后 ß 与 SS 相同
+	equal, _ = caseInsensitive.Equal([]byte("Straße"), []byte("STRASSE"))
+	if !equal {
+		t.Error("case_insensitive collation expected equal")
+		return
+	}
+	equal, _ = caseInsensitive.Equal([]byte("안녕하세요"), []byte("안녕하세요\x00\x00"))
+	if !equal {
+		t.Error("case_insensitive collation expected equal")
+		return
+	}
+
+	// 按字节比较时 é (0xC3 0xA9) 排在 f 之后，Unicode 排序规则中排在 f 之前
+	greater, _ := binary.Greater([]byte("é"), []byte("f"))
+	if !greater {
+		t.Error("binary collation expected greater")
+		return
+	}
+	less, _ := unicode.Less([]byte("é"), []byte("f"))
+	if !less {
+		t.Error("unicode collation expected less")
+		return
+	}
+	// Unicode 排序规则中大小写只在最后一级比较
+	less, _ = unicode.Less([]byte("apple"), []byte("Banana"))
+	if !less {
+		t.Error("unicode collation expected less")
+		return
+	}
+
+	// IndexKey 按字节比较的结果需要和排序规则一致
+	words := []string{"zebra", "Émile", "apple", "Banana", "éclair", "日本語", "안녕"}
+	for _, fieldType := range []MetaType{binary, caseInsensitive, unicode} {
+		byLess := append([]string{}, words...)
+		sort.SliceStable(byLess, func(i, j int) bool {
+			r, _ := fieldType.Less([]byte(byLess[i]), []byte(byLess[j]))
+			return r
+		})
+		byKey := append([]string{}, words...)
+		sort.SliceStable(byKey, func(i, j int) bool {
+			return bytes.Compare(IndexKey(fieldType, []byte(byKey[i])), IndexKey(fieldType, []byte(byKey[j]))) < 0
+		})
+		for i := range byLess {
+			if byLess[i] != byKey[i] {
+				t.Errorf("IndexKey order not same as Less, %v, %v", byLess, byKey)
+				return
+			}
+		}
+	}
+
+	// ILike 使用 case folding
+	like, _ := CharType.ILike([]byte("%STRASSE%"), []byte("Die Straße"))
+	if !like {
+		t.Error("ILike expected true")
+		return
+	}
+}
+
+func TestFieldInfo_Collation(t *testing.T) {
+	info := &FieldInfo{
+		Name:      "name",
+		Length:    20,
+		FieldType: NewCharType(base.CollationUnicode),
+	}
+	err := info.FillingRawFieldType()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if info.Collation != base.CollationUnicode {
+		t.Errorf("expected collation %s, but got %s", base.CollationUnicode, info.Collation)
+		return
+	}
+	info.FieldType = nil
+	err = info.LoadFieldType()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if info.FieldType != NewCharType(base.CollationUnicode) {
+		t.Error("LoadFieldType collation error")
+		return
+	}
+	err = info.Verification()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	info.Collation = "unknown"
+	err = info.Verification()
+	if err == nil {
+		t.Error("expected error, but got nil")
+		return
+	}
+
+	info = &FieldInfo{
+		Name:      "id",
+		Length:    8,
+		FieldType: BigIntType,
+		Collation: base.CollationCaseInsensitive,
+	}
+	err = info.Verification()
+	if err == nil {
+		t.Error("expected error, but got nil")
+		return
+	}
+}
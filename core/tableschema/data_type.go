@@ -1,7 +1,14 @@
 package tableschema
 
 import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"regexp"
 	"strconv"
 	"strings"
 
@@ -11,6 +18,14 @@ import (
 
 const (
 	NullStringByte = 0x00
+
+	// BlobHexPrefix blob 可读值的十六进制前缀，base64 需要带上 BlobBase64Prefix，不带前缀的可读值是错误的
+	BlobHexPrefix         = "0x"
+	BlobPostgresHexPrefix = "\\x"
+	BlobBase64Prefix      = "base64:"
+
+	// enumLabelSeparator enum 可选值之间的连接符，可选值中不能包含
+	enumLabelSeparator = "\x00"
 )
 
 type MetaType interface {
@@ -34,13 +49,28 @@ type MetaType interface {
 	Like([]byte, []byte) (bool, base.StandardError)
 	// ILike 数据对比: iLike
 	ILike([]byte, []byte) (bool, base.StandardError)
+	// Regexp 数据对比: 正则匹配，参数为编译后的正则表达式，只有文本类型支持，其余类型返回错误
+	Regexp(*regexp.Regexp, []byte) (bool, base.StandardError)
 	// IsNull 数据对比: 是否为Null
 	IsNull([]byte) (bool, base.StandardError)
 }
 
+// unsupportedComparatorError 类型不支持的比较符
+func unsupportedComparatorError(t MetaType, operate base.DataComparator) base.StandardError {
+	errMsg := fmt.Sprintf("类型<%s>不支持比较符: %s", t.GetType(), operate)
+	utils.LogError("[unsupportedComparatorError] " + errMsg)
+	return base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+}
+
 type bigIntType struct {
 }
 
+// BigIntValue int64 转化为 bigint 的值，注意 0 和 Null 的存储相同
+func BigIntValue(v int64) []byte {
+	r, _ := base.Int64ToByteList(v)
+	return r
+}
+
 func (t bigIntType) GetType() base.DBDataTypeEnumeration {
 	return base.DBDataTypeBigInt
 }
@@ -135,6 +165,11 @@ func (t bigIntType) ILike(originValue []byte, compareValue []byte) (bool, base.S
 	return false, nil
 }
 
+func (t bigIntType) Regexp(pattern *regexp.Regexp, compareValue []byte) (bool, base.StandardError) {
+	// 数字没有Regexp
+	return false, unsupportedComparatorError(t, base.DataComparatorRegexp)
+}
+
 func (t bigIntType) IsNull(checkValue []byte) (bool, base.StandardError) {
 	value, err := base.ByteListToInt64(checkValue)
 	if err != nil {
@@ -144,7 +179,27 @@ func (t bigIntType) IsNull(checkValue []byte) (bool, base.StandardError) {
 	return value == 0, nil
 }
 
+// charType 字符类型，比较、排序和相等判断都按照列的排序规则进行
 type charType struct {
+	collation base.Collation
+}
+
+// NewCharType 通过排序规则生成字符类型
+func NewCharType(collation base.Collation) MetaType {
+	if collation == base.CollationBinary {
+		collation = ""
+	}
+	return charType{
+		collation: collation,
+	}
+}
+
+// Collation 获取排序规则
+func (t charType) Collation() base.Collation {
+	if t.collation == "" {
+		return base.CollationBinary
+	}
+	return t.collation
 }
 
 func (t charType) GetType() base.DBDataTypeEnumeration {
@@ -197,89 +252,617 @@ func (t charType) TrimRaw(data []byte) []byte {
 }
 
 func (t charType) Greater(data1 []byte, data2 []byte) (bool, base.StandardError) {
-	value1 := string(data1)
-	value2 := string(data2)
-	result := strings.Compare(value1, value2)
-
-	switch result {
-	case -1:
-		return false, nil
-	case 0:
-		return false, nil
-	case 1:
-		return true, nil
-	}
-	return false, nil
+	return collationCompare(t.collation, t.TrimRaw(data1), t.TrimRaw(data2)) > 0, nil
 }
 
 func (t charType) Equal(data1 []byte, data2 []byte) (bool, base.StandardError) {
-	value1 := string(data1)
-	value2 := string(data2)
-	result := strings.Compare(value1, value2)
-
-	switch result {
-	case -1:
-		return false, nil
-	case 0:
-		return true, nil
-	case 1:
-		return false, nil
-	}
-	return false, nil
+	return collationCompare(t.collation, t.TrimRaw(data1), t.TrimRaw(data2)) == 0, nil
 }
 
 func (t charType) Less(data1 []byte, data2 []byte) (bool, base.StandardError) {
-	value1 := string(data1)
-	value2 := string(data2)
-	result := strings.Compare(value1, value2)
+	return collationCompare(t.collation, t.TrimRaw(data1), t.TrimRaw(data2)) < 0, nil
+}
 
-	switch result {
-	case -1:
-		return true, nil
-	case 0:
-		return false, nil
-	case 1:
-		return false, nil
+// Like originValue 为 like 表达式（使用默认转义字符 \），compareValue 为需要判断的值
+// 忽略大小写的排序规则下，like 同样忽略大小写
+func (t charType) Like(originValue []byte, compareValue []byte) (bool, base.StandardError) {
+	if t.collation == base.CollationCaseInsensitive {
+		return LikeMatch(foldCase(originValue), foldCase(t.TrimRaw(compareValue)))
 	}
-	return false, nil
+	return LikeMatch(originValue, t.TrimRaw(compareValue))
 }
 
-func (t charType) Like(originValue []byte, compareValue []byte) (bool, base.StandardError) {
+// ILike 使用 Unicode case folding 忽略大小写
+func (t charType) ILike(originValue []byte, compareValue []byte) (bool, base.StandardError) {
+	return charType{}.Like(foldCase(originValue), foldCase(compareValue))
+}
+
+// Regexp 在值中查找是否有匹配正则表达式的部分，需要完全匹配时在表达式中使用 ^ 和 $
+// 排序规则不影响正则匹配，忽略大小写需要在表达式中使用 (?i)
+func (t charType) Regexp(pattern *regexp.Regexp, compareValue []byte) (bool, base.StandardError) {
+	return pattern.Match(t.TrimRaw(compareValue)), nil
+}
+
+func (t charType) IsNull(checkValue []byte) (bool, base.StandardError) {
+	empty := checkValue == nil || len(checkValue) == 0
+	nullString := len(checkValue) == 1 && checkValue[0] == NullStringByte
+	return empty || nullString, nil
+}
+
+// blobType 二进制类型
+// 储存格式为: 4字节的头部(大端) + 原始数据 + 填充的0x00
+// 与 charType 不同，数据本身可以包含 0x00，所以不能通过 0x00 判断结尾，需要依赖头部记录的长度
+// 头部记录的是 实际长度+1，头部为 0（即全部为 0x00）表示 Null，头部为 1 表示空的二进制数据
+type blobType struct {
+}
+
+// blobPayload 获取去掉长度头部之后的原始数据，isNull 表示值为 Null
+func blobPayload(data []byte) (payload []byte, isNull bool, err base.StandardError) {
+	if len(data) < base.DataByteLengthBlobHeader {
+		utils.LogError(fmt.Sprintf("[blobPayload] err: blob 数据长度不足: %d", len(data)))
+		return nil, false, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf("blob 数据长度不足: %d", len(data)))
+	}
+	header, err := base.ByteListToUint32(data[:base.DataByteLengthBlobHeader])
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[blobPayload.base.ByteListToUint32] err: %s", err.Error()))
+		return nil, false, err
+	}
+	if header == 0 {
+		return nil, true, nil
+	}
+	length := int(header - 1)
+	if length > len(data)-base.DataByteLengthBlobHeader {
+		utils.LogError(fmt.Sprintf("[blobPayload] err: blob 声明长度 %d 超过实际长度 %d", length, len(data)-base.DataByteLengthBlobHeader))
+		return nil, false, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf("blob 声明长度 %d 超过实际长度 %d", length, len(data)-base.DataByteLengthBlobHeader))
+	}
+	return data[base.DataByteLengthBlobHeader : base.DataByteLengthBlobHeader+length], false, nil
+}
+
+// BlobValue 原始数据转化为 blob 的值（带长度头部），空的原始数据不是 Null
+func BlobValue(raw []byte) ([]byte, base.StandardError) {
+	header, err := base.Uint32ToByteList(uint32(len(raw)) + 1)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[BlobValue.base.Uint32ToByteList] err: %s", err.Error()))
+		return nil, err
+	}
+	return append(header, raw...), nil
+}
+
+// BlobNullValue blob 的 Null 值
+func BlobNullValue() []byte {
+	return make([]byte, base.DataByteLengthBlobHeader)
+}
+
+func (t blobType) GetType() base.DBDataTypeEnumeration {
+	return base.DBDataTypeBlob
+}
+
+// StringValue Null 返回空字符串，其余返回 0x 开头的十六进制
+func (t blobType) StringValue(data []byte) string {
+	payload, isNull, err := blobPayload(data)
+	if err != nil {
+		return base.ValueStringErrorValue
+	}
+	if isNull {
+		return ""
+	}
+	return BlobHexPrefix + hex.EncodeToString(payload)
+}
+
+// StringToByte 可读值必须带上编码前缀: 0x / \x 开头的十六进制，base64: 开头的标准 base64
+// 前缀之后的内容都是编码后的数据，所以原始数据本身以 0x、\x 开头也不会有歧义；空字符串为 Null
+func (t blobType) StringToByte(data string) ([]byte, base.StandardError) {
 	var (
-		origin  = ""
-		compare = string(compareValue)
+		raw []byte
+		er  error
 	)
-	if originValue[0] == base.SymbolDataComparatorLikePlaceholder && originValue[len(originValue)-1] == base.SymbolDataComparatorLikePlaceholder {
-		if len(originValue) > 2 {
-			origin = string(originValue[1 : len(originValue)-2])
-		}
-	} else if originValue[len(originValue)-1] == base.SymbolDataComparatorLikePlaceholder {
-		if len(originValue) > 1 {
-			origin = string(originValue[:len(originValue)-2])
-		}
-	} else if originValue[0] == base.SymbolDataComparatorLikePlaceholder {
-		if len(originValue) > 1 {
-			origin = string(originValue[1:])
+	switch {
+	case data == "":
+		return BlobNullValue(), nil
+	case strings.HasPrefix(data, BlobHexPrefix):
+		raw, er = hex.DecodeString(data[len(BlobHexPrefix):])
+	case strings.HasPrefix(data, BlobPostgresHexPrefix):
+		raw, er = hex.DecodeString(data[len(BlobPostgresHexPrefix):])
+	case strings.HasPrefix(data, BlobBase64Prefix):
+		raw, er = base64.StdEncoding.DecodeString(data[len(BlobBase64Prefix):])
+	default:
+		er = fmt.Errorf("blob 的值需要以 %s、%s 或 %s 开头", BlobHexPrefix, BlobPostgresHexPrefix, BlobBase64Prefix)
+	}
+	if er != nil {
+		utils.LogError(fmt.Sprintf("[blobType.StringToByte] err: %s", er.Error()))
+		return nil, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, er)
+	}
+	return BlobValue(raw)
+}
+
+func (t blobType) LengthPadding(waitHandleData []byte, length int) ([]byte, base.StandardError) {
+	if _, _, err := blobPayload(waitHandleData); err != nil {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[blobType.LengthPadding.blobPayload] err: %s", err.Error()))
+		return nil, err
+	}
+	if len(waitHandleData) == length {
+		return waitHandleData, nil
+	}
+	if len(waitHandleData) > length {
+		utils.LogError(fmt.Sprintf("[blobType.LengthPadding] err: blob 数据长度不对"))
+		return nil, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf("blob 数据长度不对"))
+	}
+	padding := make([]byte, length-len(waitHandleData))
+	return append(waitHandleData, padding...), nil
+}
+
+// TrimRaw 去掉尾部的填充，保留长度头部
+func (t blobType) TrimRaw(data []byte) []byte {
+	payload, _, err := blobPayload(data)
+	if err != nil {
+		return make([]byte, 0)
+	}
+	return data[:base.DataByteLengthBlobHeader+len(payload)]
+}
+
+// compare Null 小于任何非 Null 的值（包括空的二进制数据）
+func (t blobType) compare(data1 []byte, data2 []byte) (int, base.StandardError) {
+	value1, isNull1, err := blobPayload(data1)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[blobType.compare.blobPayload] err: %s", err.Error()))
+		return 0, err
+	}
+	value2, isNull2, err := blobPayload(data2)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[blobType.compare.blobPayload] err: %s", err.Error()))
+		return 0, err
+	}
+	if isNull1 || isNull2 {
+		switch {
+		case isNull1 && isNull2:
+			return 0, nil
+		case isNull1:
+			return -1, nil
+		default:
+			return 1, nil
 		}
 	}
-	return strings.Contains(compare, origin), nil
+	return bytes.Compare(value1, value2), nil
 }
 
-func (t charType) ILike(originValue []byte, compareValue []byte) (bool, base.StandardError) {
-	origin := string(originValue)
-	compare := string(compareValue)
-	newOrigin := strings.ToLower(origin)
-	newCompare := strings.ToLower(compare)
-	return t.Like([]byte(newOrigin), []byte(newCompare))
+func (t blobType) Greater(data1 []byte, data2 []byte) (bool, base.StandardError) {
+	result, err := t.compare(data1, data2)
+	if err != nil {
+		return false, err
+	}
+	return result > 0, nil
 }
 
-func (t charType) IsNull(checkValue []byte) (bool, base.StandardError) {
+func (t blobType) Equal(data1 []byte, data2 []byte) (bool, base.StandardError) {
+	result, err := t.compare(data1, data2)
+	if err != nil {
+		return false, err
+	}
+	return result == 0, nil
+}
+
+func (t blobType) Less(data1 []byte, data2 []byte) (bool, base.StandardError) {
+	result, err := t.compare(data1, data2)
+	if err != nil {
+		return false, err
+	}
+	return result < 0, nil
+}
+
+// Like 二进制数据没有 Like，返回错误，避免条件被当作不匹配而静默返回空结果
+func (t blobType) Like(originValue []byte, compareValue []byte) (bool, base.StandardError) {
+	return false, unsupportedComparatorError(t, base.DataComparatorLike)
+}
+
+func (t blobType) ILike(originValue []byte, compareValue []byte) (bool, base.StandardError) {
+	return false, unsupportedComparatorError(t, base.DataComparatorILike)
+}
+
+func (t blobType) Regexp(pattern *regexp.Regexp, compareValue []byte) (bool, base.StandardError) {
+	// 二进制数据没有Regexp
+	return false, unsupportedComparatorError(t, base.DataComparatorRegexp)
+}
+
+// IsNull 只有头部为 0 才是 Null，空的二进制数据不是 Null
+func (t blobType) IsNull(checkValue []byte) (bool, base.StandardError) {
+	if checkValue == nil || len(checkValue) == 0 {
+		return true, nil
+	}
+	_, isNull, err := blobPayload(checkValue)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[blobType.IsNull.blobPayload] err: %s", err.Error()))
+		return false, err
+	}
+	return isNull, nil
+}
+
+// jsonType json 类型
+// 写入时校验并压缩 json 文档，储存方式和 charType 一致（合法的 json 文本中不会出现 0x00）
+type jsonType struct {
+}
+
+func (t jsonType) GetType() base.DBDataTypeEnumeration {
+	return base.DBDataTypeJSON
+}
+
+func (t jsonType) StringValue(data []byte) string {
+	return string(data)
+}
+
+func (t jsonType) StringToByte(data string) ([]byte, base.StandardError) {
+	if !json.Valid([]byte(data)) {
+		utils.LogError(fmt.Sprintf("[jsonType.StringToByte] err: 不合法的json: %s", data))
+		return nil, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf("不合法的json: %s", data))
+	}
+	buffer := bytes.Buffer{}
+	er := json.Compact(&buffer, []byte(data))
+	if er != nil {
+		utils.LogError(fmt.Sprintf("[jsonType.StringToByte.json.Compact] err: %s", er.Error()))
+		return nil, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, er)
+	}
+	return buffer.Bytes(), nil
+}
+
+func (t jsonType) LengthPadding(waitHandleData []byte, length int) ([]byte, base.StandardError) {
+	if len(waitHandleData) > 0 && !json.Valid(waitHandleData) {
+		utils.LogError(fmt.Sprintf("[jsonType.LengthPadding] err: 不合法的json"))
+		return nil, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf("不合法的json"))
+	}
+	return CharType.LengthPadding(waitHandleData, length)
+}
+
+func (t jsonType) TrimRaw(data []byte) []byte {
+	return CharType.TrimRaw(data)
+}
+
+func (t jsonType) compare(data1 []byte, data2 []byte) (int, base.StandardError) {
+	value1, err := decodeJSONValue(data1)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[jsonType.compare.decodeJSONValue] err: %s", err.Error()))
+		return 0, err
+	}
+	value2, err := decodeJSONValue(data2)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[jsonType.compare.decodeJSONValue] err: %s", err.Error()))
+		return 0, err
+	}
+	if result, ok := compareJSONValue(value1, value2); ok {
+		return result, nil
+	}
+	// 类型不同无法比较时，按压缩后的文本比较，保证有稳定的顺序
+	return bytes.Compare(data1, data2), nil
+}
+
+func (t jsonType) Greater(data1 []byte, data2 []byte) (bool, base.StandardError) {
+	result, err := t.compare(data1, data2)
+	if err != nil {
+		return false, err
+	}
+	return result > 0, nil
+}
+
+// Equal 按 json 语义比较，对象中键的顺序不影响结果
+func (t jsonType) Equal(data1 []byte, data2 []byte) (bool, base.StandardError) {
+	value1, err := decodeJSONValue(data1)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[jsonType.Equal.decodeJSONValue] err: %s", err.Error()))
+		return false, err
+	}
+	value2, err := decodeJSONValue(data2)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[jsonType.Equal.decodeJSONValue] err: %s", err.Error()))
+		return false, err
+	}
+	return equalJSONValue(value1, value2), nil
+}
+
+func (t jsonType) Less(data1 []byte, data2 []byte) (bool, base.StandardError) {
+	result, err := t.compare(data1, data2)
+	if err != nil {
+		return false, err
+	}
+	return result < 0, nil
+}
+
+// Like json 需要通过路径取值之后再比较，整个值不支持 Like
+func (t jsonType) Like(originValue []byte, compareValue []byte) (bool, base.StandardError) {
+	return false, unsupportedComparatorError(t, base.DataComparatorLike)
+}
+
+func (t jsonType) ILike(originValue []byte, compareValue []byte) (bool, base.StandardError) {
+	return false, unsupportedComparatorError(t, base.DataComparatorILike)
+}
+
+func (t jsonType) Regexp(pattern *regexp.Regexp, compareValue []byte) (bool, base.StandardError) {
+	// json 没有Regexp，需要通过路径取值之后再比较
+	return false, unsupportedComparatorError(t, base.DataComparatorRegexp)
+}
+
+func (t jsonType) IsNull(checkValue []byte) (bool, base.StandardError) {
 	empty := checkValue == nil || len(checkValue) == 0
 	nullString := len(checkValue) == 1 && checkValue[0] == NullStringByte
-	return empty || nullString, nil
+	return empty || nullString || string(checkValue) == "null", nil
+}
+
+// uuidType uuid 类型，固定 16 字节储存，按字节序比较
+type uuidType struct {
+}
+
+// NewUUID 生成一个随机的（version 4）uuid
+func NewUUID() ([]byte, base.StandardError) {
+	data := make([]byte, base.DataByteLengthUUID)
+	_, er := rand.Read(data)
+	if er != nil {
+		utils.LogError(fmt.Sprintf("[NewUUID.rand.Read] err: %s", er.Error()))
+		return nil, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeSystem, base.ErrorBaseCodeInnerDataError, er)
+	}
+	data[6] = (data[6] & 0x0f) | 0x40 // version 4
+	data[8] = (data[8] & 0x3f) | 0x80 // variant RFC 4122
+	return data, nil
+}
+
+func (t uuidType) GetType() base.DBDataTypeEnumeration {
+	return base.DBDataTypeUUID
+}
+
+func (t uuidType) StringValue(data []byte) string {
+	if len(data) != base.DataByteLengthUUID {
+		return base.ValueStringErrorValue
+	}
+	h := hex.EncodeToString(data)
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
+
+// StringToByte 支持标准格式 xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx，也支持不带 - 的 32 位十六进制
+func (t uuidType) StringToByte(data string) ([]byte, base.StandardError) {
+	h := data
+	if len(data) == 36 {
+		if data[8] != '-' || data[13] != '-' || data[18] != '-' || data[23] != '-' {
+			utils.LogError(fmt.Sprintf("[uuidType.StringToByte] err: 不合法的uuid: %s", data))
+			return nil, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf("不合法的uuid: %s", data))
+		}
+		h = data[0:8] + data[9:13] + data[14:18] + data[19:23] + data[24:36]
+	}
+	if len(h) != base.DataByteLengthUUID*2 {
+		utils.LogError(fmt.Sprintf("[uuidType.StringToByte] err: 不合法的uuid: %s", data))
+		return nil, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf("不合法的uuid: %s", data))
+	}
+	byteValue, er := hex.DecodeString(h)
+	if er != nil {
+		utils.LogError(fmt.Sprintf("[uuidType.StringToByte.hex.DecodeString] err: %s", er.Error()))
+		return nil, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, er)
+	}
+	return byteValue, nil
+}
+
+func (t uuidType) LengthPadding(waitHandleData []byte, length int) ([]byte, base.StandardError) {
+	if len(waitHandleData) != base.DataByteLengthUUID {
+		utils.LogError(fmt.Sprintf("[uuidType.LengthPadding] err: uuid 数据长度不对"))
+		return nil, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf("uuid 数据长度不对"))
+	}
+	return waitHandleData, nil
+}
+
+func (t uuidType) TrimRaw(data []byte) []byte {
+	if data == nil {
+		return make([]byte, 0)
+	} else {
+		return data
+	}
+}
+
+func (t uuidType) Greater(data1 []byte, data2 []byte) (bool, base.StandardError) {
+	return bytes.Compare(data1, data2) > 0, nil
+}
+
+func (t uuidType) Equal(data1 []byte, data2 []byte) (bool, base.StandardError) {
+	return bytes.Equal(data1, data2), nil
+}
+
+func (t uuidType) Less(data1 []byte, data2 []byte) (bool, base.StandardError) {
+	return bytes.Compare(data1, data2) < 0, nil
+}
+
+// Like uuid 不支持 Like，只能按值比较
+func (t uuidType) Like(originValue []byte, compareValue []byte) (bool, base.StandardError) {
+	return false, unsupportedComparatorError(t, base.DataComparatorLike)
+}
+
+func (t uuidType) ILike(originValue []byte, compareValue []byte) (bool, base.StandardError) {
+	return false, unsupportedComparatorError(t, base.DataComparatorILike)
+}
+
+func (t uuidType) Regexp(pattern *regexp.Regexp, compareValue []byte) (bool, base.StandardError) {
+	// uuid 没有Regexp
+	return false, unsupportedComparatorError(t, base.DataComparatorRegexp)
+}
+
+func (t uuidType) IsNull(checkValue []byte) (bool, base.StandardError) {
+	for _, v := range checkValue {
+		if v != 0x00 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// enumType enum 类型，可选值在 FieldInfo.EnumValues 中声明
+// 储存的是可选值的序号（从1开始，0 表示 Null），比较时按声明的顺序
+type enumType struct {
+	labels string
+}
+
+// NewEnumType 通过可选值生成 enum 类型
+func NewEnumType(labels ...string) MetaType {
+	return enumType{
+		labels: strings.Join(labels, enumLabelSeparator),
+	}
+}
+
+// Labels 获取全部可选值
+func (t enumType) Labels() []string {
+	if t.labels == "" {
+		return make([]string, 0)
+	}
+	return strings.Split(t.labels, enumLabelSeparator)
+}
+
+func (t enumType) index(data []byte) (int, base.StandardError) {
+	if len(data) != base.DataByteLengthEnum {
+		utils.LogError(fmt.Sprintf("[enumType.index] err: enum 数据长度不对"))
+		return 0, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf("enum 数据长度不对"))
+	}
+	return int(binary.BigEndian.Uint16(data)), nil
+}
+
+func (t enumType) GetType() base.DBDataTypeEnumeration {
+	return base.DBDataTypeEnum
+}
+
+func (t enumType) StringValue(data []byte) string {
+	index, err := t.index(data)
+	if err != nil {
+		return base.ValueStringErrorValue
+	}
+	if index == 0 {
+		return base.ValueStringNullValue
+	}
+	labels := t.Labels()
+	if index > len(labels) {
+		return base.ValueStringErrorValue
+	}
+	return labels[index-1]
+}
+
+func (t enumType) StringToByte(data string) ([]byte, base.StandardError) {
+	for i, label := range t.Labels() {
+		if label == data {
+			byteValue := make([]byte, base.DataByteLengthEnum)
+			binary.BigEndian.PutUint16(byteValue, uint16(i+1))
+			return byteValue, nil
+		}
+	}
+	utils.LogError(fmt.Sprintf("[enumType.StringToByte] err: 未声明的enum值: %s", data))
+	return nil, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf("未声明的enum值: %s", data))
+}
+
+func (t enumType) LengthPadding(waitHandleData []byte, length int) ([]byte, base.StandardError) {
+	index, err := t.index(waitHandleData)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[enumType.LengthPadding.index] err: %s", err.Error()))
+		return nil, err
+	}
+	if index > len(t.Labels()) {
+		utils.LogError(fmt.Sprintf("[enumType.LengthPadding] err: 未声明的enum序号: %d", index))
+		return nil, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf("未声明的enum序号: %d", index))
+	}
+	return waitHandleData, nil
+}
+
+func (t enumType) TrimRaw(data []byte) []byte {
+	if data == nil {
+		return make([]byte, 0)
+	} else {
+		return data
+	}
+}
+
+func (t enumType) compare(data1 []byte, data2 []byte) (int, base.StandardError) {
+	index1, err := t.index(data1)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[enumType.compare.index] err: %s", err.Error()))
+		return 0, err
+	}
+	index2, err := t.index(data2)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[enumType.compare.index] err: %s", err.Error()))
+		return 0, err
+	}
+	return index1 - index2, nil
+}
+
+func (t enumType) Greater(data1 []byte, data2 []byte) (bool, base.StandardError) {
+	result, err := t.compare(data1, data2)
+	if err != nil {
+		return false, err
+	}
+	return result > 0, nil
+}
+
+func (t enumType) Equal(data1 []byte, data2 []byte) (bool, base.StandardError) {
+	result, err := t.compare(data1, data2)
+	if err != nil {
+		return false, err
+	}
+	return result == 0, nil
+}
+
+func (t enumType) Less(data1 []byte, data2 []byte) (bool, base.StandardError) {
+	result, err := t.compare(data1, data2)
+	if err != nil {
+		return false, err
+	}
+	return result < 0, nil
+}
+
+// label 可选值的文本，Null 没有文本，返回 false
+func (t enumType) label(data []byte) ([]byte, bool, base.StandardError) {
+	isNull, err := t.IsNull(data)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[enumType.label.IsNull] err: %s", err.Error()))
+		return nil, false, err
+	}
+	if isNull {
+		return nil, false, nil
+	}
+	return []byte(t.StringValue(data)), true, nil
+}
+
+// Like 使用可选值的文本进行 like，Null 不匹配任何表达式
+func (t enumType) Like(originValue []byte, compareValue []byte) (bool, base.StandardError) {
+	label, ok, err := t.label(compareValue)
+	if err != nil || !ok {
+		return false, err
+	}
+	return CharType.Like(originValue, label)
+}
+
+// ILike 使用可选值的文本进行 iLike，Null 不匹配任何表达式
+func (t enumType) ILike(originValue []byte, compareValue []byte) (bool, base.StandardError) {
+	label, ok, err := t.label(compareValue)
+	if err != nil || !ok {
+		return false, err
+	}
+	return CharType.ILike(originValue, label)
+}
+
+// Regexp 使用可选值的文本进行正则匹配，Null 不匹配任何表达式
+func (t enumType) Regexp(pattern *regexp.Regexp, compareValue []byte) (bool, base.StandardError) {
+	label, ok, err := t.label(compareValue)
+	if err != nil || !ok {
+		return false, err
+	}
+	return CharType.Regexp(pattern, label)
+}
+
+func (t enumType) IsNull(checkValue []byte) (bool, base.StandardError) {
+	if checkValue == nil || len(checkValue) == 0 {
+		return true, nil
+	}
+	index, err := t.index(checkValue)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[enumType.IsNull.index] err: %s", err.Error()))
+		return false, err
+	}
+	return index == 0, nil
 }
 
 var (
 	BigIntType = bigIntType{}
 	CharType   = charType{}
+	BlobType   = blobType{}
+	JSONType   = jsonType{}
+	UUIDType   = uuidType{}
+	// EnumType 没有可选值的 enum 类型，只用于类型判断，使用时需要通过 NewEnumType 带上可选值
+	EnumType = enumType{}
 )
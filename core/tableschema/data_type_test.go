@@ -0,0 +1,430 @@
+package tableschema
+
+import (
+	"regexp"
+	"testing"
+
+	"ne_database/core/base"
+	"ne_database/utils/list"
+)
+
+func TestBlobType(t *testing.T) {
+	// 包含 0x00 的二进制数据
+	raw := []byte{0xDE, 0xAD, 0x00, 0x00, 0xBE, 0xEF}
+	// 头部记录的是 实际长度+1
+	expectedByte := []byte{0x00, 0x00, 0x00, 0x07, 0xDE, 0xAD, 0x00, 0x00, 0xBE, 0xEF}
+
+	hexByte, err := BlobType.StringToByte("0xdead0000beef")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if !list.ByteListEqual(hexByte, expectedByte) {
+		t.Errorf("BlobType.StringToByte failed, expected %#v but got %#v", expectedByte, hexByte)
+		return
+	}
+	pgHexByte, err := BlobType.StringToByte("\\xdead0000beef")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if !list.ByteListEqual(pgHexByte, expectedByte) {
+		t.Errorf("BlobType.StringToByte failed, expected %#v but got %#v", expectedByte, pgHexByte)
+		return
+	}
+	base64Byte, err := BlobType.StringToByte("base64:3q0AAL7v")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if !list.ByteListEqual(base64Byte, expectedByte) {
+		t.Errorf("BlobType.StringToByte failed, expected %#v but got %#v", expectedByte, base64Byte)
+		return
+	}
+	_, err = BlobType.StringToByte("0xzz")
+	if err == nil {
+		t.Error("expected error, but got nil")
+		return
+	}
+	// 不带前缀的值是错误的，不会猜测编码
+	_, err = BlobType.StringToByte("3q0AAL7v")
+	if err == nil {
+		t.Error("expected error, but got nil")
+		return
+	}
+	// 原始数据以 "0x" 开头时，编码后不会有歧义
+	prefixByte, err := BlobType.StringToByte("0x3078")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if BlobType.StringValue(prefixByte) != "0x3078" {
+		t.Errorf("BlobType.StringValue failed, got %s", BlobType.StringValue(prefixByte))
+		return
+	}
+
+	if BlobType.StringValue(expectedByte) != "0xdead0000beef" {
+		t.Errorf("BlobType.StringValue failed, got %s", BlobType.StringValue(expectedByte))
+		return
+	}
+
+	// 填充后再修整，尾部的 0x00 数据不能丢失
+	padding, err := BlobType.LengthPadding(expectedByte, 16)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if len(padding) != 16 {
+		t.Errorf("BlobType.LengthPadding failed, got length %d", len(padding))
+		return
+	}
+	trimByte := BlobType.TrimRaw(padding)
+	if !list.ByteListEqual(trimByte, expectedByte) {
+		t.Errorf("BlobType.TrimRaw failed, expected %#v but got %#v", expectedByte, trimByte)
+		return
+	}
+	_, err = BlobType.LengthPadding(expectedByte, 8)
+	if err == nil {
+		t.Error("expected error, but got nil")
+		return
+	}
+
+	value, err := BlobValue(raw)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	equal, err := BlobType.Equal(value, padding)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if !equal {
+		t.Error("BlobType.Equal failed, expected true")
+		return
+	}
+	other, _ := BlobValue([]byte{0xDE, 0xAD, 0x00})
+	equal, err = BlobType.Equal(value, other)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if equal {
+		t.Error("BlobType.Equal failed, expected false")
+		return
+	}
+	greater, err := BlobType.Greater(value, other)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if !greater {
+		t.Error("BlobType.Greater failed, expected true")
+		return
+	}
+
+	_, err = BlobType.Like(value, value)
+	if err == nil {
+		t.Error("BlobType.Like failed, blob not support like, expected error")
+		return
+	}
+	_, err = BlobType.ILike(value, value)
+	if err == nil {
+		t.Error("BlobType.ILike failed, blob not support ilike, expected error")
+		return
+	}
+
+	// 空的二进制数据不是 Null
+	empty, err := BlobType.StringToByte("0x")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	isNull, err := BlobType.IsNull(empty)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if isNull {
+		t.Error("BlobType.IsNull failed, empty blob expected not null")
+		return
+	}
+	// 未填写的列全部为 0x00，是 Null
+	null, err := BlobType.LengthPadding(BlobNullValue(), 16)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	isNull, err = BlobType.IsNull(null)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if !isNull {
+		t.Error("BlobType.IsNull failed, expected true")
+		return
+	}
+	equal, err = BlobType.Equal(null, empty)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if equal {
+		t.Error("BlobType.Equal failed, null expected not equal to empty blob")
+		return
+	}
+	if BlobType.StringValue(null) != "" || BlobType.StringValue(empty) != "0x" {
+		t.Errorf("BlobType.StringValue failed, got %q and %q", BlobType.StringValue(null), BlobType.StringValue(empty))
+		return
+	}
+}
+
+func TestJSONType(t *testing.T) {
+	data, err := JSONType.StringToByte("{\"color\": \"red\",  \"size\": 10}")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if string(data) != "{\"color\":\"red\",\"size\":10}" {
+		t.Errorf("JSONType.StringToByte failed, got %s", string(data))
+		return
+	}
+	_, err = JSONType.StringToByte("{\"color\": ")
+	if err == nil {
+		t.Error("expected error, but got nil")
+		return
+	}
+
+	// 对象中键的顺序不影响相等判断
+	data2, _ := JSONType.StringToByte("{\"size\":10,\"color\":\"red\"}")
+	equal, err := JSONType.Equal(data, data2)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if !equal {
+		t.Error("JSONType.Equal failed, expected true")
+		return
+	}
+
+	padding, err := JSONType.LengthPadding(data, 64)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if !list.ByteListEqual(JSONType.TrimRaw(padding), data) {
+		t.Errorf("JSONType.TrimRaw failed, got %s", string(JSONType.TrimRaw(padding)))
+		return
+	}
+
+	// 超过 2^53 的整数，转为 float64 后相等，需要按整数精确比较
+	bigNumber, _ := JSONType.StringToByte("{\"id\":9007199254740993}")
+	match, err := JSONType.MatchPath(bigNumber, "id", base.DataComparatorGreater, [][]byte{[]byte("9007199254740992")})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if !match {
+		t.Error("JSONType.MatchPath failed, expected 9007199254740993 > 9007199254740992")
+		return
+	}
+	match, err = JSONType.MatchPath(bigNumber, "id", base.DataComparatorEqual, [][]byte{[]byte("9007199254740992")})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if match {
+		t.Error("JSONType.MatchPath failed, expected 9007199254740993 != 9007199254740992")
+		return
+	}
+	// 整数和小数比较
+	match, err = JSONType.MatchPath(bigNumber, "id", base.DataComparatorLess, [][]byte{[]byte("9007199254740993.5")})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if !match {
+		t.Error("JSONType.MatchPath failed, expected 9007199254740993 < 9007199254740993.5")
+		return
+	}
+}
+
+func TestUUIDType(t *testing.T) {
+	expectedByte := []byte{0x12, 0x3e, 0x45, 0x67, 0xe8, 0x9b, 0x12, 0xd3, 0xa4, 0x56, 0x42, 0x66, 0x14, 0x17, 0x40, 0x00}
+	data, err := UUIDType.StringToByte("123E4567-E89B-12D3-A456-426614174000")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if !list.ByteListEqual(data, expectedByte) {
+		t.Errorf("UUIDType.StringToByte failed, expected %#v but got %#v", expectedByte, data)
+		return
+	}
+	data, err = UUIDType.StringToByte("123e4567e89b12d3a456426614174000")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if !list.ByteListEqual(data, expectedByte) {
+		t.Errorf("UUIDType.StringToByte failed, expected %#v but got %#v", expectedByte, data)
+		return
+	}
+	if UUIDType.StringValue(data) != "123e4567-e89b-12d3-a456-426614174000" {
+		t.Errorf("UUIDType.StringValue failed, got %s", UUIDType.StringValue(data))
+		return
+	}
+	for _, errorValue := range []string{"123e4567-e89b-12d3-a456-42661417400", "123e4567_e89b_12d3_a456_426614174000", "zz3e4567-e89b-12d3-a456-426614174000"} {
+		_, err = UUIDType.StringToByte(errorValue)
+		if err == nil {
+			t.Errorf("expected error for %s, but got nil", errorValue)
+			return
+		}
+	}
+
+	newUUID, err := NewUUID()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if len(newUUID) != 16 || newUUID[6]>>4 != 4 {
+		t.Errorf("NewUUID failed, got %s", UUIDType.StringValue(newUUID))
+		return
+	}
+	less, err := UUIDType.Less(data, []byte{0x12, 0x3e, 0x45, 0x67, 0xe8, 0x9b, 0x12, 0xd3, 0xa4, 0x56, 0x42, 0x66, 0x14, 0x17, 0x40, 0x01})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if !less {
+		t.Error("UUIDType.Less failed, expected true")
+		return
+	}
+	isNull, _ := UUIDType.IsNull(make([]byte, 16))
+	if !isNull {
+		t.Error("UUIDType.IsNull failed, expected true")
+		return
+	}
+}
+
+func TestEnumType(t *testing.T) {
+	status := NewEnumType("pending", "paid", "shipped")
+	paid, err := status.StringToByte("paid")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if !list.ByteListEqual(paid, []byte{0x00, 0x02}) {
+		t.Errorf("EnumType.StringToByte failed, got %#v", paid)
+		return
+	}
+	if status.StringValue(paid) != "paid" {
+		t.Errorf("EnumType.StringValue failed, got %s", status.StringValue(paid))
+		return
+	}
+	_, err = status.StringToByte("refund")
+	if err == nil {
+		t.Error("expected error, but got nil")
+		return
+	}
+
+	// 按声明的顺序比较
+	shipped, _ := status.StringToByte("shipped")
+	greater, err := status.Greater(shipped, paid)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if !greater {
+		t.Error("EnumType.Greater failed, expected true")
+		return
+	}
+	like, err := status.Like([]byte("%ped"), shipped)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if !like {
+		t.Error("EnumType.Like failed, expected true")
+		return
+	}
+	isNull, _ := status.IsNull([]byte{0x00, 0x00})
+	if !isNull {
+		t.Error("EnumType.IsNull failed, expected true")
+		return
+	}
+	_, err = status.LengthPadding([]byte{0x00, 0x04}, 2)
+	if err == nil {
+		t.Error("expected error, but got nil")
+		return
+	}
+
+	// 可选值不同的 enum 类型不相同
+	if status == NewEnumType("pending", "paid") || status != NewEnumType("pending", "paid", "shipped") {
+		t.Error("EnumType compare failed")
+		return
+	}
+}
+
+func TestEnumType_Match(t *testing.T) {
+	status := NewEnumType("pending", "paid", "shipped")
+	paid, _ := status.StringToByte("paid")
+	null := []byte{0x00, 0x00}
+	testCases := []struct {
+		operate base.DataComparator
+		pattern string
+		value   []byte
+		expect  bool
+	}{
+		{base.DataComparatorLike, "pa%", paid, true},
+		{base.DataComparatorILike, "PAID", paid, true},
+		{base.DataComparatorRegexp, "^p", paid, true},
+		// Null 没有可选值的文本，不匹配任何表达式
+		{base.DataComparatorLike, "%", null, false},
+		{base.DataComparatorLike, "Null", null, false},
+		{base.DataComparatorILike, "null", null, false},
+		{base.DataComparatorRegexp, "", null, false},
+		{base.DataComparatorRegexp, "^Null$", null, false},
+	}
+	for i, c := range testCases {
+		var match bool
+		var err base.StandardError
+		switch c.operate {
+		case base.DataComparatorLike:
+			match, err = status.Like([]byte(c.pattern), c.value)
+		case base.DataComparatorILike:
+			match, err = status.ILike([]byte(c.pattern), c.value)
+		case base.DataComparatorRegexp:
+			match, err = status.Regexp(regexp.MustCompile(c.pattern), c.value)
+		}
+		if err != nil {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+			continue
+		}
+		if match != c.expect {
+			t.Errorf("case %d: %s %q expected %v, but got %v", i, c.operate, c.pattern, c.expect, match)
+		}
+	}
+}
+
+func TestMatchMetaType_Like_Unsupported(t *testing.T) {
+	cache := NewRegexpCache()
+	blob, _ := BlobType.StringToByte("0x6a736f6e")
+	document, _ := JSONType.StringToByte("{\"color\":\"red\"}")
+	id, _ := UUIDType.StringToByte("123e4567-e89b-12d3-a456-426614174000")
+	// 不支持 like 的类型返回错误，不能当作不匹配返回空结果
+	testCases := []struct {
+		fieldType MetaType
+		value     []byte
+	}{
+		{BlobType, blob},
+		{JSONType, document},
+		{UUIDType, id},
+	}
+	for i, c := range testCases {
+		for _, operate := range []base.DataComparator{base.DataComparatorLike, base.DataComparatorILike} {
+			if _, err := MatchMetaType(c.fieldType, c.value, operate, [][]byte{[]byte("%red%")}, cache); err == nil {
+				t.Errorf("case %d: %s on %s expected error, but got nil", i, operate, c.fieldType.GetType())
+			}
+		}
+	}
+}
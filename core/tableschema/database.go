@@ -0,0 +1,122 @@
+package tableschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"ne_database/core/base"
+	"ne_database/utils"
+	"ne_database/utils/set"
+)
+
+// DatabaseInfo 数据库信息，每个数据库对应数据目录下的一个子目录
+// PageSize / StorageType 为在该数据库中建表时的默认值，为空时使用全局配置
+type DatabaseInfo struct {
+	Name        string `json:"name"`
+	PageSize    int    `json:"page_size,omitempty"`
+	StorageType string `json:"storage_type,omitempty"`
+}
+
+// SplitTableName 把完整的表名拆分为数据库名和表名，没有数据库名时为默认数据库，数据库名为空
+func SplitTableName(name string) (string, string) {
+	database, table, ok := strings.Cut(name, base.DatabaseNameSeparator)
+	if !ok {
+		return "", name
+	}
+	return database, table
+}
+
+// DatabaseName 完整的表名所在的数据库
+func DatabaseName(name string) string {
+	database, _ := SplitTableName(name)
+	return database
+}
+
+// QualifiedTableName 在数据库 database 中解析表名，已经带有数据库名的表名不变
+func QualifiedTableName(database string, name string) string {
+	if database == "" || strings.Contains(name, base.DatabaseNameSeparator) {
+		return name
+	}
+	return database + base.DatabaseNameSeparator + name
+}
+
+// tableNameVerification 校验完整的表名，数据库名和表名都不能为空，不能使用系统目录的数据库
+func tableNameVerification(name string) base.StandardError {
+	nameError := func(errMsg string) base.StandardError {
+		utils.LogError("[tableNameVerification] " + errMsg)
+		return base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeType, base.ErrorBaseCodeInnerParameterError, fmt.Errorf(errMsg))
+	}
+	if name == "" {
+		return nameError("表名为空")
+	}
+	database, table := SplitTableName(name)
+	if database == base.CatalogSchemaName {
+		return nameError(fmt.Sprintf("表名<%s>使用了系统目录的数据库", name))
+	}
+	if table == "" || strings.Contains(table, base.DatabaseNameSeparator) || (strings.Contains(name, base.DatabaseNameSeparator) && database == "") {
+		return nameError(fmt.Sprintf("表名<%s>不合法，需要是 表名 或者 数据库名.表名", name))
+	}
+	return nil
+}
+
+// Verification 数据库配置校验，数据库名作为目录名，不能包含分隔符和路径字符
+func (info *DatabaseInfo) Verification() base.StandardError {
+	databaseError := func(errMsg string) base.StandardError {
+		utils.LogError("[DatabaseInfo.Verification] " + errMsg)
+		return base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeType, base.ErrorBaseCodeInnerParameterError, fmt.Errorf(errMsg))
+	}
+	if info.Name == "" {
+		return databaseError("数据库名为空")
+	}
+	if info.Name == base.CatalogSchemaName || strings.ContainsAny(info.Name, base.DatabaseNameSeparator+"/\\") {
+		return databaseError(fmt.Sprintf("数据库名<%s>不合法", info.Name))
+	}
+	if info.PageSize < 0 {
+		return databaseError(fmt.Sprintf("数据库<%s>的PageSize小于0: %d", info.Name, info.PageSize))
+	}
+	totalStorageType := set.NewStringsSet(base.StorageTypeFile, base.StorageTypeMemory)
+	if info.StorageType != "" && !totalStorageType.Contain(info.StorageType) {
+		return databaseError(fmt.Sprintf("数据库<%s>的StorageType: %s 不支持", info.Name, info.StorageType))
+	}
+	return nil
+}
+
+// FillTableDefault 表没有设置 PageSize / StorageType 时使用数据库的默认值
+func (info *DatabaseInfo) FillTableDefault(tableInfo *TableMetaInfo) {
+	if tableInfo.PageSize == 0 {
+		tableInfo.PageSize = info.PageSize
+	}
+	if tableInfo.StorageType == "" {
+		tableInfo.StorageType = info.StorageType
+	}
+}
+
+func (info *DatabaseInfo) DatabaseInfoToJsonByte() ([]byte, base.StandardError) {
+	err := info.Verification()
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[DatabaseInfoToJsonByte] 数据库校验错误, %s", err.Error()))
+		return nil, err
+	}
+	jsonByte, er := json.Marshal(info)
+	if er != nil {
+		utils.LogError(fmt.Sprintf("[DatabaseInfoToJsonByte] json.Marshal 错误, %s", er.Error()))
+		return nil, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeSystem, base.ErrorBaseCodeInnerDataError, er)
+	}
+	return jsonByte, nil
+}
+
+func InitDatabaseInfoByJson(databaseJson string) (*DatabaseInfo, base.StandardError) {
+	r := &DatabaseInfo{}
+	er := json.Unmarshal([]byte(databaseJson), r)
+	if er != nil {
+		utils.LogError(fmt.Sprintf("[InitDatabaseInfoByJson] json解析错误: %s", er.Error()))
+		return nil, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, er)
+	}
+	err := r.Verification()
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[InitDatabaseInfoByJson] Verification出错, %s", err.Error()))
+		return nil, err
+	}
+	return r, nil
+}
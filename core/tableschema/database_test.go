@@ -0,0 +1,114 @@
+package tableschema
+
+import (
+	"testing"
+
+	"ne_database/core/base"
+)
+
+func TestQualifiedTableName(t *testing.T) {
+	testCases := []struct {
+		database string
+		name     string
+		expected string
+	}{
+		{"", "users", "users"},
+		{"shop", "users", "shop.users"},
+		{"shop", "crm.users", "crm.users"},
+		{"", "crm.users", "crm.users"},
+	}
+	for _, c := range testCases {
+		got := QualifiedTableName(c.database, c.name)
+		if got != c.expected {
+			t.Errorf("QualifiedTableName(%s, %s) expected %s, got %s", c.database, c.name, c.expected, got)
+			continue
+		}
+		database, table := SplitTableName(got)
+		if QualifiedTableName(database, table) != got {
+			t.Errorf("SplitTableName(%s) got %s, %s", got, database, table)
+		}
+	}
+
+	for _, name := range []string{"", ".users", "shop.", "a.b.c", base.CatalogTableTables} {
+		if tableNameVerification(name) == nil {
+			t.Errorf("tableNameVerification(%s) expected error, but got nil", name)
+		}
+	}
+	for _, name := range []string{"", "a.b", "a/b", base.CatalogSchemaName} {
+		info := &DatabaseInfo{Name: name}
+		if info.Verification() == nil {
+			t.Errorf("DatabaseInfo.Verification(%s) expected error, but got nil", name)
+		}
+	}
+
+	// 外键引用的表名补上本表的数据库名，不能引用其他数据库的表
+	tableInfo := &TableMetaInfo{
+		Name:                "shop.orders",
+		PrimaryKeyFieldInfo: &FieldInfo{Name: "id", Length: 8, FieldType: BigIntType},
+		ValueFieldInfo:      []*FieldInfo{{Name: "user_id", Length: 8, FieldType: BigIntType}},
+		PageSize:            1000,
+		StorageType:         base.StorageTypeMemory,
+		ForeignKeys:         []*ForeignKey{{Column: "user_id", RefTable: "users", RefColumn: "id"}},
+	}
+	err := tableInfo.Verification()
+	if err != nil || tableInfo.ForeignKeys[0].RefTable != "shop.users" {
+		t.Errorf("foreign key ref table failed, got %s, %v", tableInfo.ForeignKeys[0].RefTable, err)
+		return
+	}
+	tableInfo.ForeignKeys[0].RefTable = "crm.users"
+	if tableInfo.Verification() == nil {
+		t.Error("expected error, but got nil")
+	}
+}
+
+// 数据库名和 json 路径使用相同的分隔符: 表名中的分隔符为数据库的分隔符，列名中的分隔符为 json 路径的分隔符
+func TestSeparatorDisambiguation(t *testing.T) {
+	tableInfo := &TableMetaInfo{
+		Name:                "shop.products",
+		PrimaryKeyFieldInfo: &FieldInfo{Name: "id", Length: 8, FieldType: BigIntType},
+		ValueFieldInfo:      []*FieldInfo{{Name: "attrs", Length: 100, FieldType: JSONType}},
+		PageSize:            1000,
+		StorageType:         base.StorageTypeMemory,
+	}
+	err := tableInfo.Verification()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	database, table := SplitTableName(tableInfo.Name)
+	if database != "shop" || table != "products" {
+		t.Errorf("SplitTableName failed, got %s, %s", database, table)
+		return
+	}
+
+	// 带有数据库名的表中，TargetColumn 的第一段为列名，之后为 json 路径
+	item := &base.WherePartItem{TargetColumn: "attrs.dim.w", Operate: base.DataComparatorEqual, Args: [][]byte{[]byte("3")}}
+	column, path := item.ColumnAndPath()
+	if column != "attrs" || path != "dim.w" {
+		t.Errorf("ColumnAndPath failed, got %s, %s", column, path)
+		return
+	}
+	attrs, _ := JSONType.StringToByte("{\"dim\":{\"w\":3}}")
+	id, _ := BigIntType.StringToByte("1")
+	match, err := tableInfo.MatchWherePartItem(item, map[string][]byte{"id": id, "attrs": attrs}, nil)
+	if err != nil || !match {
+		t.Errorf("MatchWherePartItem failed, got %v, %v", match, err)
+		return
+	}
+
+	// 列名不能包含分隔符，否则和 json 路径有歧义
+	for _, field := range []*FieldInfo{
+		{Name: "attrs.color", Length: 10, FieldType: NewCharType("")},
+		{Name: "", Length: 10, FieldType: NewCharType("")},
+	} {
+		tableInfo.ValueFieldInfo = []*FieldInfo{field}
+		if tableInfo.Verification() == nil {
+			t.Errorf("column name <%s> expected error, but got nil", field.Name)
+		}
+	}
+	tableInfo.ValueFieldInfo = []*FieldInfo{{Name: "attrs", Length: 100, FieldType: JSONType}}
+	tableInfo.PrimaryKeyFieldInfo = &FieldInfo{Name: "shop.id", Length: 8, FieldType: BigIntType}
+	if tableInfo.Verification() == nil {
+		t.Error("expected error, but got nil")
+	}
+}
@@ -0,0 +1,257 @@
+package tableschema
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"ne_database/core/base"
+	"ne_database/utils"
+)
+
+// DefaultValueKind 默认值的种类
+type DefaultValueKind int
+
+const (
+	DefaultValueKindNone             DefaultValueKind = iota // 没有默认值
+	DefaultValueKindNull                                     // NULL
+	DefaultValueKindLiteral                                  // 字面值
+	DefaultValueKindCurrentTimestamp                         // CURRENT_TIMESTAMP
+	DefaultValueKindUUID                                     // UUID()
+	DefaultValueKindNextValue                                // NEXTVAL(序列名)
+	DefaultValueKindExpression                               // 表达式
+)
+
+// DefaultValue 解析后的默认值
+type DefaultValue struct {
+	Kind         DefaultValueKind
+	Literal      []byte              // 字面值对应的储存值
+	SequenceName string              // NEXTVAL 使用的序列名
+	Expression   *CompiledExpression // 校验过的默认值表达式
+}
+
+// SequenceNextValueFunc 获取序列的下一个值，由上层（engine）提供
+type SequenceNextValueFunc func(sequenceName string) (int64, base.StandardError)
+
+// unquoteDefaultValue 去掉字面值两边的单引号，并将转义的两个单引号还原为一个
+func unquoteDefaultValue(raw string) string {
+	if len(raw) >= 2 && strings.HasPrefix(raw, "'") && strings.HasSuffix(raw, "'") {
+		return strings.ReplaceAll(raw[1:len(raw)-1], "''", "'")
+	}
+	return raw
+}
+
+// ParseDefaultValue 解析建表语句中的默认值，并校验默认值和列类型是否匹配
+func (info *FieldInfo) ParseDefaultValue() (*DefaultValue, base.StandardError) {
+	raw := strings.TrimSpace(info.DefaultValue)
+	if info.DefaultExpression != nil {
+		return info.parseDefaultExpression(raw)
+	}
+	if raw == "" {
+		return &DefaultValue{Kind: DefaultValueKindNone}, nil
+	}
+	t := info.FieldType.GetType()
+	upper := strings.ToUpper(raw)
+	typeError := func(function string) base.StandardError {
+		errMsg := fmt.Sprintf("列<%s>的类型<%s>不支持默认值: %s", info.Name, t, function)
+		utils.LogError("[FieldInfo.ParseDefaultValue] " + errMsg)
+		return base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeType, base.ErrorBaseCodeTableSchemaError, fmt.Errorf(errMsg))
+	}
+	// 函数生成的文本需要能放入 char 列，在建表时发现，避免每次插入时报错
+	lengthError := func(textLength int) base.StandardError {
+		errMsg := fmt.Sprintf("列<%s>的长度<%d>小于默认值%s生成的文本长度<%d>", info.Name, info.Length, raw, textLength)
+		utils.LogError("[FieldInfo.ParseDefaultValue] " + errMsg)
+		return base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeType, base.ErrorBaseCodeTableSchemaError, fmt.Errorf(errMsg))
+	}
+
+	switch {
+	case upper == base.DefaultValueNull:
+		return &DefaultValue{Kind: DefaultValueKindNull}, nil
+	case upper == base.DefaultValueFunctionCurrentTimestamp:
+		if t != base.DBDataTypeBigInt && t != base.DBDataTypeChar {
+			return nil, typeError(raw)
+		}
+		if t == base.DBDataTypeChar && info.Length < len(base.DefaultValueCurrentTimestampFormat) {
+			return nil, lengthError(len(base.DefaultValueCurrentTimestampFormat))
+		}
+		return &DefaultValue{Kind: DefaultValueKindCurrentTimestamp}, nil
+	case upper == base.DefaultValueFunctionUUID:
+		if t != base.DBDataTypeUUID && t != base.DBDataTypeChar {
+			return nil, typeError(raw)
+		}
+		// char 中储存的是标准格式的文本
+		textLength := len(UUIDType.StringValue(make([]byte, base.DataByteLengthUUID)))
+		if t == base.DBDataTypeChar && info.Length < textLength {
+			return nil, lengthError(textLength)
+		}
+		return &DefaultValue{Kind: DefaultValueKindUUID}, nil
+	case strings.HasPrefix(upper, base.DefaultValueFunctionNextValue+"(") && strings.HasSuffix(upper, ")"):
+		if t != base.DBDataTypeBigInt {
+			return nil, typeError(raw)
+		}
+		name := unquoteDefaultValue(strings.TrimSpace(raw[len(base.DefaultValueFunctionNextValue)+1 : len(raw)-1]))
+		if name == "" {
+			errMsg := fmt.Sprintf("列<%s>的默认值缺少序列名: %s", info.Name, raw)
+			utils.LogError("[FieldInfo.ParseDefaultValue] " + errMsg)
+			return nil, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeType, base.ErrorBaseCodeTableSchemaError, fmt.Errorf(errMsg))
+		}
+		return &DefaultValue{Kind: DefaultValueKindNextValue, SequenceName: name}, nil
+	}
+
+	value, err := info.FieldType.StringToByte(unquoteDefaultValue(raw))
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[FieldInfo.ParseDefaultValue] 列<%s>默认值<%s>错误: %s", info.Name, raw, err.Error()))
+		return nil, err
+	}
+	_, err = info.FieldType.LengthPadding(value, info.Length)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[FieldInfo.ParseDefaultValue] 列<%s>默认值<%s>超长: %s", info.Name, raw, err.Error()))
+		return nil, err
+	}
+	return &DefaultValue{Kind: DefaultValueKindLiteral, Literal: value}, nil
+}
+
+// parseDefaultExpression 校验默认值表达式，表达式不能使用列，结果的类型需要可以写入该列
+func (info *FieldInfo) parseDefaultExpression(raw string) (*DefaultValue, base.StandardError) {
+	if raw != "" {
+		errMsg := fmt.Sprintf("列<%s>不能同时设置默认值和默认值表达式", info.Name)
+		utils.LogError("[FieldInfo.ParseDefaultValue] " + errMsg)
+		return nil, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeInput, base.ErrorBaseCodeTableSchemaError, fmt.Errorf(errMsg))
+	}
+	expression, err := CompileExpression(nil, info.DefaultExpression)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[FieldInfo.ParseDefaultValue] 列<%s>默认值表达式错误: %s", info.Name, err.Error()))
+		return nil, err
+	}
+	if !info.ExpressionTypeAssignable(expression.Type()) {
+		errMsg := fmt.Sprintf("列<%s>的类型<%s>不能使用类型为<%s>的默认值表达式", info.Name, info.FieldType.GetType(), expression.Type())
+		utils.LogError("[FieldInfo.ParseDefaultValue] " + errMsg)
+		return nil, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeType, base.ErrorBaseCodeTableSchemaError, fmt.Errorf(errMsg))
+	}
+	return &DefaultValue{Kind: DefaultValueKindExpression, Expression: expression}, nil
+}
+
+// DefaultDefinition 默认值的定义，默认值表达式为 JSON
+func (info *FieldInfo) DefaultDefinition() string {
+	if info.DefaultExpression != nil {
+		return utils.ToJSON(info.DefaultExpression)
+	}
+	return info.DefaultValue
+}
+
+// NullValue 列的空值，所有类型的全 0 数据都是 Null
+func (info *FieldInfo) NullValue() []byte {
+	return make([]byte, info.Length)
+}
+
+// parsedDefaultValue 解析后的默认值，Verification 之后使用解析的结果，否则重新解析
+func (info *FieldInfo) parsedDefaultValue() (*DefaultValue, base.StandardError) {
+	if info.defaultValue != nil {
+		return info.defaultValue, nil
+	}
+	return info.ParseDefaultValue()
+}
+
+// DefaultValueByte 计算列的默认值，第二个返回值表示该列是否有默认值
+func (info *FieldInfo) DefaultValueByte(nextValue SequenceNextValueFunc) ([]byte, bool, base.StandardError) {
+	defaultValue, err := info.parsedDefaultValue()
+	if err != nil {
+		return nil, false, err
+	}
+	var value []byte
+	switch defaultValue.Kind {
+	case DefaultValueKindNone:
+		return nil, false, nil
+	case DefaultValueKindNull:
+		value = info.NullValue()
+	case DefaultValueKindLiteral:
+		value = defaultValue.Literal
+	case DefaultValueKindCurrentTimestamp:
+		now := time.Now()
+		if info.FieldType.GetType() == base.DBDataTypeBigInt {
+			value, err = base.Int64ToByteList(now.Unix())
+		} else {
+			value = []byte(now.Format(base.DefaultValueCurrentTimestampFormat))
+		}
+	case DefaultValueKindUUID:
+		value, err = NewUUID()
+		if err == nil && info.FieldType.GetType() == base.DBDataTypeChar {
+			value = []byte(UUIDType.StringValue(value))
+		}
+	case DefaultValueKindExpression:
+		var v *ExpressionValue
+		v, err = defaultValue.Expression.Evaluate(nil)
+		if err == nil {
+			value, err = info.ExpressionValueToByte(v)
+		}
+	case DefaultValueKindNextValue:
+		if nextValue == nil {
+			errMsg := fmt.Sprintf("列<%s>的默认值使用了序列<%s>，但没有可用的序列", info.Name, defaultValue.SequenceName)
+			utils.LogError("[FieldInfo.DefaultValueByte] " + errMsg)
+			return nil, false, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+		}
+		var next int64
+		next, err = nextValue(defaultValue.SequenceName)
+		if err == nil {
+			value, err = base.Int64ToByteList(next)
+		}
+	}
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[FieldInfo.DefaultValueByte] 列<%s>计算默认值错误: %s", info.Name, err.Error()))
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+// FillDefaultValues 为插入的数据补齐没有提供的列
+// 自增主键使用对应序列的下一个值，有默认值的列使用默认值，没有默认值的列使用 Null；主键没有提供且没有默认值时报错
+func (info *TableMetaInfo) FillDefaultValues(values map[string][]byte, nextValue SequenceNextValueFunc) (map[string][]byte, base.StandardError) {
+	r := make(map[string][]byte, len(info.ValueFieldInfo)+1)
+	for name, value := range values {
+		if _, ok := info.FieldInfoByName(name); !ok {
+			errMsg := fmt.Sprintf("列<%s>不存在", name)
+			utils.LogError("[TableMetaInfo.FillDefaultValues] " + errMsg)
+			return nil, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+		}
+		r[name] = value
+	}
+
+	fields := append([]*FieldInfo{info.PrimaryKeyFieldInfo}, info.ValueFieldInfo...)
+	for _, field := range fields {
+		if _, ok := r[field.Name]; ok {
+			continue
+		}
+		if field.AutoIncrement {
+			if nextValue == nil {
+				errMsg := fmt.Sprintf("自增主键<%s>没有可用的序列", field.Name)
+				utils.LogError("[TableMetaInfo.FillDefaultValues] " + errMsg)
+				return nil, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+			}
+			next, err := nextValue(AutoIncrementSequenceName(info.Name, field.Name))
+			if err != nil {
+				utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[TableMetaInfo.FillDefaultValues] 自增主键获取序列值错误: %s", err.Error()))
+				return nil, err
+			}
+			r[field.Name], err = base.Int64ToByteList(next)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		value, ok, err := field.DefaultValueByte(nextValue)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[TableMetaInfo.FillDefaultValues] DefaultValueByte 错误: %s", err.Error()))
+			return nil, err
+		}
+		if !ok {
+			if field == info.PrimaryKeyFieldInfo {
+				errMsg := fmt.Sprintf("主键<%s>没有提供值", field.Name)
+				utils.LogError("[TableMetaInfo.FillDefaultValues] " + errMsg)
+				return nil, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+			}
+			value = field.NullValue()
+		}
+		r[field.Name] = value
+	}
+	return r, nil
+}
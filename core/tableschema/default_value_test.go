@@ -0,0 +1,193 @@
+package tableschema
+
+import (
+	"testing"
+
+	"ne_database/core/base"
+	"ne_database/utils/list"
+)
+
+func TestFieldInfo_ParseDefaultValue(t *testing.T) {
+	testCases := []struct {
+		field     *FieldInfo
+		expectErr bool
+		kind      DefaultValueKind
+	}{
+		{&FieldInfo{Name: "a", Length: 8, FieldType: BigIntType}, false, DefaultValueKindNone},
+		{&FieldInfo{Name: "a", Length: 8, FieldType: BigIntType, DefaultValue: "10"}, false, DefaultValueKindLiteral},
+		{&FieldInfo{Name: "a", Length: 8, FieldType: BigIntType, DefaultValue: "abc"}, true, DefaultValueKindNone},
+		{&FieldInfo{Name: "a", Length: 8, FieldType: BigIntType, DefaultValue: "current_timestamp"}, false, DefaultValueKindCurrentTimestamp},
+		{&FieldInfo{Name: "a", Length: 8, FieldType: BigIntType, DefaultValue: "NEXTVAL(order_seq)"}, false, DefaultValueKindNextValue},
+		{&FieldInfo{Name: "a", Length: 8, FieldType: BigIntType, DefaultValue: "NEXTVAL()"}, true, DefaultValueKindNone},
+		{&FieldInfo{Name: "a", Length: 8, FieldType: BigIntType, DefaultValue: "UUID()"}, true, DefaultValueKindNone},
+		{&FieldInfo{Name: "a", Length: 8, FieldType: BigIntType, DefaultValue: "NULL"}, false, DefaultValueKindNull},
+		{&FieldInfo{Name: "a", Length: 3, FieldType: CharType, DefaultValue: "'it''s'"}, true, DefaultValueKindNone},
+		{&FieldInfo{Name: "a", Length: 8, FieldType: CharType, DefaultValue: "'it''s'"}, false, DefaultValueKindLiteral},
+		{&FieldInfo{Name: "a", Length: 16, FieldType: UUIDType, DefaultValue: "uuid()"}, false, DefaultValueKindUUID},
+		{&FieldInfo{Name: "a", Length: 16, FieldType: UUIDType, DefaultValue: "CURRENT_TIMESTAMP"}, true, DefaultValueKindNone},
+		// char 列需要能放下函数生成的文本
+		{&FieldInfo{Name: "a", Length: 19, FieldType: CharType, DefaultValue: "CURRENT_TIMESTAMP"}, false, DefaultValueKindCurrentTimestamp},
+		{&FieldInfo{Name: "a", Length: 10, FieldType: CharType, DefaultValue: "CURRENT_TIMESTAMP"}, true, DefaultValueKindNone},
+		{&FieldInfo{Name: "a", Length: 36, FieldType: CharType, DefaultValue: "UUID()"}, false, DefaultValueKindUUID},
+		{&FieldInfo{Name: "a", Length: 32, FieldType: CharType, DefaultValue: "UUID()"}, true, DefaultValueKindNone},
+		{&FieldInfo{Name: "a", Length: 2, FieldType: NewEnumType("new", "paid"), DefaultValue: "new"}, false, DefaultValueKindLiteral},
+		{&FieldInfo{Name: "a", Length: 2, FieldType: NewEnumType("new", "paid"), DefaultValue: "refund"}, true, DefaultValueKindNone},
+	}
+	for i, c := range testCases {
+		defaultValue, err := c.field.ParseDefaultValue()
+		if c.expectErr {
+			if err == nil {
+				t.Errorf("case %d: expected error, but got nil", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+			continue
+		}
+		if defaultValue.Kind != c.kind {
+			t.Errorf("case %d: expected kind %d but got %d", i, c.kind, defaultValue.Kind)
+		}
+	}
+
+	// 建表校验时检查默认值
+	err := (&FieldInfo{Name: "a", Length: 8, FieldType: BigIntType, DefaultValue: "abc"}).Verification()
+	if err == nil {
+		t.Error("expected error, but got nil")
+		return
+	}
+}
+
+func TestTableMetaInfo_FillDefaultValues(t *testing.T) {
+	tableInfo := &TableMetaInfo{
+		Name: "orders",
+		PrimaryKeyFieldInfo: &FieldInfo{
+			Name:         "id",
+			Length:       8,
+			FieldType:    BigIntType,
+			DefaultValue: "NEXTVAL(order_seq)",
+		},
+		ValueFieldInfo: []*FieldInfo{
+			{Name: "status", Length: 2, FieldType: NewEnumType("new", "paid"), DefaultValue: "'new'"},
+			{Name: "token", Length: 16, FieldType: UUIDType, DefaultValue: "UUID()"},
+			{Name: "created_at", Length: 8, FieldType: BigIntType, DefaultValue: "CURRENT_TIMESTAMP"},
+			{Name: "remark", Length: 20, FieldType: CharType},
+		},
+		PageSize:    1000,
+		StorageType: base.StorageTypeMemory,
+	}
+	nextValue := func(sequenceName string) (int64, base.StandardError) {
+		if sequenceName != "order_seq" {
+			t.Errorf("unexpected sequence: %s", sequenceName)
+		}
+		return 7, nil
+	}
+
+	row, err := tableInfo.FillDefaultValues(map[string][]byte{"remark": []byte("hi")}, nextValue)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	expectID, _ := base.Int64ToByteList(7)
+	if !list.ByteListEqual(row["id"], expectID) {
+		t.Errorf("FillDefaultValues id failed, got %#v", row["id"])
+		return
+	}
+	if !list.ByteListEqual(row["status"], []byte{0x00, 0x01}) {
+		t.Errorf("FillDefaultValues status failed, got %#v", row["status"])
+		return
+	}
+	isNull, _ := UUIDType.IsNull(row["token"])
+	if len(row["token"]) != 16 || isNull {
+		t.Errorf("FillDefaultValues token failed, got %#v", row["token"])
+		return
+	}
+	isNull, _ = BigIntType.IsNull(row["created_at"])
+	if isNull {
+		t.Error("FillDefaultValues created_at failed, got Null")
+		return
+	}
+	if string(row["remark"]) != "hi" {
+		t.Errorf("FillDefaultValues remark failed, got %s", string(row["remark"]))
+		return
+	}
+
+	// 没有序列时不能使用 NEXTVAL
+	_, err = tableInfo.FillDefaultValues(map[string][]byte{}, nil)
+	if err == nil {
+		t.Error("expected error, but got nil")
+		return
+	}
+	_, err = tableInfo.FillDefaultValues(map[string][]byte{"price": []byte("1")}, nextValue)
+	if err == nil {
+		t.Error("expected error, but got nil")
+		return
+	}
+
+	// 没有默认值的主键必须提供
+	tableInfo.PrimaryKeyFieldInfo.DefaultValue = ""
+	_, err = tableInfo.FillDefaultValues(map[string][]byte{}, nextValue)
+	if err == nil {
+		t.Error("expected error, but got nil")
+		return
+	}
+	id, _ := base.Int64ToByteList(1)
+	row, err = tableInfo.FillDefaultValues(map[string][]byte{"id": id}, nextValue)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	isNull, _ = CharType.IsNull(CharType.TrimRaw(row["remark"]))
+	if !isNull {
+		t.Error("FillDefaultValues remark failed, expected Null")
+		return
+	}
+}
+
+func TestFieldInfo_DefaultValueByte_Parsed(t *testing.T) {
+	// 加载表结构时解析默认值，计算默认值时使用解析的结果
+	tableInfo := &TableMetaInfo{
+		Name:                "orders",
+		PrimaryKeyFieldInfo: &FieldInfo{Name: "id", Length: 8, FieldType: BigIntType},
+		ValueFieldInfo: []*FieldInfo{
+			{Name: "status", Length: 10, FieldType: CharType, DefaultValue: "'new'"},
+			{Name: "created_at", Length: 20, FieldType: CharType, DefaultValue: "CURRENT_TIMESTAMP"},
+		},
+		PageSize:    1000,
+		StorageType: base.StorageTypeMemory,
+	}
+	jsonByte, err := tableInfo.TableMetaInfoToJsonByte()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	loadInfo, err := InitTableMetaInfoByJson(string(jsonByte))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	testCases := []struct {
+		field  *FieldInfo
+		kind   DefaultValueKind
+		expect string // 为空时不比较
+	}{
+		{loadInfo.ValueFieldInfo[0], DefaultValueKindLiteral, "new"},
+		{loadInfo.ValueFieldInfo[1], DefaultValueKindCurrentTimestamp, ""},
+	}
+	for i, c := range testCases {
+		if c.field.defaultValue == nil || c.field.defaultValue.Kind != c.kind {
+			t.Errorf("case %d: expected parsed default value of kind %d, but got %#v", i, c.kind, c.field.defaultValue)
+			continue
+		}
+		parsed := c.field.defaultValue
+		value, ok, err := c.field.DefaultValueByte(nil)
+		if err != nil || !ok {
+			t.Errorf("case %d: unexpected result: %v, %v", i, ok, err)
+			continue
+		}
+		if c.field.defaultValue != parsed {
+			t.Errorf("case %d: expected parsed default value to be reused", i)
+		}
+		if c.expect != "" && string(value) != c.expect {
+			t.Errorf("case %d: expected %s, but got %s", i, c.expect, string(value))
+		}
+	}
+}
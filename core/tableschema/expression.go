@@ -0,0 +1,831 @@
+package tableschema
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"ne_database/core/base"
+	"ne_database/utils"
+)
+
+// ExpressionValue 表达式的值，Type 为 null 时是 Null
+type ExpressionValue struct {
+	Type   base.ExpressionType
+	Int    int64
+	String string
+	Bool   bool
+}
+
+func (v *ExpressionValue) IsNull() bool {
+	return v.Type == base.ExpressionTypeNull
+}
+
+// StringValue 可读值，bool 为 true / false
+func (v *ExpressionValue) StringValue() string {
+	switch v.Type {
+	case base.ExpressionTypeBigInt:
+		return strconv.FormatInt(v.Int, 10)
+	case base.ExpressionTypeBool:
+		return strconv.FormatBool(v.Bool)
+	case base.ExpressionTypeChar:
+		return v.String
+	}
+	return base.ValueStringNullValue
+}
+
+var nullExpressionValue = &ExpressionValue{Type: base.ExpressionTypeNull}
+
+func intExpressionValue(i int64) *ExpressionValue {
+	return &ExpressionValue{Type: base.ExpressionTypeBigInt, Int: i}
+}
+
+func charExpressionValue(s string) *ExpressionValue {
+	return &ExpressionValue{Type: base.ExpressionTypeChar, String: s}
+}
+
+func boolExpressionValue(b bool) *ExpressionValue {
+	return &ExpressionValue{Type: base.ExpressionTypeBool, Bool: b}
+}
+
+// CompiledExpression 校验过列、运算符、函数和参数类型的表达式，可以对多行重复计算
+type CompiledExpression struct {
+	expr      *base.Expression
+	valueType base.ExpressionType // 结果的类型，为 null 时结果只能是 Null
+	field     *FieldInfo          // column 的列
+	value     *ExpressionValue    // literal 的值
+	args      []*CompiledExpression
+}
+
+// expressionFunctionSpec 函数的参数和结果的类型
+// args 为每个参数的类型，参数比 args 多时使用最后一个的类型，类型为空时可以是任何类型；maxArgs 为 -1 时不限制参数个数
+type expressionFunctionSpec struct {
+	args    []base.ExpressionType
+	minArgs int
+	maxArgs int
+	result  base.ExpressionType
+}
+
+var expressionFunctionSpecs = map[base.ExpressionFunction]*expressionFunctionSpec{
+	base.ExpressionFunctionLower:         {[]base.ExpressionType{base.ExpressionTypeChar}, 1, 1, base.ExpressionTypeChar},
+	base.ExpressionFunctionUpper:         {[]base.ExpressionType{base.ExpressionTypeChar}, 1, 1, base.ExpressionTypeChar},
+	base.ExpressionFunctionLength:        {[]base.ExpressionType{base.ExpressionTypeChar}, 1, 1, base.ExpressionTypeBigInt},
+	base.ExpressionFunctionTrim:          {[]base.ExpressionType{base.ExpressionTypeChar}, 1, 1, base.ExpressionTypeChar},
+	base.ExpressionFunctionSubstr:        {[]base.ExpressionType{base.ExpressionTypeChar, base.ExpressionTypeBigInt}, 2, 3, base.ExpressionTypeChar},
+	base.ExpressionFunctionConcat:        {[]base.ExpressionType{""}, 1, -1, base.ExpressionTypeChar},
+	base.ExpressionFunctionReplace:       {[]base.ExpressionType{base.ExpressionTypeChar}, 3, 3, base.ExpressionTypeChar},
+	base.ExpressionFunctionStrpos:        {[]base.ExpressionType{base.ExpressionTypeChar}, 2, 2, base.ExpressionTypeBigInt},
+	base.ExpressionFunctionAbs:           {[]base.ExpressionType{base.ExpressionTypeBigInt}, 1, 1, base.ExpressionTypeBigInt},
+	base.ExpressionFunctionMod:           {[]base.ExpressionType{base.ExpressionTypeBigInt}, 2, 2, base.ExpressionTypeBigInt},
+	base.ExpressionFunctionNow:           {nil, 0, 0, base.ExpressionTypeChar},
+	base.ExpressionFunctionDate:          {[]base.ExpressionType{base.ExpressionTypeChar}, 1, 1, base.ExpressionTypeChar},
+	base.ExpressionFunctionYear:          {[]base.ExpressionType{base.ExpressionTypeChar}, 1, 1, base.ExpressionTypeBigInt},
+	base.ExpressionFunctionMonth:         {[]base.ExpressionType{base.ExpressionTypeChar}, 1, 1, base.ExpressionTypeBigInt},
+	base.ExpressionFunctionDay:           {[]base.ExpressionType{base.ExpressionTypeChar}, 1, 1, base.ExpressionTypeBigInt},
+	base.ExpressionFunctionHour:          {[]base.ExpressionType{base.ExpressionTypeChar}, 1, 1, base.ExpressionTypeBigInt},
+	base.ExpressionFunctionMinute:        {[]base.ExpressionType{base.ExpressionTypeChar}, 1, 1, base.ExpressionTypeBigInt},
+	base.ExpressionFunctionSecond:        {[]base.ExpressionType{base.ExpressionTypeChar}, 1, 1, base.ExpressionTypeBigInt},
+	base.ExpressionFunctionUnixTimestamp: {[]base.ExpressionType{base.ExpressionTypeChar}, 1, 1, base.ExpressionTypeBigInt},
+	base.ExpressionFunctionFromUnixTime:  {[]base.ExpressionType{base.ExpressionTypeBigInt}, 1, 1, base.ExpressionTypeChar},
+	base.ExpressionFunctionDateAdd:       {[]base.ExpressionType{base.ExpressionTypeChar, base.ExpressionTypeBigInt}, 2, 2, base.ExpressionTypeChar},
+	base.ExpressionFunctionDateDiff:      {[]base.ExpressionType{base.ExpressionTypeChar}, 2, 2, base.ExpressionTypeBigInt},
+}
+
+func expressionError(errMsg string) base.StandardError {
+	utils.LogError("[Expression] " + errMsg)
+	return base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+}
+
+// FieldExpressionType 列的值在表达式中的类型，bigint 之外的类型都使用可读值作为 char
+func FieldExpressionType(field *FieldInfo) base.ExpressionType {
+	if field.FieldType.GetType() == base.DBDataTypeBigInt {
+		return base.ExpressionTypeBigInt
+	}
+	return base.ExpressionTypeChar
+}
+
+// commonExpressionType 两个类型的公共类型，Null 可以和任何类型一起使用
+func commonExpressionType(a base.ExpressionType, b base.ExpressionType) (base.ExpressionType, bool) {
+	switch {
+	case a == base.ExpressionTypeNull:
+		return b, true
+	case b == base.ExpressionTypeNull || a == b:
+		return a, true
+	}
+	return "", false
+}
+
+func (c *CompiledExpression) argsCommonType(args []*CompiledExpression) (base.ExpressionType, base.StandardError) {
+	r := base.ExpressionType(base.ExpressionTypeNull)
+	for _, arg := range args {
+		t, ok := commonExpressionType(r, arg.valueType)
+		if !ok {
+			return "", expressionError(fmt.Sprintf("表达式<%s>的参数类型<%s>和<%s>不同", c.name(), r, arg.valueType))
+		}
+		r = t
+	}
+	return r, nil
+}
+
+func (c *CompiledExpression) name() string {
+	switch c.expr.Kind {
+	case base.ExpressionKindOperator:
+		return string(c.expr.Operator)
+	case base.ExpressionKindFunction:
+		return string(c.expr.Function)
+	}
+	return string(c.expr.Kind)
+}
+
+func (c *CompiledExpression) expectArgs(minArgs int, maxArgs int) base.StandardError {
+	if len(c.args) < minArgs || (maxArgs >= 0 && len(c.args) > maxArgs) {
+		return expressionError(fmt.Sprintf("表达式<%s>的参数个数<%d>错误", c.name(), len(c.args)))
+	}
+	return nil
+}
+
+func (c *CompiledExpression) expectType(t base.ExpressionType) base.StandardError {
+	for _, arg := range c.args {
+		if arg.valueType != t && arg.valueType != base.ExpressionTypeNull {
+			return expressionError(fmt.Sprintf("表达式<%s>的参数类型应该为<%s>，实际为<%s>", c.name(), t, arg.valueType))
+		}
+	}
+	return nil
+}
+
+// CompileExpression 校验表达式，info 为 nil 时不能使用列（如: 默认值）
+func CompileExpression(info *TableMetaInfo, expr *base.Expression) (*CompiledExpression, base.StandardError) {
+	if expr == nil {
+		return nil, expressionError("表达式为空")
+	}
+	c := &CompiledExpression{expr: expr}
+	for _, arg := range expr.Args {
+		compiled, err := CompileExpression(info, arg)
+		if err != nil {
+			return nil, err
+		}
+		c.args = append(c.args, compiled)
+	}
+	var err base.StandardError
+	switch expr.Kind {
+	case base.ExpressionKindColumn:
+		if info == nil {
+			return nil, expressionError(fmt.Sprintf("表达式不能使用列<%s>", expr.Column))
+		}
+		field, ok := info.FieldInfoByName(expr.Column)
+		if !ok {
+			return nil, expressionError(fmt.Sprintf("表达式使用的列<%s>不存在", expr.Column))
+		}
+		c.field = field
+		c.valueType = FieldExpressionType(field)
+		err = c.expectArgs(0, 0)
+	case base.ExpressionKindLiteral:
+		c.value, err = castExpressionValue(charExpressionValue(expr.Value), expr.Type)
+		if expr.Type == base.ExpressionTypeNull {
+			c.value, err = nullExpressionValue, nil
+		}
+		if err == nil {
+			c.valueType = c.value.Type
+			err = c.expectArgs(0, 0)
+		}
+	case base.ExpressionKindOperator:
+		err = c.compileOperator()
+	case base.ExpressionKindFunction:
+		err = c.compileFunction()
+	case base.ExpressionKindCase:
+		err = c.expectArgs(2, -1)
+		if err == nil {
+			results := make([]*CompiledExpression, 0, len(c.args)/2+1)
+			for i, arg := range c.args {
+				if i%2 == 1 || i == len(c.args)-1 {
+					results = append(results, arg)
+				} else if arg.valueType != base.ExpressionTypeBool && arg.valueType != base.ExpressionTypeNull {
+					return nil, expressionError(fmt.Sprintf("CASE 的条件类型应该为<bool>，实际为<%s>", arg.valueType))
+				}
+			}
+			c.valueType, err = c.argsCommonType(results)
+		}
+	case base.ExpressionKindParameter:
+		return nil, expressionError(fmt.Sprintf("参数<%s>没有绑定", expr.Value))
+	default:
+		return nil, expressionError(fmt.Sprintf("不支持的表达式种类: %s", expr.Kind))
+	}
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[CompileExpression] 表达式错误: %s, %s", utils.ToJSON(expr), err.Error()))
+		return nil, err
+	}
+	return c, nil
+}
+
+// CompileCondition 校验作为条件的表达式，结果需要是 bool
+func CompileCondition(info *TableMetaInfo, expr *base.Expression) (*CompiledExpression, base.StandardError) {
+	c, err := CompileExpression(info, expr)
+	if err != nil {
+		return nil, err
+	}
+	if c.valueType != base.ExpressionTypeBool && c.valueType != base.ExpressionTypeNull {
+		return nil, expressionError(fmt.Sprintf("条件的类型应该为<bool>，实际为<%s>", c.valueType))
+	}
+	return c, nil
+}
+
+func (c *CompiledExpression) compileOperator() base.StandardError {
+	var err base.StandardError
+	switch c.expr.Operator {
+	case base.ExpressionOperatorAdd, base.ExpressionOperatorSubtract, base.ExpressionOperatorMultiply,
+		base.ExpressionOperatorDivide, base.ExpressionOperatorModulo:
+		minArgs := 2
+		if c.expr.Operator == base.ExpressionOperatorSubtract {
+			minArgs = 1
+		}
+		c.valueType = base.ExpressionTypeBigInt
+		err = c.expectArgs(minArgs, 2)
+		if err == nil {
+			err = c.expectType(base.ExpressionTypeBigInt)
+		}
+	case base.ExpressionOperatorEqual, base.ExpressionOperatorNotEqual, base.ExpressionOperatorGreater,
+		base.ExpressionOperatorGreaterAndEqual, base.ExpressionOperatorLess, base.ExpressionOperatorLessAndEqual:
+		c.valueType = base.ExpressionTypeBool
+		err = c.expectArgs(2, 2)
+		if err == nil {
+			_, err = c.argsCommonType(c.args)
+		}
+	case base.ExpressionOperatorIn:
+		c.valueType = base.ExpressionTypeBool
+		err = c.expectArgs(2, -1)
+		if err == nil {
+			_, err = c.argsCommonType(c.args)
+		}
+	case base.ExpressionOperatorAnd, base.ExpressionOperatorOr:
+		c.valueType = base.ExpressionTypeBool
+		err = c.expectArgs(2, -1)
+		if err == nil {
+			err = c.expectType(base.ExpressionTypeBool)
+		}
+	case base.ExpressionOperatorNot:
+		c.valueType = base.ExpressionTypeBool
+		err = c.expectArgs(1, 1)
+		if err == nil {
+			err = c.expectType(base.ExpressionTypeBool)
+		}
+	case base.ExpressionOperatorIsNull, base.ExpressionOperatorIsNotNull:
+		c.valueType = base.ExpressionTypeBool
+		err = c.expectArgs(1, 1)
+	case base.ExpressionOperatorLike:
+		c.valueType = base.ExpressionTypeBool
+		err = c.expectArgs(2, 2)
+		if err == nil {
+			err = c.expectType(base.ExpressionTypeChar)
+		}
+	default:
+		return expressionError(fmt.Sprintf("不支持的运算符: %s", c.expr.Operator))
+	}
+	return err
+}
+
+func (c *CompiledExpression) compileFunction() base.StandardError {
+	var err base.StandardError
+	switch c.expr.Function {
+	case base.ExpressionFunctionGreatest, base.ExpressionFunctionLeast, base.ExpressionFunctionCoalesce:
+		err = c.expectArgs(1, -1)
+		if err == nil {
+			c.valueType, err = c.argsCommonType(c.args)
+		}
+		return err
+	case base.ExpressionFunctionNullIf:
+		err = c.expectArgs(2, 2)
+		if err == nil {
+			c.valueType, err = c.argsCommonType(c.args)
+		}
+		return err
+	case base.ExpressionFunctionCast:
+		switch c.expr.Type {
+		case base.ExpressionTypeBigInt, base.ExpressionTypeChar, base.ExpressionTypeBool:
+		default:
+			return expressionError(fmt.Sprintf("不支持 cast 为类型<%s>", c.expr.Type))
+		}
+		c.valueType = c.expr.Type
+		return c.expectArgs(1, 1)
+	}
+	spec, ok := expressionFunctionSpecs[c.expr.Function]
+	if !ok {
+		return expressionError(fmt.Sprintf("不支持的函数: %s", c.expr.Function))
+	}
+	err = c.expectArgs(spec.minArgs, spec.maxArgs)
+	if err != nil {
+		return err
+	}
+	for i, arg := range c.args {
+		t := spec.args[len(spec.args)-1]
+		if i < len(spec.args) {
+			t = spec.args[i]
+		}
+		if t != "" && arg.valueType != t && arg.valueType != base.ExpressionTypeNull {
+			return expressionError(fmt.Sprintf("函数<%s>的第%d个参数类型应该为<%s>，实际为<%s>", c.expr.Function, i+1, t, arg.valueType))
+		}
+	}
+	c.valueType = spec.result
+	return nil
+}
+
+// Type 表达式结果的类型
+func (c *CompiledExpression) Type() base.ExpressionType {
+	return c.valueType
+}
+
+// Evaluate 计算一行数据的表达式的值，row 为 列名 -> 值
+func (c *CompiledExpression) Evaluate(row map[string][]byte) (*ExpressionValue, base.StandardError) {
+	switch c.expr.Kind {
+	case base.ExpressionKindColumn:
+		value, ok := row[c.field.Name]
+		if !ok {
+			return nil, expressionError(fmt.Sprintf("数据中不存在列<%s>", c.field.Name))
+		}
+		return decodeExpressionValue(c.field, value)
+	case base.ExpressionKindLiteral:
+		return c.value, nil
+	case base.ExpressionKindCase:
+		for i := 0; i+1 < len(c.args); i += 2 {
+			match, err := c.args[i].Match(row)
+			if err != nil {
+				return nil, err
+			}
+			if match {
+				return c.args[i+1].Evaluate(row)
+			}
+		}
+		if len(c.args)%2 == 1 {
+			return c.args[len(c.args)-1].Evaluate(row)
+		}
+		return nullExpressionValue, nil
+	case base.ExpressionKindOperator:
+		switch c.expr.Operator {
+		case base.ExpressionOperatorAnd, base.ExpressionOperatorOr:
+			return c.evaluateLogic(row)
+		}
+	}
+
+	args := make([]*ExpressionValue, 0, len(c.args))
+	for _, arg := range c.args {
+		v, err := arg.Evaluate(row)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+	if c.expr.Kind == base.ExpressionKindOperator {
+		return c.evaluateOperator(args)
+	}
+	return c.evaluateFunction(args)
+}
+
+// Match 条件是否满足，结果为 Null 时不满足
+func (c *CompiledExpression) Match(row map[string][]byte) (bool, base.StandardError) {
+	v, err := c.Evaluate(row)
+	if err != nil {
+		return false, err
+	}
+	return v.Type == base.ExpressionTypeBool && v.Bool, nil
+}
+
+func decodeExpressionValue(field *FieldInfo, value []byte) (*ExpressionValue, base.StandardError) {
+	value = field.FieldType.TrimRaw(value)
+	isNull, err := field.FieldType.IsNull(value)
+	if err != nil {
+		return nil, err
+	}
+	if isNull {
+		return nullExpressionValue, nil
+	}
+	if field.FieldType.GetType() == base.DBDataTypeBigInt {
+		i, err := base.ByteListToInt64(value)
+		if err != nil {
+			return nil, err
+		}
+		return intExpressionValue(i), nil
+	}
+	return charExpressionValue(field.FieldType.StringValue(value)), nil
+}
+
+// evaluateLogic and / or，和 SQL 一样: and 有 false 时为 false，or 有 true 时为 true，否则有 Null 时为 Null
+func (c *CompiledExpression) evaluateLogic(row map[string][]byte) (*ExpressionValue, base.StandardError) {
+	shortCircuit := c.expr.Operator == base.ExpressionOperatorOr
+	hasNull := false
+	for _, arg := range c.args {
+		v, err := arg.Evaluate(row)
+		if err != nil {
+			return nil, err
+		}
+		if v.IsNull() {
+			hasNull = true
+			continue
+		}
+		if v.Bool == shortCircuit {
+			return boolExpressionValue(shortCircuit), nil
+		}
+	}
+	if hasNull {
+		return nullExpressionValue, nil
+	}
+	return boolExpressionValue(!shortCircuit), nil
+}
+
+// collationFieldType 比较时使用的字符类型: 参数中有 char 类型的列时使用该列的排序规则，否则按字节比较
+func (c *CompiledExpression) collationFieldType() MetaType {
+	for _, arg := range c.args {
+		if arg.field != nil && arg.field.FieldType.GetType() == base.DBDataTypeChar {
+			return arg.field.FieldType
+		}
+	}
+	return nil
+}
+
+// compareExpressionValue 比较两个相同类型、不是 Null 的值，返回 -1 / 0 / 1
+func compareExpressionValue(a *ExpressionValue, b *ExpressionValue, fieldType MetaType) (int, base.StandardError) {
+	switch a.Type {
+	case base.ExpressionTypeBigInt:
+		switch {
+		case a.Int < b.Int:
+			return -1, nil
+		case a.Int > b.Int:
+			return 1, nil
+		}
+		return 0, nil
+	case base.ExpressionTypeBool:
+		switch {
+		case a.Bool == b.Bool:
+			return 0, nil
+		case b.Bool:
+			return -1, nil
+		}
+		return 1, nil
+	}
+	if fieldType == nil {
+		return strings.Compare(a.String, b.String), nil
+	}
+	less, err := fieldType.Less([]byte(a.String), []byte(b.String))
+	if err != nil || less {
+		return -1, err
+	}
+	greater, err := fieldType.Greater([]byte(a.String), []byte(b.String))
+	if err != nil || greater {
+		return 1, err
+	}
+	return 0, nil
+}
+
+func (c *CompiledExpression) evaluateOperator(args []*ExpressionValue) (*ExpressionValue, base.StandardError) {
+	switch c.expr.Operator {
+	case base.ExpressionOperatorIsNull:
+		return boolExpressionValue(args[0].IsNull()), nil
+	case base.ExpressionOperatorIsNotNull:
+		return boolExpressionValue(!args[0].IsNull()), nil
+	case base.ExpressionOperatorNot:
+		if args[0].IsNull() {
+			return nullExpressionValue, nil
+		}
+		return boolExpressionValue(!args[0].Bool), nil
+	case base.ExpressionOperatorIn:
+		// 和 SQL 一样: 有相等的值时为 true，否则参数中有 Null 时为 Null
+		if args[0].IsNull() {
+			return nullExpressionValue, nil
+		}
+		hasNull := false
+		for _, arg := range args[1:] {
+			if arg.IsNull() {
+				hasNull = true
+				continue
+			}
+			cmp, err := compareExpressionValue(args[0], arg, c.collationFieldType())
+			if err != nil {
+				return nil, err
+			}
+			if cmp == 0 {
+				return boolExpressionValue(true), nil
+			}
+		}
+		if hasNull {
+			return nullExpressionValue, nil
+		}
+		return boolExpressionValue(false), nil
+	}
+	for _, arg := range args {
+		if arg.IsNull() {
+			return nullExpressionValue, nil
+		}
+	}
+
+	switch c.expr.Operator {
+	case base.ExpressionOperatorEqual, base.ExpressionOperatorNotEqual, base.ExpressionOperatorGreater,
+		base.ExpressionOperatorGreaterAndEqual, base.ExpressionOperatorLess, base.ExpressionOperatorLessAndEqual:
+		cmp, err := compareExpressionValue(args[0], args[1], c.collationFieldType())
+		if err != nil {
+			return nil, err
+		}
+		switch c.expr.Operator {
+		case base.ExpressionOperatorEqual:
+			return boolExpressionValue(cmp == 0), nil
+		case base.ExpressionOperatorNotEqual:
+			return boolExpressionValue(cmp != 0), nil
+		case base.ExpressionOperatorGreater:
+			return boolExpressionValue(cmp > 0), nil
+		case base.ExpressionOperatorGreaterAndEqual:
+			return boolExpressionValue(cmp >= 0), nil
+		case base.ExpressionOperatorLess:
+			return boolExpressionValue(cmp < 0), nil
+		}
+		return boolExpressionValue(cmp <= 0), nil
+	case base.ExpressionOperatorLike:
+		// 有 char 类型的列时使用列的排序规则（忽略大小写的排序规则下 like 同样忽略大小写）
+		var (
+			match bool
+			err   base.StandardError
+		)
+		if fieldType := c.collationFieldType(); fieldType != nil {
+			match, err = fieldType.Like([]byte(args[1].String), []byte(args[0].String))
+		} else {
+			match, err = LikeMatch([]byte(args[1].String), []byte(args[0].String))
+		}
+		if err != nil {
+			return nil, err
+		}
+		return boolExpressionValue(match), nil
+	}
+
+	// 算术运算
+	if len(args) == 1 {
+		if args[0].Int == math.MinInt64 {
+			return nil, expressionError("取负的结果超出bigint的范围")
+		}
+		return intExpressionValue(-args[0].Int), nil
+	}
+	a, b := args[0].Int, args[1].Int
+	overflow := false
+	var r int64
+	switch c.expr.Operator {
+	case base.ExpressionOperatorAdd:
+		r = a + b
+		overflow = (b > 0 && a > math.MaxInt64-b) || (b < 0 && a < math.MinInt64-b)
+	case base.ExpressionOperatorSubtract:
+		r = a - b
+		overflow = (b < 0 && a > math.MaxInt64+b) || (b > 0 && a < math.MinInt64+b)
+	case base.ExpressionOperatorMultiply:
+		r = a * b
+		overflow = a != 0 && (r/a != b || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64))
+	case base.ExpressionOperatorDivide, base.ExpressionOperatorModulo:
+		if b == 0 {
+			return nil, expressionError("除数为0")
+		}
+		if b == -1 {
+			// MinInt64 / -1 溢出，MinInt64 % -1 为 0
+			overflow = a == math.MinInt64 && c.expr.Operator == base.ExpressionOperatorDivide
+			if c.expr.Operator == base.ExpressionOperatorDivide {
+				r = -a
+			}
+			break
+		}
+		if c.expr.Operator == base.ExpressionOperatorDivide {
+			r = a / b
+		} else {
+			r = a % b
+		}
+	}
+	if overflow {
+		return nil, expressionError(fmt.Sprintf("运算<%s>的结果超出bigint的范围", c.expr.Operator))
+	}
+	return intExpressionValue(r), nil
+}
+
+// parseExpressionTime 解析时间，可以是 DefaultValueCurrentTimestampFormat 或者 ExpressionDateFormat 格式
+func parseExpressionTime(s string) (time.Time, base.StandardError) {
+	for _, layout := range []string{base.DefaultValueCurrentTimestampFormat, base.ExpressionDateFormat} {
+		t, er := time.ParseInLocation(layout, s, time.Local)
+		if er == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, expressionError(fmt.Sprintf("时间<%s>的格式错误", s))
+}
+
+func (c *CompiledExpression) evaluateFunction(args []*ExpressionValue) (*ExpressionValue, base.StandardError) {
+	switch c.expr.Function {
+	case base.ExpressionFunctionCoalesce:
+		for _, arg := range args {
+			if !arg.IsNull() {
+				return arg, nil
+			}
+		}
+		return nullExpressionValue, nil
+	case base.ExpressionFunctionGreatest, base.ExpressionFunctionLeast:
+		// 忽略 Null，全部为 Null 时结果为 Null
+		r := nullExpressionValue
+		for _, arg := range args {
+			if arg.IsNull() {
+				continue
+			}
+			if r.IsNull() {
+				r = arg
+				continue
+			}
+			cmp, err := compareExpressionValue(arg, r, c.collationFieldType())
+			if err != nil {
+				return nil, err
+			}
+			if (cmp > 0) == (c.expr.Function == base.ExpressionFunctionGreatest) && cmp != 0 {
+				r = arg
+			}
+		}
+		return r, nil
+	case base.ExpressionFunctionNullIf:
+		if args[0].IsNull() || args[1].IsNull() {
+			return args[0], nil
+		}
+		cmp, err := compareExpressionValue(args[0], args[1], c.collationFieldType())
+		if err != nil || cmp == 0 {
+			return nullExpressionValue, err
+		}
+		return args[0], nil
+	case base.ExpressionFunctionCast:
+		return castExpressionValue(args[0], c.expr.Type)
+	case base.ExpressionFunctionNow:
+		return charExpressionValue(time.Now().Format(base.DefaultValueCurrentTimestampFormat)), nil
+	}
+	for _, arg := range args {
+		if arg.IsNull() {
+			return nullExpressionValue, nil
+		}
+	}
+
+	switch c.expr.Function {
+	case base.ExpressionFunctionLower:
+		return charExpressionValue(strings.ToLower(args[0].String)), nil
+	case base.ExpressionFunctionUpper:
+		return charExpressionValue(strings.ToUpper(args[0].String)), nil
+	case base.ExpressionFunctionLength:
+		return intExpressionValue(int64(utf8.RuneCountInString(args[0].String))), nil
+	case base.ExpressionFunctionTrim:
+		return charExpressionValue(strings.Trim(args[0].String, " ")), nil
+	case base.ExpressionFunctionSubstr:
+		// 按字符计算，start 从 1 开始
+		runes := []rune(args[0].String)
+		length := int64(len(runes))
+		start, end := args[1].Int-1, length
+		if len(args) == 3 {
+			if args[2].Int < 0 {
+				return nil, expressionError(fmt.Sprintf("substr 的长度<%d>小于0", args[2].Int))
+			}
+			if start <= end-args[2].Int {
+				end = start + args[2].Int
+			}
+		}
+		if start < 0 {
+			start = 0
+		}
+		if start > length {
+			start = length
+		}
+		if end < start {
+			end = start
+		}
+		return charExpressionValue(string(runes[start:end])), nil
+	case base.ExpressionFunctionConcat:
+		var builder strings.Builder
+		for _, arg := range args {
+			builder.WriteString(arg.StringValue())
+		}
+		return charExpressionValue(builder.String()), nil
+	case base.ExpressionFunctionReplace:
+		return charExpressionValue(strings.ReplaceAll(args[0].String, args[1].String, args[2].String)), nil
+	case base.ExpressionFunctionStrpos:
+		index := strings.Index(args[0].String, args[1].String)
+		if index < 0 {
+			return intExpressionValue(0), nil
+		}
+		return intExpressionValue(int64(utf8.RuneCountInString(args[0].String[:index]) + 1)), nil
+	case base.ExpressionFunctionAbs:
+		if args[0].Int == math.MinInt64 {
+			return nil, expressionError("abs 的结果超出bigint的范围")
+		}
+		if args[0].Int < 0 {
+			return intExpressionValue(-args[0].Int), nil
+		}
+		return args[0], nil
+	case base.ExpressionFunctionMod:
+		if args[1].Int == 0 {
+			return nil, expressionError("除数为0")
+		}
+		if args[1].Int == -1 {
+			return intExpressionValue(0), nil
+		}
+		return intExpressionValue(args[0].Int % args[1].Int), nil
+	case base.ExpressionFunctionFromUnixTime:
+		return charExpressionValue(time.Unix(args[0].Int, 0).Format(base.DefaultValueCurrentTimestampFormat)), nil
+	}
+
+	// 参数为时间的函数
+	t, err := parseExpressionTime(args[0].String)
+	if err != nil {
+		return nil, err
+	}
+	switch c.expr.Function {
+	case base.ExpressionFunctionDate:
+		return charExpressionValue(t.Format(base.ExpressionDateFormat)), nil
+	case base.ExpressionFunctionYear:
+		return intExpressionValue(int64(t.Year())), nil
+	case base.ExpressionFunctionMonth:
+		return intExpressionValue(int64(t.Month())), nil
+	case base.ExpressionFunctionDay:
+		return intExpressionValue(int64(t.Day())), nil
+	case base.ExpressionFunctionHour:
+		return intExpressionValue(int64(t.Hour())), nil
+	case base.ExpressionFunctionMinute:
+		return intExpressionValue(int64(t.Minute())), nil
+	case base.ExpressionFunctionSecond:
+		return intExpressionValue(int64(t.Second())), nil
+	case base.ExpressionFunctionUnixTimestamp:
+		return intExpressionValue(t.Unix()), nil
+	case base.ExpressionFunctionDateAdd:
+		return charExpressionValue(t.Add(time.Duration(args[1].Int) * time.Second).Format(base.DefaultValueCurrentTimestampFormat)), nil
+	case base.ExpressionFunctionDateDiff:
+		start, err := parseExpressionTime(args[1].String)
+		if err != nil {
+			return nil, err
+		}
+		return intExpressionValue(t.Unix() - start.Unix()), nil
+	}
+	return nil, expressionError(fmt.Sprintf("不支持的函数: %s", c.expr.Function))
+}
+
+// castExpressionValue 类型转换，Null 转换后仍然是 Null
+// char 转 bigint 按十进制解析，转 bool 可以是 true / false / 1 / 0 等；bool 转 bigint 为 1 / 0
+func castExpressionValue(v *ExpressionValue, t base.ExpressionType) (*ExpressionValue, base.StandardError) {
+	if v.IsNull() || v.Type == t {
+		return v, nil
+	}
+	switch t {
+	case base.ExpressionTypeChar:
+		return charExpressionValue(v.StringValue()), nil
+	case base.ExpressionTypeBigInt:
+		switch v.Type {
+		case base.ExpressionTypeBool:
+			if v.Bool {
+				return intExpressionValue(1), nil
+			}
+			return intExpressionValue(0), nil
+		case base.ExpressionTypeChar:
+			i, er := strconv.ParseInt(strings.TrimSpace(v.String), 10, 64)
+			if er != nil {
+				return nil, expressionError(fmt.Sprintf("<%s>不能转换为bigint", v.String))
+			}
+			return intExpressionValue(i), nil
+		}
+	case base.ExpressionTypeBool:
+		switch v.Type {
+		case base.ExpressionTypeBigInt:
+			return boolExpressionValue(v.Int != 0), nil
+		case base.ExpressionTypeChar:
+			b, er := strconv.ParseBool(strings.TrimSpace(v.String))
+			if er != nil {
+				return nil, expressionError(fmt.Sprintf("<%s>不能转换为bool", v.String))
+			}
+			return boolExpressionValue(b), nil
+		}
+	}
+	return nil, expressionError(fmt.Sprintf("<%s>不能转换为类型<%s>", v.StringValue(), t))
+}
+
+// ExpressionTypeAssignable 表达式的结果是否可以写入该列: bigint 的列不能使用 char 的结果，其他的列使用可读值
+func (info *FieldInfo) ExpressionTypeAssignable(t base.ExpressionType) bool {
+	return info.FieldType.GetType() != base.DBDataTypeBigInt || t != base.ExpressionTypeChar
+}
+
+// ExpressionValueToByte 把表达式的值转换为列的储存值（没有补齐长度），超过列的长度时报错
+func (info *FieldInfo) ExpressionValueToByte(v *ExpressionValue) ([]byte, base.StandardError) {
+	if v.IsNull() {
+		return info.NullValue(), nil
+	}
+	var (
+		value []byte
+		err   base.StandardError
+	)
+	if info.FieldType.GetType() == base.DBDataTypeBigInt {
+		i, er := castExpressionValue(v, base.ExpressionTypeBigInt)
+		if er != nil {
+			return nil, er
+		}
+		value, err = base.Int64ToByteList(i.Int)
+	} else {
+		value, err = info.FieldType.StringToByte(v.StringValue())
+	}
+	if err == nil {
+		_, err = info.FieldType.LengthPadding(value, info.Length)
+	}
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[FieldInfo.ExpressionValueToByte] 列<%s>的值<%s>错误: %s", info.Name, v.StringValue(), err.Error()))
+		return nil, err
+	}
+	return value, nil
+}
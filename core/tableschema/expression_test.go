@@ -0,0 +1,143 @@
+package tableschema
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	"ne_database/core/base"
+)
+
+func TestCompiledExpression_Evaluate(t *testing.T) {
+	tableInfo := &TableMetaInfo{
+		Name:                "people",
+		PrimaryKeyFieldInfo: &FieldInfo{Name: "id", Length: 8, FieldType: BigIntType},
+		ValueFieldInfo: []*FieldInfo{
+			{Name: "name", Length: 16, FieldType: NewCharType(base.CollationCaseInsensitive), Collation: base.CollationCaseInsensitive},
+			{Name: "score", Length: 8, FieldType: BigIntType},
+		},
+	}
+	id, _ := base.Int64ToByteList(1)
+	row := map[string][]byte{"id": id, "name": []byte("Ünïcode"), "score": make([]byte, 8)}
+
+	col := func(name string) *base.Expression {
+		return &base.Expression{Kind: base.ExpressionKindColumn, Column: name}
+	}
+	lit := func(value string, valueType base.ExpressionType) *base.Expression {
+		return &base.Expression{Kind: base.ExpressionKindLiteral, Value: value, Type: valueType}
+	}
+	op := func(operator base.ExpressionOperator, args ...*base.Expression) *base.Expression {
+		return &base.Expression{Kind: base.ExpressionKindOperator, Operator: operator, Args: args}
+	}
+	fn := func(function base.ExpressionFunction, args ...*base.Expression) *base.Expression {
+		return &base.Expression{Kind: base.ExpressionKindFunction, Function: function, Args: args}
+	}
+	null := lit("", base.ExpressionTypeNull)
+	trueValue, falseValue := lit("true", base.ExpressionTypeBool), lit("false", base.ExpressionTypeBool)
+	maxInt := lit(strconv.FormatInt(math.MaxInt64, 10), base.ExpressionTypeBigInt)
+
+	testCases := []struct {
+		expr     *base.Expression
+		expected string // 结果的可读值
+	}{
+		{op(base.ExpressionOperatorModulo, lit("-7", base.ExpressionTypeBigInt), lit("3", base.ExpressionTypeBigInt)), "-1"},
+		{op(base.ExpressionOperatorSubtract, lit("5", base.ExpressionTypeBigInt)), "-5"},
+		{op(base.ExpressionOperatorAdd, col("score"), lit("1", base.ExpressionTypeBigInt)), base.ValueStringNullValue},
+		// 三值逻辑
+		{op(base.ExpressionOperatorAnd, null, falseValue), "false"},
+		{op(base.ExpressionOperatorAnd, null, trueValue), base.ValueStringNullValue},
+		{op(base.ExpressionOperatorOr, null, trueValue), "true"},
+		{op(base.ExpressionOperatorNot, null), base.ValueStringNullValue},
+		{op(base.ExpressionOperatorIn, lit("1", base.ExpressionTypeBigInt), lit("2", base.ExpressionTypeBigInt), null), base.ValueStringNullValue},
+		{op(base.ExpressionOperatorIsNull, col("score")), "true"},
+		// 使用列的排序规则
+		{op(base.ExpressionOperatorEqual, col("name"), lit("üNÏCODE", base.ExpressionTypeChar)), "true"},
+		{op(base.ExpressionOperatorLike, col("name"), lit("ü%E", base.ExpressionTypeChar)), "true"},
+		// 字符串函数按字符计算
+		{fn(base.ExpressionFunctionLength, col("name")), "7"},
+		{fn(base.ExpressionFunctionSubstr, col("name"), lit("2", base.ExpressionTypeBigInt), lit("3", base.ExpressionTypeBigInt)), "nïc"},
+		{fn(base.ExpressionFunctionSubstr, col("name"), lit("-1", base.ExpressionTypeBigInt), lit("3", base.ExpressionTypeBigInt)), "Ü"},
+		{fn(base.ExpressionFunctionStrpos, col("name"), lit("code", base.ExpressionTypeChar)), "4"},
+		{fn(base.ExpressionFunctionReplace, lit("a-b-c", base.ExpressionTypeChar), lit("-", base.ExpressionTypeChar), lit("+", base.ExpressionTypeChar)), "a+b+c"},
+		{fn(base.ExpressionFunctionTrim, lit("  x ", base.ExpressionTypeChar)), "x"},
+		{fn(base.ExpressionFunctionConcat, lit("a", base.ExpressionTypeChar), null), base.ValueStringNullValue},
+		{fn(base.ExpressionFunctionGreatest, lit("3", base.ExpressionTypeBigInt), null, lit("9", base.ExpressionTypeBigInt)), "9"},
+		{fn(base.ExpressionFunctionLeast, lit("b", base.ExpressionTypeChar), lit("a", base.ExpressionTypeChar)), "a"},
+		{fn(base.ExpressionFunctionNullIf, lit("1", base.ExpressionTypeBigInt), lit("1", base.ExpressionTypeBigInt)), base.ValueStringNullValue},
+		{fn(base.ExpressionFunctionAbs, lit("-4", base.ExpressionTypeBigInt)), "4"},
+		{fn(base.ExpressionFunctionMod, lit("7", base.ExpressionTypeBigInt), lit("-1", base.ExpressionTypeBigInt)), "0"},
+		{&base.Expression{Kind: base.ExpressionKindFunction, Function: base.ExpressionFunctionCast, Type: base.ExpressionTypeBigInt, Args: []*base.Expression{lit(" 42 ", base.ExpressionTypeChar)}}, "42"},
+		{&base.Expression{Kind: base.ExpressionKindFunction, Function: base.ExpressionFunctionCast, Type: base.ExpressionTypeBool, Args: []*base.Expression{lit("0", base.ExpressionTypeBigInt)}}, "false"},
+		// 时间函数
+		{fn(base.ExpressionFunctionHour, lit("2024-02-29 13:14:15", base.ExpressionTypeChar)), "13"},
+		{fn(base.ExpressionFunctionDateDiff, lit("2024-03-01", base.ExpressionTypeChar), lit("2024-02-28", base.ExpressionTypeChar)), "172800"},
+		{fn(base.ExpressionFunctionFromUnixTime, fn(base.ExpressionFunctionUnixTimestamp, lit("2024-02-29 13:14:15", base.ExpressionTypeChar))), "2024-02-29 13:14:15"},
+		{&base.Expression{Kind: base.ExpressionKindCase, Args: []*base.Expression{null, lit("1", base.ExpressionTypeBigInt)}}, base.ValueStringNullValue},
+	}
+	for i, testCase := range testCases {
+		c, err := CompileExpression(tableInfo, testCase.expr)
+		if err != nil {
+			t.Errorf("case %d: unexpected compile error: %v", i, err)
+			continue
+		}
+		v, err := c.Evaluate(row)
+		if err != nil {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+			continue
+		}
+		if v.StringValue() != testCase.expected {
+			t.Errorf("case %d: expected %s, got %s", i, testCase.expected, v.StringValue())
+		}
+	}
+
+	// 校验时报错
+	compileErrorCases := []*base.Expression{
+		op(base.ExpressionOperatorAdd, col("name"), lit("1", base.ExpressionTypeBigInt)),
+		op(base.ExpressionOperatorAnd, trueValue, lit("1", base.ExpressionTypeBigInt)),
+		fn(base.ExpressionFunctionLower, col("score")),
+		fn(base.ExpressionFunctionSubstr, col("name")),
+		lit("abc", base.ExpressionTypeBigInt),
+		{Kind: base.ExpressionKindCase, Args: []*base.Expression{trueValue, lit("1", base.ExpressionTypeBigInt), lit("x", base.ExpressionTypeChar)}},
+		{Kind: base.ExpressionKindFunction, Function: base.ExpressionFunctionCast, Type: base.ExpressionTypeNull, Args: []*base.Expression{col("id")}},
+	}
+	for i, expr := range compileErrorCases {
+		if _, err := CompileExpression(tableInfo, expr); err == nil {
+			t.Errorf("compile error case %d: expected error", i)
+		}
+	}
+	if _, err := CompileExpression(nil, col("id")); err == nil {
+		t.Errorf("expected error when using column without table")
+	}
+	if _, err := CompileCondition(tableInfo, col("score")); err == nil {
+		t.Errorf("expected error when condition is not bool")
+	}
+
+	// 计算时报错
+	evaluateErrorCases := []*base.Expression{
+		op(base.ExpressionOperatorAdd, maxInt, lit("1", base.ExpressionTypeBigInt)),
+		op(base.ExpressionOperatorMultiply, maxInt, lit("2", base.ExpressionTypeBigInt)),
+		op(base.ExpressionOperatorDivide, lit("1", base.ExpressionTypeBigInt), lit("0", base.ExpressionTypeBigInt)),
+		fn(base.ExpressionFunctionYear, lit("yesterday", base.ExpressionTypeChar)),
+		{Kind: base.ExpressionKindFunction, Function: base.ExpressionFunctionCast, Type: base.ExpressionTypeBigInt, Args: []*base.Expression{col("name")}},
+	}
+	for i, expr := range evaluateErrorCases {
+		c, err := CompileExpression(tableInfo, expr)
+		if err != nil {
+			t.Errorf("evaluate error case %d: unexpected compile error: %v", i, err)
+			continue
+		}
+		if _, err = c.Evaluate(row); err == nil {
+			t.Errorf("evaluate error case %d: expected error", i)
+		}
+	}
+
+	// 写入列的值
+	field := &FieldInfo{Name: "code", Length: 4, FieldType: CharType}
+	if _, err := field.ExpressionValueToByte(charExpressionValue("abcde")); err == nil {
+		t.Errorf("expected error when value is too long")
+	}
+	value, err := tableInfo.ValueFieldInfo[1].ExpressionValueToByte(boolExpressionValue(true))
+	if n, _ := base.ByteListToInt64(value); err != nil || n != 1 {
+		t.Errorf("expected 1, got %d, %v", n, err)
+	}
+}
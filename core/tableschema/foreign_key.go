@@ -0,0 +1,98 @@
+package tableschema
+
+import (
+	"fmt"
+
+	"ne_database/core/base"
+	"ne_database/utils"
+	"ne_database/utils/set"
+)
+
+// ForeignKey 外键，Column 的值需要是 RefTable 中存在的主键，Null 不做检查
+// 目前只有主键是唯一的，所以 RefColumn 只能是 RefTable 的主键
+// OnDelete / OnUpdate 为父表数据删除、主键修改时对子表数据的动作，为空时为 restrict
+type ForeignKey struct {
+	Name      string `json:"name"`
+	Column    string `json:"column"`
+	RefTable  string `json:"ref_table"`
+	RefColumn string `json:"ref_column"`
+	OnDelete  string `json:"on_delete,omitempty"`
+	OnUpdate  string `json:"on_update,omitempty"`
+}
+
+// ForeignKeyName 没有指定名称的外键的名称，为: 表名_列名_fkey
+func ForeignKeyName(tableName string, columnName string) string {
+	return fmt.Sprintf("%s_%s_%s", tableName, columnName, base.ForeignKeySuffix)
+}
+
+// DeleteAction 父表数据删除时的动作
+func (fk *ForeignKey) DeleteAction() string {
+	if fk.OnDelete == "" {
+		return base.ForeignKeyActionRestrict
+	}
+	return fk.OnDelete
+}
+
+// UpdateAction 父表主键修改时的动作
+func (fk *ForeignKey) UpdateAction() string {
+	if fk.OnUpdate == "" {
+		return base.ForeignKeyActionRestrict
+	}
+	return fk.OnUpdate
+}
+
+// foreignKeyVerification 校验外键在本表内的配置，引用的表由 ReferenceVerification 校验
+// 外键列只能是值列，没有名称的外键会补上默认名称；引用的表名没有数据库名时补上本表的数据库名，不能引用其他数据库的表
+func (info *TableMetaInfo) foreignKeyVerification() base.StandardError {
+	foreignKeyError := func(errMsg string) base.StandardError {
+		utils.LogError("[foreignKeyVerification] " + errMsg)
+		return base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeType, base.ErrorBaseCodeTableSchemaError, fmt.Errorf(errMsg))
+	}
+	totalAction := set.NewStringsSet(base.ForeignKeyActionRestrict, base.ForeignKeyActionCascade, base.ForeignKeyActionSetNull)
+	existName := set.NewStringsSet()
+	for _, fk := range info.ForeignKeys {
+		if fk == nil || fk.Column == "" || fk.RefTable == "" || fk.RefColumn == "" {
+			return foreignKeyError(fmt.Sprintf("外键配置不完整: %s", utils.ToJSON(fk)))
+		}
+		if fk.Name == "" {
+			fk.Name = ForeignKeyName(info.Name, fk.Column)
+		}
+		fk.RefTable = QualifiedTableName(DatabaseName(info.Name), fk.RefTable)
+		if DatabaseName(fk.RefTable) != DatabaseName(info.Name) {
+			return foreignKeyError(fmt.Sprintf("外键<%s>不能引用其他数据库的表<%s>", fk.Name, fk.RefTable))
+		}
+		if existName.Contain(fk.Name) {
+			return foreignKeyError(fmt.Sprintf("外键名<%s>重复", fk.Name))
+		}
+		existName.Add(fk.Name)
+		if _, ok := info.FieldInfoByName(fk.Column); !ok {
+			return foreignKeyError(fmt.Sprintf("外键<%s>的列<%s>不存在", fk.Name, fk.Column))
+		}
+		if fk.Column == info.PrimaryKeyFieldInfo.Name {
+			return foreignKeyError(fmt.Sprintf("外键<%s>的列<%s>不能是主键", fk.Name, fk.Column))
+		}
+		if !totalAction.Contain(fk.DeleteAction()) || !totalAction.Contain(fk.UpdateAction()) {
+			return foreignKeyError(fmt.Sprintf("外键<%s>的动作不支持: %s / %s", fk.Name, fk.OnDelete, fk.OnUpdate))
+		}
+	}
+	return nil
+}
+
+// ReferenceVerification 校验外键引用的表，RefColumn 需要是 refInfo 的主键，并且类型、长度和外键列一致
+func (fk *ForeignKey) ReferenceVerification(info *TableMetaInfo, refInfo *TableMetaInfo) base.StandardError {
+	foreignKeyError := func(errMsg string) base.StandardError {
+		utils.LogError("[ForeignKey.ReferenceVerification] " + errMsg)
+		return base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeType, base.ErrorBaseCodeTableSchemaError, fmt.Errorf(errMsg))
+	}
+	if refInfo.Name != fk.RefTable {
+		return foreignKeyError(fmt.Sprintf("外键<%s>引用的表<%s>与<%s>不一致", fk.Name, fk.RefTable, refInfo.Name))
+	}
+	if refInfo.PrimaryKeyFieldInfo.Name != fk.RefColumn {
+		return foreignKeyError(fmt.Sprintf("外键<%s>引用的列<%s.%s>不是主键", fk.Name, fk.RefTable, fk.RefColumn))
+	}
+	field, _ := info.FieldInfoByName(fk.Column)
+	if field.FieldType != refInfo.PrimaryKeyFieldInfo.FieldType || field.Length != refInfo.PrimaryKeyFieldInfo.Length {
+		return foreignKeyError(fmt.Sprintf("外键<%s>的列<%s>与引用的列<%s.%s>类型或长度不一致", fk.Name, fk.Column, fk.RefTable, fk.RefColumn))
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package tableschema
+
+import (
+	"testing"
+
+	"ne_database/core/base"
+)
+
+func TestTableMetaInfo_ForeignKeyVerification(t *testing.T) {
+	newTableInfo := func(fk *ForeignKey) *TableMetaInfo {
+		return &TableMetaInfo{
+			Name:                "order_items",
+			PrimaryKeyFieldInfo: &FieldInfo{Name: "id", Length: 8, FieldType: BigIntType},
+			ValueFieldInfo: []*FieldInfo{
+				{Name: "order_id", Length: 8, FieldType: BigIntType},
+				{Name: "sku", Length: 20, FieldType: CharType},
+			},
+			PageSize:    1000,
+			StorageType: base.StorageTypeMemory,
+			ForeignKeys: []*ForeignKey{fk},
+		}
+	}
+	ordersInfo := &TableMetaInfo{
+		Name:                "orders",
+		PrimaryKeyFieldInfo: &FieldInfo{Name: "id", Length: 8, FieldType: BigIntType},
+	}
+
+	tableInfo := newTableInfo(&ForeignKey{Column: "order_id", RefTable: "orders", RefColumn: "id"})
+	err := tableInfo.Verification()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	fk := tableInfo.ForeignKeys[0]
+	if fk.Name != "order_items_order_id_fkey" || fk.DeleteAction() != base.ForeignKeyActionRestrict {
+		t.Errorf("default name or action failed, got %#v", fk)
+		return
+	}
+	err = fk.ReferenceVerification(tableInfo, ordersInfo)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	testCases := []*ForeignKey{
+		{Column: "price", RefTable: "orders", RefColumn: "id"},
+		{Column: "id", RefTable: "orders", RefColumn: "id"},
+		{Column: "order_id", RefTable: "orders", RefColumn: "id", OnDelete: "no_action"},
+		{Column: "order_id", RefTable: "", RefColumn: "id"},
+	}
+	for i, c := range testCases {
+		if newTableInfo(c).Verification() == nil {
+			t.Errorf("case %d: expected error, but got nil", i)
+		}
+	}
+
+	// 引用的列需要是主键，并且类型一致
+	tableInfo = newTableInfo(&ForeignKey{Column: "order_id", RefTable: "orders", RefColumn: "code"})
+	_ = tableInfo.Verification()
+	if tableInfo.ForeignKeys[0].ReferenceVerification(tableInfo, ordersInfo) == nil {
+		t.Error("expected error, but got nil")
+	}
+	tableInfo = newTableInfo(&ForeignKey{Column: "sku", RefTable: "orders", RefColumn: "id"})
+	_ = tableInfo.Verification()
+	if tableInfo.ForeignKeys[0].ReferenceVerification(tableInfo, ordersInfo) == nil {
+		t.Error("expected error, but got nil")
+	}
+}
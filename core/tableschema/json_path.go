@@ -0,0 +1,259 @@
+package tableschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"ne_database/core/base"
+	"ne_database/utils"
+)
+
+// jsonNumberPrecision 比较非整数的 json 数字时使用的精度（二进制位数）
+const jsonNumberPrecision = 256
+
+// decodeJSONValue 解析 json，数字保留为 json.Number 避免精度丢失
+func decodeJSONValue(data []byte) (interface{}, base.StandardError) {
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	er := decoder.Decode(&value)
+	if er != nil {
+		utils.LogError(fmt.Sprintf("[decodeJSONValue] json解析错误: %s", er.Error()))
+		return nil, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, er)
+	}
+	return value, nil
+}
+
+// decodeJSONArg 解析查询参数，不是合法 json 的参数当作字符串处理
+// 如: red 和 "red" 都当作字符串 red，10 当作数字，"10" 当作字符串
+func decodeJSONArg(arg []byte) interface{} {
+	value, err := decodeJSONValue(arg)
+	if err != nil {
+		return string(arg)
+	}
+	return value
+}
+
+// ExtractJSONPath 按路径获取 json 中的值
+// 路径各层之间使用 base.SymbolJSONPathSeparator 分隔，数组使用数字下标，如: attrs.tags.0
+// 第二个返回值表示路径是否存在
+func ExtractJSONPath(data []byte, path string) (interface{}, bool, base.StandardError) {
+	value, err := decodeJSONValue(data)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[ExtractJSONPath.decodeJSONValue] err: %s", err.Error()))
+		return nil, false, err
+	}
+	if path == "" {
+		return value, true, nil
+	}
+	for _, key := range strings.Split(path, base.SymbolJSONPathSeparator) {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			next, ok := v[key]
+			if !ok {
+				return nil, false, nil
+			}
+			value = next
+		case []interface{}:
+			index, er := strconv.Atoi(key)
+			if er != nil || index < 0 || index >= len(v) {
+				return nil, false, nil
+			}
+			value = v[index]
+		default:
+			return nil, false, nil
+		}
+	}
+	return value, true, nil
+}
+
+// compareJSONValue 比较两个 json 值，只有同为数字、字符串或布尔值时可以比较
+func compareJSONValue(value1 interface{}, value2 interface{}) (int, bool) {
+	switch v1 := value1.(type) {
+	case json.Number:
+		v2, ok := value2.(json.Number)
+		if !ok {
+			return 0, false
+		}
+		return compareJSONNumber(v1, v2)
+	case string:
+		v2, ok := value2.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(v1, v2), true
+	case bool:
+		v2, ok := value2.(bool)
+		if !ok {
+			return 0, false
+		}
+		if v1 == v2 {
+			return 0, true
+		} else if v1 {
+			return 1, true
+		}
+		return -1, true
+	}
+	return 0, false
+}
+
+// compareJSONNumber 比较两个 json 数字
+// 两个数字都是整数时按整数精确比较（float64 只能精确表示 2^53 以内的整数），否则使用高精度浮点数比较
+func compareJSONNumber(v1 json.Number, v2 json.Number) (int, bool) {
+	i1, ok1 := new(big.Int).SetString(v1.String(), 10)
+	i2, ok2 := new(big.Int).SetString(v2.String(), 10)
+	if ok1 && ok2 {
+		return i1.Cmp(i2), true
+	}
+	f1, _, er := big.ParseFloat(v1.String(), 10, jsonNumberPrecision, big.ToNearestEven)
+	if er != nil {
+		return 0, false
+	}
+	f2, _, er := big.ParseFloat(v2.String(), 10, jsonNumberPrecision, big.ToNearestEven)
+	if er != nil {
+		return 0, false
+	}
+	return f1.Cmp(f2), true
+}
+
+// equalJSONValue 按 json 语义判断是否相等
+func equalJSONValue(value1 interface{}, value2 interface{}) bool {
+	switch v1 := value1.(type) {
+	case map[string]interface{}:
+		v2, ok := value2.(map[string]interface{})
+		if !ok || len(v1) != len(v2) {
+			return false
+		}
+		for k, i := range v1 {
+			j, ok := v2[k]
+			if !ok || !equalJSONValue(i, j) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		v2, ok := value2.([]interface{})
+		if !ok || len(v1) != len(v2) {
+			return false
+		}
+		for index := range v1 {
+			if !equalJSONValue(v1[index], v2[index]) {
+				return false
+			}
+		}
+		return true
+	case nil:
+		return value2 == nil
+	}
+	result, ok := compareJSONValue(value1, value2)
+	return ok && result == 0
+}
+
+// containsJSONValue 判断 container 是否包含 target
+// 对象: target 为对象且每个键值都被包含；数组: 包含 target 元素（或 target 为数组时每个元素都被包含）；字符串: 包含子串
+func containsJSONValue(container interface{}, target interface{}) bool {
+	switch c := container.(type) {
+	case map[string]interface{}:
+		t, ok := target.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for k, i := range t {
+			j, ok := c[k]
+			if !ok || !containsJSONValue(j, i) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		if t, ok := target.([]interface{}); ok {
+			for _, i := range t {
+				if !containsJSONValue(c, i) {
+					return false
+				}
+			}
+			return true
+		}
+		for _, i := range c {
+			if equalJSONValue(i, target) || containsJSONValue(i, target) {
+				return true
+			}
+		}
+		return false
+	case string:
+		t, ok := target.(string)
+		if !ok {
+			return false
+		}
+		return strings.Contains(c, t)
+	}
+	return equalJSONValue(container, target)
+}
+
+// MatchPath 对 json 路径取出的值进行条件判断
+func (t jsonType) MatchPath(data []byte, path string, operate base.DataComparator, args [][]byte) (bool, base.StandardError) {
+	value, exist, err := ExtractJSONPath(data, path)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[jsonType.MatchPath.ExtractJSONPath] err: %s", err.Error()))
+		return false, err
+	}
+	isNull := !exist || value == nil
+	switch operate {
+	case base.DataComparatorIsNull:
+		return isNull, nil
+	case base.DataComparatorIsNotNull:
+		return !isNull, nil
+	}
+	if isNull {
+		// 路径不存在时，除了 is_null 以外的条件都不满足
+		return false, nil
+	}
+
+	compareArg := func(index int) (int, bool) {
+		return compareJSONValue(value, decodeJSONArg(args[index]))
+	}
+	switch operate {
+	case base.DataComparatorEqual:
+		return equalJSONValue(value, decodeJSONArg(args[0])), nil
+	case base.DataComparatorNotEqual:
+		return !equalJSONValue(value, decodeJSONArg(args[0])), nil
+	case base.DataComparatorGreater:
+		result, ok := compareArg(0)
+		return ok && result > 0, nil
+	case base.DataComparatorGreaterAndEqual:
+		result, ok := compareArg(0)
+		return ok && result >= 0, nil
+	case base.DataComparatorLess:
+		result, ok := compareArg(0)
+		return ok && result < 0, nil
+	case base.DataComparatorLessAndEqual:
+		result, ok := compareArg(0)
+		return ok && result <= 0, nil
+	case base.DataComparatorBetween:
+		lower, ok := compareArg(0)
+		if !ok {
+			return false, nil
+		}
+		upper, ok := compareArg(1)
+		return ok && lower >= 0 && upper <= 0, nil
+	case base.DataComparatorIn, base.DataComparatorNotIn:
+		in := false
+		for _, arg := range args {
+			if equalJSONValue(value, decodeJSONArg(arg)) {
+				in = true
+				break
+			}
+		}
+		return in == (operate == base.DataComparatorIn), nil
+	case base.DataComparatorContains:
+		return containsJSONValue(value, decodeJSONArg(args[0])), nil
+	default:
+		errMsg := fmt.Sprintf("json 路径不支持比较符: %s", operate)
+		utils.LogError("[jsonType.MatchPath] " + errMsg)
+		return false, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+	}
+}
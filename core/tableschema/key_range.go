@@ -0,0 +1,407 @@
+package tableschema
+
+import (
+	"fmt"
+	"sort"
+
+	"ne_database/core/base"
+	"ne_database/utils"
+)
+
+// KeyRange 主键的查找范围，Min / Max 为 nil 时表示没有下限 / 上限
+type KeyRange struct {
+	Min          []byte
+	MinInclusive bool
+	Max          []byte
+	MaxInclusive bool
+	Empty        bool // 范围为空，不需要查找
+}
+
+// tightenMin 收紧下限
+func (r *KeyRange) tightenMin(fieldType MetaType, value []byte, inclusive bool) base.StandardError {
+	if r.Min != nil {
+		greater, err := fieldType.Greater(value, r.Min)
+		if err != nil {
+			return err
+		}
+		equal, err := fieldType.Equal(value, r.Min)
+		if err != nil {
+			return err
+		}
+		if !greater && !(equal && !inclusive) {
+			return nil
+		}
+	}
+	r.Min = value
+	r.MinInclusive = inclusive
+	return nil
+}
+
+// tightenMax 收紧上限
+func (r *KeyRange) tightenMax(fieldType MetaType, value []byte, inclusive bool) base.StandardError {
+	if r.Max != nil {
+		less, err := fieldType.Less(value, r.Max)
+		if err != nil {
+			return err
+		}
+		equal, err := fieldType.Equal(value, r.Max)
+		if err != nil {
+			return err
+		}
+		if !less && !(equal && !inclusive) {
+			return nil
+		}
+	}
+	r.Max = value
+	r.MaxInclusive = inclusive
+	return nil
+}
+
+// AfterMax 判断 key 是否已经超过上限，用于顺序扫描时提前结束
+func (r *KeyRange) AfterMax(fieldType MetaType, key []byte) (bool, base.StandardError) {
+	if r.Max == nil {
+		return false, nil
+	}
+	greater, err := fieldType.Greater(key, r.Max)
+	if err != nil || greater {
+		return greater, err
+	}
+	if r.MaxInclusive {
+		return false, nil
+	}
+	return fieldType.Equal(key, r.Max)
+}
+
+// BeforeMin 判断 key 是否还没有达到下限
+func (r *KeyRange) BeforeMin(fieldType MetaType, key []byte) (bool, base.StandardError) {
+	if r.Min == nil {
+		return false, nil
+	}
+	less, err := fieldType.Less(key, r.Min)
+	if err != nil || less {
+		return less, err
+	}
+	if r.MinInclusive {
+		return false, nil
+	}
+	return fieldType.Equal(key, r.Min)
+}
+
+// Contains 判断 key 是否在范围内
+func (r *KeyRange) Contains(fieldType MetaType, key []byte) (bool, base.StandardError) {
+	if r.Empty {
+		return false, nil
+	}
+	before, err := r.BeforeMin(fieldType, key)
+	if err != nil || before {
+		return false, err
+	}
+	after, err := r.AfterMax(fieldType, key)
+	if err != nil {
+		return false, err
+	}
+	return !after, nil
+}
+
+// prefixSuccessor 获取按字节比较时，大于所有以 prefix 开头的值的最小值，不存在时返回 nil
+func prefixSuccessor(prefix []byte) []byte {
+	for i := len(prefix) - 1; i >= 0; i-- {
+		if prefix[i] != 0xFF {
+			successor := append(make([]byte, 0, i+1), prefix[:i+1]...)
+			successor[i]++
+			return successor
+		}
+	}
+	return nil
+}
+
+// likeKeyRange 将前缀形式的 like 表达式（如: abc%、abc_%x）转化为主键范围
+// 只有按字节（或忽略大小写后按字节）排序的字符类型可以使用，其他情况返回 false
+func likeKeyRange(fieldType MetaType, item *base.WherePartItem) (prefix []byte, successor []byte, exact bool, ok bool, err base.StandardError) {
+	t, isChar := fieldType.(charType)
+	if !isChar || t.collation == base.CollationUnicode {
+		return nil, nil, false, false, nil
+	}
+	if item.Operate == base.DataComparatorILike && t.collation != base.CollationCaseInsensitive {
+		return nil, nil, false, false, nil
+	}
+	pattern := item.Args[0]
+	if len(item.Args) == base.DataComparatorArgsCountLikeWithEscape {
+		pattern, err = NormalizeLikePattern(item.Args[0], item.Args[1])
+		if err != nil {
+			return nil, nil, false, false, err
+		}
+	}
+	prefix, exact, err = LikePrefix(pattern)
+	if err != nil || len(prefix) == 0 {
+		return nil, nil, false, false, err
+	}
+	if t.collation == base.CollationCaseInsensitive {
+		prefix = foldCase(prefix)
+	}
+	return prefix, prefixSuccessor(prefix), exact, true, nil
+}
+
+// PrimaryKeyRange 根据查询条件获取主键的查找范围，各个条件之间是 and 的关系
+// 只使用主键上可以转化为范围的条件，返回的范围可能比实际结果更大，查找到的数据仍需要使用 MatchWhereParts 过滤
+func (info *TableMetaInfo) PrimaryKeyRange(items []*base.WherePartItem) (*KeyRange, base.StandardError) {
+	var (
+		r         = &KeyRange{}
+		fieldType = info.PrimaryKeyFieldInfo.FieldType
+		err       base.StandardError
+	)
+	for _, item := range items {
+		if item == nil || item.TargetColumn != info.PrimaryKeyFieldInfo.Name {
+			continue
+		}
+		if !item.Validation() {
+			errMsg := fmt.Sprintf("不合法查询: %s", utils.ToJSON(item))
+			utils.LogError("[PrimaryKeyRange] " + errMsg)
+			return nil, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+		}
+		switch item.Operate {
+		case base.DataComparatorEqual:
+			err = r.tightenMin(fieldType, item.Args[0], true)
+			if err == nil {
+				err = r.tightenMax(fieldType, item.Args[0], true)
+			}
+		case base.DataComparatorGreater:
+			err = r.tightenMin(fieldType, item.Args[0], false)
+		case base.DataComparatorGreaterAndEqual:
+			err = r.tightenMin(fieldType, item.Args[0], true)
+		case base.DataComparatorLess:
+			err = r.tightenMax(fieldType, item.Args[0], false)
+		case base.DataComparatorLessAndEqual:
+			err = r.tightenMax(fieldType, item.Args[0], true)
+		case base.DataComparatorBetween:
+			err = r.tightenMin(fieldType, item.Args[0], true)
+			if err == nil {
+				err = r.tightenMax(fieldType, item.Args[1], true)
+			}
+		case base.DataComparatorIn:
+			// 使用参数中的最小值和最大值作为范围
+			minArg, maxArg := item.Args[0], item.Args[0]
+			for _, arg := range item.Args[1:] {
+				var less, greater bool
+				less, err = fieldType.Less(arg, minArg)
+				if err != nil {
+					break
+				}
+				if less {
+					minArg = arg
+				}
+				greater, err = fieldType.Greater(arg, maxArg)
+				if err != nil {
+					break
+				}
+				if greater {
+					maxArg = arg
+				}
+			}
+			if err == nil {
+				err = r.tightenMin(fieldType, minArg, true)
+			}
+			if err == nil {
+				err = r.tightenMax(fieldType, maxArg, true)
+			}
+		case base.DataComparatorLike, base.DataComparatorILike:
+			prefix, successor, exact, ok, er := likeKeyRange(fieldType, item)
+			if er != nil || !ok {
+				err = er
+				break
+			}
+			err = r.tightenMin(fieldType, prefix, true)
+			if err == nil && exact {
+				err = r.tightenMax(fieldType, prefix, true)
+			} else if err == nil && successor != nil {
+				err = r.tightenMax(fieldType, successor, false)
+			}
+		}
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[PrimaryKeyRange] 比较出错, %s", err.Error()))
+			return nil, err
+		}
+	}
+
+	err = r.updateEmpty(fieldType)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// updateEmpty 下限大于上限（或者相等但不都包含）时范围为空
+func (r *KeyRange) updateEmpty(fieldType MetaType) base.StandardError {
+	if r.Min == nil || r.Max == nil {
+		return nil
+	}
+	cmp, err := compareKey(fieldType, r.Min, r.Max)
+	if err != nil {
+		return err
+	}
+	r.Empty = r.Empty || cmp > 0 || (cmp == 0 && !(r.MinInclusive && r.MaxInclusive))
+	return nil
+}
+
+// compareKey 按主键的类型比较两个值，返回 -1 / 0 / 1
+func compareKey(fieldType MetaType, data1 []byte, data2 []byte) (int, base.StandardError) {
+	less, err := fieldType.Less(data1, data2)
+	if err != nil || less {
+		return -1, err
+	}
+	equal, err := fieldType.Equal(data1, data2)
+	if err != nil || equal {
+		return 0, err
+	}
+	return 1, nil
+}
+
+// intersect 两个范围的交集
+func (r *KeyRange) intersect(fieldType MetaType, other *KeyRange) (*KeyRange, base.StandardError) {
+	result := *r
+	result.Empty = r.Empty || other.Empty
+	var err base.StandardError
+	if other.Min != nil {
+		err = result.tightenMin(fieldType, other.Min, other.MinInclusive)
+	}
+	if err == nil && other.Max != nil {
+		err = result.tightenMax(fieldType, other.Max, other.MaxInclusive)
+	}
+	if err == nil {
+		err = result.updateEmpty(fieldType)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// unionKeyRanges 去掉空的范围，按下限排序后合并相交或者相邻的范围，结果按主键的顺序排列且互不相交
+func unionKeyRanges(fieldType MetaType, ranges []*KeyRange) ([]*KeyRange, base.StandardError) {
+	r := make([]*KeyRange, 0, len(ranges))
+	for _, keyRange := range ranges {
+		if !keyRange.Empty {
+			copied := *keyRange
+			r = append(r, &copied)
+		}
+	}
+	var err base.StandardError
+	sort.SliceStable(r, func(i, j int) bool {
+		// 没有下限的在前，下限相同时包含下限的在前
+		if r[i].Min == nil || r[j].Min == nil || err != nil {
+			return r[i].Min == nil && r[j].Min != nil
+		}
+		var cmp int
+		cmp, err = compareKey(fieldType, r[i].Min, r[j].Min)
+		return cmp < 0 || (cmp == 0 && r[i].MinInclusive && !r[j].MinInclusive)
+	})
+	if err != nil || len(r) == 0 {
+		return r, err
+	}
+
+	merged := []*KeyRange{r[0]}
+	for _, next := range r[1:] {
+		cur := merged[len(merged)-1]
+		if cur.Max == nil {
+			// 当前范围没有上限，之后的范围都包含在内
+			break
+		}
+		overlap := next.Min == nil
+		if !overlap {
+			cmp, err := compareKey(fieldType, next.Min, cur.Max)
+			if err != nil {
+				return nil, err
+			}
+			overlap = cmp < 0 || (cmp == 0 && (cur.MaxInclusive || next.MinInclusive))
+		}
+		if !overlap {
+			merged = append(merged, next)
+			continue
+		}
+		if next.Max == nil {
+			cur.Max, cur.MaxInclusive = nil, false
+			continue
+		}
+		cmp, err := compareKey(fieldType, next.Max, cur.Max)
+		if err != nil {
+			return nil, err
+		}
+		if cmp > 0 || (cmp == 0 && next.MaxInclusive) {
+			cur.Max, cur.MaxInclusive = next.Max, next.MaxInclusive
+		}
+	}
+	return merged, nil
+}
+
+// PrimaryKeyRanges 根据条件树获取主键的查找范围，返回的范围按主键的顺序排列且互不相交，为空时不需要查找
+// and 取各个子节点范围的交集，or 取并集；主键上的 in 为每个参数一个范围；not 和其他列上的条件为全部的范围
+// 返回的范围可能比实际结果更大，查找到的数据仍需要使用 MatchWhereNode 过滤
+func (info *TableMetaInfo) PrimaryKeyRanges(node *base.WhereNode) ([]*KeyRange, base.StandardError) {
+	fieldType := info.PrimaryKeyFieldInfo.FieldType
+	if node == nil {
+		return []*KeyRange{{}}, nil
+	}
+	if !node.Validation() {
+		errMsg := fmt.Sprintf("不合法条件: %s", utils.ToJSON(node))
+		utils.LogError("[PrimaryKeyRanges] " + errMsg)
+		return nil, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+	}
+
+	var (
+		r   []*KeyRange
+		err base.StandardError
+	)
+	switch node.Logic {
+	case base.WhereLogicAnd:
+		r = []*KeyRange{{}}
+		for _, child := range node.Children {
+			childRanges, err := info.PrimaryKeyRanges(child)
+			if err != nil {
+				return nil, err
+			}
+			intersection := make([]*KeyRange, 0, len(r)*len(childRanges))
+			for _, a := range r {
+				for _, b := range childRanges {
+					keyRange, err := a.intersect(fieldType, b)
+					if err != nil {
+						return nil, err
+					}
+					intersection = append(intersection, keyRange)
+				}
+			}
+			r, err = unionKeyRanges(fieldType, intersection)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return r, nil
+	case base.WhereLogicOr:
+		for _, child := range node.Children {
+			childRanges, err := info.PrimaryKeyRanges(child)
+			if err != nil {
+				return nil, err
+			}
+			r = append(r, childRanges...)
+		}
+	case base.WhereLogicNot:
+		return []*KeyRange{{}}, nil
+	default:
+		if node.Item.TargetColumn == info.PrimaryKeyFieldInfo.Name && node.Item.Operate == base.DataComparatorIn {
+			for _, arg := range node.Item.Args {
+				r = append(r, &KeyRange{Min: arg, MinInclusive: true, Max: arg, MaxInclusive: true})
+			}
+			break
+		}
+		keyRange, err := info.PrimaryKeyRange([]*base.WherePartItem{node.Item})
+		if err != nil {
+			return nil, err
+		}
+		r = []*KeyRange{keyRange}
+	}
+	r, err = unionKeyRanges(fieldType, r)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[PrimaryKeyRanges] 合并范围出错, %s", err.Error()))
+		return nil, err
+	}
+	return r, nil
+}
@@ -0,0 +1,142 @@
+package tableschema
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"ne_database/core/base"
+	"ne_database/utils"
+)
+
+const (
+	likeTokenLiteral = iota // 普通字符
+	likeTokenAny            // %: 任意个字符
+	likeTokenOne            // _: 一个字符
+)
+
+type likeToken struct {
+	kind int
+	r    rune
+}
+
+// parseLikePattern 解析 like 表达式，hasEscape 为 false 时没有转义字符
+func parseLikePattern(pattern []byte, escape rune, hasEscape bool) ([]likeToken, base.StandardError) {
+	tokens := make([]likeToken, 0, len(pattern))
+	runes := []rune(string(pattern))
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case hasEscape && r == escape:
+			if i == len(runes)-1 {
+				errMsg := fmt.Sprintf("like 表达式不能以转义字符结尾: %s", string(pattern))
+				utils.LogError("[parseLikePattern] " + errMsg)
+				return nil, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+			}
+			i++
+			tokens = append(tokens, likeToken{kind: likeTokenLiteral, r: runes[i]})
+		case r == base.SymbolDataComparatorLikePlaceholder:
+			// 连续的 % 等同于一个
+			if len(tokens) == 0 || tokens[len(tokens)-1].kind != likeTokenAny {
+				tokens = append(tokens, likeToken{kind: likeTokenAny})
+			}
+		case r == base.SymbolDataComparatorLikeSingle:
+			tokens = append(tokens, likeToken{kind: likeTokenOne})
+		default:
+			tokens = append(tokens, likeToken{kind: likeTokenLiteral, r: r})
+		}
+	}
+	return tokens, nil
+}
+
+// LikeMatch 使用默认转义字符（\）的 SQL like 匹配
+// % 匹配任意个字符，_ 匹配一个字符（按 UTF-8 字符，而不是字节），需要整个值完全匹配
+func LikeMatch(pattern []byte, value []byte) (bool, base.StandardError) {
+	tokens, err := parseLikePattern(pattern, base.SymbolDataComparatorLikeEscape, true)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[LikeMatch.parseLikePattern] err: %s", err.Error()))
+		return false, err
+	}
+	var (
+		runes     = []rune(string(value))
+		p, v      = 0, 0
+		starToken = -1 // 最近一个 % 的位置，匹配失败时回溯到这里
+		starValue = 0  // 最近一个 % 已经匹配到的值的位置
+	)
+	for v < len(runes) {
+		if p < len(tokens) && (tokens[p].kind == likeTokenOne || (tokens[p].kind == likeTokenLiteral && tokens[p].r == runes[v])) {
+			p++
+			v++
+		} else if p < len(tokens) && tokens[p].kind == likeTokenAny {
+			starToken = p
+			starValue = v
+			p++
+		} else if starToken != -1 {
+			// % 多匹配一个字符之后重试
+			p = starToken + 1
+			starValue++
+			v = starValue
+		} else {
+			return false, nil
+		}
+	}
+	for p < len(tokens) && tokens[p].kind == likeTokenAny {
+		p++
+	}
+	return p == len(tokens), nil
+}
+
+// NormalizeLikePattern 将使用 escape 作为转义字符的 like 表达式，转化为使用默认转义字符（\）的表达式
+// escape 为空时表示没有转义字符
+func NormalizeLikePattern(pattern []byte, escape []byte) ([]byte, base.StandardError) {
+	var (
+		escapeRune rune
+		hasEscape  = len(escape) > 0
+	)
+	if hasEscape {
+		r, size := utf8.DecodeRune(escape)
+		if r == utf8.RuneError || size != len(escape) {
+			errMsg := fmt.Sprintf("ESCAPE 只能是一个字符: %s", string(escape))
+			utils.LogError("[NormalizeLikePattern] " + errMsg)
+			return nil, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+		}
+		escapeRune = r
+	}
+	tokens, err := parseLikePattern(pattern, escapeRune, hasEscape)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[NormalizeLikePattern.parseLikePattern] err: %s", err.Error()))
+		return nil, err
+	}
+	r := make([]byte, 0, len(pattern))
+	for _, token := range tokens {
+		switch token.kind {
+		case likeTokenAny:
+			r = append(r, base.SymbolDataComparatorLikePlaceholder)
+		case likeTokenOne:
+			r = append(r, base.SymbolDataComparatorLikeSingle)
+		default:
+			if token.r == base.SymbolDataComparatorLikePlaceholder || token.r == base.SymbolDataComparatorLikeSingle || token.r == base.SymbolDataComparatorLikeEscape {
+				r = append(r, base.SymbolDataComparatorLikeEscape)
+			}
+			r = utf8.AppendRune(r, token.r)
+		}
+	}
+	return r, nil
+}
+
+// LikePrefix 获取使用默认转义字符的 like 表达式中，第一个通配符之前的固定前缀
+// 第二个返回值表示表达式中是否没有通配符（即完全等于前缀）
+func LikePrefix(pattern []byte) ([]byte, bool, base.StandardError) {
+	tokens, err := parseLikePattern(pattern, base.SymbolDataComparatorLikeEscape, true)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[LikePrefix.parseLikePattern] err: %s", err.Error()))
+		return nil, false, err
+	}
+	prefix := make([]byte, 0, len(pattern))
+	for _, token := range tokens {
+		if token.kind != likeTokenLiteral {
+			return prefix, false, nil
+		}
+		prefix = utf8.AppendRune(prefix, token.r)
+	}
+	return prefix, true, nil
+}
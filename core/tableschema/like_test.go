@@ -0,0 +1,281 @@
+package tableschema
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"ne_database/core/base"
+)
+
+func TestLikeMatch(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		value   string
+		expect  bool
+	}{
+		{"abc_%x", "abcdx", true},
+		{"abc_%x", "abcdefx", true},
+		{"abc_%x", "abcx", false},
+		{"abc_%x", "abcdxy", false},
+		{"abc", "abc", true},
+		{"abc", "abcd", false},
+		{"abc", "xabc", false},
+		{"%bc", "abc", true},
+		{"a%", "abc", true},
+		{"%b%", "abc", true},
+		{"%%", "", true},
+		{"_", "", false},
+		{"a_c", "abc", true},
+		{"a_c", "ac", false},
+		{"%a%b%c%", "xaybzc", true},
+		{"%aab", "aaab", true},
+		{"中_", "中文", true},
+		{"__", "中文", true},
+		{"100\\%", "100%", true},
+		{"100\\%", "1000", false},
+		{"a\\_b", "a_b", true},
+		{"a\\_b", "axb", false},
+		{"a\\\\b", "a\\b", true},
+	}
+	for i, c := range testCases {
+		match, err := LikeMatch([]byte(c.pattern), []byte(c.value))
+		if err != nil {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+			continue
+		}
+		if match != c.expect {
+			t.Errorf("case %d: LikeMatch(%s, %s) expected %v but got %v", i, c.pattern, c.value, c.expect, match)
+		}
+	}
+
+	_, err := LikeMatch([]byte("abc\\"), []byte("abc"))
+	if err == nil {
+		t.Error("expected error, but got nil")
+	}
+}
+
+func TestNormalizeLikePattern(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		escape  string
+		expect  string
+	}{
+		{"10!%", "!", "10\\%"},
+		{"a!_b\\c", "!", "a\\_b\\\\c"},
+		{"a!!b%", "!", "a!b%"},
+		{"a\\b%", "", "a\\\\b%"},
+		{"a#_%", "#", "a\\_%"},
+	}
+	for i, c := range testCases {
+		pattern, err := NormalizeLikePattern([]byte(c.pattern), []byte(c.escape))
+		if err != nil {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+			continue
+		}
+		if string(pattern) != c.expect {
+			t.Errorf("case %d: NormalizeLikePattern expected %s but got %s", i, c.expect, string(pattern))
+		}
+	}
+
+	for _, escape := range []string{"!!", "ab"} {
+		_, err := NormalizeLikePattern([]byte("a%"), []byte(escape))
+		if err == nil {
+			t.Errorf("expected error for escape %s, but got nil", escape)
+		}
+	}
+}
+
+func TestCharType_Like(t *testing.T) {
+	padding, _ := CharType.LengthPadding([]byte("abcdx"), 10)
+	match, err := MatchMetaType(CharType, padding, base.DataComparatorLike, [][]byte{[]byte("abc_%x")}, nil)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if !match {
+		t.Error("CharType.Like failed, expected true")
+		return
+	}
+
+	// 指定 ESCAPE
+	match, err = MatchMetaType(CharType, []byte("50%off"), base.DataComparatorLike, [][]byte{[]byte("50!%%"), []byte("!")}, nil)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if !match {
+		t.Error("CharType.Like with escape failed, expected true")
+		return
+	}
+	match, _ = MatchMetaType(CharType, []byte("50off"), base.DataComparatorLike, [][]byte{[]byte("50!%%"), []byte("!")}, nil)
+	if match {
+		t.Error("CharType.Like with escape failed, expected false")
+		return
+	}
+
+	match, err = MatchMetaType(CharType, []byte("ABCdX"), base.DataComparatorILike, [][]byte{[]byte("abc_%x")}, nil)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if !match {
+		t.Error("CharType.ILike failed, expected true")
+		return
+	}
+	match, _ = MatchMetaType(NewCharType(base.CollationCaseInsensitive), []byte("ABCdX"), base.DataComparatorLike, [][]byte{[]byte("abc_%x")}, nil)
+	if !match {
+		t.Error("CharType.Like with case_insensitive collation failed, expected true")
+		return
+	}
+}
+
+func TestTableMetaInfo_PrimaryKeyRange(t *testing.T) {
+	tableInfo := &TableMetaInfo{
+		Name: "product",
+		PrimaryKeyFieldInfo: &FieldInfo{
+			Name:      "code",
+			Length:    20,
+			FieldType: CharType,
+		},
+		PageSize:    1000,
+		StorageType: base.StorageTypeMemory,
+	}
+
+	r, err := tableInfo.PrimaryKeyRange([]*base.WherePartItem{
+		{TargetColumn: "code", Operate: base.DataComparatorLike, Args: [][]byte{[]byte("abc_%x")}},
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if string(r.Min) != "abc" || !r.MinInclusive || string(r.Max) != "abd" || r.MaxInclusive || r.Empty {
+		t.Errorf("PrimaryKeyRange failed, got %#v", r)
+		return
+	}
+	for value, expect := range map[string]bool{"abc": true, "abczzz": true, "abd": false, "abb": false} {
+		contains, err := r.Contains(CharType, []byte(value))
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		if contains != expect {
+			t.Errorf("KeyRange.Contains(%s) expected %v but got %v", value, expect, contains)
+		}
+	}
+
+	// 多个条件取交集
+	r, err = tableInfo.PrimaryKeyRange([]*base.WherePartItem{
+		{TargetColumn: "code", Operate: base.DataComparatorGreater, Args: [][]byte{[]byte("abc1")}},
+		{TargetColumn: "code", Operate: base.DataComparatorLike, Args: [][]byte{[]byte("abc%")}},
+		{TargetColumn: "name", Operate: base.DataComparatorEqual, Args: [][]byte{[]byte("x")}},
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if string(r.Min) != "abc1" || r.MinInclusive || string(r.Max) != "abd" {
+		t.Errorf("PrimaryKeyRange failed, got %#v", r)
+		return
+	}
+
+	// 以通配符开头的 like 不能确定范围
+	r, _ = tableInfo.PrimaryKeyRange([]*base.WherePartItem{
+		{TargetColumn: "code", Operate: base.DataComparatorLike, Args: [][]byte{[]byte("%abc")}},
+	})
+	if r.Min != nil || r.Max != nil {
+		t.Errorf("PrimaryKeyRange failed, got %#v", r)
+		return
+	}
+
+	r, _ = tableInfo.PrimaryKeyRange([]*base.WherePartItem{
+		{TargetColumn: "code", Operate: base.DataComparatorLess, Args: [][]byte{[]byte("b")}},
+		{TargetColumn: "code", Operate: base.DataComparatorGreaterAndEqual, Args: [][]byte{[]byte("b")}},
+	})
+	if !r.Empty {
+		t.Errorf("PrimaryKeyRange failed, expected empty but got %#v", r)
+		return
+	}
+}
+
+func TestTableMetaInfo_PrimaryKeyRanges(t *testing.T) {
+	tableInfo := &TableMetaInfo{
+		Name:                "product",
+		PrimaryKeyFieldInfo: &FieldInfo{Name: "id", Length: 8, FieldType: BigIntType},
+		ValueFieldInfo:      []*FieldInfo{{Name: "name", Length: 20, FieldType: CharType}},
+		PageSize:            1000,
+		StorageType:         base.StorageTypeMemory,
+	}
+	int64Value := func(v int64) []byte {
+		r, _ := base.Int64ToByteList(v)
+		return r
+	}
+	item := func(column string, operate base.DataComparator, args ...int64) *base.WhereNode {
+		values := make([][]byte, 0, len(args))
+		for _, arg := range args {
+			values = append(values, int64Value(arg))
+		}
+		return &base.WhereNode{Item: &base.WherePartItem{TargetColumn: column, Operate: operate, Args: values}}
+	}
+	or := func(children ...*base.WhereNode) *base.WhereNode {
+		return &base.WhereNode{Logic: base.WhereLogicOr, Children: children}
+	}
+	and := func(children ...*base.WhereNode) *base.WhereNode {
+		return &base.WhereNode{Logic: base.WhereLogicAnd, Children: children}
+	}
+	not := &base.WhereNode{Logic: base.WhereLogicNot, Children: []*base.WhereNode{
+		{Item: &base.WherePartItem{TargetColumn: "name", Operate: base.DataComparatorEqual, Args: [][]byte{[]byte("x")}}},
+	}}
+	// 范围的可读形式，如: (-inf,5] [8,8] (20,+inf)
+	format := func(ranges []*KeyRange) string {
+		r := make([]string, 0, len(ranges))
+		for _, keyRange := range ranges {
+			left, right := "(-inf", "+inf)"
+			if keyRange.Min != nil {
+				n, _ := base.ByteListToInt64(keyRange.Min)
+				left = fmt.Sprintf("(%d", n)
+				if keyRange.MinInclusive {
+					left = fmt.Sprintf("[%d", n)
+				}
+			}
+			if keyRange.Max != nil {
+				n, _ := base.ByteListToInt64(keyRange.Max)
+				right = fmt.Sprintf("%d)", n)
+				if keyRange.MaxInclusive {
+					right = fmt.Sprintf("%d]", n)
+				}
+			}
+			r = append(r, left+","+right)
+		}
+		return strings.Join(r, " ")
+	}
+
+	testCases := []struct {
+		node     *base.WhereNode
+		expected string
+	}{
+		{or(item("id", base.DataComparatorLess, 3), item("id", base.DataComparatorBetween, 2, 5),
+			item("id", base.DataComparatorIn, 10, 8, 10), item("id", base.DataComparatorGreater, 20)), "(-inf,5] [8,8] [10,10] (20,+inf)"},
+		// 相邻的范围合并
+		{or(item("id", base.DataComparatorLess, 3), item("id", base.DataComparatorGreaterAndEqual, 3)), "(-inf,+inf)"},
+		{or(item("id", base.DataComparatorLess, 3), item("id", base.DataComparatorGreater, 3)), "(-inf,3) (3,+inf)"},
+		{and(item("id", base.DataComparatorGreaterAndEqual, 5), or(item("id", base.DataComparatorLess, 3), item("id", base.DataComparatorGreater, 7)), not), "(7,+inf)"},
+		{and(item("id", base.DataComparatorGreater, 5), item("id", base.DataComparatorLess, 3)), ""},
+		// 其他列和 not 无法确定范围
+		{or(item("id", base.DataComparatorEqual, 1), not), "(-inf,+inf)"},
+		{not, "(-inf,+inf)"},
+	}
+	for i, testCase := range testCases {
+		ranges, err := tableInfo.PrimaryKeyRanges(testCase.node)
+		if err != nil {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+			continue
+		}
+		if format(ranges) != testCase.expected {
+			t.Errorf("case %d: expected %s, got %s", i, testCase.expected, format(ranges))
+		}
+	}
+	if _, err := tableInfo.PrimaryKeyRanges(&base.WhereNode{Logic: base.WhereLogicOr}); err == nil {
+		t.Error("expected error for invalid node")
+	}
+}
@@ -0,0 +1,37 @@
+package tableschema
+
+import (
+	"fmt"
+	"regexp"
+
+	"ne_database/core/base"
+	"ne_database/utils"
+)
+
+// RegexpCache 一次查询中编译后的正则表达式缓存，避免每一行数据都重新编译
+// 每次查询创建一个新的缓存，不是并发安全的；为 nil 时每次都重新编译
+type RegexpCache map[string]*regexp.Regexp
+
+// NewRegexpCache 创建正则表达式缓存
+func NewRegexpCache() RegexpCache {
+	return make(RegexpCache)
+}
+
+// Compile 获取编译后的正则表达式（RE2 语法）
+func (c RegexpCache) Compile(pattern []byte) (*regexp.Regexp, base.StandardError) {
+	if c != nil {
+		if r, ok := c[string(pattern)]; ok {
+			return r, nil
+		}
+	}
+	r, er := regexp.Compile(string(pattern))
+	if er != nil {
+		errMsg := fmt.Sprintf("正则表达式错误: %s", er.Error())
+		utils.LogError("[RegexpCache.Compile] " + errMsg)
+		return nil, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+	}
+	if c != nil {
+		c[string(pattern)] = r
+	}
+	return r, nil
+}
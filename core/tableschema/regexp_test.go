@@ -0,0 +1,94 @@
+package tableschema
+
+import (
+	"testing"
+
+	"ne_database/core/base"
+)
+
+func TestRegexpCache_Compile(t *testing.T) {
+	cache := NewRegexpCache()
+	r1, err := cache.Compile([]byte("^ERROR \\[[a-z]+\\]"))
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	r2, err := cache.Compile([]byte("^ERROR \\[[a-z]+\\]"))
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if r1 != r2 || len(cache) != 1 {
+		t.Error("RegexpCache.Compile failed, expected cached pattern")
+		return
+	}
+	_, err = cache.Compile([]byte("(abc"))
+	if err == nil {
+		t.Error("expected error, but got nil")
+		return
+	}
+}
+
+func TestMatchMetaType_Regexp(t *testing.T) {
+	cache := NewRegexpCache()
+	padding, _ := CharType.LengthPadding([]byte("ERROR [db] timeout 30s"), 40)
+	level := NewEnumType("info", "warn", "error")
+	warn, _ := level.StringToByte("warn")
+	id, _ := base.Int64ToByteList(10)
+	null := make([]byte, 40)
+
+	testCases := []struct {
+		fieldType MetaType
+		value     []byte
+		operate   base.DataComparator
+		pattern   string
+		expect    bool
+	}{
+		{CharType, padding, base.DataComparatorRegexp, "^ERROR \\[[a-z]+\\]", true},
+		{CharType, padding, base.DataComparatorRegexp, "timeout [0-9]+s$", true},
+		{CharType, padding, base.DataComparatorRegexp, "^timeout", false},
+		{CharType, padding, base.DataComparatorRegexp, "(?i)^error", true},
+		{CharType, padding, base.DataComparatorNotRegexp, "WARN|INFO", true},
+		{level, warn, base.DataComparatorRegexp, "^w", true},
+		// Null 对 regexp 和 not_regexp 都不满足
+		{CharType, null, base.DataComparatorRegexp, "^$", false},
+		{CharType, null, base.DataComparatorNotRegexp, "WARN|INFO", false},
+	}
+	for i, c := range testCases {
+		match, err := MatchMetaType(c.fieldType, c.value, c.operate, [][]byte{[]byte(c.pattern)}, cache)
+		if err != nil {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+			continue
+		}
+		if match != c.expect {
+			t.Errorf("case %d: expected %v but got %v", i, c.expect, match)
+		}
+	}
+
+	_, err := MatchMetaType(CharType, padding, base.DataComparatorRegexp, [][]byte{[]byte("[a-")}, cache)
+	if err == nil {
+		t.Error("expected error, but got nil")
+		return
+	}
+	// 非文本类型不支持正则匹配，not_regexp 也不能把不支持当作满足
+	for _, operate := range []base.DataComparator{base.DataComparatorRegexp, base.DataComparatorNotRegexp} {
+		_, err = MatchMetaType(BigIntType, id, operate, [][]byte{[]byte("10")}, cache)
+		if err == nil {
+			t.Errorf("%s on bigint: expected error, but got nil", operate)
+			return
+		}
+	}
+}
+
+func TestWherePartItem_ValidationRegexp(t *testing.T) {
+	valid := &base.WherePartItem{TargetColumn: "message", Operate: base.DataComparatorRegexp, Args: [][]byte{[]byte("^ERROR")}}
+	if !valid.Validation() {
+		t.Error("WherePartItem.Validation failed, expected valid")
+		return
+	}
+	invalid := &base.WherePartItem{TargetColumn: "message", Operate: base.DataComparatorNotRegexp, Args: [][]byte{[]byte("[a-")}}
+	if invalid.Validation() {
+		t.Error("WherePartItem.Validation failed, expected invalid pattern")
+		return
+	}
+}
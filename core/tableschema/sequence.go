@@ -0,0 +1,91 @@
+package tableschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"ne_database/core/base"
+	"ne_database/utils"
+)
+
+// SequenceInfo 序列信息
+// Next 是下一个还没有被预先分配的值，每次预先分配一批值时先持久化新的 Next，
+// 所以崩溃重启之后已经分配但没有使用的值会被跳过，但不会重复
+type SequenceInfo struct {
+	Name      string `json:"name"`
+	Start     int64  `json:"start"`
+	Increment int64  `json:"increment"`
+	CacheSize int    `json:"cache_size"` // 每次预先分配的数量，为 0 时使用配置中的默认值
+	Next      int64  `json:"next"`
+}
+
+// InitSequenceInfo 创建序列，increment 为 0 时默认为 1
+func InitSequenceInfo(name string, start int64, increment int64) *SequenceInfo {
+	if increment == 0 {
+		increment = 1
+	}
+	return &SequenceInfo{
+		Name:      name,
+		Start:     start,
+		Increment: increment,
+		Next:      start,
+	}
+}
+
+// AutoIncrementSequenceName 表的自增主键对应的序列名，格式见 base.AutoIncrementSequenceSuffix
+func AutoIncrementSequenceName(tableName string, columnName string) string {
+	separator := base.AutoIncrementSequenceSeparator
+	return fmt.Sprintf("%s%s%s%s%d%s%s", tableName, separator, columnName, separator, len(columnName), separator, base.AutoIncrementSequenceSuffix)
+}
+
+// IsAutoIncrementSequenceName 是否是自增主键使用的序列名
+func IsAutoIncrementSequenceName(name string) bool {
+	return strings.HasSuffix(name, base.AutoIncrementSequenceSeparator+base.AutoIncrementSequenceSuffix)
+}
+
+// Verification 序列配置校验
+func (info *SequenceInfo) Verification() base.StandardError {
+	if info.Name == "" {
+		utils.LogError("[SequenceInfo.Verification] 序列名为空")
+		return base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeType, base.ErrorBaseCodeInnerParameterError, fmt.Errorf("序列名为空"))
+	}
+	if info.Increment == 0 {
+		utils.LogError(fmt.Sprintf("[SequenceInfo.Verification] 序列<%s>的步长为0", info.Name))
+		return base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeType, base.ErrorBaseCodeInnerParameterError, fmt.Errorf("序列<%s>的步长为0", info.Name))
+	}
+	if info.CacheSize < 0 {
+		utils.LogError(fmt.Sprintf("[SequenceInfo.Verification] 序列<%s>的预分配数量小于0: %d", info.Name, info.CacheSize))
+		return base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeType, base.ErrorBaseCodeInnerParameterError, fmt.Errorf("序列<%s>的预分配数量小于0: %d", info.Name, info.CacheSize))
+	}
+	return nil
+}
+
+func (info *SequenceInfo) SequenceInfoToJsonByte() ([]byte, base.StandardError) {
+	err := info.Verification()
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[SequenceInfoToJsonByte] 序列校验错误, %s", err.Error()))
+		return nil, err
+	}
+	jsonByte, er := json.Marshal(info)
+	if er != nil {
+		utils.LogError(fmt.Sprintf("[SequenceInfoToJsonByte] json.Marshal 错误, %s", er.Error()))
+		return nil, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeSystem, base.ErrorBaseCodeInnerDataError, er)
+	}
+	return jsonByte, nil
+}
+
+func InitSequenceInfoByJson(sequenceJson string) (*SequenceInfo, base.StandardError) {
+	r := &SequenceInfo{}
+	er := json.Unmarshal([]byte(sequenceJson), r)
+	if er != nil {
+		utils.LogError(fmt.Sprintf("[InitSequenceInfoByJson] json解析错误: %s", er.Error()))
+		return nil, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, er)
+	}
+	err := r.Verification()
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[InitSequenceInfoByJson] Verification出错, %s", err.Error()))
+		return nil, err
+	}
+	return r, nil
+}
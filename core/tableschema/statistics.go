@@ -0,0 +1,249 @@
+package tableschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+
+	"ne_database/core/base"
+	"ne_database/utils"
+)
+
+// 没有统计信息时条件的默认选择率
+const (
+	defaultEqualSelectivity = 0.005
+	defaultRangeSelectivity = 1.0 / 3
+	defaultMatchSelectivity = 0.1 // like / regexp / contains 等无法估计的条件
+)
+
+// ColumnStatistics 列的统计信息，由采样得到
+// Histogram 为非 Null 值的等深直方图的边界（包括最小值和最大值），相邻两个边界之间的行数大致相同；没有非 Null 的值时为空
+type ColumnStatistics struct {
+	Name          string   `json:"name"`
+	DistinctCount int64    `json:"distinct_count"`
+	NullFraction  float64  `json:"null_fraction"`
+	Histogram     [][]byte `json:"histogram,omitempty"`
+}
+
+// TableStatistics 表的统计信息，由 Analyze 采样叶子结点生成并保存在系统目录中
+// RowCount 为估计的总行数；PageCount 为采样时数据文件的页数，之后按照当前的页数等比例估计行数
+type TableStatistics struct {
+	TableName   string              `json:"table_name"`
+	RowCount    int64               `json:"row_count"`
+	PageCount   int64               `json:"page_count"`
+	LeafCount   int64               `json:"leaf_count"`
+	Height      int                 `json:"height"` // B+树的高度，只有根结点时为1
+	SampledRows int64               `json:"sampled_rows"`
+	Columns     []*ColumnStatistics `json:"columns"`
+}
+
+// BuildColumnStatistics 根据采样的值生成列的统计信息，totalRows 为估计的总行数，bucketCount 为直方图的桶数
+// 采样不是全部数据时，不同值的数量使用 Duj1 估计: n*d / (n - f1 + f1*n/N)，f1 为采样中只出现一次的值的数量
+func BuildColumnStatistics(field *FieldInfo, values [][]byte, totalRows int64, bucketCount int) (*ColumnStatistics, base.StandardError) {
+	r := &ColumnStatistics{Name: field.Name}
+	nonNull := make([][]byte, 0, len(values))
+	for _, value := range values {
+		isNull, err := field.FieldType.IsNull(value)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[BuildColumnStatistics] IsNull错误, %s", err.Error()))
+			return nil, err
+		}
+		if !isNull {
+			nonNull = append(nonNull, field.FieldType.TrimRaw(value))
+		}
+	}
+	if len(values) == 0 || len(nonNull) == 0 {
+		if len(values) > 0 {
+			r.NullFraction = 1
+		}
+		return r, nil
+	}
+	r.NullFraction = float64(len(values)-len(nonNull)) / float64(len(values))
+
+	var sortErr base.StandardError
+	sort.SliceStable(nonNull, func(i, j int) bool {
+		less, err := field.FieldType.Less(nonNull[i], nonNull[j])
+		if err != nil && sortErr == nil {
+			sortErr = err
+		}
+		return less
+	})
+	if sortErr != nil {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[BuildColumnStatistics] Less错误, %s", sortErr.Error()))
+		return nil, sortErr
+	}
+
+	counts := make(map[string]int)
+	for _, value := range nonNull {
+		counts[string(IndexKey(field.FieldType, value))]++
+	}
+	distinct := float64(len(counts))
+	sampleRows := float64(len(nonNull))
+	totalNonNull := float64(totalRows) * (1 - r.NullFraction)
+	if totalNonNull > sampleRows {
+		once := 0.0
+		for _, count := range counts {
+			if count == 1 {
+				once++
+			}
+		}
+		distinct = sampleRows * distinct / (sampleRows - once + once*sampleRows/totalNonNull)
+		distinct = math.Min(math.Max(distinct, float64(len(counts))), totalNonNull)
+	}
+	r.DistinctCount = int64(math.Round(distinct))
+
+	if bucketCount > len(nonNull) {
+		bucketCount = len(nonNull)
+	}
+	if bucketCount < 1 {
+		bucketCount = 1
+	}
+	for i := 0; i <= bucketCount; i++ {
+		r.Histogram = append(r.Histogram, nonNull[i*(len(nonNull)-1)/bucketCount])
+	}
+	return r, nil
+}
+
+// Column 列的统计信息
+func (s *TableStatistics) Column(name string) (*ColumnStatistics, bool) {
+	for _, c := range s.Columns {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// fractionBelow 非 Null 值中小于 value（inclusive 为 true 时小于等于）的比例，按照直方图估计
+func (c *ColumnStatistics) fractionBelow(fieldType MetaType, value []byte, inclusive bool) float64 {
+	if len(c.Histogram) == 0 {
+		return 0
+	}
+	below := 0
+	for _, bound := range c.Histogram {
+		less, err := fieldType.Less(bound, value)
+		if err != nil {
+			return defaultRangeSelectivity
+		}
+		if !less && inclusive {
+			less, _ = fieldType.Equal(bound, value)
+		}
+		if !less {
+			break
+		}
+		below++
+	}
+	switch below {
+	case 0:
+		return 0
+	case len(c.Histogram):
+		return 1
+	}
+	// value 在第 below 个桶中，bigint 按照桶的上下限线性插值，其他类型估计为桶的一半
+	inBucket := 0.5
+	if fieldType.GetType() == base.DBDataTypeBigInt {
+		low, err1 := base.ByteListToInt64(c.Histogram[below-1])
+		high, err2 := base.ByteListToInt64(c.Histogram[below])
+		v, err3 := base.ByteListToInt64(value)
+		if err1 == nil && err2 == nil && err3 == nil && high > low {
+			inBucket = math.Min(1, math.Max(0, (float64(v)-float64(low))/(float64(high)-float64(low))))
+		}
+	}
+	return (float64(below) - 1 + inBucket) / float64(len(c.Histogram)-1)
+}
+
+func (c *ColumnStatistics) equalSelectivity() float64 {
+	if c.DistinctCount <= 0 {
+		return 0
+	}
+	return (1 - c.NullFraction) / float64(c.DistinctCount)
+}
+
+// whereItemSelectivity 单个条件的选择率，c 为 nil 时使用默认值
+func whereItemSelectivity(fieldType MetaType, c *ColumnStatistics, item *base.WherePartItem) float64 {
+	if c == nil {
+		switch item.Operate {
+		case base.DataComparatorEqual:
+			return defaultEqualSelectivity
+		case base.DataComparatorIn:
+			return math.Min(1, defaultEqualSelectivity*float64(len(item.Args)))
+		case base.DataComparatorNotEqual, base.DataComparatorNotIn, base.DataComparatorIsNotNull:
+			return 1 - defaultEqualSelectivity
+		case base.DataComparatorIsNull:
+			return defaultEqualSelectivity
+		case base.DataComparatorGreater, base.DataComparatorGreaterAndEqual, base.DataComparatorLess, base.DataComparatorLessAndEqual, base.DataComparatorBetween:
+			return defaultRangeSelectivity
+		}
+		return defaultMatchSelectivity
+	}
+	notNull := 1 - c.NullFraction
+	arg := func(i int) []byte {
+		if i < len(item.Args) {
+			return item.Args[i]
+		}
+		return nil
+	}
+	switch item.Operate {
+	case base.DataComparatorEqual:
+		return c.equalSelectivity()
+	case base.DataComparatorNotEqual:
+		return math.Max(0, notNull-c.equalSelectivity())
+	case base.DataComparatorIn:
+		return math.Min(notNull, c.equalSelectivity()*float64(len(item.Args)))
+	case base.DataComparatorNotIn:
+		return math.Max(0, notNull-c.equalSelectivity()*float64(len(item.Args)))
+	case base.DataComparatorIsNull:
+		return c.NullFraction
+	case base.DataComparatorIsNotNull:
+		return notNull
+	case base.DataComparatorGreater:
+		return notNull * (1 - c.fractionBelow(fieldType, arg(0), true))
+	case base.DataComparatorGreaterAndEqual:
+		return notNull * (1 - c.fractionBelow(fieldType, arg(0), false))
+	case base.DataComparatorLess:
+		return notNull * c.fractionBelow(fieldType, arg(0), false)
+	case base.DataComparatorLessAndEqual:
+		return notNull * c.fractionBelow(fieldType, arg(0), true)
+	case base.DataComparatorBetween:
+		return notNull * math.Max(0, c.fractionBelow(fieldType, arg(1), true)-c.fractionBelow(fieldType, arg(0), false))
+	}
+	return notNull * defaultMatchSelectivity
+}
+
+// Selectivity 估计满足全部条件的行的比例，条件之间假设相互独立；stats 为 nil 时使用默认值
+func Selectivity(tableInfo *TableMetaInfo, stats *TableStatistics, items []*base.WherePartItem) float64 {
+	r := 1.0
+	for _, item := range items {
+		field, ok := tableInfo.FieldInfoByName(item.TargetColumn)
+		if !ok {
+			r *= defaultMatchSelectivity
+			continue
+		}
+		var c *ColumnStatistics
+		if stats != nil {
+			c, _ = stats.Column(item.TargetColumn)
+		}
+		r *= whereItemSelectivity(field.FieldType, c, item)
+	}
+	return r
+}
+
+func (s *TableStatistics) TableStatisticsToJsonByte() ([]byte, base.StandardError) {
+	jsonByte, er := json.Marshal(s)
+	if er != nil {
+		utils.LogError(fmt.Sprintf("[TableStatisticsToJsonByte] json.Marshal 错误, %s", er.Error()))
+		return nil, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeSystem, base.ErrorBaseCodeInnerDataError, er)
+	}
+	return jsonByte, nil
+}
+
+func InitTableStatisticsByJson(statisticsJson string) (*TableStatistics, base.StandardError) {
+	r := &TableStatistics{}
+	er := json.Unmarshal([]byte(statisticsJson), r)
+	if er != nil {
+		utils.LogError(fmt.Sprintf("[InitTableStatisticsByJson] json解析错误: %s", er.Error()))
+		return nil, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, er)
+	}
+	return r, nil
+}
@@ -0,0 +1,91 @@
+package tableschema
+
+import (
+	"math"
+	"testing"
+
+	"ne_database/core/base"
+)
+
+func TestBuildColumnStatistics(t *testing.T) {
+	field := &FieldInfo{Name: "score", Length: 8, FieldType: BigIntType}
+	tableInfo := &TableMetaInfo{
+		Name:                "scores",
+		PrimaryKeyFieldInfo: &FieldInfo{Name: "id", Length: 8, FieldType: BigIntType},
+		ValueFieldInfo:      []*FieldInfo{field},
+	}
+	int64Value := func(v int64) []byte {
+		r, _ := base.Int64ToByteList(v)
+		return r
+	}
+
+	// 1..100 各一次，另外 25 个 Null（bigint 的 0）
+	values := make([][]byte, 0)
+	for i := int64(1); i <= 100; i++ {
+		values = append(values, int64Value(i))
+	}
+	for i := 0; i < 25; i++ {
+		values = append(values, int64Value(0))
+	}
+	c, err := BuildColumnStatistics(field, values, int64(len(values)), 4)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if c.DistinctCount != 100 || c.NullFraction != 0.2 || len(c.Histogram) != 5 {
+		t.Errorf("BuildColumnStatistics failed, got %#v", c)
+		return
+	}
+	for i, expected := range []int64{1, 25, 50, 75, 100} {
+		n, _ := base.ByteListToInt64(c.Histogram[i])
+		if n != expected {
+			t.Errorf("histogram bound %d expected %d, got %d", i, expected, n)
+			return
+		}
+	}
+
+	stats := &TableStatistics{TableName: tableInfo.Name, RowCount: 125, Columns: []*ColumnStatistics{c}}
+	testCases := []struct {
+		items    []*base.WherePartItem
+		expected float64 // 实际的比例
+	}{
+		{[]*base.WherePartItem{{TargetColumn: "score", Operate: base.DataComparatorEqual, Args: [][]byte{int64Value(10)}}}, 1.0 / 125},
+		{[]*base.WherePartItem{{TargetColumn: "score", Operate: base.DataComparatorLess, Args: [][]byte{int64Value(50)}}}, 49.0 / 125},
+		{[]*base.WherePartItem{{TargetColumn: "score", Operate: base.DataComparatorGreater, Args: [][]byte{int64Value(100)}}}, 0},
+		{[]*base.WherePartItem{{TargetColumn: "score", Operate: base.DataComparatorBetween, Args: [][]byte{int64Value(30), int64Value(70)}}}, 41.0 / 125},
+		{[]*base.WherePartItem{{TargetColumn: "score", Operate: base.DataComparatorIsNull}}, 25.0 / 125},
+		{[]*base.WherePartItem{
+			{TargetColumn: "score", Operate: base.DataComparatorGreaterAndEqual, Args: [][]byte{int64Value(1)}},
+			{TargetColumn: "score", Operate: base.DataComparatorIn, Args: [][]byte{int64Value(1), int64Value(2)}},
+		}, 2.0 / 125},
+	}
+	for _, testCase := range testCases {
+		got := Selectivity(tableInfo, stats, testCase.items)
+		if math.Abs(got-testCase.expected) > 0.1 {
+			t.Errorf("Selectivity(%s) expected about %f, got %f", testCase.items[0].Operate, testCase.expected, got)
+		}
+	}
+	// 没有统计信息时使用默认值
+	got := Selectivity(tableInfo, nil, []*base.WherePartItem{{TargetColumn: "score", Operate: base.DataComparatorEqual, Args: [][]byte{int64Value(10)}}})
+	if got != defaultEqualSelectivity {
+		t.Errorf("Selectivity without statistics expected %f, got %f", defaultEqualSelectivity, got)
+	}
+
+	// 采样只是部分数据时估计不同值的数量: 采样中的值都只出现一次时接近总行数，都出现多次时接近采样中不同值的数量
+	values = values[:50]
+	c, _ = BuildColumnStatistics(field, values, 1000, 4)
+	if c.DistinctCount != 1000 {
+		t.Errorf("DistinctCount expected 1000, got %d", c.DistinctCount)
+	}
+	values = append(values[:25:25], values[:25]...)
+	c, _ = BuildColumnStatistics(field, values, 1000, 4)
+	if c.DistinctCount != 25 {
+		t.Errorf("DistinctCount expected 25, got %d", c.DistinctCount)
+	}
+
+	// 全部为 Null
+	c, _ = BuildColumnStatistics(field, [][]byte{int64Value(0)}, 1, 4)
+	if c.NullFraction != 1 || c.DistinctCount != 0 || len(c.Histogram) != 0 {
+		t.Errorf("BuildColumnStatistics all null failed, got %#v", c)
+	}
+}
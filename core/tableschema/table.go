@@ -3,6 +3,8 @@ package tableschema
 import (
 	"encoding/json"
 	"fmt"
+	"math"
+	"strings"
 
 	"ne_database/core/base"
 	"ne_database/utils"
@@ -10,11 +12,21 @@ import (
 )
 
 type FieldInfo struct {
-	Name         string   `json:"name"`
-	Length       int      `json:"length"`
-	FieldType    MetaType `json:"-"`
-	DefaultValue string   `json:"default"` // 这个值是建表语句的原始值，使用需要进行处理
-	RawFieldType string   `json:"type"`
+	Name         string         `json:"name"`
+	Length       int            `json:"length"`
+	FieldType    MetaType       `json:"-"`
+	DefaultValue string         `json:"default"` // 这个值是建表语句的原始值，使用需要进行处理
+	RawFieldType string         `json:"type"`
+	EnumValues   []string       `json:"enum_values,omitempty"` // enum 类型的可选值
+	Collation    base.Collation `json:"collation,omitempty"`   // char 类型的排序规则，为空时按字节比较
+	// DefaultExpression 使用表达式的默认值，不能使用列，和 DefaultValue 只能设置一个
+	DefaultExpression *base.Expression `json:"default_expression,omitempty"`
+	// AutoIncrement 自增，只有 bigint 类型的主键可以设置，插入时没有提供主键则使用对应序列的下一个值
+	AutoIncrement bool `json:"auto_increment,omitempty"`
+	// Check 列级 CHECK 约束，只能使用本列，约束名为: 表名_列名_check
+	Check CheckExpression `json:"check,omitempty"`
+
+	defaultValue *DefaultValue // Verification 时解析的默认值，加载表结构时解析一次，插入时不再重复解析
 }
 
 type TableMetaInfo struct {
@@ -23,18 +35,87 @@ type TableMetaInfo struct {
 	ValueFieldInfo      []*FieldInfo `json:"value"`
 	PageSize            int          `json:"page_size"`
 	StorageType         string       `json:"storage_type"`
+	// CheckConstraints 表级 CHECK 约束，可以使用多个列
+	CheckConstraints []*CheckConstraint `json:"checks,omitempty"`
+	// ForeignKeys 外键，引用的表在建表时由 engine 校验
+	ForeignKeys []*ForeignKey `json:"foreign_keys,omitempty"`
+	// Version 表结构的版本，叶子结点中的每行数据记录写入时的版本，为 0 时是不记录版本的旧表
+	Version int `json:"version,omitempty"`
+	// History 数据中可能还存在的旧版本的表结构，读取时用于把旧版本的数据转换为当前版本
+	History []*SchemaVersion `json:"history,omitempty"`
 }
 
 // Verification 值配置校验
 func (info *FieldInfo) Verification() base.StandardError {
 	t := info.FieldType
+	if info.Collation != "" {
+		err := CollationVerification(info.Collation)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[Verification] 类型<%s>校验错误, %s", t.GetType(), err.Error()))
+			return err
+		}
+		c, ok := t.(charType)
+		if !ok {
+			utils.LogError(fmt.Sprintf("[Verification] 类型<%s>校验错误, 只有char类型可以设置排序规则", t.GetType()))
+			return base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeType, base.ErrorBaseCodeInnerParameterError, fmt.Errorf("只有char类型可以设置排序规则"))
+		}
+		if c.Collation() != NewCharType(info.Collation).(charType).Collation() {
+			utils.LogError(fmt.Sprintf("[Verification] 类型<%s>校验错误, Collation 与类型的排序规则不一致", t.GetType()))
+			return base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeType, base.ErrorBaseCodeInnerParameterError, fmt.Errorf("Collation 与类型的排序规则不一致"))
+		}
+	}
 	switch t.GetType() {
+	case base.DBDataTypeChar:
+		err := CollationVerification(t.(charType).collation)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[Verification] 类型<%s>校验错误, %s", t.GetType(), err.Error()))
+			return err
+		}
 	case base.DBDataTypeBigInt:
 		if info.Length != base.DataByteLengthInt64 {
 			utils.LogError(fmt.Sprintf("[Verification] 类型<%s>校验错误, 类型长度错误: %d", t.GetType(), info.Length))
 			return base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeType, base.ErrorBaseCodeInnerParameterError, fmt.Errorf("int64类型长度错误: %d", info.Length))
 		}
+	case base.DBDataTypeBlob:
+		if info.Length <= base.DataByteLengthBlobHeader {
+			utils.LogError(fmt.Sprintf("[Verification] 类型<%s>校验错误, 类型长度错误: %d", t.GetType(), info.Length))
+			return base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeType, base.ErrorBaseCodeInnerParameterError, fmt.Errorf("blob类型长度需要大于%d: %d", base.DataByteLengthBlobHeader, info.Length))
+		}
+	case base.DBDataTypeUUID:
+		if info.Length != base.DataByteLengthUUID {
+			utils.LogError(fmt.Sprintf("[Verification] 类型<%s>校验错误, 类型长度错误: %d", t.GetType(), info.Length))
+			return base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeType, base.ErrorBaseCodeInnerParameterError, fmt.Errorf("uuid类型长度错误: %d", info.Length))
+		}
+	case base.DBDataTypeEnum:
+		if info.Length != base.DataByteLengthEnum {
+			utils.LogError(fmt.Sprintf("[Verification] 类型<%s>校验错误, 类型长度错误: %d", t.GetType(), info.Length))
+			return base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeType, base.ErrorBaseCodeInnerParameterError, fmt.Errorf("enum类型长度错误: %d", info.Length))
+		}
+		labels := t.(enumType).Labels()
+		if len(labels) == 0 || len(labels) > math.MaxUint16 {
+			utils.LogError(fmt.Sprintf("[Verification] 类型<%s>校验错误, 可选值数量错误: %d", t.GetType(), len(labels)))
+			return base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeType, base.ErrorBaseCodeInnerParameterError, fmt.Errorf("enum可选值数量错误: %d", len(labels)))
+		}
+		existLabel := set.NewStringsSet()
+		for _, label := range labels {
+			if label == "" || strings.Contains(label, enumLabelSeparator) || existLabel.Contain(label) {
+				utils.LogError(fmt.Sprintf("[Verification] 类型<%s>校验错误, 可选值<%s>为空、重复或包含非法字符", t.GetType(), label))
+				return base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeType, base.ErrorBaseCodeInnerParameterError, fmt.Errorf("enum可选值<%s>为空、重复或包含非法字符", label))
+			}
+			existLabel.Add(label)
+		}
+		if info.EnumValues != nil && strings.Join(info.EnumValues, enumLabelSeparator) != strings.Join(labels, enumLabelSeparator) {
+			utils.LogError(fmt.Sprintf("[Verification] 类型<%s>校验错误, EnumValues 与类型的可选值不一致", t.GetType()))
+			return base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeType, base.ErrorBaseCodeInnerParameterError, fmt.Errorf("EnumValues 与类型的可选值不一致"))
+		}
+	}
+	// 默认值需要能转化为该类型的值
+	defaultValue, err := info.ParseDefaultValue()
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[Verification] 类型<%s>校验错误, 默认值错误: %s", t.GetType(), err.Error()))
+		return err
 	}
+	info.defaultValue = defaultValue
 	return nil
 }
 
@@ -55,6 +136,18 @@ func (info *FieldInfo) CompareFieldInfo(info2 *FieldInfo) bool {
 		utils.LogDev(string(base.FunctionModelCoreTableSchema))("[CompareFieldInfo] 值信息：默认值不一致")
 		return false
 	}
+	if utils.ToJSON(info.DefaultExpression) != utils.ToJSON(info2.DefaultExpression) {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))("[CompareFieldInfo] 值信息：默认值表达式不一致")
+		return false
+	}
+	if info.AutoIncrement != info2.AutoIncrement {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))("[CompareFieldInfo] 值信息：自增不一致")
+		return false
+	}
+	if utils.ToJSON(info.Check) != utils.ToJSON(info2.Check) {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))("[CompareFieldInfo] 值信息：CHECK约束不一致")
+		return false
+	}
 	return true
 }
 
@@ -66,21 +159,41 @@ func (info *TableMetaInfo) ValueFieldInfoMap() (map[string]*FieldInfo, base.Stan
 	return valueFieldInfoMap, nil
 }
 
+// columnNameVerification 校验列名，列名不能为空，也不能包含 json 路径的分隔符，否则 TargetColumn 中无法区分列名和路径
+func columnNameVerification(name string) base.StandardError {
+	if name == "" || strings.Contains(name, base.SymbolJSONPathSeparator) {
+		errMsg := fmt.Sprintf("列名<%s>不合法，不能为空，也不能包含<%s>", name, base.SymbolJSONPathSeparator)
+		utils.LogError("[columnNameVerification] " + errMsg)
+		return base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeType, base.ErrorBaseCodeInnerParameterError, fmt.Errorf(errMsg))
+	}
+	return nil
+}
+
 // Verification 表配置校验
 func (info *TableMetaInfo) Verification() base.StandardError {
-	if info.Name == "" {
-		utils.LogError(fmt.Sprintf("[Verification] 表校验错误, 表名为空"))
-		return base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeType, base.ErrorBaseCodeInnerParameterError, fmt.Errorf("表名为空"))
+	err := tableNameVerification(info.Name)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[Verification] 表校验错误 表名校验出错, %s", err.Error()))
+		return err
 	}
 	if info.PrimaryKeyFieldInfo == nil {
 		utils.LogError(fmt.Sprintf("[Verification] 表校验错误, 主键配置为空"))
 		return base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeType, base.ErrorBaseCodeInnerParameterError, fmt.Errorf("主键配置为空"))
 	}
-	err := info.PrimaryKeyFieldInfo.Verification()
+	err = columnNameVerification(info.PrimaryKeyFieldInfo.Name)
+	if err == nil {
+		err = info.PrimaryKeyFieldInfo.Verification()
+	}
 	if err != nil {
 		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[Verification] 表校验错误 primaryKey info Verification 出错, %s", err.Error()))
 		return err
 	}
+	if info.PrimaryKeyFieldInfo.AutoIncrement {
+		if info.PrimaryKeyFieldInfo.FieldType.GetType() != base.DBDataTypeBigInt || info.PrimaryKeyFieldInfo.DefaultValue != "" {
+			utils.LogError(fmt.Sprintf("[Verification] 表校验错误, 自增主键必须是bigint类型，并且不能设置默认值"))
+			return base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeType, base.ErrorBaseCodeInnerParameterError, fmt.Errorf("自增主键必须是bigint类型，并且不能设置默认值"))
+		}
+	}
 	if info.ValueFieldInfo == nil || len(info.ValueFieldInfo) == 0 {
 		utils.LogError(fmt.Sprintf("[Verification] 表校验错误, 值配置为空"))
 		return base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeType, base.ErrorBaseCodeInnerParameterError, fmt.Errorf("值配置为空"))
@@ -91,11 +204,18 @@ func (info *TableMetaInfo) Verification() base.StandardError {
 			utils.LogError(fmt.Sprintf("[Verification] 表校验错误, 值配置为空"))
 			return base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeType, base.ErrorBaseCodeInnerParameterError, fmt.Errorf("值配置为空"))
 		}
-		err := i.Verification()
+		err := columnNameVerification(i.Name)
+		if err == nil {
+			err = i.Verification()
+		}
 		if err != nil {
 			utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[Verification] 表校验错误 value info Verification 出错, %s", err.Error()))
 			return err
 		}
+		if i.AutoIncrement {
+			utils.LogError(fmt.Sprintf("[Verification] 表校验错误, 值<%s>不能设置自增", i.Name))
+			return base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeType, base.ErrorBaseCodeInnerParameterError, fmt.Errorf("值<%s>不能设置自增，只有主键可以设置自增", i.Name))
+		}
 		if existName.Contain(i.Name) {
 			utils.LogError(fmt.Sprintf("[Verification] 表校验错误, 值配置名<%s>重复", i.Name))
 			return base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeType, base.ErrorBaseCodeInnerParameterError, fmt.Errorf("值配置名<%s>重复", i.Name))
@@ -111,6 +231,21 @@ func (info *TableMetaInfo) Verification() base.StandardError {
 		utils.LogError(fmt.Sprintf("[Verification] 表校验错误, StorageType: %s 不支持", info.StorageType))
 		return base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeType, base.ErrorBaseCodeInnerParameterError, fmt.Errorf("StorageType: %s 不支持", info.StorageType))
 	}
+	err = info.checkConstraintVerification()
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[Verification] 表校验错误 CHECK约束校验出错, %s", err.Error()))
+		return err
+	}
+	err = info.foreignKeyVerification()
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[Verification] 表校验错误 外键校验出错, %s", err.Error()))
+		return err
+	}
+	err = info.versionVerification()
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[Verification] 表校验错误 版本校验出错, %s", err.Error()))
+		return err
+	}
 	return nil
 }
 
@@ -139,13 +274,66 @@ func (info *TableMetaInfo) CompareTableInfo(info2 *TableMetaInfo) bool {
 			return false
 		}
 	}
+
+	// 4. 对比CheckConstraints
+	if utils.ToJSON(info.CheckConstraints) != utils.ToJSON(info2.CheckConstraints) {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))("[CompareTableInfo] 两表CHECK约束不一致")
+		return false
+	}
+
+	// 5. 对比ForeignKeys
+	if utils.ToJSON(info.ForeignKeys) != utils.ToJSON(info2.ForeignKeys) {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))("[CompareTableInfo] 两表外键不一致")
+		return false
+	}
+
+	// 6. 对比版本
+	if info.Version != info2.Version || utils.ToJSON(info.History) != utils.ToJSON(info2.History) {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))("[CompareTableInfo] 两表版本不一致")
+		return false
+	}
 	return true
 }
 
+// FillingRawFieldType 通过 FieldType 填充需要储存的 RawFieldType 以及类型的附加信息
+func (info *FieldInfo) FillingRawFieldType() base.StandardError {
+	var err base.StandardError
+
+	info.RawFieldType, err = FieldTypeToRaw(info.FieldType)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[FieldInfo.FillingRawFieldType] 获取RawFieldType出错, %s", err.Error()))
+		return err
+	}
+	switch t := info.FieldType.(type) {
+	case enumType:
+		info.EnumValues = t.Labels()
+	case charType:
+		info.Collation = t.collation
+	}
+	return nil
+}
+
+// LoadFieldType 通过储存的 RawFieldType 以及类型的附加信息获取真实的 FieldType
+func (info *FieldInfo) LoadFieldType() base.StandardError {
+	fieldType, err := RawToFieldType(info.RawFieldType)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[FieldInfo.LoadFieldType] RawToFieldType出错, %s", err.Error()))
+		return err
+	}
+	switch fieldType {
+	case EnumType:
+		fieldType = NewEnumType(info.EnumValues...)
+	case CharType:
+		fieldType = NewCharType(info.Collation)
+	}
+	info.FieldType = fieldType
+	return nil
+}
+
 func (info *TableMetaInfo) FillingRawFieldType() base.StandardError {
 	var err base.StandardError
 
-	info.PrimaryKeyFieldInfo.RawFieldType, err = FieldTypeToRaw(info.PrimaryKeyFieldInfo.FieldType)
+	err = info.PrimaryKeyFieldInfo.FillingRawFieldType()
 	if err != nil {
 		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[TableMetaInfo.FillingRawFieldType] 获取RawFieldType出错, %s", err.Error()))
 		return err
@@ -156,13 +344,22 @@ func (info *TableMetaInfo) FillingRawFieldType() base.StandardError {
 	}
 	for _, i := range info.ValueFieldInfo {
 		if i != nil {
-			i.RawFieldType, err = FieldTypeToRaw(i.FieldType)
+			err = i.FillingRawFieldType()
 			if err != nil {
 				utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[TableMetaInfo.FillingRawFieldType] 获取RawFieldType出错, %s", err.Error()))
 				return err
 			}
 		}
 	}
+	for _, h := range info.History {
+		for _, i := range h.ValueFieldInfo {
+			err = i.FillingRawFieldType()
+			if err != nil {
+				utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[TableMetaInfo.FillingRawFieldType] 获取版本<%d>的RawFieldType出错, %s", h.Version, err.Error()))
+				return err
+			}
+		}
+	}
 	return nil
 }
 
@@ -194,6 +391,11 @@ func (info *TableMetaInfo) TableMetaInfoToJsonByte() ([]byte, base.StandardError
 	return jsonByte, nil
 }
 
+// PrimaryKeyIndexName 主键约束和索引的名称，为: 表名_pkey
+func PrimaryKeyIndexName(tableName string) string {
+	return fmt.Sprintf("%s_%s", tableName, base.PrimaryKeySuffix)
+}
+
 // InitTableMetaInfo
 // 确定一个表，需要：
 // 1. 主键名称，及其类型和长度
@@ -202,12 +404,21 @@ func InitTableMetaInfo(name string) *TableMetaInfo {
 	return nil
 }
 
+// RawToFieldType 类型名转为字段类型，int64 和 string 是旧版本表结构文件中的类型名，仍然兼容
 func RawToFieldType(raw string) (MetaType, base.StandardError) {
 	switch raw {
-	case string(base.DBDataTypeBigInt):
+	case string(base.DBDataTypeBigInt), "int64":
 		return BigIntType, nil
-	case string(base.DBDataTypeChar):
+	case string(base.DBDataTypeChar), "string":
 		return CharType, nil
+	case string(base.DBDataTypeBlob):
+		return BlobType, nil
+	case string(base.DBDataTypeJSON):
+		return JSONType, nil
+	case string(base.DBDataTypeUUID):
+		return UUIDType, nil
+	case string(base.DBDataTypeEnum):
+		return EnumType, nil
 	default:
 		utils.LogError(fmt.Sprintf("[RawToFieldType] 错误的RawFieldType: %s", raw))
 		return nil, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeInput, base.ErrorBaseCodeInnerParameterError, fmt.Errorf("错误的RawFieldType: %s", raw))
@@ -215,11 +426,19 @@ func RawToFieldType(raw string) (MetaType, base.StandardError) {
 }
 
 func FieldTypeToRaw(fieldType MetaType) (string, base.StandardError) {
-	switch fieldType {
-	case BigIntType:
+	switch fieldType.(type) {
+	case bigIntType:
 		return string(base.DBDataTypeBigInt), nil
-	case CharType:
+	case charType:
 		return string(base.DBDataTypeChar), nil
+	case blobType:
+		return string(base.DBDataTypeBlob), nil
+	case jsonType:
+		return string(base.DBDataTypeJSON), nil
+	case uuidType:
+		return string(base.DBDataTypeUUID), nil
+	case enumType:
+		return string(base.DBDataTypeEnum), nil
 	default:
 		utils.LogError(fmt.Sprintf("[FieldTypeToRaw] 错误的fieldType: %#v", fieldType))
 		return "", base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeType, base.ErrorBaseCodeInnerTypeError, fmt.Errorf("错误的fieldType: %#v", fieldType))
@@ -239,12 +458,11 @@ func InitTableMetaInfoByJson(metaJson string) (*TableMetaInfo, base.StandardErro
 		return nil, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, er)
 	}
 	// 替换主键的 FieldType 为真实
-	pkFieldType, err := RawToFieldType(r.PrimaryKeyFieldInfo.RawFieldType)
+	err := r.PrimaryKeyFieldInfo.LoadFieldType()
 	if err != nil {
-		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[InitTableMetaInfoByJson] primaryKey RawToFieldType出错, %s", err.Error()))
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[InitTableMetaInfoByJson] primaryKey LoadFieldType出错, %s", err.Error()))
 		return nil, err
 	}
-	r.PrimaryKeyFieldInfo.FieldType = pkFieldType
 	err = r.PrimaryKeyFieldInfo.Verification()
 	if err != nil {
 		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[InitTableMetaInfoByJson] PrimaryKeyFieldInfo.Verification出错, %s", err.Error()))
@@ -253,12 +471,11 @@ func InitTableMetaInfoByJson(metaJson string) (*TableMetaInfo, base.StandardErro
 
 	// 替换值的 FieldType 为真实
 	for _, v := range r.ValueFieldInfo {
-		valueFieldType, err := RawToFieldType(v.RawFieldType)
+		err := v.LoadFieldType()
 		if err != nil {
-			utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[InitTableMetaInfoByJson] value RawToFieldType出错, %s", err.Error()))
+			utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[InitTableMetaInfoByJson] value LoadFieldType出错, %s", err.Error()))
 			return nil, err
 		}
-		v.FieldType = valueFieldType
 		err = v.Verification()
 		if err != nil {
 			utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[InitTableMetaInfoByJson] value.Verification出错, %s", err.Error()))
@@ -266,6 +483,17 @@ func InitTableMetaInfoByJson(metaJson string) (*TableMetaInfo, base.StandardErro
 		}
 	}
 
+	// 替换旧版本的值的 FieldType 为真实
+	for _, h := range r.History {
+		for _, v := range h.ValueFieldInfo {
+			err := v.LoadFieldType()
+			if err != nil {
+				utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[InitTableMetaInfoByJson] 版本<%d>的value LoadFieldType出错, %s", h.Version, err.Error()))
+				return nil, err
+			}
+		}
+	}
+
 	err = r.Verification()
 	if err != nil {
 		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[InitTableMetaInfoByJson] table.Verification出错, %s", err.Error()))
@@ -360,3 +360,81 @@ func TestTableMetaInfo_CompareTableInfo(t *testing.T) {
 		return
 	}
 }
+
+func TestFieldInfo_Verification_Enum(t *testing.T) {
+	info := &FieldInfo{
+		Name:      "status",
+		Length:    2,
+		FieldType: NewEnumType("pending", "paid"),
+	}
+	err := info.Verification()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	info.FieldType = NewEnumType("pending", "pending")
+	err = info.Verification()
+	if err == nil {
+		t.Error("expected error, but got nil")
+		return
+	}
+
+	info.FieldType = EnumType
+	err = info.Verification()
+	if err == nil {
+		t.Error("expected error, but got nil")
+		return
+	}
+
+	info.FieldType = NewEnumType("pending", "paid")
+	info.EnumValues = []string{"paid", "pending"}
+	err = info.Verification()
+	if err == nil {
+		t.Error("expected error, but got nil")
+		return
+	}
+}
+
+func TestInitTableMetaInfoByJson_UUIDAndEnum(t *testing.T) {
+	tableInfo := &TableMetaInfo{
+		Name: "orders",
+		PrimaryKeyFieldInfo: &FieldInfo{
+			Name:      "id",
+			Length:    16,
+			FieldType: UUIDType,
+		},
+		ValueFieldInfo: []*FieldInfo{
+			{
+				Name:      "status",
+				Length:    2,
+				FieldType: NewEnumType("pending", "paid", "shipped"),
+			},
+		},
+		PageSize:    1000,
+		StorageType: base.StorageTypeFile,
+	}
+	jsonByte, err := tableInfo.TableMetaInfoToJsonByte()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	loadTableInfo, err := InitTableMetaInfoByJson(string(jsonByte))
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if !loadTableInfo.CompareTableInfo(tableInfo) {
+		t.Error("expected same")
+		return
+	}
+	shipped, err := loadTableInfo.ValueFieldInfo[0].FieldType.StringToByte("shipped")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if loadTableInfo.ValueFieldInfo[0].FieldType.StringValue(shipped) != "shipped" {
+		t.Error("unexpected enum value")
+		return
+	}
+}
@@ -0,0 +1,206 @@
+package tableschema
+
+import (
+	"fmt"
+
+	"ne_database/core/base"
+	"ne_database/utils"
+)
+
+// SchemaVersion 表结构的旧版本，只记录读取该版本的行数据需要的信息
+// 主键的类型和长度在各个版本之间不变，所以只记录值的配置
+type SchemaVersion struct {
+	Version        int               `json:"version"`
+	ValueFieldInfo []*FieldInfo      `json:"value"`
+	Source         map[string]string `json:"source"` // 当前版本的列名 -> 该版本的列名，新增的列不在其中
+}
+
+// RowVersioned 叶子结点中的行数据是否记录表结构版本
+func (info *TableMetaInfo) RowVersioned() bool {
+	return info.Version > base.TableSchemaVersionLegacy
+}
+
+// versionVerification 校验版本信息，旧版本需要小于当前版本，并且不能重复
+func (info *TableMetaInfo) versionVerification() base.StandardError {
+	versionError := func(errMsg string) base.StandardError {
+		utils.LogError("[versionVerification] " + errMsg)
+		return base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeType, base.ErrorBaseCodeTableSchemaError, fmt.Errorf(errMsg))
+	}
+	if info.Version < base.TableSchemaVersionLegacy {
+		return versionError(fmt.Sprintf("表结构版本<%d>小于0", info.Version))
+	}
+	if !info.RowVersioned() && len(info.History) > 0 {
+		return versionError("没有版本的表不能有旧版本的表结构")
+	}
+	existVersion := make(map[int]bool, len(info.History))
+	for _, h := range info.History {
+		if h == nil || len(h.ValueFieldInfo) == 0 {
+			return versionError("旧版本的表结构为空")
+		}
+		if h.Version < base.TableSchemaVersionInitial || h.Version >= info.Version || existVersion[h.Version] {
+			return versionError(fmt.Sprintf("旧版本<%d>不合法，当前版本为<%d>", h.Version, info.Version))
+		}
+		existVersion[h.Version] = true
+		for _, field := range h.ValueFieldInfo {
+			if field == nil || field.FieldType == nil {
+				return versionError(fmt.Sprintf("旧版本<%d>的值配置为空", h.Version))
+			}
+		}
+	}
+	return nil
+}
+
+// schemaVersion 获取旧版本的表结构
+func (info *TableMetaInfo) schemaVersion(version int) (*SchemaVersion, base.StandardError) {
+	for _, h := range info.History {
+		if h.Version == version {
+			return h, nil
+		}
+	}
+	errMsg := fmt.Sprintf("表<%s>不存在版本<%d>的表结构", info.Name, version)
+	utils.LogError("[TableMetaInfo.schemaVersion] " + errMsg)
+	return nil, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeInput, base.ErrorBaseCodeInnerDataError, fmt.Errorf(errMsg))
+}
+
+// VersionValueFieldInfo 获取某个版本写入的行数据中值的配置，按照储存的顺序
+func (info *TableMetaInfo) VersionValueFieldInfo(version int) ([]*FieldInfo, base.StandardError) {
+	if version == info.Version {
+		return info.ValueFieldInfo, nil
+	}
+	h, err := info.schemaVersion(version)
+	if err != nil {
+		return nil, err
+	}
+	return h.ValueFieldInfo, nil
+}
+
+// upgradeDefaultValue 旧版本中不存在的列在转换时使用的值，只能使用 Null 或者字面值，保证每次读取的结果一致
+func (info *FieldInfo) upgradeDefaultValue() ([]byte, base.StandardError) {
+	defaultValue, err := info.parsedDefaultValue()
+	if err != nil {
+		return nil, err
+	}
+	switch defaultValue.Kind {
+	case DefaultValueKindNone, DefaultValueKindNull:
+		return info.FieldType.TrimRaw(info.NullValue()), nil
+	case DefaultValueKindLiteral:
+		return info.FieldType.TrimRaw(defaultValue.Literal), nil
+	}
+	errMsg := fmt.Sprintf("列<%s>的默认值<%s>不能用于转换旧版本的数据", info.Name, info.DefaultDefinition())
+	utils.LogError("[FieldInfo.upgradeDefaultValue] " + errMsg)
+	return nil, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeInput, base.ErrorBaseCodeTableSchemaError, fmt.Errorf(errMsg))
+}
+
+// UpgradeRowValues 把 version 版本写入的一行数据转换为当前版本，values 为 列名 -> 去掉补齐的值
+// 旧版本中不存在的列使用默认值，没有默认值时为 Null
+func (info *TableMetaInfo) UpgradeRowValues(version int, values map[string][]byte) (map[string][]byte, base.StandardError) {
+	if version == info.Version {
+		return values, nil
+	}
+	h, err := info.schemaVersion(version)
+	if err != nil {
+		return nil, err
+	}
+	r := make(map[string][]byte, len(info.ValueFieldInfo))
+	for _, field := range info.ValueFieldInfo {
+		if name, ok := h.Source[field.Name]; ok {
+			r[field.Name] = values[name]
+			continue
+		}
+		r[field.Name], err = field.upgradeDefaultValue()
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[TableMetaInfo.UpgradeRowValues] upgradeDefaultValue错误, %s", err.Error()))
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// CanUpgradeLazily 判断由 info 修改为 newInfo 之后，已有的数据能否不重写，在读取时再转换
+// 需要: info 记录了行版本，主键长度不变，保留的列类型不变并且长度不变小，新增的列没有 CHECK 约束并且默认值是 Null 或者字面值
+// source 为 新列名 -> 原来的列名；页内能放下的行数由 engine 另外判断
+func (info *TableMetaInfo) CanUpgradeLazily(newInfo *TableMetaInfo, source map[string]string) bool {
+	if !info.RowVersioned() || info.PrimaryKeyFieldInfo.Length != newInfo.PrimaryKeyFieldInfo.Length {
+		return false
+	}
+	for _, field := range newInfo.ValueFieldInfo {
+		name, ok := source[field.Name]
+		if !ok {
+			if field.Check != nil {
+				return false
+			}
+			if _, err := field.upgradeDefaultValue(); err != nil {
+				return false
+			}
+			continue
+		}
+		oldField, ok := info.FieldInfoByName(name)
+		if !ok || oldField.FieldType != field.FieldType || oldField.Length > field.Length {
+			return false
+		}
+	}
+	return true
+}
+
+// SameRowLayout 判断 newInfo 的行数据和 info 的储存方式是否完全一致，即只修改了表名、列名或约束
+// 一致时已有的数据不需要转换，也不需要修改版本
+func (info *TableMetaInfo) SameRowLayout(newInfo *TableMetaInfo, source map[string]string) bool {
+	if info.PrimaryKeyFieldInfo.FieldType != newInfo.PrimaryKeyFieldInfo.FieldType || info.PrimaryKeyFieldInfo.Length != newInfo.PrimaryKeyFieldInfo.Length {
+		return false
+	}
+	if len(info.ValueFieldInfo) != len(newInfo.ValueFieldInfo) {
+		return false
+	}
+	for i, field := range newInfo.ValueFieldInfo {
+		oldField := info.ValueFieldInfo[i]
+		if source[field.Name] != oldField.Name || oldField.FieldType != field.FieldType || oldField.Length != field.Length {
+			return false
+		}
+	}
+	return true
+}
+
+// composeHistory 旧版本的列名映射改为对应 newInfo 的列名
+func (info *TableMetaInfo) composeHistory(source map[string]string) []*SchemaVersion {
+	history := make([]*SchemaVersion, 0, len(info.History)+1)
+	for _, h := range info.History {
+		s := make(map[string]string, len(source))
+		for newName, oldName := range source {
+			if name, ok := h.Source[oldName]; ok {
+				s[newName] = name
+			}
+		}
+		history = append(history, &SchemaVersion{Version: h.Version, ValueFieldInfo: h.ValueFieldInfo, Source: s})
+	}
+	return history
+}
+
+// KeepVersion 行数据的储存方式不变时（见 SameRowLayout），newInfo 沿用 info 的版本，旧版本的列名映射跟随修改
+func (info *TableMetaInfo) KeepVersion(newInfo *TableMetaInfo, source map[string]string) {
+	newInfo.Version = info.Version
+	newInfo.History = nil
+	if len(info.History) > 0 {
+		newInfo.History = info.composeHistory(source)
+	}
+}
+
+// NextVersion 设置修改后的表结构 newInfo 的版本
+// keepHistory 为 true 时已有的数据不重写，info 以及 info 的旧版本都保留在 newInfo.History 中；否则清空旧版本
+func (info *TableMetaInfo) NextVersion(newInfo *TableMetaInfo, source map[string]string, keepHistory bool) {
+	newInfo.Version = info.Version + 1
+	newInfo.History = nil
+	if !keepHistory {
+		return
+	}
+	valueFieldInfo := make([]*FieldInfo, 0, len(info.ValueFieldInfo))
+	for _, field := range info.ValueFieldInfo {
+		f := *field
+		f.Check = nil
+		valueFieldInfo = append(valueFieldInfo, &f)
+	}
+	s := make(map[string]string, len(source))
+	for newName, oldName := range source {
+		s[newName] = oldName
+	}
+	newInfo.History = append(info.composeHistory(source), &SchemaVersion{Version: info.Version, ValueFieldInfo: valueFieldInfo, Source: s})
+}
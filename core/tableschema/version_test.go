@@ -0,0 +1,105 @@
+package tableschema
+
+import (
+	"bytes"
+	"testing"
+
+	"ne_database/core/base"
+)
+
+func TestTableMetaInfo_UpgradeRowValues(t *testing.T) {
+	v1 := checkTestTableInfo()
+	v1.Version = base.TableSchemaVersionInitial
+
+	// v1 -> v2: 修改列名、删除列、新增有默认值的列
+	v2, source, err := v1.Alter([]*AlterTableItem{
+		{Action: base.AlterTableActionRenameColumn, Column: "discount", NewName: "rebate"},
+		{Action: base.AlterTableActionAddColumn, Field: &FieldInfo{Name: "remark", Length: 10, FieldType: CharType, DefaultValue: "'none'"}},
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if !v1.CanUpgradeLazily(v2, source) {
+		t.Error("CanUpgradeLazily failed, expected true")
+		return
+	}
+	v1.NextVersion(v2, source, true)
+	// v2 -> v3: 再次修改列名
+	v3, source, err := v2.Alter([]*AlterTableItem{
+		{Action: base.AlterTableActionRenameColumn, Column: "rebate", NewName: "bonus"},
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	v2.NextVersion(v3, source, true)
+	if v3.Version != 3 || len(v3.History) != 2 {
+		t.Errorf("NextVersion failed, got version %d, %d history", v3.Version, len(v3.History))
+		return
+	}
+
+	// json 储存之后旧版本仍然可用
+	jsonByte, err := v3.TableMetaInfoToJsonByte()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	v3, err = InitTableMetaInfoByJson(string(jsonByte))
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+
+	qty, _ := base.Int64ToByteList(5)
+	discount, _ := base.Int64ToByteList(10)
+	row, err := v3.UpgradeRowValues(1, map[string][]byte{"qty": qty, "discount": discount, "status": []byte("paid")})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if !bytes.Equal(row["bonus"], discount) || !bytes.Equal(row["qty"], qty) || string(row["remark"]) != "none" || len(row) != 4 {
+		t.Errorf("UpgradeRowValues failed, got %#v", row)
+		return
+	}
+	row, err = v3.UpgradeRowValues(2, map[string][]byte{"qty": qty, "rebate": discount, "status": []byte("paid"), "remark": []byte("vip")})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if !bytes.Equal(row["bonus"], discount) || string(row["remark"]) != "vip" {
+		t.Errorf("UpgradeRowValues failed, got %#v", row)
+		return
+	}
+	_, err = v3.UpgradeRowValues(9, map[string][]byte{})
+	if err == nil {
+		t.Error("expected error, but got nil")
+		return
+	}
+
+	testCases := [][]*AlterTableItem{
+		// 默认值不是字面值
+		{{Action: base.AlterTableActionAddColumn, Field: &FieldInfo{Name: "created", Length: 8, FieldType: BigIntType, DefaultValue: "CURRENT_TIMESTAMP"}}},
+		// 长度变小
+		{{Action: base.AlterTableActionModifyLength, Column: "status", Length: 5}},
+		// 新增的列有 CHECK 约束
+		{{Action: base.AlterTableActionAddColumn, Field: &FieldInfo{Name: "level", Length: 8, FieldType: BigIntType, Check: CheckExpression{{
+			{TargetColumn: "level", Operate: base.DataComparatorGreater, Args: [][]byte{qty}},
+		}}}}},
+	}
+	for i, c := range testCases {
+		newInfo, source, err := v3.Alter(c)
+		if err != nil {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+			continue
+		}
+		if v3.CanUpgradeLazily(newInfo, source) {
+			t.Errorf("case %d: CanUpgradeLazily failed, expected false", i)
+		}
+	}
+	legacy := checkTestTableInfo()
+	newInfo, source, _ := legacy.Alter([]*AlterTableItem{{Action: base.AlterTableActionRenameTable, NewName: "legacy_orders"}})
+	if legacy.CanUpgradeLazily(newInfo, source) {
+		t.Error("CanUpgradeLazily failed, table without version should be rewritten")
+	}
+}
@@ -0,0 +1,83 @@
+package tableschema
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"ne_database/core/base"
+	"ne_database/utils"
+)
+
+// ViewInfo 视图信息，Query 中的表名都带有数据库名
+// 普通视图只保存查询，在查询视图时展开；物化视图的数据保存在同名的表中，由 REFRESH 按照 Query 重新生成，
+// 表的第一列为主键，所以查询结果的第一列必须唯一
+type ViewInfo struct {
+	Name         string      `json:"name"`
+	Query        *base.Query `json:"query"`
+	Materialized bool        `json:"materialized,omitempty"`
+	// Columns 普通视图的列，创建时按照查询结果确定，不执行查询时（如预处理语句确定参数的类型）使用；查询视图时结果的列名或者类型不同则报错
+	// 物化视图的列为同名的表的列，Columns 为空
+	Columns []*FieldInfo `json:"columns,omitempty"`
+}
+
+// Verification 视图校验
+func (info *ViewInfo) Verification() base.StandardError {
+	// 物化视图的表和视图同名，视图名和表名的规则相同
+	err := tableNameVerification(info.Name)
+	if err != nil {
+		return err
+	}
+	if info.Query == nil || info.Query.From == "" {
+		utils.LogError(fmt.Sprintf("[ViewInfo.Verification] 视图<%s>的查询为空", info.Name))
+		return base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeType, base.ErrorBaseCodeInnerParameterError, fmt.Errorf("视图<%s>的查询为空", info.Name))
+	}
+	return nil
+}
+
+// Definition 视图的定义，为查询的 json
+func (info *ViewInfo) Definition() string {
+	return utils.ToJSON(info.Query)
+}
+
+func (info *ViewInfo) ViewInfoToJsonByte() ([]byte, base.StandardError) {
+	err := info.Verification()
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[ViewInfoToJsonByte] 视图校验错误, %s", err.Error()))
+		return nil, err
+	}
+	for _, column := range info.Columns {
+		err = column.FillingRawFieldType()
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[ViewInfoToJsonByte] 列<%s>获取RawFieldType出错, %s", column.Name, err.Error()))
+			return nil, err
+		}
+	}
+	jsonByte, er := json.Marshal(info)
+	if er != nil {
+		utils.LogError(fmt.Sprintf("[ViewInfoToJsonByte] json.Marshal 错误, %s", er.Error()))
+		return nil, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeSystem, base.ErrorBaseCodeInnerDataError, er)
+	}
+	return jsonByte, nil
+}
+
+func InitViewInfoByJson(viewJson string) (*ViewInfo, base.StandardError) {
+	r := &ViewInfo{}
+	er := json.Unmarshal([]byte(viewJson), r)
+	if er != nil {
+		utils.LogError(fmt.Sprintf("[InitViewInfoByJson] json解析错误: %s", er.Error()))
+		return nil, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, er)
+	}
+	err := r.Verification()
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[InitViewInfoByJson] Verification出错, %s", err.Error()))
+		return nil, err
+	}
+	for _, column := range r.Columns {
+		err = column.LoadFieldType()
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[InitViewInfoByJson] 列<%s> LoadFieldType出错, %s", column.Name, err.Error()))
+			return nil, err
+		}
+	}
+	return r, nil
+}
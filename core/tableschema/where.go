@@ -0,0 +1,199 @@
+package tableschema
+
+import (
+	"fmt"
+
+	"ne_database/core/base"
+	"ne_database/utils"
+)
+
+// FieldInfoByName 通过列名获取列信息，包含主键
+func (info *TableMetaInfo) FieldInfoByName(name string) (*FieldInfo, bool) {
+	if info.PrimaryKeyFieldInfo != nil && info.PrimaryKeyFieldInfo.Name == name {
+		return info.PrimaryKeyFieldInfo, true
+	}
+	for _, i := range info.ValueFieldInfo {
+		if i != nil && i.Name == name {
+			return i, true
+		}
+	}
+	return nil, false
+}
+
+// MatchWhereParts 判断一行数据是否满足全部查询条件，各个条件之间是 and 的关系
+// row 为 列名 -> 值，主键也需要包含在内；cache 为本次查询的正则表达式缓存，可以为 nil
+func (info *TableMetaInfo) MatchWhereParts(items []*base.WherePartItem, row map[string][]byte, cache RegexpCache) (bool, base.StandardError) {
+	for _, item := range items {
+		match, err := info.MatchWherePartItem(item, row, cache)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[MatchWhereParts.MatchWherePartItem] err: %s", err.Error()))
+			return false, err
+		}
+		if !match {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// MatchWhereNode 判断一行数据是否满足条件树
+func (info *TableMetaInfo) MatchWhereNode(node *base.WhereNode, row map[string][]byte, cache RegexpCache) (bool, base.StandardError) {
+	if !node.Validation() {
+		errMsg := fmt.Sprintf("不合法条件: %s", utils.ToJSON(node))
+		utils.LogError("[MatchWhereNode] " + errMsg)
+		return false, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+	}
+	switch node.Logic {
+	case base.WhereLogicAnd, base.WhereLogicOr:
+		// and 遇到 false、or 遇到 true 即可返回
+		shortCircuit := node.Logic == base.WhereLogicOr
+		for _, child := range node.Children {
+			match, err := info.MatchWhereNode(child, row, cache)
+			if err != nil {
+				return false, err
+			}
+			if match == shortCircuit {
+				return shortCircuit, nil
+			}
+		}
+		return !shortCircuit, nil
+	case base.WhereLogicNot:
+		match, err := info.MatchWhereNode(node.Children[0], row, cache)
+		if err != nil {
+			return false, err
+		}
+		return !match, nil
+	default:
+		return info.MatchWherePartItem(node.Item, row, cache)
+	}
+}
+
+// MatchWherePartItem 判断一行数据是否满足单个查询条件
+// TargetColumn 可以带上 json 路径（如: attrs.color），此时该列必须是 json 类型
+func (info *TableMetaInfo) MatchWherePartItem(item *base.WherePartItem, row map[string][]byte, cache RegexpCache) (bool, base.StandardError) {
+	if item == nil || !item.Validation() {
+		errMsg := fmt.Sprintf("不合法查询: %s", utils.ToJSON(item))
+		utils.LogError("[MatchWherePartItem] " + errMsg)
+		return false, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+	}
+	column, path := item.ColumnAndPath()
+	fieldInfo, ok := info.FieldInfoByName(column)
+	if !ok {
+		errMsg := fmt.Sprintf("列<%s>不存在", column)
+		utils.LogError("[MatchWherePartItem] " + errMsg)
+		return false, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+	}
+	value, ok := row[column]
+	if !ok {
+		errMsg := fmt.Sprintf("数据中不存在列<%s>", column)
+		utils.LogError("[MatchWherePartItem] " + errMsg)
+		return false, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+	}
+	fieldType := fieldInfo.FieldType
+	value = fieldType.TrimRaw(value)
+
+	if path != "" {
+		jsonFieldType, ok := fieldType.(jsonType)
+		if !ok {
+			errMsg := fmt.Sprintf("列<%s>不是json类型，不能使用路径: %s", column, path)
+			utils.LogError("[MatchWherePartItem] " + errMsg)
+			return false, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+		}
+		isNull, err := jsonFieldType.IsNull(value)
+		if err != nil {
+			return false, err
+		}
+		if isNull {
+			return item.Operate == base.DataComparatorIsNull, nil
+		}
+		return jsonFieldType.MatchPath(value, path, item.Operate, item.Args)
+	}
+	return MatchMetaType(fieldType, value, item.Operate, item.Args, cache)
+}
+
+// MatchMetaType 使用列类型的比较方法判断值是否满足条件
+func MatchMetaType(fieldType MetaType, value []byte, operate base.DataComparator, args [][]byte, cache RegexpCache) (bool, base.StandardError) {
+	var (
+		result bool
+		err    base.StandardError
+	)
+	switch operate {
+	case base.DataComparatorGreater:
+		result, err = fieldType.Greater(value, args[0])
+	case base.DataComparatorGreaterAndEqual:
+		result, err = fieldType.Less(value, args[0])
+		result = !result
+	case base.DataComparatorEqual:
+		result, err = fieldType.Equal(value, args[0])
+	case base.DataComparatorNotEqual:
+		result, err = fieldType.Equal(value, args[0])
+		result = !result
+	case base.DataComparatorLess:
+		result, err = fieldType.Less(value, args[0])
+	case base.DataComparatorLessAndEqual:
+		result, err = fieldType.Greater(value, args[0])
+		result = !result
+	case base.DataComparatorIn, base.DataComparatorNotIn:
+		for _, arg := range args {
+			result, err = fieldType.Equal(value, arg)
+			if err != nil || result {
+				break
+			}
+		}
+		if operate == base.DataComparatorNotIn {
+			result = !result
+		}
+	case base.DataComparatorBetween:
+		var less, greater bool
+		less, err = fieldType.Less(value, args[0])
+		if err == nil && !less {
+			greater, err = fieldType.Greater(value, args[1])
+			result = !greater
+		}
+	case base.DataComparatorLike, base.DataComparatorILike:
+		pattern := args[0]
+		if len(args) == base.DataComparatorArgsCountLikeWithEscape {
+			pattern, err = NormalizeLikePattern(args[0], args[1])
+			if err != nil {
+				break
+			}
+		}
+		if operate == base.DataComparatorLike {
+			result, err = fieldType.Like(pattern, value)
+		} else {
+			result, err = fieldType.ILike(pattern, value)
+		}
+	case base.DataComparatorRegexp, base.DataComparatorNotRegexp:
+		pattern, er := cache.Compile(args[0])
+		if er != nil {
+			err = er
+			break
+		}
+		result, err = fieldType.Regexp(pattern, value)
+		if err != nil {
+			break
+		}
+		// Null 和 SQL 一样，regexp 和 not_regexp 都不满足
+		var isNull bool
+		isNull, err = fieldType.IsNull(fieldType.TrimRaw(value))
+		if isNull {
+			result = false
+		} else if operate == base.DataComparatorNotRegexp {
+			result = !result
+		}
+	case base.DataComparatorIsNull:
+		result, err = fieldType.IsNull(value)
+	case base.DataComparatorIsNotNull:
+		result, err = fieldType.IsNull(value)
+		result = !result
+	default:
+		errMsg := fmt.Sprintf("类型<%s>不支持比较符: %s", fieldType.GetType(), operate)
+		utils.LogError("[MatchMetaType] " + errMsg)
+		return false, base.NewDBError(base.FunctionModelCoreTableSchema, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+	}
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreTableSchema))(fmt.Sprintf("[MatchMetaType] 比较出错, %s", err.Error()))
+		return false, err
+	}
+	return result, nil
+}
@@ -0,0 +1,82 @@
+package tableschema
+
+import (
+	"testing"
+
+	"ne_database/core/base"
+)
+
+func TestTableMetaInfo_MatchWherePartItem(t *testing.T) {
+	tableInfo := &TableMetaInfo{
+		Name: "product",
+		PrimaryKeyFieldInfo: &FieldInfo{
+			Name:      "id",
+			Length:    8,
+			FieldType: BigIntType,
+		},
+		ValueFieldInfo: []*FieldInfo{
+			{
+				Name:      "name",
+				Length:    20,
+				FieldType: CharType,
+			},
+			{
+				Name:      "attrs",
+				Length:    100,
+				FieldType: JSONType,
+			},
+		},
+		PageSize:    1000,
+		StorageType: base.StorageTypeMemory,
+	}
+	id, _ := base.Int64ToByteList(1)
+	attrs, _ := JSONType.StringToByte("{\"color\":\"red\",\"size\":10,\"tags\":[\"new\",\"hot\"],\"dim\":{\"w\":3}}")
+	row := map[string][]byte{
+		"id":    id,
+		"name":  []byte("shirt"),
+		"attrs": attrs,
+	}
+
+	testCases := []struct {
+		item   *base.WherePartItem
+		expect bool
+	}{
+		{&base.WherePartItem{TargetColumn: "id", Operate: base.DataComparatorEqual, Args: [][]byte{id}}, true},
+		{&base.WherePartItem{TargetColumn: "name", Operate: base.DataComparatorNotEqual, Args: [][]byte{[]byte("shirt")}}, false},
+		{&base.WherePartItem{TargetColumn: "attrs.color", Operate: base.DataComparatorEqual, Args: [][]byte{[]byte("red")}}, true},
+		{&base.WherePartItem{TargetColumn: "attrs.color", Operate: base.DataComparatorEqual, Args: [][]byte{[]byte("\"blue\"")}}, false},
+		{&base.WherePartItem{TargetColumn: "attrs.size", Operate: base.DataComparatorGreater, Args: [][]byte{[]byte("9")}}, true},
+		{&base.WherePartItem{TargetColumn: "attrs.size", Operate: base.DataComparatorLessAndEqual, Args: [][]byte{[]byte("9.5")}}, false},
+		{&base.WherePartItem{TargetColumn: "attrs.size", Operate: base.DataComparatorBetween, Args: [][]byte{[]byte("10"), []byte("20")}}, true},
+		{&base.WherePartItem{TargetColumn: "attrs.size", Operate: base.DataComparatorIn, Args: [][]byte{[]byte("1"), []byte("10")}}, true},
+		{&base.WherePartItem{TargetColumn: "attrs.tags", Operate: base.DataComparatorContains, Args: [][]byte{[]byte("hot")}}, true},
+		{&base.WherePartItem{TargetColumn: "attrs.tags.0", Operate: base.DataComparatorEqual, Args: [][]byte{[]byte("new")}}, true},
+		{&base.WherePartItem{TargetColumn: "attrs.dim", Operate: base.DataComparatorContains, Args: [][]byte{[]byte("{\"w\":3}")}}, true},
+		{&base.WherePartItem{TargetColumn: "attrs.weight", Operate: base.DataComparatorIsNull, Args: [][]byte{}}, true},
+		{&base.WherePartItem{TargetColumn: "attrs.weight", Operate: base.DataComparatorEqual, Args: [][]byte{[]byte("1")}}, false},
+	}
+	for i, c := range testCases {
+		match, err := tableInfo.MatchWherePartItem(c.item, row, nil)
+		if err != nil {
+			t.Errorf("case %d unexpected error: %v", i, err)
+			return
+		}
+		if match != c.expect {
+			t.Errorf("case %d expect %v, but got %v", i, c.expect, match)
+			return
+		}
+	}
+
+	// 非 json 列不能使用路径
+	_, err := tableInfo.MatchWherePartItem(&base.WherePartItem{TargetColumn: "name.first", Operate: base.DataComparatorEqual, Args: [][]byte{[]byte("a")}}, row, nil)
+	if err == nil {
+		t.Error("expected error, but got nil")
+		return
+	}
+	// 不存在的列
+	_, err = tableInfo.MatchWherePartItem(&base.WherePartItem{TargetColumn: "price", Operate: base.DataComparatorEqual, Args: [][]byte{[]byte("a")}}, row, nil)
+	if err == nil {
+		t.Error("expected error, but got nil")
+		return
+	}
+}
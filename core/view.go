@@ -0,0 +1,416 @@
+package core
+
+import (
+	"fmt"
+	"os"
+
+	"ne_database/core/base"
+	"ne_database/core/tableschema"
+	"ne_database/utils"
+	"ne_database/utils/set"
+)
+
+func viewError(errMsg string) base.StandardError {
+	utils.LogError("[View] " + errMsg)
+	return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+}
+
+func getViewFilePath(viewName string) string {
+	return getTableFilePath(viewName, base.DataIOFileViewSuffix)
+}
+
+// CheckViewExist 判断视图（包括物化视图）是否存在
+func (e *Engine) CheckViewExist(viewName string) (bool, base.StandardError) {
+	return e.viewExist(e.qualifiedName(viewName))
+}
+
+// viewExist 判断视图是否存在，viewName 为完整的视图名
+func (e *Engine) viewExist(viewName string) (bool, base.StandardError) {
+	exist, er := utils.FileExist(getViewFilePath(viewName))
+	if er != nil {
+		errMsg := fmt.Sprintf("检查视图文件是否存在报错: %s", er.Error())
+		utils.LogError("[Engine CheckViewExist] " + errMsg)
+		return false, base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeIO, base.ErrorBaseCodeIOError, fmt.Errorf(errMsg))
+	}
+	return exist, nil
+}
+
+// LoadViewInfo 读取视图信息
+func (e *Engine) LoadViewInfo(viewName string) (*tableschema.ViewInfo, base.StandardError) {
+	return e.loadViewInfo(e.qualifiedName(viewName))
+}
+
+// loadViewInfo 读取视图信息，viewName 为完整的视图名
+func (e *Engine) loadViewInfo(viewName string) (*tableschema.ViewInfo, base.StandardError) {
+	data, er := os.ReadFile(getViewFilePath(viewName))
+	if er != nil {
+		errMsg := fmt.Sprintf("读取视图<%s>时发生错误: %s", viewName, er.Error())
+		utils.LogError("[Engine LoadViewInfo] " + errMsg)
+		return nil, base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeIO, base.ErrorBaseCodeIOError, fmt.Errorf(errMsg))
+	}
+	return tableschema.InitViewInfoByJson(string(data))
+}
+
+// lookupView 读取视图，视图不存在时第二个返回值为 false，viewName 为完整的视图名
+func (e *Engine) lookupView(viewName string) (*tableschema.ViewInfo, bool, base.StandardError) {
+	exist, err := e.viewExist(viewName)
+	if err != nil || !exist {
+		return nil, false, err
+	}
+	info, err := e.loadViewInfo(viewName)
+	if err != nil {
+		return nil, false, err
+	}
+	return info, true, nil
+}
+
+// saveViewInfo 持久化视图信息并写入系统目录
+func (e *Engine) saveViewInfo(info *tableschema.ViewInfo) base.StandardError {
+	data, err := info.ViewInfoToJsonByte()
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[saveViewInfo] ViewInfoToJsonByte错误, %s", err.Error()))
+		return err
+	}
+	er := utils.WriteFileAtomic(getViewFilePath(info.Name), data, base.DataIOFileTempSuffix)
+	if er != nil {
+		errMsg := fmt.Sprintf("写入视图<%s>时发生错误: %s", info.Name, er.Error())
+		utils.LogError("[Engine saveViewInfo] " + errMsg)
+		return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeIO, base.ErrorBaseCodeIOError, fmt.Errorf(errMsg))
+	}
+	return e.catalogSetView(info.Name, data)
+}
+
+// checkNotView 视图不能直接修改，物化视图的表只能通过 RefreshMaterializedView 修改，tableName 为完整的名称
+func (e *Engine) checkNotView(tableName string) base.StandardError {
+	exist, err := e.viewExist(tableName)
+	if err != nil || !exist {
+		return err
+	}
+	return viewError(fmt.Sprintf("<%s>是视图，不能直接修改", tableName))
+}
+
+// runView 执行视图的查询；视图中不能使用外层查询的 CTE，视图之间循环引用时报错
+func (e *Engine) runView(scope *queryScope, info *tableschema.ViewInfo) (*relation, base.StandardError) {
+	expanding := scope.expandingViews()
+	for _, name := range expanding {
+		if name == info.Name {
+			return nil, viewError(fmt.Sprintf("视图<%s>循环引用", info.Name))
+		}
+	}
+	inner := &queryScope{relations: make(map[string]*relation), views: append(append([]string{}, expanding...), info.Name)}
+	r, err := e.runQuery(inner, info.Query)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[runView] 执行视图<%s>的查询错误, %s", info.Name, err.Error()))
+		return nil, err
+	}
+	if len(info.Columns) == 0 {
+		return r, nil
+	}
+	// 结果中 char 类型的列的长度和数据有关，只比较列名和类型
+	fields := r.fields()
+	same := len(fields) == len(info.Columns)
+	for i := 0; same && i < len(fields); i++ {
+		same = fields[i].Name == info.Columns[i].Name && fields[i].FieldType.GetType() == info.Columns[i].FieldType.GetType()
+	}
+	if !same {
+		return nil, viewError(fmt.Sprintf("视图<%s>的查询结果的列和创建时不同，需要重新创建视图", info.Name))
+	}
+	return r, nil
+}
+
+// viewSchema 普通视图的列，使用创建时保存的列，不执行视图的查询；没有保存列的视图执行一次查询
+func (e *Engine) viewSchema(info *tableschema.ViewInfo) (*tableschema.TableMetaInfo, base.StandardError) {
+	if len(info.Columns) > 0 {
+		return relationInfo(info.Name, info.Columns), nil
+	}
+	r, err := e.runView(nil, info)
+	if err != nil {
+		return nil, err
+	}
+	return r.tableInfo, nil
+}
+
+// copyColumns 复制查询结果的列，只保留列名、长度、类型和排序规则
+func copyColumns(fields []*tableschema.FieldInfo) []*tableschema.FieldInfo {
+	columns := make([]*tableschema.FieldInfo, 0, len(fields))
+	for _, field := range fields {
+		columns = append(columns, &tableschema.FieldInfo{Name: field.Name, Length: field.Length, FieldType: field.FieldType, Collation: field.Collation})
+	}
+	return columns
+}
+
+// viewDependencies 返回 names 以及其中的视图直接或者间接用到的表和视图，都是完整的名称
+func (e *Engine) viewDependencies(names []string) ([]string, base.StandardError) {
+	seen := set.NewStringsSet()
+	r := make([]string, 0, len(names))
+	for pending := names; len(pending) > 0; {
+		name := pending[0]
+		pending = pending[1:]
+		if seen.Contain(name) {
+			continue
+		}
+		seen.Add(name)
+		r = append(r, name)
+		info, ok, err := e.lookupView(name)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		w := &stmtWalker{}
+		w.walkQuery(info.Query, nil)
+		for _, source := range w.sources {
+			pending = append(pending, source.From)
+		}
+	}
+	return r, nil
+}
+
+// materializedTable 按照查询结果生成物化视图的表结构和每一行的主键，第一列为主键，重复时报错
+func materializedTable(viewName string, r *relation) (*tableschema.TableMetaInfo, [][]byte, base.StandardError) {
+	columns := copyColumns(r.fields())
+	pkInfo := columns[0]
+	keys := make([][]byte, 0, len(r.rows))
+	seen := set.NewStringsSet()
+	for _, row := range r.rows {
+		key, err := pkInfo.FieldType.LengthPadding(row[pkInfo.Name], pkInfo.Length)
+		if err != nil {
+			return nil, nil, err
+		}
+		if seen.Contain(string(key)) {
+			return nil, nil, viewError(fmt.Sprintf("物化视图<%s>的第一列<%s>作为主键，值重复: %s", viewName, pkInfo.Name, pkInfo.FieldType.StringValue(key)))
+		}
+		seen.Add(string(key))
+		keys = append(keys, key)
+	}
+	return &tableschema.TableMetaInfo{Name: viewName, PrimaryKeyFieldInfo: pkInfo, ValueFieldInfo: columns[1:]}, keys, nil
+}
+
+// materializedScanner 按照 keys 和 rows 逐行提供物化视图的数据，rows 中的列名和表的列名相同
+func materializedScanner(tableInfo *tableschema.TableMetaInfo, keys [][]byte, rows []map[string][]byte) (map[string]string, tableRowScanner) {
+	source := make(map[string]string, len(tableInfo.ValueFieldInfo))
+	for _, valueInfo := range tableInfo.ValueFieldInfo {
+		source[valueInfo.Name] = valueInfo.Name
+	}
+	return source, func(fn func(key []byte, values map[string][]byte) (bool, base.StandardError)) base.StandardError {
+		for i, key := range keys {
+			next, err := fn(key, rows[i])
+			if err != nil || !next {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// rewriteMaterializedView 按照 tableInfo 重写物化视图的表，表不存在时创建
+// 数据逐行写入临时数据文件中新的B+树，之后写入替换记录，再替换数据文件、写入表结构，崩溃时数据和表结构不会不一致
+func (e *Engine) rewriteMaterializedView(tableInfo *tableschema.TableMetaInfo, keys [][]byte, rows []map[string][]byte) base.StandardError {
+	source, scan := materializedScanner(tableInfo, keys, rows)
+	tempPath, err := e.writeTableDataFile(tableInfo, source, scan)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[rewriteMaterializedView] writeTableDataFile错误, %s", err.Error()))
+		return err
+	}
+	err = e.swapTableData(tableInfo, tempPath, "")
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[rewriteMaterializedView] swapTableData错误, %s", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// refreshConcurrently 按照物化视图原来的表结构把新的数据写入临时数据文件，再通过一次重命名替换原来的数据文件
+// 表结构不变，新的值按照原来的长度补齐，超过长度时报错并且原来的数据不变；
+// 替换之前已经打开的读取继续读取原来的数据，之后的读取读取新的数据，不会读取到一部分刷新的结果
+func (e *Engine) refreshConcurrently(tableInfo *tableschema.TableMetaInfo, rows []map[string][]byte) base.StandardError {
+	pkInfo := tableInfo.PrimaryKeyFieldInfo
+	keys := make([][]byte, len(rows))
+	for i, row := range rows {
+		var err base.StandardError
+		keys[i], err = pkInfo.FieldType.LengthPadding(row[pkInfo.Name], pkInfo.Length)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[refreshConcurrently] 主键长度错误, %s", err.Error()))
+			return err
+		}
+	}
+	source, scan := materializedScanner(tableInfo, keys, rows)
+	err := e.rebuildTableData(tableInfo, source, scan)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[refreshConcurrently] rebuildTableData错误, %s", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// removeView 删除视图文件和系统目录中的视图，物化视图同时删除表（表不存在时不做处理）
+func (e *Engine) removeView(info *tableschema.ViewInfo) base.StandardError {
+	if info.Materialized {
+		err := e.deleteTable(info.Name, false)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[removeView] 删除物化视图的表错误, %s", err.Error()))
+			return err
+		}
+	}
+	er := os.Remove(getViewFilePath(info.Name))
+	if er != nil && !os.IsNotExist(er) {
+		errMsg := fmt.Sprintf("删除视图<%s>发生错误: %s", info.Name, er.Error())
+		utils.LogError("[Engine removeView] " + errMsg)
+		return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeIO, base.ErrorBaseCodeIOError, fmt.Errorf(errMsg))
+	}
+	return e.catalogSetView(info.Name, nil)
+}
+
+// CreateView 创建视图，视图名没有数据库名时建在当前数据库中，查询中没有数据库名的表名在当前数据库中解析
+// 创建时执行一次查询作为校验；普通视图保存查询和结果的列，物化视图按照查询结果建表（第一列为主键）并写入数据
+// 先写入视图，物化视图的表在视图之后出现，不会被当作普通的表修改；出错时删除已经写入的视图和表
+func (e *Engine) CreateView(info *tableschema.ViewInfo) base.StandardError {
+	if info == nil {
+		errMsg := "输入的viewInfo为空"
+		utils.LogError("[Engine CreateView] " + errMsg)
+		return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+	}
+	info.Name = e.qualifiedName(info.Name)
+	err := info.Verification()
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[CreateView] 视图校验错误, %s", err.Error()))
+		return err
+	}
+	_, err = e.nameDatabaseInfo(info.Name)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[CreateView] nameDatabaseInfo错误, %s", err.Error()))
+		return err
+	}
+	// 保存的查询不依赖创建时的当前数据库
+	info.Query, err = cloneQuery(info.Query)
+	if err != nil {
+		return err
+	}
+	w := &stmtWalker{}
+	w.walkQuery(info.Query, nil)
+	for _, source := range w.sources {
+		source.From = e.qualifiedName(source.From)
+	}
+
+	e.viewLock.Lock()
+	defer e.viewLock.Unlock()
+	exist, err := e.viewExist(info.Name)
+	if err != nil {
+		return err
+	}
+	if exist {
+		return viewError(fmt.Sprintf("视图: %s 已存在", info.Name))
+	}
+	exist, err = e.CheckTableExist(info.Name)
+	if err != nil {
+		return err
+	}
+	if exist {
+		return viewError(fmt.Sprintf("表: %s 已存在", info.Name))
+	}
+	info.Columns = nil
+	r, err := e.runView(nil, info)
+	if err != nil {
+		return err
+	}
+	var (
+		tableInfo *tableschema.TableMetaInfo
+		keys      [][]byte
+	)
+	if info.Materialized {
+		tableInfo, keys, err = materializedTable(info.Name, r)
+		if err != nil {
+			return err
+		}
+		err = e.fillTableDefault(tableInfo)
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[CreateView] fillTableDefault错误, %s", err.Error()))
+			return err
+		}
+		tableInfo.Version = base.TableSchemaVersionInitial
+		err = tableInfo.Verification()
+		if err != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[CreateView] 物化视图的表校验错误, %s", err.Error()))
+			return err
+		}
+	} else {
+		info.Columns = copyColumns(r.fields())
+	}
+
+	err = e.saveViewInfo(info)
+	if err == nil && info.Materialized {
+		err = e.rewriteMaterializedView(tableInfo, keys, r.rows)
+	}
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[CreateView] 写入视图<%s>错误, %s", info.Name, err.Error()))
+		if er := e.removeView(info); er != nil {
+			utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[CreateView] 删除写入一半的视图<%s>错误, %s", info.Name, er.Error()))
+		}
+		return err
+	}
+	return nil
+}
+
+// DropView 删除视图，物化视图同时删除对应的表；视图不存在时不做处理
+// 不检查其他视图是否使用了该视图，使用的视图在查询时报错
+func (e *Engine) DropView(viewName string) base.StandardError {
+	viewName = e.qualifiedName(viewName)
+	e.viewLock.Lock()
+	defer e.viewLock.Unlock()
+	info, exist, err := e.lookupView(viewName)
+	if err != nil || !exist {
+		return err
+	}
+	return e.removeView(info)
+}
+
+// RefreshMaterializedView 按照视图的查询重新生成物化视图的数据，两种方式都会删除表的统计信息，都不会留下一部分刷新的结果
+// concurrently 为 false 时按照查询结果重建表（列的长度可以变化），通过替换记录替换数据文件和表结构，替换的过程中读取物化视图可能报错；
+// 为 true 时要求列名和类型不变并且值不超过原来的长度，表结构不变，只替换数据文件，刷新时可以正常读取
+func (e *Engine) RefreshMaterializedView(viewName string, concurrently bool) base.StandardError {
+	viewName = e.qualifiedName(viewName)
+	e.viewLock.Lock()
+	defer e.viewLock.Unlock()
+	info, exist, err := e.lookupView(viewName)
+	if err != nil {
+		return err
+	}
+	if !exist || !info.Materialized {
+		return viewError(fmt.Sprintf("物化视图: %s 不存在", viewName))
+	}
+	r, err := e.runView(nil, info)
+	if err != nil {
+		return err
+	}
+	newInfo, keys, err := materializedTable(viewName, r)
+	if err != nil {
+		return err
+	}
+	oldInfo, err := e.loadTableSchemaInfo(viewName)
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[RefreshMaterializedView] loadTableSchemaInfo错误, %s", err.Error()))
+		return err
+	}
+
+	if concurrently {
+		oldFields := append([]*tableschema.FieldInfo{oldInfo.PrimaryKeyFieldInfo}, oldInfo.ValueFieldInfo...)
+		newFields := append([]*tableschema.FieldInfo{newInfo.PrimaryKeyFieldInfo}, newInfo.ValueFieldInfo...)
+		same := len(oldFields) == len(newFields)
+		for i := 0; same && i < len(oldFields); i++ {
+			same = oldFields[i].Name == newFields[i].Name && oldFields[i].FieldType.GetType() == newFields[i].FieldType.GetType()
+		}
+		if !same {
+			return viewError(fmt.Sprintf("物化视图<%s>的列发生变化，不能 CONCURRENTLY 刷新", viewName))
+		}
+		err = e.refreshConcurrently(oldInfo, r.rows)
+	} else {
+		newInfo.PageSize, newInfo.StorageType, newInfo.Version = oldInfo.PageSize, oldInfo.StorageType, base.TableSchemaVersionInitial
+		err = e.rewriteMaterializedView(newInfo, keys, r.rows)
+	}
+	if err != nil {
+		utils.LogDev(string(base.FunctionModelCoreEngine))(fmt.Sprintf("[RefreshMaterializedView] 写入物化视图<%s>的数据错误, %s", viewName, err.Error()))
+		return err
+	}
+	return e.catalogSetStatistics(viewName, nil)
+}
@@ -0,0 +1,290 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"ne_database/core/base"
+	"ne_database/core/tableschema"
+	"ne_database/utils"
+)
+
+// newTestViewEngine id 为 1 ~ 10，name 为 n0 ~ n4，score 为 id*10，返回的视图依次为:
+// score 大于 50 的 id 和 name、基于前一个视图 name 为 n1 的行、id 不大于 5 的 id 和 score 的物化视图
+func newTestViewEngine(t *testing.T) (*Engine, *tableschema.TableMetaInfo, []string) {
+	t.Helper()
+	items := newTestTableInfo("engine_view_items", testCharField("name", 8), testBigIntField("score"))
+	e := newTestEngine(t, items)
+	for i := int64(1); i <= 10; i++ {
+		insertTestRows(t, e, items.Name, map[string][]byte{"id": testInt64(i), "name": []byte(fmt.Sprintf("n%d", i%5)), "score": testInt64(i * 10)})
+	}
+	views := []*tableschema.ViewInfo{
+		{Name: "engine_view_high", Query: &base.Query{From: items.Name,
+			Where: []*base.WherePartItem{{TargetColumn: "score", Operate: base.DataComparatorGreater, Args: [][]byte{testInt64(50)}}}, Columns: []string{"id", "name"}}},
+		{Name: "engine_view_high_n1", Query: &base.Query{From: "engine_view_high", Where: testWhereEqual("name", []byte("n1"))}},
+		{Name: "engine_view_scores", Materialized: true, Query: &base.Query{From: items.Name,
+			Where: []*base.WherePartItem{{TargetColumn: "id", Operate: base.DataComparatorLessAndEqual, Args: [][]byte{testInt64(5)}}}, Columns: []string{"id", "score"}}},
+	}
+	names := make([]string, 0, len(views))
+	for _, info := range views {
+		if err := e.CreateView(info); err != nil {
+			t.Fatalf("create view %s: unexpected error: %v", info.Name, err)
+		}
+		name := info.Name
+		names = append(names, name)
+		t.Cleanup(func() {
+			_ = e.DropView(name)
+		})
+	}
+	return e, items, names
+}
+
+// testMaterializedRows 物化视图中的 id:score，以逗号连接
+func testMaterializedRows(e *Engine, viewName string) string {
+	_, rows, err := e.Select(viewName, nil)
+	if err != nil {
+		return err.Error()
+	}
+	r := make([]string, 0, len(rows))
+	for _, row := range rows {
+		r = append(r, fmt.Sprintf("%s:%s", tableschema.BigIntType.StringValue(row["id"]), tableschema.BigIntType.StringValue(row["score"])))
+	}
+	return strings.Join(r, ",")
+}
+
+func TestEngine_View(t *testing.T) {
+	e, items, views := newTestViewEngine(t)
+	insertTestRows(t, e, items.Name, map[string][]byte{"id": testInt64(11), "name": []byte("n1"), "score": testInt64(110)})
+	orderByID := []*base.OrderByItem{{Column: "id"}}
+	// 视图在查询时展开，可以基于其他视图
+	testCases := []struct {
+		query  *base.Query
+		expect string
+	}{
+		{&base.Query{From: views[0], Where: []*base.WherePartItem{{TargetColumn: "id", Operate: base.DataComparatorLess, Args: [][]byte{testInt64(9)}}}, OrderBy: orderByID}, "6,7,8"},
+		{&base.Query{From: views[1], OrderBy: orderByID}, "6,11"},
+	}
+	for i, c := range testCases {
+		_, rows, err := e.Query(c.query)
+		if err != nil {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+			continue
+		}
+		if r := testInt64Values(rows, "id"); r != c.expect || len(rows[0]) != 2 {
+			t.Errorf("case %d: expected %s with 2 columns, but got %s, %v", i, c.expect, r, rows)
+		}
+	}
+}
+
+func TestEngine_View_Prepare(t *testing.T) {
+	e, _, views := newTestViewEngine(t)
+	// 普通视图保存创建时的列，预处理语句确定参数类型时不执行视图的查询
+	viewInfo, err := e.LoadViewInfo(views[0])
+	if err != nil || len(viewInfo.Columns) != 2 || viewInfo.Columns[1].Name != "name" || viewInfo.Columns[1].FieldType.GetType() != base.DBDataTypeChar {
+		t.Fatalf("unexpected view columns: %v, %v", viewInfo, err)
+	}
+	stmt, err := e.Prepare(&base.Query{From: views[0], Where: []*base.WherePartItem{testWhereParameter("name", base.DataComparatorEqual, "?")}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, rows, err := stmt.Execute("n2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r := testInt64Values(rows, "id"); r != "7" {
+		t.Errorf("expected 7, but got %s", r)
+	}
+}
+
+func TestEngine_RefreshMaterializedView(t *testing.T) {
+	e, items, views := newTestViewEngine(t)
+	// 物化视图的数据在刷新时更新
+	testCases := []struct {
+		change       func() base.StandardError
+		refresh      bool
+		concurrently bool
+		expect       string
+	}{
+		{func() base.StandardError { return nil }, false, false, "1:10,2:20,3:30,4:40,5:50"},
+		{func() base.StandardError {
+			if _, err := e.Update(items.Name, testWhereEqual("id", testInt64(1)), map[string][]byte{"score": testInt64(15)}); err != nil {
+				return err
+			}
+			_, err := e.Delete(items.Name, testWhereEqual("id", testInt64(2)))
+			return err
+		}, false, false, "1:10,2:20,3:30,4:40,5:50"},
+		{func() base.StandardError { return nil }, true, true, "1:15,3:30,4:40,5:50"},
+		{func() base.StandardError {
+			_, _, err := e.Insert(items.Name, map[string][]byte{"id": testInt64(2), "name": []byte("n2"), "score": testInt64(20)})
+			return err
+		}, true, false, "1:15,2:20,3:30,4:40,5:50"},
+	}
+	for i, c := range testCases {
+		if err := c.change(); err != nil {
+			t.Fatalf("case %d: unexpected error: %v", i, err)
+		}
+		if c.refresh {
+			if err := e.RefreshMaterializedView(views[2], c.concurrently); err != nil {
+				t.Fatalf("case %d: unexpected error: %v", i, err)
+			}
+		}
+		if r := testMaterializedRows(e, views[2]); r != c.expect {
+			t.Errorf("case %d: expected %s, but got %s", i, c.expect, r)
+		}
+	}
+}
+
+func TestEngine_RefreshMaterializedView_OpenedReader(t *testing.T) {
+	e, items, views := newTestViewEngine(t)
+	// CONCURRENTLY 刷新一次替换数据文件: 已经打开的读取继续读取原来的数据，之后的读取读取新的数据
+	reader, err := e.openTable(e.qualifiedName(views[2]))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer reader.DataManager.Close()
+	before, _, err := reader.Search(nil)
+	if err != nil || len(before) != 5 {
+		t.Fatalf("unexpected rows before refresh: %d, %v", len(before), err)
+	}
+	_, err = e.Delete(items.Name, []*base.WherePartItem{{TargetColumn: "id", Operate: base.DataComparatorLessAndEqual, Args: [][]byte{testInt64(2)}}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = e.RefreshMaterializedView(views[2], true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	after, values, err := reader.Search(nil)
+	if err != nil || len(after) != 5 {
+		t.Fatalf("expected opened reader to keep old rows, got %d, %v", len(after), err)
+	}
+	if v, _ := base.ByteListToInt64(values[0]["score"]); v != 10 {
+		t.Errorf("expected old score 10, got %d", v)
+	}
+	if r := testMaterializedRows(e, views[2]); r != "3:30,4:40,5:50" {
+		t.Errorf("unexpected rows after concurrent refresh: %s", r)
+	}
+}
+
+func TestEngine_RefreshMaterializedView_Recovery(t *testing.T) {
+	e, _, views := newTestViewEngine(t)
+	// 重建物化视图时写入替换记录之后崩溃，Init 继续完成替换；没有替换记录的临时数据文件不生效
+	viewTable, err := e.loadTableSchemaInfo(e.qualifiedName(views[2]))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	source, scan := materializedScanner(viewTable, [][]byte{testInt64(7)}, []map[string][]byte{{"score": testInt64(70)}})
+	tempPath, err := e.writeTableDataFile(viewTable, source, scan)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	viewTableJson, err := viewTable.TableMetaInfoToJsonByte()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	journal := &refreshJournal{Name: viewTable.Name, TableInfo: string(viewTableJson), TempPath: tempPath}
+	er := utils.WriteFileAtomic(getRefreshJournalFilePath(viewTable.Name), []byte(utils.ToJSON(journal)), base.DataIOFileTempSuffix)
+	if er != nil {
+		t.Fatalf("unexpected error: %v", er)
+	}
+	if r := testMaterializedRows(e, views[2]); r != "1:10,2:20,3:30,4:40,5:50" {
+		t.Errorf("rows changed before recovery: %s", r)
+	}
+	if err = e.Init(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r := testMaterializedRows(e, views[2]); r != "7:70" {
+		t.Errorf("expected refresh to be finished by Init, got %s", r)
+	}
+	if exist, _ := utils.FileExist(getRefreshJournalFilePath(viewTable.Name)); exist {
+		t.Error("expected refresh journal to be removed")
+	}
+	source, scan = materializedScanner(viewTable, [][]byte{testInt64(8)}, []map[string][]byte{{"score": testInt64(80)}})
+	if _, err = e.writeTableDataFile(viewTable, source, scan); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = e.Init(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r := testMaterializedRows(e, views[2]); r != "7:70" {
+		t.Errorf("expected temp data without journal to be ignored, got %s", r)
+	}
+	if err = e.RefreshMaterializedView(views[2], false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r := testMaterializedRows(e, views[2]); r != "1:10,2:20,3:30,4:40,5:50" {
+		t.Errorf("unexpected rows after refresh: %s", r)
+	}
+}
+
+func TestEngine_View_Catalog(t *testing.T) {
+	e, _, _ := newTestViewEngine(t)
+	// 视图记录在系统目录中
+	count, rows, err := e.Select(base.CatalogTableViews, []*base.WherePartItem{{TargetColumn: "table_name", Operate: base.DataComparatorLike, Args: [][]byte{[]byte("engine_view_%")}}})
+	if err != nil || count != 3 {
+		t.Fatalf("expected 3 views, got %d, %v", count, err)
+	}
+	if string(rows[2]["is_materialized"]) != "YES" || string(rows[0]["is_materialized"]) != "NO" {
+		t.Errorf("unexpected is_materialized: %s, %s", rows[0]["is_materialized"], rows[2]["is_materialized"])
+	}
+}
+
+func TestEngine_View_Error(t *testing.T) {
+	e, items, views := newTestViewEngine(t)
+	testCases := []func() base.StandardError{
+		// 视图只能通过视图的接口修改
+		func() base.StandardError {
+			_, _, err := e.Insert(views[2], map[string][]byte{"id": testInt64(20), "score": testInt64(1)})
+			return err
+		},
+		func() base.StandardError { return e.TruncateTable(views[2]) },
+		func() base.StandardError { return e.DropTableCascade(views[2]) },
+		func() base.StandardError {
+			return e.CreateTable(newTestTableInfo(views[0], testCharField("name", 8)))
+		},
+		func() base.StandardError {
+			return e.CreateView(&tableschema.ViewInfo{Name: items.Name, Query: &base.Query{From: views[0]}})
+		},
+		func() base.StandardError { return e.RefreshMaterializedView(views[0], false) },
+		// 表名不能改为已有的视图名
+		func() base.StandardError { return e.RenameTable(items.Name, views[0]) },
+		// 物化视图的第一列作为主键，不能重复
+		func() base.StandardError {
+			return e.CreateView(&tableschema.ViewInfo{Name: "engine_view_names", Materialized: true, Query: &base.Query{From: items.Name, Columns: []string{"name", "id"}}})
+		},
+		func() base.StandardError {
+			return e.CreateView(&tableschema.ViewInfo{Name: "engine_view_unknown", Query: &base.Query{From: "engine_view_unknown_table"}})
+		},
+	}
+	for i, c := range testCases {
+		if err := c(); err == nil {
+			t.Errorf("case %d: expected error, but got nil", i)
+		}
+	}
+	// 创建失败的视图不会留下视图或者表
+	for _, name := range []string{"engine_view_names", "engine_view_unknown"} {
+		viewExist, _ := e.CheckViewExist(name)
+		tableExist, _ := e.CheckTableExist(name)
+		if viewExist || tableExist {
+			t.Errorf("expected failed view %s to leave nothing, got view %v, table %v", name, viewExist, tableExist)
+		}
+	}
+}
+
+func TestEngine_DropView(t *testing.T) {
+	e, _, views := newTestViewEngine(t)
+	// 删除被使用的视图之后，使用它的视图在查询时报错；删除物化视图时同时删除表
+	if err := e.DropView(views[0]); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := e.Query(&base.Query{From: views[1]}); err == nil {
+		t.Error("expected error after dependent view dropped")
+	}
+	if err := e.DropView(views[2]); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	exist, err := e.CheckTableExist(views[2])
+	if err != nil || exist {
+		t.Errorf("expected materialized table to be dropped, got %v, %v", exist, err)
+	}
+}
@@ -0,0 +1,418 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"ne_database/core/base"
+	"ne_database/core/tableschema"
+	"ne_database/utils"
+	"ne_database/utils/set"
+)
+
+// windowSpec 校验后的窗口函数
+type windowSpec struct {
+	item            *base.WindowItem
+	argField        *tableschema.FieldInfo // 参数的列，没有参数时为 nil
+	partitionFields []*tableschema.FieldInfo
+	orderFields     []*tableschema.FieldInfo
+	resultField     *tableschema.FieldInfo
+	frame           *base.WindowFrame  // 只有 count / sum / avg / first_value / last_value 有
+	err             base.StandardError // 比较时的第一个错误，排序的比较函数不能返回错误，排序和计算之后检查
+}
+
+func windowError(errMsg string) base.StandardError {
+	utils.LogError("[window] " + errMsg)
+	return base.NewDBError(base.FunctionModelCoreEngine, base.ErrorTypeInput, base.ErrorBaseCodeParameterError, fmt.Errorf(errMsg))
+}
+
+// windowResultName 窗口函数结果的列名
+func windowResultName(item *base.WindowItem) string {
+	if item.Alias != "" {
+		return item.Alias
+	}
+	return fmt.Sprintf("%s(%s)", item.Function, item.Column)
+}
+
+// newWindowSpec 校验窗口函数，生成结果的列
+// row_number / rank / dense_rank / count / sum / avg 的结果为 bigint，avg 为截断后的整数；其他的结果和参数的列的类型相同
+func newWindowSpec(tableInfo *tableschema.TableMetaInfo, item *base.WindowItem) (*windowSpec, base.StandardError) {
+	if item == nil {
+		return nil, windowError("窗口函数为空")
+	}
+	name := windowResultName(item)
+	if _, ok := tableInfo.FieldInfoByName(name); ok {
+		return nil, windowError(fmt.Sprintf("窗口函数结果的列名<%s>和已有的列重复", name))
+	}
+	s := &windowSpec{item: item}
+	existColumn := set.NewStringsSet()
+	for _, column := range item.PartitionBy {
+		field, ok := tableInfo.FieldInfoByName(column)
+		if !ok {
+			return nil, windowError(fmt.Sprintf("分区的列<%s>不存在", column))
+		}
+		if existColumn.Contain(column) {
+			return nil, windowError(fmt.Sprintf("分区的列<%s>重复", column))
+		}
+		existColumn.Add(column)
+		s.partitionFields = append(s.partitionFields, field)
+	}
+	for _, order := range item.OrderBy {
+		if order == nil {
+			return nil, windowError("排序的列为空")
+		}
+		field, ok := tableInfo.FieldInfoByName(order.Column)
+		if !ok {
+			return nil, windowError(fmt.Sprintf("排序的列<%s>不存在", order.Column))
+		}
+		s.orderFields = append(s.orderFields, field)
+	}
+	if item.Column != "" {
+		field, ok := tableInfo.FieldInfoByName(item.Column)
+		if !ok {
+			return nil, windowError(fmt.Sprintf("窗口函数<%s>的列<%s>不存在", name, item.Column))
+		}
+		s.argField = field
+	}
+
+	s.resultField = &tableschema.FieldInfo{Name: name, Length: base.DataByteLengthInt64, FieldType: tableschema.BigIntType}
+	switch item.Function {
+	case base.WindowFunctionRowNumber, base.WindowFunctionRank, base.WindowFunctionDenseRank:
+		if s.argField != nil {
+			return nil, windowError(fmt.Sprintf("窗口函数<%s>没有参数", name))
+		}
+		return s, nil
+	case base.WindowFunctionLag, base.WindowFunctionLead:
+		if item.Offset < 0 {
+			return nil, windowError(fmt.Sprintf("窗口函数<%s>的偏移<%d>小于0", name, item.Offset))
+		}
+	case base.WindowFunctionCount, base.WindowFunctionFirstValue, base.WindowFunctionLastValue:
+	case base.WindowFunctionSum, base.WindowFunctionAvg:
+		if s.argField == nil || s.argField.FieldType.GetType() != base.DBDataTypeBigInt {
+			return nil, windowError(fmt.Sprintf("窗口函数<%s>只能用于bigint类型的列", name))
+		}
+	default:
+		return nil, windowError(fmt.Sprintf("不支持的窗口函数: %s", item.Function))
+	}
+	switch item.Function {
+	case base.WindowFunctionLag, base.WindowFunctionLead, base.WindowFunctionFirstValue, base.WindowFunctionLastValue:
+		if s.argField == nil {
+			return nil, windowError(fmt.Sprintf("窗口函数<%s>需要指定列", name))
+		}
+		f := *s.argField
+		f.Name = name
+		s.resultField = &f
+	}
+	if item.Function == base.WindowFunctionLag || item.Function == base.WindowFunctionLead {
+		return s, nil
+	}
+
+	frame, err := s.resolveFrame()
+	if err != nil {
+		return nil, err
+	}
+	s.frame = frame
+	return s, nil
+}
+
+// resolveFrame 校验窗口的范围，没有指定时使用默认的范围
+func (s *windowSpec) resolveFrame() (*base.WindowFrame, base.StandardError) {
+	frame := s.item.Frame
+	if frame == nil {
+		if len(s.orderFields) == 0 {
+			return &base.WindowFrame{Unit: base.WindowFrameUnitRows}, nil
+		}
+		currentRow := int64(0)
+		return &base.WindowFrame{Unit: base.WindowFrameUnitRange, End: &currentRow}, nil
+	}
+	if frame.Start != nil && frame.End != nil && *frame.Start > *frame.End {
+		return nil, windowError(fmt.Sprintf("窗口范围的开始<%d>大于结束<%d>", *frame.Start, *frame.End))
+	}
+	switch frame.Unit {
+	case base.WindowFrameUnitRows:
+	case base.WindowFrameUnitRange:
+		offset := (frame.Start != nil && *frame.Start != 0) || (frame.End != nil && *frame.End != 0)
+		if offset && (len(s.orderFields) != 1 || s.orderFields[0].FieldType.GetType() != base.DBDataTypeBigInt) {
+			return nil, windowError("range 的偏移不为0时只能有一个bigint类型的排序列")
+		}
+	default:
+		return nil, windowError(fmt.Sprintf("不支持的窗口范围: %s", frame.Unit))
+	}
+	return frame, nil
+}
+
+// compareField 比较两行中的一列，出错时记录第一个错误（见 windowSpec.err），这两个值视为相等
+func (s *windowSpec) compareField(field *tableschema.FieldInfo, a map[string][]byte, b map[string][]byte) int {
+	c, err := compareFieldValue(field.FieldType, a[field.Name], b[field.Name])
+	if err != nil && s.err == nil {
+		s.err = err
+	}
+	return c
+}
+
+func (s *windowSpec) compareOrder(a map[string][]byte, b map[string][]byte) int {
+	for i, field := range s.orderFields {
+		c := s.compareField(field, a, b)
+		if s.item.OrderBy[i].Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func (s *windowSpec) samePartition(a map[string][]byte, b map[string][]byte) bool {
+	for _, field := range s.partitionFields {
+		if s.compareField(field, a, b) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// windowRelation 计算窗口函数，结果的行保持输入的顺序
+// 全部的行在内存中按照 分区、排序 的顺序稳定排序，之后逐个分区计算
+func windowRelation(r *relation, item *base.WindowItem) (*relation, base.StandardError) {
+	s, err := newWindowSpec(r.tableInfo, item)
+	if err != nil {
+		return nil, err
+	}
+	order := make([]int, len(r.rows))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := r.rows[order[i]], r.rows[order[j]]
+		for _, field := range s.partitionFields {
+			if c := s.compareField(field, a, b); c != 0 {
+				return c < 0
+			}
+		}
+		return s.compareOrder(a, b) < 0
+	})
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	values := make([][]byte, len(r.rows))
+	for start := 0; start < len(order); {
+		end := start + 1
+		for end < len(order) && s.samePartition(r.rows[order[start]], r.rows[order[end]]) {
+			end++
+		}
+		partition := make([]map[string][]byte, 0, end-start)
+		for _, i := range order[start:end] {
+			partition = append(partition, r.rows[i])
+		}
+		result, err := s.evaluate(partition)
+		if err == nil {
+			err = s.err
+		}
+		if err != nil {
+			return nil, err
+		}
+		for k, i := range order[start:end] {
+			values[i] = result[k]
+		}
+		start = end
+	}
+
+	result := &relation{
+		tableInfo: relationInfo(r.tableInfo.Name, append(r.fields(), s.resultField)),
+		rows:      make([]map[string][]byte, 0, len(r.rows)),
+	}
+	for i, row := range r.rows {
+		// 输入的行可能被 CTE 共享，复制之后再加入结果的列
+		copied := make(map[string][]byte, len(row)+1)
+		for k, v := range row {
+			copied[k] = v
+		}
+		copied[s.resultField.Name] = values[i]
+		result.rows = append(result.rows, copied)
+	}
+	return result, nil
+}
+
+// evaluate 计算一个已经排好序的分区中每一行的结果
+func (s *windowSpec) evaluate(partition []map[string][]byte) ([][]byte, base.StandardError) {
+	n := len(partition)
+	r := make([][]byte, n)
+	null := s.resultField.FieldType.TrimRaw(s.resultField.NullValue())
+	switch s.item.Function {
+	case base.WindowFunctionRowNumber:
+		for k := range partition {
+			r[k] = tableschema.BigIntValue(int64(k + 1))
+		}
+		return r, nil
+	case base.WindowFunctionRank, base.WindowFunctionDenseRank:
+		rank, denseRank := int64(1), int64(1)
+		for k := range partition {
+			if k > 0 && s.compareOrder(partition[k-1], partition[k]) != 0 {
+				rank = int64(k + 1)
+				denseRank++
+			}
+			if s.item.Function == base.WindowFunctionRank {
+				r[k] = tableschema.BigIntValue(rank)
+			} else {
+				r[k] = tableschema.BigIntValue(denseRank)
+			}
+		}
+		return r, nil
+	case base.WindowFunctionLag, base.WindowFunctionLead:
+		offset := int(s.item.Offset)
+		if offset == 0 {
+			offset = 1
+		}
+		if s.item.Function == base.WindowFunctionLag {
+			offset = -offset
+		}
+		for k := range partition {
+			switch j := k + offset; {
+			case j >= 0 && j < n:
+				r[k] = partition[j][s.argField.Name]
+			case s.item.Default != nil:
+				r[k] = s.item.Default
+			default:
+				r[k] = null
+			}
+		}
+		return r, nil
+	}
+
+	// count / sum / avg 使用前缀和，prefixCount[k] / prefixSum[k] 为前 k 行中非 Null 的值的数量和总和
+	prefixCount := make([]int64, n+1)
+	prefixSum := make([]int64, n+1)
+	for k, row := range partition {
+		prefixCount[k+1], prefixSum[k+1] = prefixCount[k], prefixSum[k]
+		if s.argField == nil {
+			prefixCount[k+1]++
+			continue
+		}
+		isNull, err := s.argField.FieldType.IsNull(row[s.argField.Name])
+		if err != nil {
+			return nil, err
+		}
+		if isNull {
+			continue
+		}
+		prefixCount[k+1]++
+		if s.item.Function == base.WindowFunctionSum || s.item.Function == base.WindowFunctionAvg {
+			v, err := base.ByteListToInt64(row[s.argField.Name])
+			if err != nil {
+				return nil, err
+			}
+			sum := prefixSum[k]
+			if (v > 0 && sum > math.MaxInt64-v) || (v < 0 && sum < math.MinInt64-v) {
+				return nil, windowError(fmt.Sprintf("窗口函数<%s>的结果超出bigint的范围", s.resultField.Name))
+			}
+			prefixSum[k+1] = sum + v
+		}
+	}
+	bounds, err := s.frameBounds(partition)
+	if err != nil {
+		return nil, err
+	}
+	for k := range partition {
+		lo, hi := bounds[k][0], bounds[k][1]
+		r[k] = null
+		if lo > hi {
+			if s.item.Function == base.WindowFunctionCount {
+				r[k] = tableschema.BigIntValue(0)
+			}
+			continue
+		}
+		count := prefixCount[hi+1] - prefixCount[lo]
+		switch s.item.Function {
+		case base.WindowFunctionCount:
+			r[k] = tableschema.BigIntValue(count)
+		case base.WindowFunctionSum:
+			if count > 0 {
+				r[k] = tableschema.BigIntValue(prefixSum[hi+1] - prefixSum[lo])
+			}
+		case base.WindowFunctionAvg:
+			if count > 0 {
+				r[k] = tableschema.BigIntValue((prefixSum[hi+1] - prefixSum[lo]) / count)
+			}
+		case base.WindowFunctionFirstValue:
+			r[k] = partition[lo][s.argField.Name]
+		case base.WindowFunctionLastValue:
+			r[k] = partition[hi][s.argField.Name]
+		}
+	}
+	return r, nil
+}
+
+// frameBounds 每一行的窗口在分区中的范围 [lo, hi]，lo > hi 时窗口为空
+func (s *windowSpec) frameBounds(partition []map[string][]byte) ([][2]int, base.StandardError) {
+	n := len(partition)
+	r := make([][2]int, n)
+	if s.frame.Unit == base.WindowFrameUnitRows {
+		for k := range partition {
+			lo, hi := 0, n-1
+			if s.frame.Start != nil {
+				lo = int(math.Max(0, float64(int64(k)+*s.frame.Start)))
+			}
+			if s.frame.End != nil {
+				hi = int(math.Min(float64(n-1), float64(int64(k)+*s.frame.End)))
+			}
+			r[k] = [2]int{lo, hi}
+		}
+		return r, nil
+	}
+
+	// range: 偏移为 0 时为当前行的 peer；否则按照排序列的值计算，降序时把值取反后按照升序处理
+	var keys []int64
+	offset := (s.frame.Start != nil && *s.frame.Start != 0) || (s.frame.End != nil && *s.frame.End != 0)
+	if offset {
+		keys = make([]int64, n)
+		for k, row := range partition {
+			v, err := base.ByteListToInt64(row[s.orderFields[0].Name])
+			if err != nil {
+				return nil, err
+			}
+			if s.item.OrderBy[0].Desc {
+				v = -v
+			}
+			keys[k] = v
+		}
+	}
+	peerStart, peerEnd := make([]int, n), make([]int, n)
+	for k := range partition {
+		if k > 0 && s.compareOrder(partition[k-1], partition[k]) == 0 {
+			peerStart[k] = peerStart[k-1]
+		} else {
+			peerStart[k] = k
+		}
+	}
+	for k := n - 1; k >= 0; k-- {
+		if k < n-1 && s.compareOrder(partition[k], partition[k+1]) == 0 {
+			peerEnd[k] = peerEnd[k+1]
+		} else {
+			peerEnd[k] = k
+		}
+	}
+	for k := range partition {
+		lo, hi := 0, n-1
+		switch {
+		case s.frame.Start == nil:
+		case *s.frame.Start == 0:
+			lo = peerStart[k]
+		default:
+			low := keys[k] + *s.frame.Start
+			lo = sort.Search(n, func(j int) bool { return keys[j] >= low })
+		}
+		switch {
+		case s.frame.End == nil:
+		case *s.frame.End == 0:
+			hi = peerEnd[k]
+		default:
+			high := keys[k] + *s.frame.End
+			hi = sort.Search(n, func(j int) bool { return keys[j] > high }) - 1
+		}
+		r[k] = [2]int{lo, hi}
+	}
+	return r, nil
+}
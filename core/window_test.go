@@ -0,0 +1,96 @@
+package core
+
+import (
+	"testing"
+
+	"ne_database/core/base"
+	"ne_database/core/tableschema"
+)
+
+// newTestWindowEngine 每个用户的事件，amount 为 0 时是 Null
+func newTestWindowEngine(t *testing.T) (*Engine, *tableschema.TableMetaInfo) {
+	t.Helper()
+	events := newTestTableInfo("engine_window_events", testBigIntField("user_id"), testBigIntField("ts"), testBigIntField("amount"))
+	e := newTestEngine(t, events)
+	data := [][]int64{{1, 1, 10, 5}, {2, 1, 20, 10}, {3, 1, 100, 10}, {4, 2, 5, 7}, {5, 2, 15, 3}, {6, 2, 16, 0}, {7, 3, 50, 20}}
+	for _, d := range data {
+		row := map[string][]byte{"id": testInt64(d[0])}
+		for i, field := range events.ValueFieldInfo {
+			row[field.Name] = testInt64(d[i+1])
+		}
+		insertTestRows(t, e, events.Name, row)
+	}
+	return e, events
+}
+
+// testWindowOffset 窗口范围的偏移
+func testWindowOffset(v int64) *int64 {
+	return &v
+}
+
+func TestEngine_Window(t *testing.T) {
+	e, events := newTestWindowEngine(t)
+	byUser := []string{"user_id"}
+	byTs := []*base.OrderByItem{{Column: "ts"}}
+	byAmountDesc := []*base.OrderByItem{{Column: "amount", Desc: true}}
+	testCases := []struct {
+		window *base.WindowItem
+		expect string // 按照 id 排序后每一行的结果
+	}{
+		{&base.WindowItem{Function: base.WindowFunctionRowNumber, PartitionBy: byUser, OrderBy: byTs}, "1,2,3,1,2,3,1"},
+		{&base.WindowItem{Function: base.WindowFunctionRank, OrderBy: byAmountDesc}, "5,2,2,4,6,7,1"},
+		{&base.WindowItem{Function: base.WindowFunctionDenseRank, OrderBy: byAmountDesc}, "4,2,2,3,5,6,1"},
+		{&base.WindowItem{Function: base.WindowFunctionLag, Column: "ts", Default: testInt64(-1), PartitionBy: byUser, OrderBy: byTs}, "-1,10,20,-1,5,15,-1"},
+		{&base.WindowItem{Function: base.WindowFunctionLead, Column: "ts", Offset: 2, PartitionBy: byUser, OrderBy: byTs}, "100,0,0,16,0,0,0"},
+		// 默认的范围为 range 从 unbounded preceding 到 current row
+		{&base.WindowItem{Function: base.WindowFunctionSum, Column: "amount", PartitionBy: byUser, OrderBy: byTs}, "5,15,25,7,10,10,20"},
+		{&base.WindowItem{Function: base.WindowFunctionSum, Column: "amount", OrderBy: []*base.OrderByItem{{Column: "amount"}}}, "8,35,35,15,3,0,55"},
+		// 没有排序时为整个分区，Null 不参与计算
+		{&base.WindowItem{Function: base.WindowFunctionAvg, Column: "amount", PartitionBy: byUser}, "8,8,8,5,5,5,20"},
+		{&base.WindowItem{Function: base.WindowFunctionCount, Column: "amount", PartitionBy: byUser}, "3,3,3,2,2,2,1"},
+		{&base.WindowItem{Function: base.WindowFunctionCount, PartitionBy: byUser}, "3,3,3,3,3,3,1"},
+		{&base.WindowItem{Function: base.WindowFunctionFirstValue, Column: "ts", PartitionBy: byUser, OrderBy: []*base.OrderByItem{{Column: "ts", Desc: true}}}, "100,100,100,16,16,16,50"},
+		{&base.WindowItem{Function: base.WindowFunctionLastValue, Column: "ts", PartitionBy: byUser, OrderBy: byTs,
+			Frame: &base.WindowFrame{Unit: base.WindowFrameUnitRows, Start: testWindowOffset(-1), End: testWindowOffset(1)}}, "20,100,100,15,16,16,50"},
+		// 最近 10 个时间单位内的金额
+		{&base.WindowItem{Function: base.WindowFunctionSum, Column: "amount", PartitionBy: byUser, OrderBy: byTs,
+			Frame: &base.WindowFrame{Unit: base.WindowFrameUnitRange, Start: testWindowOffset(-10), End: testWindowOffset(0)}}, "5,15,10,7,10,3,20"},
+		{&base.WindowItem{Function: base.WindowFunctionSum, Column: "amount", PartitionBy: byUser, OrderBy: []*base.OrderByItem{{Column: "ts", Desc: true}},
+			Frame: &base.WindowFrame{Unit: base.WindowFrameUnitRange, Start: testWindowOffset(-10), End: testWindowOffset(0)}}, "15,10,10,10,3,0,20"},
+	}
+	for i, c := range testCases {
+		c.window.Alias = "result"
+		_, rows, err := e.Query(&base.Query{
+			From:    events.Name,
+			Windows: []*base.WindowItem{c.window},
+			Columns: []string{"id", "result"},
+			OrderBy: []*base.OrderByItem{{Column: "id"}},
+		})
+		if err != nil {
+			t.Errorf("case %d: unexpected error: %v", i, err)
+			continue
+		}
+		if r := testInt64Values(rows, "result"); r != c.expect {
+			t.Errorf("case %d %s: expected %s, but got %s", i, c.window.Function, c.expect, r)
+		}
+	}
+}
+
+func TestEngine_Window_Error(t *testing.T) {
+	e, events := newTestWindowEngine(t)
+	testCases := []*base.WindowItem{
+		{Function: "median", Column: "amount"},
+		// 别名和表中的列重名
+		{Function: base.WindowFunctionRowNumber, Alias: "ts"},
+		{Function: base.WindowFunctionLag, Column: "ts", Offset: -1},
+		// range 的偏移只能用于一个排序列
+		{Function: base.WindowFunctionSum, Column: "amount", OrderBy: []*base.OrderByItem{{Column: "ts"}, {Column: "id"}},
+			Frame: &base.WindowFrame{Unit: base.WindowFrameUnitRange, Start: testWindowOffset(-10)}},
+		{Function: base.WindowFunctionSum, Column: "amount", Frame: &base.WindowFrame{Unit: base.WindowFrameUnitRows, Start: testWindowOffset(1), End: testWindowOffset(-1)}},
+	}
+	for i, window := range testCases {
+		if _, _, err := e.Query(&base.Query{From: events.Name, Windows: []*base.WindowItem{window}}); err == nil {
+			t.Errorf("case %d: expected error, but got nil", i)
+		}
+	}
+}
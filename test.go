@@ -1,3 +1,7 @@
+// B+树 v2 的独立示例程序，使用 go run test.go 运行，不参与包的编译
+
+//go:build ignore
+
 package main
 
 import (
@@ -2073,10 +2077,9 @@ func (bt *BTree) Delete(key []byte) error {
 	if !rootNode.isLeaf && rootNode.numKeys == 0 {
 		// 根节点现在只有一个子节点（在 children[0]）
 		// 这个子节点成为新的根节点
-		oldRootID := bt.rootPageID
 		bt.rootPageID = rootNode.children[0]
 		bt.metaDirty = true
-		// fmt.Printf("树高度降低。旧根 %d 删除，新根 %d。\n", oldRootID, bt.rootPageID)
+		// fmt.Printf("树高度降低。旧根 %d 删除，新根 %d。\n", rootNode.pageID, bt.rootPageID)
 
 		// 保存元数据更新
 		if metaErr := bt.saveMetaInternal(); metaErr != nil {
@@ -2084,7 +2087,7 @@ func (bt *BTree) Delete(key []byte) error {
 			return fmt.Errorf("严重错误：降低树高度后保存元数据失败 (新根 %d): %w", bt.rootPageID, metaErr)
 		}
 
-		// TODO: 理想情况下，应该将旧的根页面 ID (oldRootID) 添加到空闲列表以供重用。
+		// TODO: 理想情况下，应该将旧的根页面 ID (rootNode.pageID) 添加到空闲列表以供重用。
 		// 在这个实现中，我们暂时只是“遗弃”它。
 	}
 
@@ -16,3 +16,25 @@ func FileExist(path string) (bool, error) {
 		return true, err
 	}
 }
+
+// WriteFileAtomic 先写入临时文件并落盘，再重命名为目标文件，避免崩溃时目标文件只写入了一半
+func WriteFileAtomic(path string, data []byte, tempSuffix string) error {
+	tempPath := path + "." + tempSuffix
+	f, err := os.Create(tempPath)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	closeErr := f.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tempPath)
+		return err
+	}
+	return os.Rename(tempPath, path)
+}